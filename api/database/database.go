@@ -156,6 +156,7 @@ func SetupDatabase() (*gorm.DB, error) {
 var database_models []interface{} = []interface{}{
 	&models.User{},
 	&models.AccessToken{},
+	&models.APIKey{},
 	&models.SiteInfo{},
 	&models.Media{},
 	&models.MediaURL{},
@@ -166,6 +167,16 @@ var database_models []interface{} = []interface{}{
 	&models.UserMediaData{},
 	&models.UserAlbums{},
 	&models.UserPreferences{},
+	&models.MediaStack{},
+	&models.MediaMetadataEdit{},
+	&models.AuditEvent{},
+	&models.AlbumGrant{},
+	&models.TwoFactorRecoveryCode{},
+	&models.TwoFactorChallenge{},
+	&models.FailedLoginAttempt{},
+	&models.LoginThrottle{},
+	&models.ShareTokenAccess{},
+	&models.ShareTokenClient{},
 
 	// Face detection
 	&models.FaceGroup{},
@@ -178,6 +189,9 @@ func MigrateDatabase(db *gorm.DB) error {
 		log.Printf("Setup UserAlbums join table failed: %v\n", err)
 	}
 
+	// Dates of MediaEXIF are converted to UTC, the dates saved before are the local time of the camera
+	exifDatesLocal := db.Migrator().HasTable(&models.MediaEXIF{}) && !db.Migrator().HasColumn(&models.MediaEXIF{}, "timezone_offset")
+
 	if err := db.AutoMigrate(database_models...); err != nil {
 		log.Printf("Auto migration failed: %v\n", err)
 	}
@@ -193,6 +207,12 @@ func MigrateDatabase(db *gorm.DB) error {
 		log.Printf("Failed to run exif fields migration: %v\n", err)
 	}
 
+	if exifDatesLocal {
+		if err := migrate_exif_dates_local(db); err != nil {
+			log.Printf("Failed to run exif dates migration: %v\n", err)
+		}
+	}
+
 	return nil
 }
 
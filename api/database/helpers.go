@@ -44,3 +44,23 @@ func DateExtract(db *gorm.DB, component DateComponent, attribute string) string
 
 	return result
 }
+
+// Floor is a helper function that is used to generate the proper SQL syntax
+// for rounding a numeric expression down to the nearest integer for different database backends.
+// SQLite has no FLOOR function unless compiled with math functions, so it is emulated by truncating
+// the value and correcting negative values that are not whole numbers.
+func Floor(db *gorm.DB, expression string) string {
+
+	var result string
+
+	switch drivers.GetDatabaseDriverType(db) {
+	case drivers.MYSQL, drivers.POSTGRES:
+		result = fmt.Sprintf("FLOOR(%s)", expression)
+	case drivers.SQLITE:
+		result = fmt.Sprintf("(CAST(%[1]s AS INTEGER) - ((%[1]s) < CAST(%[1]s AS INTEGER)))", expression)
+	default:
+		log.Panicf("unsupported database backend: %s", drivers.GetDatabaseDriverType(db))
+	}
+
+	return result
+}
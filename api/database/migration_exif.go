@@ -194,3 +194,14 @@ func migrate_exif_fields_flash(db *gorm.DB) error {
 
 	return nil
 }
+
+// Mark the dates of existing MediaEXIF as the local time of the camera,
+// such that the scanner reads them again from the media files and converts them to UTC
+func migrate_exif_dates_local(db *gorm.DB) error {
+	err := db.Model(&models.MediaEXIF{}).Where("date_shot IS NOT NULL").Update("date_shot_local", true).Error
+	if err != nil {
+		return errors.Wrap(err, "mark exif dates as local time")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package database_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/database"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(test_utils.IntegrationTestRun(m))
+}
+
+func TestMigrateExifDatesLocal(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	// Go back to the schema from before the dates were converted to UTC
+	for _, column := range []string{"timezone_offset", "date_shot_local"} {
+		if !assert.NoError(t, db.Migrator().DropColumn(&models.MediaEXIF{}, column)) {
+			return
+		}
+	}
+
+	now := time.Now()
+	dateShot := time.Date(2021, 9, 27, 16, 0, 0, 0, time.UTC)
+	assert.NoError(t, db.Exec("INSERT INTO media_exif (created_at, updated_at, date_shot) VALUES (?, ?, ?)", now, now, dateShot).Error)
+	assert.NoError(t, db.Exec("INSERT INTO media_exif (created_at, updated_at) VALUES (?, ?)", now, now).Error)
+
+	if !assert.NoError(t, database.MigrateDatabase(db)) {
+		return
+	}
+
+	var exifs []*models.MediaEXIF
+	if !assert.NoError(t, db.Order("id").Find(&exifs).Error) || !assert.Len(t, exifs, 2) {
+		return
+	}
+
+	assert.True(t, exifs[0].DateShotLocal, "existing dates are the local time of the camera")
+	assert.False(t, exifs[1].DateShotLocal, "exif without a date is left as is")
+
+	t.Run("Dates saved after the migration are not marked", func(t *testing.T) {
+		exif := models.MediaEXIF{DateShot: &dateShot}
+		if !assert.NoError(t, db.Create(&exif).Error) {
+			return
+		}
+
+		if !assert.NoError(t, database.MigrateDatabase(db)) {
+			return
+		}
+
+		assert.NoError(t, db.First(&exif, exif.ID).Error)
+		assert.False(t, exif.DateShotLocal)
+	})
+}
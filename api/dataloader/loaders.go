@@ -5,18 +5,23 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/photoview/photoview/api/graphql/models"
 	"gorm.io/gorm"
 )
 
 const loadersKey = "dataloaders"
 
 type Loaders struct {
-	MediaThumbnail      *MediaURLLoader
-	MediaHighres        *MediaURLLoader
-	MediaOriginal       *MediaURLLoader
-	MediaVideoWeb       *MediaURLLoader
-	UserFromAccessToken *UserLoader
-	UserMediaFavorite   *UserFavoritesLoader
+	MediaThumbnail        *MediaURLLoader
+	MediaHighres          *MediaURLLoader
+	MediaOriginal         *MediaURLLoader
+	MediaVideoWeb         *MediaURLLoader
+	MediaVideoPreview     *MediaURLLoader
+	MediaVideoSprite      *MediaURLLoader
+	MediaVideoSpriteIndex *MediaURLLoader
+	MediaMotionVideo      *MediaURLLoader
+	UserFromAccessToken   *UserLoader
+	UserMediaFavorite     *UserFavoritesLoader
 }
 
 func Middleware(db *gorm.DB) mux.MiddlewareFunc {
@@ -24,12 +29,16 @@ func Middleware(db *gorm.DB) mux.MiddlewareFunc {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 			ctx := context.WithValue(r.Context(), loadersKey, &Loaders{
-				MediaThumbnail:      NewThumbnailMediaURLLoader(db),
-				MediaHighres:        NewHighresMediaURLLoader(db),
-				MediaOriginal:       NewOriginalMediaURLLoader(db),
-				MediaVideoWeb:       NewVideoWebMediaURLLoader(db),
-				UserFromAccessToken: NewUserLoaderByToken(db),
-				UserMediaFavorite:   NewUserFavoriteLoader(db),
+				MediaThumbnail:        NewThumbnailMediaURLLoader(db),
+				MediaHighres:          NewHighresMediaURLLoader(db),
+				MediaOriginal:         NewOriginalMediaURLLoader(db),
+				MediaVideoWeb:         NewVideoWebMediaURLLoader(db),
+				MediaVideoPreview:     NewPurposeMediaURLLoader(db, models.VideoPreview),
+				MediaVideoSprite:      NewPurposeMediaURLLoader(db, models.VideoSprite),
+				MediaVideoSpriteIndex: NewPurposeMediaURLLoader(db, models.VideoSpriteIndex),
+				MediaMotionVideo:      NewPurposeMediaURLLoader(db, models.MotionVideo),
+				UserFromAccessToken:   NewUserLoaderByToken(db),
+				UserMediaFavorite:     NewUserFavoriteLoader(db),
 			})
 
 			r = r.WithContext(ctx)
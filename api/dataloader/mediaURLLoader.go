@@ -80,3 +80,14 @@ func NewVideoWebMediaURLLoader(db *gorm.DB) *MediaURLLoader {
 		}),
 	}
 }
+
+// NewPurposeMediaURLLoader makes a loader for the media urls of a single purpose
+func NewPurposeMediaURLLoader(db *gorm.DB, purpose models.MediaPurpose) *MediaURLLoader {
+	return &MediaURLLoader{
+		maxBatch: 100,
+		wait:     5 * time.Millisecond,
+		fetch: makeMediaURLLoader(db, func(query *gorm.DB) *gorm.DB {
+			return query.Where("purpose = ?", purpose)
+		}),
+	}
+}
@@ -18,28 +18,49 @@ var ErrUnauthorized = errors.New("unauthorized")
 // A private key for context that only this package can access. This is important
 // to prevent collisions between different context uses
 var userCtxKey = &contextKey{"user"}
+var tokenCtxKey = &contextKey{"token"}
+var apiKeyCtxKey = &contextKey{"api_key"}
 
 type contextKey struct {
 	name string
 }
 
-// Middleware decodes the share session cookie and packs the session into context
-func Middleware(db *gorm.DB) func(http.Handler) http.Handler {
+// Middleware decodes the share session cookie and packs the session into context.
+// An access token or API key can also be passed as a bearer token in the Authorization header.
+// If proxyAuth is not nil, the user set in its header by a trusted proxy is used instead of the auth-token cookie.
+func Middleware(db *gorm.DB, proxyAuth *ProxyAuth) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-			if tokenCookie, err := r.Cookie("auth-token"); err == nil {
-				user, err := dataloader.For(r.Context()).UserFromAccessToken.Load(tokenCookie.Value)
-				// user, err := models.VerifyTokenAndGetUser(db, tokenCookie.Value)
+			r = r.WithContext(models.ContextWithSessionClient(r.Context(), proxyAuth.ClientFromRequest(r)))
+
+			if username := proxyAuth.username(r); username != "" {
+				user, err := proxyAuth.user(db.WithContext(r.Context()), username)
+				if err != nil {
+					log.Printf("Invalid proxy authentication: %s\n", err)
+					http.Error(w, "unknown user", http.StatusForbidden)
+					return
+				}
+
+				ctx, err := addTwoFactorRequirement(AddUserToContext(r.Context(), user), db, user)
+				if err != nil {
+					log.Printf("Could not check two-factor requirement: %s\n", err)
+					http.Error(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if token, found := requestToken(r); found {
+				ctx, err := authorizeToken(r.Context(), db, token)
 				if err != nil {
 					log.Printf("Invalid token: %s\n", err)
 					http.Error(w, "invalid authorization token", http.StatusForbidden)
 					return
 				}
 
-				// put it in context
-				ctx := AddUserToContext(r.Context(), user)
-
 				// and call the next with our new context
 				r = r.WithContext(ctx)
 			} else {
@@ -51,12 +72,58 @@ func Middleware(db *gorm.DB) func(http.Handler) http.Handler {
 	}
 }
 
+// requestToken returns the bearer token of the Authorization header if present, otherwise the value of the auth-token cookie
+func requestToken(r *http.Request) (string, bool) {
+	if bearer := r.Header.Get("Authorization"); bearer != "" {
+		if token, err := TokenFromBearer(&bearer); err == nil {
+			return *token, true
+		}
+	}
+
+	if tokenCookie, err := r.Cookie("auth-token"); err == nil {
+		return tokenCookie.Value, true
+	}
+
+	return "", false
+}
+
+// authorizeToken verifies an access token or API key, and puts the user it belongs to in the context
+func authorizeToken(ctx context.Context, db *gorm.DB, token string) (context.Context, error) {
+	if models.IsAPIKey(token) {
+		apiKey, err := models.VerifyAPIKey(db, token)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, err = addTwoFactorRequirement(AddUserToContext(ctx, &apiKey.User), db, &apiKey.User)
+		if err != nil {
+			return nil, err
+		}
+		return context.WithValue(ctx, apiKeyCtxKey, apiKey), nil
+	}
+
+	user, err := dataloader.For(ctx).UserFromAccessToken.Load(token)
+	// user, err := models.VerifyTokenAndGetUser(db, token)
+	if err != nil {
+		return nil, err
+	}
+
+	touchSession(db, token, ClientFromContext(ctx))
+
+	// put it in context
+	ctx, err = addTwoFactorRequirement(AddUserToContext(ctx, user), db, user)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, tokenCtxKey, token), nil
+}
+
 func AddUserToContext(ctx context.Context, user *models.User) context.Context {
 	return context.WithValue(ctx, userCtxKey, user)
 }
 
 func TokenFromBearer(bearer *string) (*string, error) {
-	regex, _ := regexp.Compile("^(?i)Bearer ([a-zA-Z0-9]{24})$")
+	regex, _ := regexp.Compile("^(?i:Bearer) ([a-zA-Z0-9]{24}|" + models.APIKeyPrefix + "[a-zA-Z0-9]{40})$")
 	matches := regex.FindStringSubmatch(*bearer)
 	if len(matches) != 2 {
 		return nil, errors.New("invalid bearer format")
@@ -66,6 +133,32 @@ func TokenFromBearer(bearer *string) (*string, error) {
 	return &token, nil
 }
 
+// TokenFromContext finds the access token used to authorize the request, or nil if none was used
+func TokenFromContext(ctx context.Context) *string {
+	token, ok := ctx.Value(tokenCtxKey).(string)
+	if !ok {
+		return nil
+	}
+	return &token
+}
+
+// ClientFromContext finds the client that sent the request. REQUIRES Middleware to have run.
+func ClientFromContext(ctx context.Context) models.SessionClient {
+	return models.SessionClientFromContext(ctx)
+}
+
+// APIKeyFromContext finds the API key used to authorize the request, or nil if the request was not authorized by one
+func APIKeyFromContext(ctx context.Context) *models.APIKey {
+	apiKey, _ := ctx.Value(apiKeyCtxKey).(*models.APIKey)
+	return apiKey
+}
+
+// HasScope returns false if the request was authorized by an API key that has not been granted the scope
+func HasScope(ctx context.Context, scope models.APIKeyScope) bool {
+	apiKey := APIKeyFromContext(ctx)
+	return apiKey == nil || apiKey.HasScope(scope)
+}
+
 // UserFromContext finds the user from the context. REQUIRES Middleware to have run.
 func UserFromContext(ctx context.Context) *models.User {
 	raw, _ := ctx.Value(userCtxKey).(*models.User)
@@ -75,6 +168,11 @@ func UserFromContext(ctx context.Context) *models.User {
 func AuthWebsocketInit(db *gorm.DB) func(context.Context, handler.InitPayload) (context.Context, error) {
 	return func(ctx context.Context, initPayload handler.InitPayload) (context.Context, error) {
 
+		// the upgrade request was authenticated by a trusted proxy
+		if UserFromContext(ctx) != nil && TokenFromContext(ctx) == nil && APIKeyFromContext(ctx) == nil {
+			return ctx, nil
+		}
+
 		bearer, exists := initPayload["Authorization"].(string)
 		if !exists {
 			return ctx, nil
@@ -86,16 +184,12 @@ func AuthWebsocketInit(db *gorm.DB) func(context.Context, handler.InitPayload) (
 			return nil, err
 		}
 
-		user, err := dataloader.For(ctx).UserFromAccessToken.Load(*token)
-		// user, err := models.VerifyTokenAndGetUser(db, *token)
+		userCtx, err := authorizeToken(ctx, db, *token)
 		if err != nil {
 			log.Printf("Invalid token in websocket: %s\n", err)
 			return nil, errors.New("invalid authorization token")
 		}
 
-		// put it in context
-		userCtx := context.WithValue(ctx, userCtxKey, user)
-
 		// and return it so the resolvers can see it
 		return userCtx, nil
 	}
@@ -1,6 +1,7 @@
 package auth_test
 
 import (
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -10,7 +11,7 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	os.Exit(test_utils.UnitTestRun(m))
+	os.Exit(test_utils.IntegrationTestRun(m))
 }
 
 func TestTokenFromBearer(t *testing.T) {
@@ -26,6 +27,9 @@ func TestTokenFromBearer(t *testing.T) {
 		{"Missing bearer start", "ZY9YfxFa3TapSAD37XUBFryo", "", false},
 		{"Empty input", "", "", false},
 		{"Invalid token value", "Bearer THIS_IS_INVALID", "", false},
+		{"Valid api key", "Bearer pvk_Qm3Xk8sT1vZb4NwR7yLc2HdF9gJpA5eU6iOq0WtE", "pvk_Qm3Xk8sT1vZb4NwR7yLc2HdF9gJpA5eU6iOq0WtE", true},
+		{"Api key of wrong length", "Bearer pvk_Qm3Xk8sT1vZb4NwR7yLc", "", false},
+		{"Api key with wrong prefix", "Bearer abc_Qm3Xk8sT1vZb4NwR7yLc2HdF9gJpA5eU6iOq0WtE", "", false},
 	}
 
 	for _, test := range testsValues {
@@ -42,3 +46,16 @@ func TestTokenFromBearer(t *testing.T) {
 		})
 	}
 }
+
+func TestClientFromRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/graphql", nil)
+	req.RemoteAddr = "192.168.1.10:54321"
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	client := auth.ClientFromRequest(req)
+	assert.Equal(t, "192.168.1.10", client.IPAddress)
+	assert.Equal(t, "Mozilla/5.0", client.UserAgent)
+
+	req.RemoteAddr = "[::1]:54321"
+	assert.Equal(t, "::1", auth.ClientFromRequest(req).IPAddress)
+}
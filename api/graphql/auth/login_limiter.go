@@ -0,0 +1,453 @@
+package auth
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginKeyIP is the login limiter key of the failed attempts from an ip address
+func LoginKeyIP(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// LoginKeyUser is the login limiter key of the failed attempts to log in as a user
+func LoginKeyUser(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// LoginKeyShareToken is the login limiter key of the failed attempts to guess the password of a share token
+func LoginKeyShareToken(shareTokenID int) string {
+	return fmt.Sprintf("share_token:%d", shareTokenID)
+}
+
+// LoginLimitPolicy describes how failed login attempts are throttled
+type LoginLimitPolicy struct {
+	// FreeAttempts is the number of failed attempts before they are delayed
+	FreeAttempts int
+	// BaseDelay is the delay after the first delayed attempt, it is doubled for every further failed attempt
+	BaseDelay time.Duration
+	// MaxAttempts is the number of failed attempts for an account or share before it is locked out
+	MaxAttempts int
+	// MaxAttemptsPerIP is the number of failed attempts from an ip address before it is locked out
+	MaxAttemptsPerIP int
+	// LockoutDuration is how long a lockout lasts, failed attempts are also forgotten after this long
+	LockoutDuration time.Duration
+}
+
+func DefaultLoginLimitPolicy() LoginLimitPolicy {
+	return LoginLimitPolicy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxAttempts:      10,
+		MaxAttemptsPerIP: 50,
+		LockoutDuration:  15 * time.Minute,
+	}
+}
+
+func (p LoginLimitPolicy) maxAttempts(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return p.MaxAttemptsPerIP
+	}
+	return p.MaxAttempts
+}
+
+// LoginBlockedError is returned when a login is rejected because of too many failed attempts
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again in %s", (e.RetryAfter + time.Second - 1).Truncate(time.Second))
+}
+
+// Number of locks the keys of the login limiter are spread over
+const loginLimiterKeyLocks = 64
+
+// LoginLimiter throttles failed login attempts by key, with an exponential backoff and a temporary lockout
+type LoginLimiter struct {
+	policy LoginLimitPolicy
+	store  loginThrottleStore
+	now    func() time.Time
+
+	// lock guards pending, which counts the attempts that have passed the check but not finished yet
+	lock    sync.Mutex
+	pending map[string]int
+
+	// keyLocks serialize the updates of the throttle of a key, such that concurrent failures are all counted,
+	// without holding the global lock while the store is accessed
+	keyLocks [loginLimiterKeyLocks]sync.Mutex
+}
+
+// NewLoginLimiter returns a limiter that keeps the failed attempts in memory,
+// or in the database if db is not nil
+func NewLoginLimiter(policy LoginLimitPolicy, db *gorm.DB) *LoginLimiter {
+	var store loginThrottleStore = &memoryThrottleStore{throttles: make(map[string]models.LoginThrottle)}
+	if db != nil {
+		store = &databaseThrottleStore{db: db}
+	}
+
+	return &LoginLimiter{
+		policy:  policy,
+		store:   store,
+		now:     time.Now,
+		pending: make(map[string]int),
+	}
+}
+
+// LoginAttempt is an attempt that has passed the check of the login limiter.
+// It must be finished by calling Failure or Done, Done may safely be deferred.
+type LoginAttempt struct {
+	limiter  *LoginLimiter
+	keys     []string
+	finished bool
+}
+
+// Attempt checks that attempts for the keys are not blocked, and reserves an attempt for each of them.
+// Attempts in progress count towards the limits, such that concurrent attempts can not all pass the check
+// before their failures are recorded. A LoginBlockedError is returned if an attempt is not allowed.
+func (l *LoginLimiter) Attempt(keys ...string) (*LoginAttempt, error) {
+	// failures can not be recorded for the keys until the attempt is reserved,
+	// otherwise a failure recorded after the throttles are read could be missed
+	unlock := l.lockKeys(keys)
+	defer unlock()
+
+	throttles := make([]*models.LoginThrottle, len(keys))
+	for i, key := range keys {
+		throttle, err := l.store.get(key)
+		if err != nil {
+			return nil, err
+		}
+		throttles[i] = throttle
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	var retryAfter time.Duration
+
+	for i, key := range keys {
+		throttle := throttles[i]
+
+		failures := 0
+		if throttle != nil && now.Sub(throttle.LastFailure) < l.policy.LockoutDuration {
+			failures = throttle.Failures
+		}
+
+		if throttle != nil && throttle.BlockedUntil != nil && throttle.BlockedUntil.After(now) {
+			if wait := throttle.BlockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		} else if l.pending[key] >= l.policy.concurrentAttempts(key, failures) {
+			if retryAfter < l.policy.BaseDelay {
+				retryAfter = l.policy.BaseDelay
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		return nil, &LoginBlockedError{RetryAfter: retryAfter}
+	}
+
+	for _, key := range keys {
+		l.pending[key]++
+	}
+
+	return &LoginAttempt{limiter: l, keys: keys}, nil
+}
+
+// concurrentAttempts is the number of attempts for the key that may be in progress at the same time,
+// such that they can not make more failures than allowed if they were made one after another
+func (p LoginLimitPolicy) concurrentAttempts(key string, failures int) int {
+	allowed := p.FreeAttempts - failures
+	if remaining := p.maxAttempts(key) - failures; remaining < allowed {
+		allowed = remaining
+	}
+
+	if allowed < 1 {
+		return 1
+	}
+	return allowed
+}
+
+// Failure records that the attempt failed, and blocks further attempts for a while once there are too many
+func (a *LoginAttempt) Failure() error {
+	if a.finished {
+		return nil
+	}
+	defer a.Done()
+
+	for _, key := range a.keys {
+		if err := a.limiter.recordFailure(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Done ends the attempt without recording a failure, it does nothing if the attempt has already ended
+func (a *LoginAttempt) Done() {
+	if a.finished {
+		return
+	}
+	a.finished = true
+
+	l := a.limiter
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, key := range a.keys {
+		if l.pending[key] <= 1 {
+			delete(l.pending, key)
+		} else {
+			l.pending[key]--
+		}
+	}
+}
+
+// keyLock returns the index of the lock that serializes the updates of the throttle of the key
+func keyLock(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % loginLimiterKeyLocks)
+}
+
+// lockKeys takes the locks of all the keys, in a fixed order to avoid deadlocks, and returns a function that releases them
+func (l *LoginLimiter) lockKeys(keys []string) func() {
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		indexes = append(indexes, keyLock(key))
+	}
+	sort.Ints(indexes)
+
+	locked := make([]int, 0, len(indexes))
+	for i, index := range indexes {
+		if i > 0 && indexes[i-1] == index {
+			continue
+		}
+		l.keyLocks[index].Lock()
+		locked = append(locked, index)
+	}
+
+	return func() {
+		for _, index := range locked {
+			l.keyLocks[index].Unlock()
+		}
+	}
+}
+
+// recordFailure counts a failed attempt for the key, and blocks the key if there have been too many
+func (l *LoginLimiter) recordFailure(key string) error {
+	unlock := l.lockKeys([]string{key})
+	defer unlock()
+
+	now := l.now()
+
+	throttle, err := l.store.get(key)
+	if err != nil {
+		return err
+	}
+
+	if throttle == nil || now.Sub(throttle.LastFailure) >= l.policy.LockoutDuration {
+		throttle = &models.LoginThrottle{Key: key}
+	}
+
+	throttle.Failures++
+	throttle.LastFailure = now
+	throttle.BlockedUntil = nil
+
+	if throttle.Failures >= l.policy.maxAttempts(key) {
+		blockedUntil := now.Add(l.policy.LockoutDuration)
+		throttle.BlockedUntil = &blockedUntil
+	} else if delayed := throttle.Failures - l.policy.FreeAttempts; delayed > 0 {
+		delay := l.policy.LockoutDuration
+		if delayed < 32 && l.policy.BaseDelay<<(delayed-1) < delay {
+			delay = l.policy.BaseDelay << (delayed - 1)
+		}
+
+		blockedUntil := now.Add(delay)
+		throttle.BlockedUntil = &blockedUntil
+	}
+
+	return l.store.save(throttle)
+}
+
+// Reset forgets the failed attempts of the keys, eg. after a successful login
+func (l *LoginLimiter) Reset(keys ...string) error {
+	for _, key := range keys {
+		unlock := l.lockKeys([]string{key})
+		err := l.store.delete(key)
+		unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cleanup forgets failed attempts that are older than the lockout duration
+func (l *LoginLimiter) Cleanup() error {
+	return l.store.deleteBefore(l.now().Add(-l.policy.LockoutDuration))
+}
+
+type loginThrottleStore interface {
+	// get returns the throttle of the key, or nil if there have been no recent failures
+	get(key string) (*models.LoginThrottle, error)
+	save(throttle *models.LoginThrottle) error
+	delete(key string) error
+	// deleteBefore deletes the throttles with a last failure before the time
+	deleteBefore(before time.Time) error
+}
+
+type memoryThrottleStore struct {
+	lock      sync.Mutex
+	throttles map[string]models.LoginThrottle
+}
+
+func (s *memoryThrottleStore) get(key string) (*models.LoginThrottle, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	throttle, found := s.throttles[key]
+	if !found {
+		return nil, nil
+	}
+	return &throttle, nil
+}
+
+func (s *memoryThrottleStore) save(throttle *models.LoginThrottle) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.throttles[throttle.Key] = *throttle
+	return nil
+}
+
+func (s *memoryThrottleStore) delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.throttles, key)
+	return nil
+}
+
+func (s *memoryThrottleStore) deleteBefore(before time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key, throttle := range s.throttles {
+		if throttle.LastFailure.Before(before) {
+			delete(s.throttles, key)
+		}
+	}
+	return nil
+}
+
+type databaseThrottleStore struct {
+	db *gorm.DB
+}
+
+func (s *databaseThrottleStore) get(key string) (*models.LoginThrottle, error) {
+	var throttles []*models.LoginThrottle
+	if err := s.db.Where("limiter_key = ?", key).Limit(1).Find(&throttles).Error; err != nil {
+		return nil, errors.Wrap(err, "get login throttle from database")
+	}
+
+	if len(throttles) == 0 {
+		return nil, nil
+	}
+	return throttles[0], nil
+}
+
+func (s *databaseThrottleStore) save(throttle *models.LoginThrottle) error {
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "limiter_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"failures", "last_failure", "blocked_until"}),
+	}).Create(throttle).Error
+	if err != nil {
+		return errors.Wrap(err, "save login throttle to database")
+	}
+
+	return nil
+}
+
+func (s *databaseThrottleStore) delete(key string) error {
+	if err := s.db.Where("limiter_key = ?", key).Delete(&models.LoginThrottle{}).Error; err != nil {
+		return errors.Wrap(err, "delete login throttle from database")
+	}
+	return nil
+}
+
+func (s *databaseThrottleStore) deleteBefore(before time.Time) error {
+	if err := s.db.Where("last_failure < ?", before).Delete(&models.LoginThrottle{}).Error; err != nil {
+		return errors.Wrap(err, "delete old login throttles from database")
+	}
+	return nil
+}
+
+var loginLimiter = NewLoginLimiter(DefaultLoginLimitPolicy(), nil)
+
+// InitializeLoginLimiter configures the login limiter from the environment variables
+func InitializeLoginLimiter(db *gorm.DB) error {
+	policy, err := loginLimitPolicyFromEnv()
+	if err != nil {
+		return err
+	}
+
+	var storeDB *gorm.DB
+	if utils.EnvLoginPersistLimiter.GetBool() {
+		storeDB = db
+	}
+
+	loginLimiter = NewLoginLimiter(policy, storeDB)
+	return nil
+}
+
+func loginLimitPolicyFromEnv() (LoginLimitPolicy, error) {
+	policy := DefaultLoginLimitPolicy()
+	lockoutMinutes := int(policy.LockoutDuration.Minutes())
+
+	settings := []struct {
+		variable utils.EnvironmentVariable
+		value    *int
+	}{
+		{utils.EnvLoginMaxAttempts, &policy.MaxAttempts},
+		{utils.EnvLoginMaxAttemptsPerIP, &policy.MaxAttemptsPerIP},
+		{utils.EnvLoginLockoutMinutes, &lockoutMinutes},
+	}
+
+	for _, setting := range settings {
+		value := strings.TrimSpace(setting.variable.GetValue())
+		if value == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 1 {
+			return policy, errors.Errorf("%s must be a positive number, got %s", setting.variable.GetName(), value)
+		}
+		*setting.value = number
+	}
+
+	policy.LockoutDuration = time.Duration(lockoutMinutes) * time.Minute
+	return policy, nil
+}
+
+// GetLoginLimiter returns the limiter used for logins and share token passwords
+func GetLoginLimiter() *LoginLimiter {
+	return loginLimiter
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewLoginLimiter(LoginLimitPolicy{
+		FreeAttempts:     2,
+		BaseDelay:        time.Second,
+		MaxAttempts:      5,
+		MaxAttemptsPerIP: 8,
+		LockoutDuration:  10 * time.Minute,
+	}, nil)
+	limiter.now = func() time.Time { return now }
+
+	user := LoginKeyUser("Alice")
+	ip := LoginKeyIP("10.0.0.1")
+
+	// retryAfter returns how long the keys are blocked, or 0 if they are not
+	retryAfter := func(keys ...string) time.Duration {
+		attempt, err := limiter.Attempt(keys...)
+		if err == nil {
+			attempt.Done()
+			return 0
+		}
+
+		blocked, ok := err.(*LoginBlockedError)
+		if !assert.True(t, ok, "expected a LoginBlockedError, got %v", err) {
+			return 0
+		}
+		return blocked.RetryAfter
+	}
+
+	// fail records a failed attempt for the keys, even if they are blocked
+	fail := func(keys ...string) {
+		for _, key := range keys {
+			assert.NoError(t, limiter.recordFailure(key))
+		}
+	}
+
+	t.Run("Free attempts are not delayed", func(t *testing.T) {
+		fail(user, ip)
+		fail(user, ip)
+		assert.Zero(t, retryAfter(user, ip))
+	})
+
+	t.Run("Delay doubles for every failure", func(t *testing.T) {
+		fail(user, ip)
+		assert.Equal(t, time.Second, retryAfter(user, ip))
+
+		now = now.Add(time.Second)
+		assert.Zero(t, retryAfter(user, ip))
+
+		fail(user, ip)
+		assert.Equal(t, 2*time.Second, retryAfter(user))
+		assert.Equal(t, 2*time.Second, retryAfter(LoginKeyUser("alice")), "usernames are case insensitive")
+	})
+
+	t.Run("Locked out after max attempts", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		fail(user, ip)
+		assert.Equal(t, 10*time.Minute, retryAfter(user))
+		assert.Equal(t, 4*time.Second, retryAfter(ip), "the ip address has its own limit")
+
+		_, err := limiter.Attempt(user)
+		assert.EqualError(t, err, "too many failed attempts, try again in 10m0s")
+	})
+
+	t.Run("Failures are forgotten after the lockout", func(t *testing.T) {
+		now = now.Add(10 * time.Minute)
+		assert.Zero(t, retryAfter(user, ip))
+
+		fail(user)
+		assert.Zero(t, retryAfter(user), "counts from one again")
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		other := LoginKeyUser("bob")
+		for i := 0; i < 5; i++ {
+			fail(other)
+		}
+		assert.NotZero(t, retryAfter(other))
+
+		assert.NoError(t, limiter.Reset(other))
+		assert.Zero(t, retryAfter(other))
+	})
+
+	t.Run("Attempts in progress count towards the limit", func(t *testing.T) {
+		other := LoginKeyUser("carol")
+
+		first, err := limiter.Attempt(other)
+		assert.NoError(t, err)
+		second, err := limiter.Attempt(other)
+		assert.NoError(t, err)
+
+		_, err = limiter.Attempt(other)
+		assert.Error(t, err, "only the free attempts may be in progress at once")
+
+		assert.NoError(t, first.Failure())
+		assert.NoError(t, second.Failure())
+
+		third, err := limiter.Attempt(other)
+		assert.NoError(t, err)
+
+		_, err = limiter.Attempt(other)
+		assert.Error(t, err, "delayed attempts are made one at a time")
+
+		third.Done()
+		assert.Zero(t, retryAfter(other), "a finished attempt does not count as a failure")
+		assert.NoError(t, limiter.Reset(other))
+	})
+
+	t.Run("Concurrent failures", func(t *testing.T) {
+		other := LoginKeyUser("dave")
+
+		var passed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				attempt, err := limiter.Attempt(other)
+				if err != nil {
+					return
+				}
+				atomic.AddInt32(&passed, 1)
+				assert.NoError(t, attempt.Failure())
+			}()
+		}
+		wg.Wait()
+
+		assert.GreaterOrEqual(t, int(passed), 1)
+		assert.LessOrEqual(t, int(passed), 3, "no more attempts than when they are made one after another")
+		assert.NoError(t, limiter.Reset(other))
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		store := limiter.store.(*memoryThrottleStore)
+		assert.NotEmpty(t, store.throttles)
+
+		now = now.Add(11 * time.Minute)
+		assert.NoError(t, limiter.Cleanup())
+		assert.Empty(t, store.throttles)
+	})
+}
+
+func TestLoginLimitPolicyFromEnv(t *testing.T) {
+	t.Setenv("PHOTOVIEW_LOGIN_MAX_ATTEMPTS", "4")
+	t.Setenv("PHOTOVIEW_LOGIN_LOCKOUT_MINUTES", "30")
+
+	policy, err := loginLimitPolicyFromEnv()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 4, policy.MaxAttempts)
+	assert.Equal(t, DefaultLoginLimitPolicy().MaxAttemptsPerIP, policy.MaxAttemptsPerIP)
+	assert.Equal(t, 30*time.Minute, policy.LockoutDuration)
+
+	t.Setenv("PHOTOVIEW_LOGIN_MAX_ATTEMPTS_PER_IP", "zero")
+	_, err = loginLimitPolicyFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ProxyAuth configures authentication by a header set by an authenticating reverse proxy
+type ProxyAuth struct {
+	// Header holds the username of the user that the proxy has authenticated
+	Header string
+	// TrustedProxies are the networks that requests with the header are trusted from
+	TrustedProxies []*net.IPNet
+	// AutoCreate creates users that do not exist yet, instead of rejecting the request
+	AutoCreate bool
+}
+
+// ProxyAuthFromEnv reads the reverse proxy authentication from the environment variables,
+// it returns nil if it has not been enabled
+func ProxyAuthFromEnv() (*ProxyAuth, error) {
+	header := strings.TrimSpace(utils.EnvProxyAuthHeader.GetValue())
+	if header == "" {
+		return nil, nil
+	}
+
+	trustedProxies, err := ParseTrustedProxies(utils.EnvProxyAuthTrustedProxies.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(trustedProxies) == 0 {
+		return nil, errors.Errorf("%s must be set when %s is set", utils.EnvProxyAuthTrustedProxies.GetName(), utils.EnvProxyAuthHeader.GetName())
+	}
+
+	return &ProxyAuth{
+		Header:         header,
+		TrustedProxies: trustedProxies,
+		AutoCreate:     utils.EnvProxyAuthAutoCreate.GetBool(),
+	}, nil
+}
+
+// ParseTrustedProxies parses a comma separated list of ip addresses and CIDR ranges
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, errors.Errorf("invalid trusted proxy ip address: %s", entry)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trusted proxy range: %s", entry)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// Trusts returns true if the request was sent directly by one of the trusted proxies
+func (p *ProxyAuth) Trusts(r *http.Request) bool {
+	return p.trustsAddress(ClientFromRequest(r).IPAddress)
+}
+
+func (p *ProxyAuth) trustsAddress(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range p.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientFromRequest returns the client of the request like the package level ClientFromRequest,
+// except that the ip address of requests forwarded by a trusted proxy is the address the proxy received the request from,
+// taken from the X-Forwarded-For or X-Real-IP header
+func (p *ProxyAuth) ClientFromRequest(r *http.Request) models.SessionClient {
+	client := ClientFromRequest(r)
+	if p == nil || !p.Trusts(r) {
+		return client
+	}
+
+	// the proxies append the address they received the request from, so the rightmost untrusted address is the client,
+	// addresses to the left of it may have been set by the client itself
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		addresses := strings.Split(forwardedFor, ",")
+		for i := len(addresses) - 1; i >= 0; i-- {
+			address := strings.TrimSpace(addresses[i])
+			if net.ParseIP(address) == nil {
+				break
+			}
+
+			client.IPAddress = address
+			if !p.trustsAddress(address) {
+				break
+			}
+		}
+
+		return client
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		client.IPAddress = realIP
+	}
+
+	return client
+}
+
+// username returns the username set by the proxy, or an empty string if the request was not authenticated by a trusted proxy
+func (p *ProxyAuth) username(r *http.Request) string {
+	if p == nil || !p.Trusts(r) {
+		return ""
+	}
+
+	return strings.TrimSpace(r.Header.Get(p.Header))
+}
+
+// user finds the user with the username set by the proxy, creating it if allowed
+func (p *ProxyAuth) user(db *gorm.DB, username string) (*models.User, error) {
+	var users []*models.User
+	if err := db.Where("username = ?", username).Limit(1).Find(&users).Error; err != nil {
+		return nil, errors.Wrap(err, "get proxy authenticated user from database")
+	}
+
+	if len(users) > 0 {
+		return users[0], nil
+	}
+
+	if !p.AutoCreate {
+		return nil, errors.Errorf("user %s set by the authenticating proxy does not exist", username)
+	}
+
+	var user *models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = models.RegisterUser(tx, username, nil, false)
+		if err != nil {
+			return err
+		}
+
+		return models.RecordAuditEvent(tx, nil, models.AuditActionCreateUser, models.AuditTargetUser(user), map[string]interface{}{
+			"username": username,
+			"source":   "proxy_auth",
+		})
+	})
+
+	if err != nil {
+		// the user may have been created by a concurrent request
+		if findErr := db.Where("username = ?", username).First(&user).Error; findErr == nil {
+			return user, nil
+		}
+		return nil, errors.Wrap(err, "create proxy authenticated user")
+	}
+
+	return user, nil
+}
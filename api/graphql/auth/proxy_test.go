@@ -0,0 +1,181 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/handler"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	networks, err := auth.ParseTrustedProxies(" 10.0.0.0/8, 127.0.0.1,,::1 ")
+	if assert.NoError(t, err) && assert.Len(t, networks, 3) {
+		assert.Equal(t, "10.0.0.0/8", networks[0].String())
+		assert.Equal(t, "127.0.0.1/32", networks[1].String())
+		assert.Equal(t, "::1/128", networks[2].String())
+	}
+
+	networks, err = auth.ParseTrustedProxies("")
+	assert.NoError(t, err)
+	assert.Empty(t, networks)
+
+	_, err = auth.ParseTrustedProxies("10.0.0.0/8,proxy")
+	assert.Error(t, err)
+
+	_, err = auth.ParseTrustedProxies("10.0.0.0/33")
+	assert.Error(t, err)
+}
+
+func TestProxyAuthTrusts(t *testing.T) {
+	networks, err := auth.ParseTrustedProxies("172.16.0.0/12,::1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	proxyAuth := auth.ProxyAuth{Header: "Remote-User", TrustedProxies: networks}
+
+	req := httptest.NewRequest("GET", "/", nil)
+
+	for remoteAddr, trusted := range map[string]bool{
+		"172.20.0.5:1234":  true,
+		"[::1]:1234":       true,
+		"192.168.1.5:1234": false,
+		"[::2]:1234":       false,
+		"invalid":          false,
+	} {
+		req.RemoteAddr = remoteAddr
+		assert.Equal(t, trusted, proxyAuth.Trusts(req), remoteAddr)
+	}
+}
+
+func TestProxyAuthClientFromRequest(t *testing.T) {
+	networks, err := auth.ParseTrustedProxies("172.16.0.0/12")
+	if !assert.NoError(t, err) {
+		return
+	}
+	proxyAuth := &auth.ProxyAuth{Header: "Remote-User", TrustedProxies: networks}
+
+	request := func(remoteAddr string, headers map[string]string) *http.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		return req
+	}
+
+	for _, test := range []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"forwarded by trusted proxy", "172.20.0.5:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+		{"spoofed address left of the client", "172.20.0.5:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.7, 172.20.0.9"}, "203.0.113.7"},
+		{"real ip header", "172.20.0.5:1234", map[string]string{"X-Real-IP": "203.0.113.7"}, "203.0.113.7"},
+		{"untrusted sender", "192.168.1.5:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "192.168.1.5"},
+		{"no forwarding headers", "172.20.0.5:1234", nil, "172.20.0.5"},
+	} {
+		client := proxyAuth.ClientFromRequest(request(test.remoteAddr, test.headers))
+		assert.Equal(t, test.expected, client.IPAddress, test.name)
+	}
+
+	var noProxy *auth.ProxyAuth
+	client := noProxy.ClientFromRequest(request("172.20.0.5:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}))
+	assert.Equal(t, "172.20.0.5", client.IPAddress, "headers are ignored if no proxies are trusted")
+}
+
+func TestProxyAuthMiddleware(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "alice", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	networks, err := auth.ParseTrustedProxies("10.0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	proxyAuth := &auth.ProxyAuth{Header: "Remote-User", TrustedProxies: networks}
+
+	// request returns the status and the user the middleware passed on
+	request := func(remoteAddr string, username string, cookie *http.Cookie) (int, *models.User) {
+		var contextUser *models.User
+		handler := auth.Middleware(db, proxyAuth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contextUser = auth.UserFromContext(r.Context())
+		}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Remote-User", username)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code, contextUser
+	}
+
+	t.Run("Trusted proxy", func(t *testing.T) {
+		status, contextUser := request("10.0.0.1:1234", "alice", &http.Cookie{Name: "auth-token", Value: "invalid"})
+		assert.Equal(t, http.StatusOK, status)
+		if assert.NotNil(t, contextUser) {
+			assert.Equal(t, user.ID, contextUser.ID)
+		}
+	})
+
+	t.Run("Untrusted client", func(t *testing.T) {
+		status, contextUser := request("10.0.0.2:1234", "alice", nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Nil(t, contextUser)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		status, contextUser := request("10.0.0.1:1234", "bob", nil)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Nil(t, contextUser)
+	})
+
+	t.Run("Auto create user", func(t *testing.T) {
+		proxyAuth.AutoCreate = true
+		defer func() { proxyAuth.AutoCreate = false }()
+
+		status, contextUser := request("10.0.0.1:1234", "bob", nil)
+		assert.Equal(t, http.StatusOK, status)
+		if !assert.NotNil(t, contextUser) {
+			return
+		}
+		assert.Equal(t, "bob", contextUser.Username)
+		assert.Nil(t, contextUser.Password)
+		assert.False(t, contextUser.Admin)
+
+		var event models.AuditEvent
+		if assert.NoError(t, db.Where("action = ? AND target_id = ?", models.AuditActionCreateUser, contextUser.ID).First(&event).Error) {
+			assert.Nil(t, event.UserID)
+			assert.Equal(t, "10.0.0.1", event.IPAddress)
+		}
+
+		status, secondUser := request("10.0.0.1:1234", "bob", nil)
+		assert.Equal(t, http.StatusOK, status)
+		if assert.NotNil(t, secondUser) {
+			assert.Equal(t, contextUser.ID, secondUser.ID)
+		}
+	})
+
+	t.Run("Websocket authenticated by proxy", func(t *testing.T) {
+		ctx := auth.AddUserToContext(context.Background(), user)
+
+		wsCtx, err := auth.AuthWebsocketInit(db)(ctx, handler.InitPayload{"Authorization": "Bearer invalid"})
+		if assert.NoError(t, err) {
+			assert.Equal(t, user, auth.UserFromContext(wsCtx))
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"gorm.io/gorm"
+)
+
+// How often the last used time of a session is saved, to avoid a database write on every request
+const sessionTouchThrottle = 5 * time.Minute
+
+// How often expired access tokens are deleted from the database
+const sessionCleanupInterval = time.Hour
+
+var sessionTouches = struct {
+	sync.Mutex
+	lastTouched map[string]time.Time
+}{
+	lastTouched: make(map[string]time.Time),
+}
+
+// ClientFromRequest returns the user agent and IP address of the client that sent the request
+func ClientFromRequest(r *http.Request) models.SessionClient {
+	ipAddress := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ipAddress = host
+	}
+
+	return models.SessionClient{
+		UserAgent: r.UserAgent(),
+		IPAddress: ipAddress,
+	}
+}
+
+// touchSession records that the access token was just used by the client,
+// at most once every sessionTouchThrottle for each token
+func touchSession(db *gorm.DB, token string, client models.SessionClient) {
+	now := time.Now()
+
+	sessionTouches.Lock()
+	lastTouched, found := sessionTouches.lastTouched[token]
+	if found && now.Sub(lastTouched) < sessionTouchThrottle {
+		sessionTouches.Unlock()
+		return
+	}
+	sessionTouches.lastTouched[token] = now
+	sessionTouches.Unlock()
+
+	updates := map[string]interface{}{
+		"last_used_at": now,
+		"ip_address":   client.IPAddress,
+	}
+
+	if client.UserAgent != "" {
+		updates["user_agent"] = client.UserAgent
+	}
+
+	if err := db.Model(&models.AccessToken{}).Where("value = ?", token).Updates(updates).Error; err != nil {
+		log.Printf("WARN: could not update last used time of session: %s\n", err)
+	}
+}
+
+// CleanupSessions deletes expired access tokens and two-factor challenges from the database,
+// and forgets old failed login attempts of the login limiter
+func CleanupSessions(db *gorm.DB) error {
+	deleted, err := models.DeleteExpiredAccessTokens(db)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("Deleted %d expired sessions\n", deleted)
+	}
+
+	if _, err := models.DeleteExpiredTwoFactorChallenges(db); err != nil {
+		return err
+	}
+
+	if err := loginLimiter.Cleanup(); err != nil {
+		return err
+	}
+
+	threshold := time.Now().Add(-sessionTouchThrottle)
+
+	sessionTouches.Lock()
+	defer sessionTouches.Unlock()
+
+	for token, lastTouched := range sessionTouches.lastTouched {
+		if lastTouched.Before(threshold) {
+			delete(sessionTouches.lastTouched, token)
+		}
+	}
+
+	return nil
+}
+
+// InitializeSessionCleanup starts a background job that periodically deletes expired access tokens
+func InitializeSessionCleanup(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(sessionCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := CleanupSessions(db); err != nil {
+				log.Printf("ERROR: cleaning up expired sessions: %s\n", err)
+			}
+
+			<-ticker.C
+		}
+	}()
+}
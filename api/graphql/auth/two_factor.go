@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"gorm.io/gorm"
+)
+
+var twoFactorPendingCtxKey = &contextKey{"two_factor_pending"}
+
+// addTwoFactorRequirement marks the context if the user is an admin that has not enabled two-factor authentication,
+// while the site requires it for admins
+func addTwoFactorRequirement(ctx context.Context, db *gorm.DB, user *models.User) (context.Context, error) {
+	if !user.Admin || user.TOTPEnabled {
+		return ctx, nil
+	}
+
+	siteInfo, err := models.GetSiteInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if !siteInfo.RequireAdminTwoFactor {
+		return ctx, nil
+	}
+
+	return context.WithValue(ctx, twoFactorPendingCtxKey, true), nil
+}
+
+// TwoFactorPending returns true if the user of the request is an admin that must enable two-factor authentication,
+// before admin features can be used
+func TwoFactorPending(ctx context.Context) bool {
+	pending, _ := ctx.Value(twoFactorPendingCtxKey).(bool)
+	return pending
+}
@@ -0,0 +1,65 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/photoview/photoview/api/dataloader"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTwoFactorPending(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	admin, err := models.RegisterUser(db, "admin", &password, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	user, err := models.RegisterUser(db, "user", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// pending returns whether the request of the user was marked as pending two-factor authentication
+	pending := func(user *models.User) bool {
+		token, err := user.GenerateAccessToken(db)
+		if !assert.NoError(t, err) {
+			return false
+		}
+
+		var result bool
+		handler := dataloader.Middleware(db)(auth.Middleware(db, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.NotNil(t, auth.UserFromContext(r.Context()))
+			result = auth.TwoFactorPending(r.Context())
+		})))
+
+		req := httptest.NewRequest("POST", "/api/graphql", nil)
+		req.AddCookie(&http.Cookie{Name: "auth-token", Value: token.Value})
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		return result
+	}
+
+	assert.False(t, pending(admin), "not required")
+
+	if _, err := models.GetSiteInfo(db); !assert.NoError(t, err) {
+		return
+	}
+	err = db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.SiteInfo{}).Update("require_admin_two_factor", true).Error
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, pending(admin))
+	assert.False(t, pending(user), "not an admin")
+
+	assert.NoError(t, db.Model(admin).Update("totp_enabled", true).Error)
+	assert.False(t, pending(admin), "enabled two-factor authentication")
+}
@@ -3,17 +3,32 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
 )
 
+var errTwoFactorPending = errors.New("two-factor authentication must be enabled to use admin features")
+
 func IsAdmin(ctx context.Context, obj interface{}, next graphql.Resolver) (res interface{}, err error) {
 	user := auth.UserFromContext(ctx)
 	if user == nil || user.Admin == false {
 		return nil, errors.New("user must be admin")
 	}
 
+	if auth.TwoFactorPending(ctx) {
+		return nil, errTwoFactorPending
+	}
+
+	// Fields with a scope of their own are checked by HasScope instead
+	if fieldContext := graphql.GetFieldContext(ctx); fieldContext == nil || fieldContext.Field.Definition.Directives.ForName("hasScope") == nil {
+		if !auth.HasScope(ctx, models.APIKeyScopeAdmin) {
+			return nil, errors.New("api key does not have the ADMIN scope")
+		}
+	}
+
 	return next(ctx)
 }
 
@@ -25,3 +40,51 @@ func IsAuthorized(ctx context.Context, obj interface{}, next graphql.Resolver) (
 
 	return next(ctx)
 }
+
+func IsRetoucher(ctx context.Context, obj interface{}, next graphql.Resolver) (res interface{}, err error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	if !user.Admin && !user.Retoucher {
+		return nil, errors.New("user must be a retoucher")
+	}
+
+	if user.Admin && auth.TwoFactorPending(ctx) {
+		return nil, errTwoFactorPending
+	}
+
+	return next(ctx)
+}
+
+func HasScope(ctx context.Context, obj interface{}, next graphql.Resolver, scope models.APIKeyScope) (res interface{}, err error) {
+	if !auth.HasScope(ctx, scope) {
+		return nil, fmt.Errorf("api key does not have the %s scope", scope)
+	}
+
+	return next(ctx)
+}
+
+// RestrictAPIKeys denies requests authorized by an API key access to top level fields,
+// that have not declared which scope is required by either @hasScope or @isAdmin
+func RestrictAPIKeys(ctx context.Context, next graphql.Resolver) (res interface{}, err error) {
+	if auth.APIKeyFromContext(ctx) == nil {
+		return next(ctx)
+	}
+
+	fieldContext := graphql.GetFieldContext(ctx)
+	if fieldContext == nil || fieldContext.Field.Definition == nil {
+		return next(ctx)
+	}
+
+	switch fieldContext.Object {
+	case "Query", "Mutation", "Subscription":
+		directives := fieldContext.Field.Definition.Directives
+		if directives.ForName("hasScope") == nil && directives.ForName("isAdmin") == nil {
+			return nil, fmt.Errorf("%s is not available to api keys", fieldContext.Field.Name)
+		}
+	}
+
+	return next(ctx)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	photoview_graphql "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/auth"
 	"github.com/photoview/photoview/api/graphql/resolvers"
 	"github.com/photoview/photoview/api/server"
 	"github.com/photoview/photoview/api/utils"
@@ -19,6 +20,8 @@ func GraphqlEndpoint(db *gorm.DB) *graphql_handler.Server {
 	graphqlDirective := photoview_graphql.DirectiveRoot{}
 	graphqlDirective.IsAdmin = photoview_graphql.IsAdmin
 	graphqlDirective.IsAuthorized = photoview_graphql.IsAuthorized
+	graphqlDirective.IsRetoucher = photoview_graphql.IsRetoucher
+	graphqlDirective.HasScope = photoview_graphql.HasScope
 
 	graphqlConfig := photoview_graphql.Config{
 		Resolvers:  &graphqlResolver,
@@ -29,6 +32,7 @@ func GraphqlEndpoint(db *gorm.DB) *graphql_handler.Server {
 	graphqlServer.AddTransport(transport.Websocket{
 		KeepAlivePingInterval: 10 * time.Second,
 		Upgrader:              server.WebsocketUpgrader(utils.DevelopmentMode()),
+		InitFunc:              auth.AuthWebsocketInit(db),
 	})
 	graphqlServer.AddTransport(transport.Options{})
 	graphqlServer.AddTransport(transport.GET{})
@@ -37,6 +41,8 @@ func GraphqlEndpoint(db *gorm.DB) *graphql_handler.Server {
 
 	graphqlServer.SetQueryCache(lru.New(1000))
 
+	graphqlServer.AroundFields(photoview_graphql.RestrictAPIKeys)
+
 	graphqlServer.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New(100),
 	})
@@ -1,6 +1,10 @@
 package actions
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
@@ -62,12 +66,12 @@ func Album(db *gorm.DB, user *models.User, id int) (*models.Album, error) {
 		return nil, err
 	}
 
-	ownsAlbum, err := user.OwnsAlbum(db, &album)
+	canView, err := user.HasAlbumRole(db, &album, models.AlbumRoleViewer)
 	if err != nil {
 		return nil, err
 	}
 
-	if !ownsAlbum {
+	if !canView {
 		return nil, errors.New("forbidden")
 	}
 
@@ -86,16 +90,16 @@ func AlbumPath(db *gorm.DB, user *models.User, album *models.Album) ([]*models.A
 		SELECT * FROM path_albums WHERE id != ?
 	`, album.ID, album.ID).Scan(&album_path).Error
 
-	// Make sure to only return albums this user owns
+	// Make sure to only return albums this user can view
 	for i := len(album_path) - 1; i >= 0; i-- {
 		album := album_path[i]
 
-		owns, err := user.OwnsAlbum(db, album)
+		canView, err := user.HasAlbumRole(db, album, models.AlbumRoleViewer)
 		if err != nil {
 			return nil, err
 		}
 
-		if !owns {
+		if !canView {
 			album_path = album_path[i+1:]
 			break
 		}
@@ -122,12 +126,12 @@ func SetAlbumCover(db *gorm.DB, user *models.User, mediaID int) (*models.Album,
 		return nil, err
 	}
 
-	ownsAlbum, err := user.OwnsAlbum(db, &album)
+	canEdit, err := user.HasAlbumRole(db, &album, models.AlbumRoleEditor)
 	if err != nil {
 		return nil, err
 	}
 
-	if !ownsAlbum {
+	if !canEdit {
 		return nil, errors.New("forbidden")
 	}
 
@@ -144,12 +148,12 @@ func ResetAlbumCover(db *gorm.DB, user *models.User, albumID int) (*models.Album
 		return nil, err
 	}
 
-	ownsAlbum, err := user.OwnsAlbum(db, &album)
+	canEdit, err := user.HasAlbumRole(db, &album, models.AlbumRoleEditor)
 	if err != nil {
 		return nil, err
 	}
 
-	if !ownsAlbum {
+	if !canEdit {
 		return nil, errors.New("forbidden")
 	}
 
@@ -159,3 +163,74 @@ func ResetAlbumCover(db *gorm.DB, user *models.User, albumID int) (*models.Album
 
 	return &album, nil
 }
+
+// userAlbumsScope returns a scope that limits a query to the rows where column is the id of an album that the user owns,
+// or has been granted the required role, or a role that includes it, on
+func userAlbumsScope(db *gorm.DB, user *models.User, column string, required models.AlbumRole) (func(*gorm.DB) *gorm.DB, error) {
+	grantedAlbumIDs, err := user.GrantedAlbumIDs(db, required)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Where(
+			fmt.Sprintf("(%s IN (SELECT user_albums.album_id FROM user_albums WHERE user_albums.user_id = ?) OR %s IN (?))", column, column),
+			user.ID, grantedAlbumIDs)
+	}, nil
+}
+
+// viewableSubAlbumIDs returns the ids of the album and all of its sub albums, if the user can view the album
+func viewableSubAlbumIDs(db *gorm.DB, user *models.User, albumID int) ([]int, error) {
+	var album models.Album
+	if err := db.First(&album, albumID).Error; err != nil {
+		return nil, errors.Wrap(err, "get album from database")
+	}
+
+	canView, err := user.HasAlbumRole(db, &album, models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canView {
+		return nil, errors.New("forbidden")
+	}
+
+	subAlbums, err := album.GetChildren(db, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "get sub albums")
+	}
+
+	albumIDs := make([]int, len(subAlbums))
+	for i, subAlbum := range subAlbums {
+		albumIDs[i] = subAlbum.ID
+	}
+
+	return albumIDs, nil
+}
+
+// pathInside returns true if the path is the directory or is inside of it
+func pathInside(dir string, filePath string) bool {
+	relativePath, err := filepath.Rel(dir, filePath)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// userAlbumContainingPath returns the outermost album of the user that the path is inside of,
+// or nil if the path is not inside any of the albums of the user
+func userAlbumContainingPath(db *gorm.DB, user *models.User, filePath string) (*models.Album, error) {
+	if err := user.FillAlbums(db); err != nil {
+		return nil, err
+	}
+
+	var outermost *models.Album
+	for i, album := range user.Albums {
+		if !pathInside(album.Path, filePath) {
+			continue
+		}
+
+		if outermost == nil || len(album.Path) < len(outermost.Path) {
+			outermost = &user.Albums[i]
+		}
+	}
+
+	return outermost, nil
+}
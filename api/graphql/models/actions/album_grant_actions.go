@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// managedAlbum returns the album if the user is a manager of it
+func managedAlbum(db *gorm.DB, user *models.User, albumID int) (*models.Album, error) {
+	var album models.Album
+	if err := db.First(&album, albumID).Error; err != nil {
+		return nil, errors.Wrap(err, "get album from database")
+	}
+
+	isManager, err := user.HasAlbumRole(db, &album, models.AlbumRoleManager)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isManager {
+		return nil, errors.New("forbidden")
+	}
+
+	return &album, nil
+}
+
+// GrantAlbumAccess gives the user with the username a role on the album, replacing any role previously granted
+// to that user on the album. The granting user must be a manager of the album.
+func GrantAlbumAccess(db *gorm.DB, user *models.User, albumID int, username string, role models.AlbumRole) (*models.AlbumGrant, error) {
+	if !role.IsValid() {
+		return nil, errors.Errorf("invalid album role: %s", role)
+	}
+
+	album, err := managedAlbum(db, user, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	var grantee models.User
+	if err := db.Where("username = ?", username).First(&grantee).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.Wrap(err, "get user from database")
+	}
+
+	if grantee.ID == user.ID {
+		return nil, errors.New("cannot grant a role to yourself")
+	}
+
+	grant := models.AlbumGrant{
+		AlbumID:     album.ID,
+		UserID:      grantee.ID,
+		Role:        role,
+		GrantedByID: &user.ID,
+	}
+
+	transactionError := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "album_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role", "granted_by_id", "updated_at"}),
+		}).Create(&grant).Error
+		if err != nil {
+			return errors.Wrap(err, "save album grant")
+		}
+
+		if err := tx.Where("album_id = ? AND user_id = ?", album.ID, grantee.ID).First(&grant).Error; err != nil {
+			return errors.Wrap(err, "get album grant from database")
+		}
+
+		return models.RecordAuditEvent(tx, user, models.AuditActionGrantAlbumAccess, models.AuditTargetAlbum(album), map[string]interface{}{
+			"user_id": grantee.ID,
+			"role":    role,
+		})
+	})
+
+	if transactionError != nil {
+		return nil, transactionError
+	}
+
+	return &grant, nil
+}
+
+// RevokeAlbumAccess deletes the role granted to a user on the album, the revoking user must be a manager of the album
+func RevokeAlbumAccess(db *gorm.DB, user *models.User, albumID int, userID int) (*models.AlbumGrant, error) {
+	album, err := managedAlbum(db, user, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	var grant models.AlbumGrant
+	if err := db.Where("album_id = ? AND user_id = ?", album.ID, userID).First(&grant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("album grant not found")
+		}
+		return nil, errors.Wrap(err, "get album grant from database")
+	}
+
+	transactionError := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&grant).Error; err != nil {
+			return errors.Wrap(err, "delete album grant")
+		}
+
+		return models.RecordAuditEvent(tx, user, models.AuditActionRevokeAlbumAccess, models.AuditTargetAlbum(album), map[string]interface{}{
+			"user_id": userID,
+			"role":    grant.Role,
+		})
+	})
+
+	if transactionError != nil {
+		return nil, transactionError
+	}
+
+	return &grant, nil
+}
+
+// AlbumGrants returns the roles granted directly on the album, or an empty list if the user is not a manager of it
+func AlbumGrants(db *gorm.DB, user *models.User, album *models.Album) ([]*models.AlbumGrant, error) {
+	isManager, err := user.HasAlbumRole(db, album, models.AlbumRoleManager)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]*models.AlbumGrant, 0)
+	if !isManager {
+		return grants, nil
+	}
+
+	if err := db.Where("album_id = ?", album.ID).Order("created_at").Find(&grants).Error; err != nil {
+		return nil, errors.Wrap(err, "get album grants from database")
+	}
+
+	return grants, nil
+}
+
+// SharedAlbums returns the albums that roles have been granted to the user on directly
+func SharedAlbums(db *gorm.DB, user *models.User) ([]*models.Album, error) {
+	var albums []*models.Album
+	err := db.Model(&models.Album{}).
+		Where("id IN (?)", db.Model(&models.AlbumGrant{}).Select("album_id").Where("user_id = ?", user.ID)).
+		Order("title").
+		Find(&albums).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get shared albums from database")
+	}
+
+	return albums, nil
+}
@@ -0,0 +1,220 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlbumGrants(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	owner, err := models.RegisterUser(db, "owner", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	guest, err := models.RegisterUser(db, "guest", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	stranger, err := models.RegisterUser(db, "stranger", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rootAlbum := models.Album{Title: "root", Path: "/photos"}
+	if !assert.NoError(t, db.Save(&rootAlbum).Error) {
+		return
+	}
+
+	subAlbum := models.Album{Title: "sub", Path: "/photos/sub", ParentAlbumID: &rootAlbum.ID}
+	if !assert.NoError(t, db.Save(&subAlbum).Error) {
+		return
+	}
+
+	otherAlbum := models.Album{Title: "other", Path: "/other"}
+	if !assert.NoError(t, db.Save(&otherAlbum).Error) {
+		return
+	}
+
+	assert.NoError(t, db.Model(&owner).Association("Albums").Append(&rootAlbum, &subAlbum))
+
+	media := models.Media{Title: "photo.jpg", Path: "/photos/sub/photo.jpg", AlbumID: subAlbum.ID}
+	if !assert.NoError(t, db.Save(&media).Error) {
+		return
+	}
+
+	roleOf := func(user *models.User, album *models.Album) *models.AlbumRole {
+		role, err := user.AlbumRole(db, album)
+		assert.NoError(t, err)
+		return role
+	}
+
+	t.Run("Owners are managers", func(t *testing.T) {
+		role := roleOf(owner, &subAlbum)
+		if assert.NotNil(t, role) {
+			assert.Equal(t, models.AlbumRoleManager, *role)
+		}
+
+		assert.Nil(t, roleOf(guest, &subAlbum))
+	})
+
+	t.Run("Only managers can grant roles", func(t *testing.T) {
+		_, err := actions.GrantAlbumAccess(db, stranger, rootAlbum.ID, "guest", models.AlbumRoleViewer)
+		assert.EqualError(t, err, "forbidden")
+
+		_, err = actions.GrantAlbumAccess(db, owner, rootAlbum.ID, "owner", models.AlbumRoleViewer)
+		assert.Error(t, err)
+
+		_, err = actions.GrantAlbumAccess(db, owner, rootAlbum.ID, "nobody", models.AlbumRoleViewer)
+		assert.Error(t, err)
+	})
+
+	t.Run("Viewer role is inherited by sub albums", func(t *testing.T) {
+		grant, err := actions.GrantAlbumAccess(db, owner, rootAlbum.ID, "guest", models.AlbumRoleViewer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, guest.ID, grant.UserID)
+		assert.Equal(t, owner.ID, *grant.GrantedByID)
+
+		role := roleOf(guest, &subAlbum)
+		if assert.NotNil(t, role) {
+			assert.Equal(t, models.AlbumRoleViewer, *role)
+		}
+		assert.Nil(t, roleOf(guest, &otherAlbum))
+
+		album, err := actions.Album(db, guest, subAlbum.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, subAlbum.ID, album.ID)
+		}
+
+		albumIDs, err := guest.GrantedAlbumIDs(db, models.AlbumRoleViewer)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []int{rootAlbum.ID, subAlbum.ID}, albumIDs)
+
+		albumIDs, err = guest.GrantedAlbumIDs(db, models.AlbumRoleEditor)
+		assert.NoError(t, err)
+		assert.Empty(t, albumIDs)
+
+		sharedAlbums, err := actions.SharedAlbums(db, guest)
+		if assert.NoError(t, err) && assert.Len(t, sharedAlbums, 1) {
+			assert.Equal(t, rootAlbum.ID, sharedAlbums[0].ID)
+		}
+	})
+
+	t.Run("Viewers see the media of granted albums", func(t *testing.T) {
+		myMedia, err := actions.MyMedia(db, guest, nil, nil)
+		if assert.NoError(t, err) && assert.Len(t, myMedia, 1) {
+			assert.Equal(t, media.ID, myMedia[0].ID)
+		}
+
+		timeline, err := actions.MyTimeline(db, guest, nil, nil, nil, nil)
+		if assert.NoError(t, err) {
+			assert.Len(t, timeline, 1)
+		}
+
+		buckets, err := actions.TimelineBuckets(db, guest, models.TimelineGranularityYear, nil, nil)
+		if assert.NoError(t, err) && assert.Len(t, buckets, 1) {
+			assert.Equal(t, 1, buckets[0].MediaCount)
+		}
+
+		result, err := actions.Search(db, "photo", guest.ID, nil, nil)
+		if assert.NoError(t, err) {
+			assert.Len(t, result.Media, 1)
+		}
+
+		result, err = actions.Search(db, "sub", guest.ID, nil, nil)
+		if assert.NoError(t, err) {
+			assert.Len(t, result.Albums, 1)
+		}
+
+		strangerMedia, err := actions.MyMedia(db, stranger, nil, nil)
+		if assert.NoError(t, err) {
+			assert.Empty(t, strangerMedia)
+		}
+	})
+
+	t.Run("Viewers cannot edit or share", func(t *testing.T) {
+		_, err := actions.SetAlbumCover(db, guest, media.ID)
+		assert.EqualError(t, err, "forbidden")
+
+		_, err = actions.AddAlbumShare(db, guest, subAlbum.ID, nil, nil)
+		assert.Error(t, err)
+
+		_, err = actions.GrantAlbumAccess(db, guest, subAlbum.ID, "stranger", models.AlbumRoleViewer)
+		assert.EqualError(t, err, "forbidden")
+
+		grants, err := actions.AlbumGrants(db, guest, &rootAlbum)
+		assert.NoError(t, err)
+		assert.Empty(t, grants)
+	})
+
+	t.Run("Granting again replaces the role", func(t *testing.T) {
+		_, err := actions.GrantAlbumAccess(db, owner, rootAlbum.ID, "guest", models.AlbumRoleEditor)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		grants, err := actions.AlbumGrants(db, owner, &rootAlbum)
+		if assert.NoError(t, err) && assert.Len(t, grants, 1) {
+			assert.Equal(t, models.AlbumRoleEditor, grants[0].Role)
+		}
+
+		album, err := actions.SetAlbumCover(db, guest, media.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, subAlbum.ID, album.ID)
+		}
+
+		_, err = actions.AddMediaShare(db, guest, media.ID, nil, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Managers can share and grant", func(t *testing.T) {
+		_, err := actions.GrantAlbumAccess(db, owner, subAlbum.ID, "guest", models.AlbumRoleManager)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		role := roleOf(guest, &subAlbum)
+		if assert.NotNil(t, role) {
+			assert.Equal(t, models.AlbumRoleManager, *role)
+		}
+
+		role = roleOf(guest, &rootAlbum)
+		if assert.NotNil(t, role) {
+			assert.Equal(t, models.AlbumRoleEditor, *role)
+		}
+
+		_, err = actions.AddAlbumShare(db, guest, subAlbum.ID, nil, nil)
+		assert.NoError(t, err)
+
+		_, err = actions.GrantAlbumAccess(db, guest, subAlbum.ID, "stranger", models.AlbumRoleViewer)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Revoke role", func(t *testing.T) {
+		_, err := actions.RevokeAlbumAccess(db, stranger, rootAlbum.ID, guest.ID)
+		assert.EqualError(t, err, "forbidden")
+
+		grant, err := actions.RevokeAlbumAccess(db, owner, rootAlbum.ID, guest.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.AlbumRoleEditor, grant.Role)
+		}
+
+		assert.Nil(t, roleOf(guest, &rootAlbum))
+
+		_, err = actions.RevokeAlbumAccess(db, owner, rootAlbum.ID, guest.ID)
+		assert.Error(t, err)
+
+		var auditCount int64
+		assert.NoError(t, db.Model(&models.AuditEvent{}).Where("action = ?", models.AuditActionRevokeAlbumAccess).Count(&auditCount).Error)
+		assert.EqualValues(t, 1, auditCount)
+	})
+}
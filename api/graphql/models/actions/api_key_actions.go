@@ -0,0 +1,80 @@
+package actions
+
+import (
+	"strings"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// CreateAPIKey creates an API key for the user, the key itself is returned as it cannot be retrieved later
+func CreateAPIKey(db *gorm.DB, user *models.User, name string, scopes []models.APIKeyScope, expire *time.Time) (*models.CreateAPIKeyResult, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("api key name cannot be empty")
+	}
+
+	if len(scopes) == 0 {
+		return nil, errors.New("api key must have at least one scope")
+	}
+
+	uniqueScopes := make([]models.APIKeyScope, 0, len(scopes))
+	scopeSeen := make(map[models.APIKeyScope]bool)
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, errors.Errorf("invalid api key scope: %s", scope)
+		}
+
+		if scope == models.APIKeyScopeAdmin && !user.Admin {
+			return nil, errors.New("only admins can create api keys with the ADMIN scope")
+		}
+
+		if !scopeSeen[scope] {
+			scopeSeen[scope] = true
+			uniqueScopes = append(uniqueScopes, scope)
+		}
+	}
+
+	if expire != nil && expire.Before(time.Now()) {
+		return nil, errors.New("api key cannot expire in the past")
+	}
+
+	apiKey, key, err := user.GenerateAPIKey(db, name, uniqueScopes, expire)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResult{
+		APIKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+// MyAPIKeys returns the API keys of the user, newest first
+func MyAPIKeys(db *gorm.DB, user *models.User) ([]*models.APIKey, error) {
+	var apiKeys []*models.APIKey
+	if err := db.Where("user_id = ?", user.ID).Order("created_at DESC").Order("id DESC").Find(&apiKeys).Error; err != nil {
+		return nil, errors.Wrap(err, "get api keys from database")
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey deletes an API key of the user, such that it can no longer be used
+func RevokeAPIKey(db *gorm.DB, user *models.User, apiKeyID int) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	if err := db.Where("user_id = ?", user.ID).First(&apiKey, apiKeyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, errors.Wrap(err, "get api key from database")
+	}
+
+	if err := db.Delete(&apiKey).Error; err != nil {
+		return nil, errors.Wrap(err, "delete api key")
+	}
+
+	return &apiKey, nil
+}
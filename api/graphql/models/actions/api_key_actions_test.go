@@ -0,0 +1,107 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var createdKey *models.CreateAPIKeyResult
+
+	t.Run("Create api key", func(t *testing.T) {
+		result, err := actions.CreateAPIKey(db, user, "ingest", []models.APIKeyScope{models.APIKeyScopeScan, models.APIKeyScopeRead, models.APIKeyScopeScan}, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.True(t, models.IsAPIKey(result.Key))
+		assert.Equal(t, result.Key[:len(result.APIKey.Prefix)], result.APIKey.Prefix)
+		assert.NotContains(t, result.APIKey.KeyHash, result.Key)
+		assert.Equal(t, []models.APIKeyScope{models.APIKeyScopeScan, models.APIKeyScopeRead}, result.APIKey.ScopeList())
+
+		createdKey = result
+	})
+
+	t.Run("Create admin key as regular user", func(t *testing.T) {
+		_, err := actions.CreateAPIKey(db, user, "admin", []models.APIKeyScope{models.APIKeyScopeAdmin}, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Create key without scopes", func(t *testing.T) {
+		_, err := actions.CreateAPIKey(db, user, "empty", []models.APIKeyScope{}, nil)
+		assert.Error(t, err)
+	})
+
+	if createdKey == nil {
+		return
+	}
+
+	t.Run("Verify api key", func(t *testing.T) {
+		apiKey, err := models.VerifyAPIKey(db, createdKey.Key)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, createdKey.APIKey.ID, apiKey.ID)
+		assert.Equal(t, user.ID, apiKey.User.ID)
+		assert.True(t, apiKey.HasScope(models.APIKeyScopeScan))
+		assert.False(t, apiKey.HasScope(models.APIKeyScopeShare))
+		assert.NotNil(t, apiKey.LastUsedAt)
+
+		_, err = models.VerifyAPIKey(db, createdKey.Key+"x")
+		assert.Error(t, err)
+	})
+
+	t.Run("Expired api key", func(t *testing.T) {
+		expire := time.Now().Add(time.Hour)
+		result, err := actions.CreateAPIKey(db, user, "short lived", []models.APIKeyScope{models.APIKeyScopeRead}, &expire)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if !assert.NoError(t, db.Model(result.APIKey).Update("expire", time.Now().Add(-time.Minute)).Error) {
+			return
+		}
+
+		_, err = models.VerifyAPIKey(db, result.Key)
+		assert.Error(t, err)
+	})
+
+	t.Run("List and revoke api keys", func(t *testing.T) {
+		apiKeys, err := actions.MyAPIKeys(db, user)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, apiKeys, 2)
+
+		otherUser, err := models.RegisterUser(db, "other", &password, false)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = actions.RevokeAPIKey(db, otherUser, createdKey.APIKey.ID)
+		assert.Error(t, err)
+
+		revoked, err := actions.RevokeAPIKey(db, user, createdKey.APIKey.ID)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, createdKey.APIKey.ID, revoked.ID)
+
+		_, err = models.VerifyAPIKey(db, createdKey.Key)
+		assert.Error(t, err)
+	})
+}
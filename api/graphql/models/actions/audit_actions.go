@@ -0,0 +1,125 @@
+package actions
+
+import (
+	"log"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// How often events older than the retention period are deleted from the audit log
+const auditLogCleanupInterval = time.Hour
+
+// AuditLog returns the events of the audit log that match the filter, most recent first
+func AuditLog(db *gorm.DB, filter *models.AuditLogFilter, paginate *models.Pagination) ([]*models.AuditEvent, error) {
+	query := db.Model(&models.AuditEvent{})
+
+	if filter != nil {
+		if filter.UserID != nil {
+			query = query.Where("user_id = ?", *filter.UserID)
+		}
+
+		if filter.Action != nil {
+			query = query.Where("action = ?", *filter.Action)
+		}
+
+		if filter.TargetType != nil {
+			query = query.Where("target_type = ?", *filter.TargetType)
+		}
+
+		if filter.TargetID != nil {
+			query = query.Where("target_id = ?", *filter.TargetID)
+		}
+
+		if filter.From != nil {
+			query = query.Where("created_at >= ?", *filter.From)
+		}
+
+		if filter.To != nil {
+			query = query.Where("created_at < ?", *filter.To)
+		}
+	}
+
+	query = query.Order("created_at DESC").Order("id DESC")
+	query = models.FormatSQL(query, nil, paginate)
+
+	var events []*models.AuditEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, errors.Wrap(err, "get audit events from database")
+	}
+
+	return events, nil
+}
+
+// SetAuditLogRetention sets how many days events are kept in the audit log, 0 keeps them forever.
+// Events older than the new retention period are deleted right away.
+func SetAuditLogRetention(db *gorm.DB, days int) (int, error) {
+	if days < 0 {
+		return 0, errors.New("retention must be 0 or above")
+	}
+
+	err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).
+		Model(&models.SiteInfo{}).
+		Update("audit_log_retention_days", days).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "update audit log retention")
+	}
+
+	if _, err := CleanupAuditLog(db); err != nil {
+		return 0, err
+	}
+
+	return days, nil
+}
+
+// CleanupAuditLog deletes events, failed login attempts and share token accesses older than the retention period of the site,
+// and returns how many were deleted
+func CleanupAuditLog(db *gorm.DB) (int64, error) {
+	siteInfo, err := models.GetSiteInfo(db)
+	if err != nil {
+		return 0, err
+	}
+
+	if siteInfo.AuditLogRetentionDays <= 0 {
+		return 0, nil
+	}
+
+	retention := time.Duration(siteInfo.AuditLogRetentionDays) * 24 * time.Hour
+	deletedEvents, err := models.DeleteExpiredAuditEvents(db, retention)
+	if err != nil {
+		return 0, err
+	}
+
+	deletedAttempts, err := models.DeleteOldFailedLoginAttempts(db, retention)
+	if err != nil {
+		return 0, err
+	}
+
+	deletedAccesses, err := models.DeleteOldShareTokenAccesses(db, retention)
+	if err != nil {
+		return 0, err
+	}
+
+	return deletedEvents + deletedAttempts + deletedAccesses, nil
+}
+
+// InitializeAuditLogCleanup starts a background job that periodically deletes expired audit events
+func InitializeAuditLogCleanup(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(auditLogCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			deleted, err := CleanupAuditLog(db)
+			if err != nil {
+				log.Printf("ERROR: cleaning up audit log: %s\n", err)
+			} else if deleted > 0 {
+				log.Printf("Deleted %d expired audit events and failed login attempts\n", deleted)
+			}
+
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,140 @@
+package actions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	admin, err := models.RegisterUser(db, "admin", &password, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	user, err := models.RegisterUser(db, "user", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	album := models.Album{Title: "album", Path: "/photos/album"}
+	if !assert.NoError(t, db.Save(&album).Error) {
+		return
+	}
+
+	ctx := models.ContextWithSessionClient(context.Background(), models.SessionClient{IPAddress: "10.0.0.5"})
+	requestDB := db.WithContext(ctx)
+
+	assert.NoError(t, models.RecordAuditEvent(requestDB, admin, models.AuditActionUpdateUser, models.AuditTargetUser(user), map[string]interface{}{
+		"admin": true,
+	}))
+	assert.NoError(t, models.RecordAuditEvent(requestDB, user, models.AuditActionMarkRetouchFile, models.AuditTargetAlbum(&album), nil))
+	assert.NoError(t, models.RecordAuditEvent(db, nil, models.AuditActionDeleteAlbum, models.AuditTargetAlbum(&album), nil))
+
+	t.Run("List all events", func(t *testing.T) {
+		events, err := actions.AuditLog(db, nil, nil)
+		if !assert.NoError(t, err) || !assert.Len(t, events, 3) {
+			return
+		}
+
+		assert.Equal(t, models.AuditActionDeleteAlbum, events[0].Action)
+		assert.Nil(t, events[0].UserID)
+		assert.Empty(t, events[0].IPAddress)
+
+		assert.Equal(t, models.AuditActionUpdateUser, events[2].Action)
+		assert.Equal(t, "10.0.0.5", events[2].IPAddress)
+		assert.Equal(t, `{"admin":true}`, events[2].Details)
+		assert.Equal(t, user.ID, *events[2].TargetID)
+	})
+
+	t.Run("Filter events", func(t *testing.T) {
+		targetType := models.AuditTargetTypeAlbum
+		events, err := actions.AuditLog(db, &models.AuditLogFilter{TargetType: &targetType, TargetID: &album.ID}, nil)
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+
+		events, err = actions.AuditLog(db, &models.AuditLogFilter{UserID: &user.ID}, nil)
+		if assert.NoError(t, err) && assert.Len(t, events, 1) {
+			assert.Equal(t, models.AuditActionMarkRetouchFile, events[0].Action)
+			assert.Equal(t, album.Path, events[0].Path)
+		}
+
+		future := time.Now().Add(time.Hour)
+		events, err = actions.AuditLog(db, &models.AuditLogFilter{From: &future}, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Paginate events", func(t *testing.T) {
+		limit := 1
+		offset := 1
+		events, err := actions.AuditLog(db, nil, &models.Pagination{Limit: &limit, Offset: &offset})
+		if assert.NoError(t, err) && assert.Len(t, events, 1) {
+			assert.Equal(t, models.AuditActionMarkRetouchFile, events[0].Action)
+		}
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		_, err := actions.SetAuditLogRetention(db, -1)
+		assert.Error(t, err)
+
+		oldEvent := models.AuditEvent{Action: models.AuditActionDeleteMedia}
+		if !assert.NoError(t, db.Create(&oldEvent).Error) {
+			return
+		}
+		assert.NoError(t, db.Model(&oldEvent).Update("created_at", time.Now().Add(-48*time.Hour)).Error)
+
+		days, err := actions.SetAuditLogRetention(db, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, days)
+
+		var count int64
+		assert.NoError(t, db.Model(&models.AuditEvent{}).Count(&count).Error)
+		assert.EqualValues(t, 4, count)
+
+		days, err = actions.SetAuditLogRetention(db, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, days)
+
+		assert.NoError(t, db.Model(&models.AuditEvent{}).Count(&count).Error)
+		assert.EqualValues(t, 3, count)
+
+		siteInfo, err := models.GetSiteInfo(db)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, siteInfo.AuditLogRetentionDays)
+		}
+	})
+
+	t.Run("Retention of events of the filesystem watcher", func(t *testing.T) {
+		// recorded as by the filesystem watcher, without a user or a client
+		err := models.RecordAuditEvent(db, nil, models.AuditActionDeleteAlbum, models.AuditTargetAlbum(&album), map[string]interface{}{
+			"source": "filesystem",
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var watcherEvent models.AuditEvent
+		if !assert.NoError(t, db.Where("details LIKE ?", "%filesystem%").Last(&watcherEvent).Error) {
+			return
+		}
+		assert.Nil(t, watcherEvent.UserID)
+		assert.NoError(t, db.Model(&watcherEvent).Update("created_at", time.Now().Add(-48*time.Hour)).Error)
+
+		deleted, err := actions.CleanupAuditLog(db)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, deleted)
+
+		var count int64
+		assert.NoError(t, db.Model(&models.AuditEvent{}).Where("id = ?", watcherEvent.ID).Count(&count).Error)
+		assert.EqualValues(t, 0, count)
+	})
+}
@@ -0,0 +1,209 @@
+package actions
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/photoview/photoview/api/database"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Number of grid cells along the width of a 256 pixel map tile, when clustering media
+const clusterCellsPerTile = 4
+
+// Maximum number of grid cells along each side of the bounding box, the grid is coarsened for larger bounding boxes
+// so that the number of returned clusters stays bounded, regardless of the zoom level
+const maxClusterCellsPerSide = 64
+
+// Zoom levels are clamped to this range, beyond the maximum zoom level clusters only contain media at the same spot
+const (
+	minClusterZoom = 0
+	maxClusterZoom = 22
+)
+
+// FilterGeoMedia returns a query on the media table joined with media_exif,
+// of the geotagged media the user can view that matches the filter
+func FilterGeoMedia(db *gorm.DB, user *models.User, filter *models.MediaGeoFilter) (*gorm.DB, error) {
+	userAlbums, err := userAlbumsScope(db, user, "media.album_id", models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Table("media").
+		Joins("INNER JOIN media_exif ON media.exif_id = media_exif.id").
+		Scopes(userAlbums).
+		Where("media.motion_photo_id IS NULL").
+		Where("media_exif.gps_latitude IS NOT NULL").
+		Where("media_exif.gps_longitude IS NOT NULL")
+
+	if filter == nil {
+		return query, nil
+	}
+
+	if filter.AlbumID != nil {
+		albumIDs, err := viewableSubAlbumIDs(db, user, *filter.AlbumID)
+		if err != nil {
+			return nil, err
+		}
+
+		query = query.Where("media.album_id IN (?)", albumIDs)
+	}
+
+	if filter.FromDate != nil {
+		query = query.Where("media.date_shot >= ?", filter.FromDate)
+	}
+
+	if filter.ToDate != nil {
+		query = query.Where("media.date_shot < ?", filter.ToDate)
+	}
+
+	return query, nil
+}
+
+func validateBoundingBox(bbox models.BoundingBoxInput) error {
+	if bbox.South < -90 || bbox.North > 90 || bbox.South > bbox.North {
+		return errors.New("invalid latitudes of bounding box")
+	}
+
+	if bbox.West < -180 || bbox.West > 180 || bbox.East < -180 || bbox.East > 180 {
+		return errors.New("invalid longitudes of bounding box")
+	}
+
+	return nil
+}
+
+// filterBoundingBox limits a query from FilterGeoMedia to media within the bounding box
+func filterBoundingBox(query *gorm.DB, bbox models.BoundingBoxInput) *gorm.DB {
+	query = query.Where("media_exif.gps_latitude BETWEEN ? AND ?", bbox.South, bbox.North)
+
+	if bbox.West > bbox.East {
+		return query.Where("(media_exif.gps_longitude >= ? OR media_exif.gps_longitude <= ?)", bbox.West, bbox.East)
+	}
+
+	return query.Where("media_exif.gps_longitude BETWEEN ? AND ?", bbox.West, bbox.East)
+}
+
+// clusterCellSize returns the size in degrees of the grid cells that media within the bounding box is clustered by at the zoom level
+func clusterCellSize(bbox models.BoundingBoxInput, zoom int) float64 {
+	if zoom < minClusterZoom {
+		zoom = minClusterZoom
+	}
+
+	if zoom > maxClusterZoom {
+		zoom = maxClusterZoom
+	}
+
+	width := bbox.East - bbox.West
+	if bbox.West > bbox.East {
+		width += 360
+	}
+
+	minCellSize := math.Max(width, bbox.North-bbox.South) / maxClusterCellsPerSide
+	return math.Max(360/math.Pow(2, float64(zoom))/clusterCellsPerTile, minCellSize)
+}
+
+type geoCluster struct {
+	Latitude      float64
+	Longitude     float64
+	MediaCount    int
+	North         float64
+	South         float64
+	East          float64
+	West          float64
+	SampleMediaID int
+}
+
+// MediaGeoClusters groups the geotagged media of the user within the bounding box into clusters,
+// by a grid of square cells that gets finer as the zoom level increases, up to maxClusterCellsPerSide cells along the bounding box
+func MediaGeoClusters(db *gorm.DB, user *models.User, bbox models.BoundingBoxInput, zoom int, filter *models.MediaGeoFilter) ([]*models.MediaGeoCluster, error) {
+	if err := validateBoundingBox(bbox); err != nil {
+		return nil, err
+	}
+
+	query, err := FilterGeoMedia(db, user, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	cellSize := strconv.FormatFloat(clusterCellSize(bbox, zoom), 'f', -1, 64)
+	latitudeCell := database.Floor(db, fmt.Sprintf("media_exif.gps_latitude / %s", cellSize))
+	longitudeCell := database.Floor(db, fmt.Sprintf("media_exif.gps_longitude / %s", cellSize))
+
+	var clusters []*geoCluster
+	err = filterBoundingBox(query, bbox).
+		Select(
+			"AVG(media_exif.gps_latitude) AS latitude, AVG(media_exif.gps_longitude) AS longitude, COUNT(*) AS media_count, " +
+				"MAX(media_exif.gps_latitude) AS north, MIN(media_exif.gps_latitude) AS south, " +
+				"MAX(media_exif.gps_longitude) AS east, MIN(media_exif.gps_longitude) AS west, " +
+				"MIN(media.id) AS sample_media_id").
+		Group(latitudeCell + ", " + longitudeCell).
+		Order("media_count DESC").
+		Scan(&clusters).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster geotagged media")
+	}
+
+	sampleMediaIDs := make([]int, len(clusters))
+	for i, cluster := range clusters {
+		sampleMediaIDs[i] = cluster.SampleMediaID
+	}
+
+	var sampleMedia []*models.Media
+	if len(sampleMediaIDs) > 0 {
+		if err := db.Where("id IN (?)", sampleMediaIDs).Find(&sampleMedia).Error; err != nil {
+			return nil, errors.Wrap(err, "get sample media of clusters")
+		}
+	}
+
+	sampleMediaByID := make(map[int]*models.Media, len(sampleMedia))
+	for _, media := range sampleMedia {
+		sampleMediaByID[media.ID] = media
+	}
+
+	result := make([]*models.MediaGeoCluster, 0, len(clusters))
+	for _, cluster := range clusters {
+		result = append(result, &models.MediaGeoCluster{
+			Latitude:   cluster.Latitude,
+			Longitude:  cluster.Longitude,
+			MediaCount: cluster.MediaCount,
+			Bounds: &models.BoundingBox{
+				North: cluster.North,
+				South: cluster.South,
+				East:  cluster.East,
+				West:  cluster.West,
+			},
+			SampleMedia: sampleMediaByID[cluster.SampleMediaID],
+		})
+	}
+
+	return result, nil
+}
+
+// MediaInBoundingBox returns the geotagged media of the user within the bounding box, newest first
+func MediaInBoundingBox(db *gorm.DB, user *models.User, bbox models.BoundingBoxInput, filter *models.MediaGeoFilter, paginate *models.Pagination) ([]*models.Media, error) {
+	if err := validateBoundingBox(bbox); err != nil {
+		return nil, err
+	}
+
+	query, err := FilterGeoMedia(db, user, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query = filterBoundingBox(query, bbox).
+		Select("media.*").
+		Order("media.date_shot DESC").
+		Order("media.id DESC")
+
+	query = models.FormatSQL(query, nil, paginate)
+
+	var media []*models.Media
+	if err := query.Find(&media).Error; err != nil {
+		return nil, errors.Wrap(err, "get media in bounding box")
+	}
+
+	return media, nil
+}
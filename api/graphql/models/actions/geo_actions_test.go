@@ -0,0 +1,155 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaGeoQueries(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	otherUser, err := models.RegisterUser(db, "other", &password, false)
+	assert.NoError(t, err)
+
+	rootAlbum := models.Album{Title: "root", Path: "/photos"}
+	assert.NoError(t, db.Save(&rootAlbum).Error)
+
+	subAlbum := models.Album{Title: "sub", Path: "/photos/sub", ParentAlbumID: &rootAlbum.ID}
+	assert.NoError(t, db.Save(&subAlbum).Error)
+
+	otherAlbum := models.Album{Title: "other", Path: "/other"}
+	assert.NoError(t, db.Save(&otherAlbum).Error)
+
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&rootAlbum, &subAlbum, &otherAlbum))
+
+	geotagged := func(title string, albumID int, latitude float64, longitude float64, dateShot time.Time) models.Media {
+		return models.Media{
+			Title:    title,
+			Path:     "/photos/" + title,
+			AlbumID:  albumID,
+			DateShot: dateShot,
+			Exif:     &models.MediaEXIF{GPSLatitude: &latitude, GPSLongitude: &longitude},
+		}
+	}
+
+	date2020 := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	date2021 := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	media := []models.Media{
+		geotagged("helsinki1", rootAlbum.ID, 60.17, 24.94, date2020),
+		geotagged("helsinki2", rootAlbum.ID, 60.18, 24.95, date2021),
+		geotagged("espoo", subAlbum.ID, 60.21, 24.66, date2021),
+		geotagged("rio", otherAlbum.ID, -22.91, -43.17, date2020),
+		geotagged("fiji", otherAlbum.ID, -17.0, 179.9, date2021),
+		{Title: "nowhere", Path: "/photos/nowhere", AlbumID: rootAlbum.ID},
+	}
+	assert.NoError(t, db.Save(&media).Error)
+
+	world := models.BoundingBoxInput{North: 90, South: -90, East: 180, West: -180}
+
+	titlesOf := func(media []*models.Media) []string {
+		titles := make([]string, 0)
+		for _, m := range media {
+			titles = append(titles, m.Title)
+		}
+		return titles
+	}
+
+	t.Run("Clusters at low zoom", func(t *testing.T) {
+		clusters, err := actions.MediaGeoClusters(db, user, world, 2, nil)
+		if !assert.NoError(t, err) || !assert.Len(t, clusters, 3) {
+			return
+		}
+
+		assert.Equal(t, 3, clusters[0].MediaCount)
+		assert.InDelta(t, 60.1867, clusters[0].Latitude, 0.001)
+		assert.Equal(t, 60.21, clusters[0].Bounds.North)
+		assert.Equal(t, 24.66, clusters[0].Bounds.West)
+		if assert.NotNil(t, clusters[0].SampleMedia) {
+			assert.Equal(t, "helsinki1", clusters[0].SampleMedia.Title)
+		}
+
+		assert.Equal(t, 1, clusters[1].MediaCount)
+		assert.Equal(t, 1, clusters[2].MediaCount)
+	})
+
+	t.Run("Clusters at high zoom", func(t *testing.T) {
+		bbox := models.BoundingBoxInput{North: 61, South: 60, East: 25, West: 24}
+		clusters, err := actions.MediaGeoClusters(db, user, bbox, 16, nil)
+		assert.NoError(t, err)
+		assert.Len(t, clusters, 3)
+	})
+
+	t.Run("Grid of large bounding box at high zoom is coarsened", func(t *testing.T) {
+		clusters, err := actions.MediaGeoClusters(db, user, world, 16, nil)
+		if assert.NoError(t, err) && assert.Len(t, clusters, 3) {
+			assert.Equal(t, 3, clusters[0].MediaCount)
+		}
+	})
+
+	t.Run("Negative coordinates are clustered by cell", func(t *testing.T) {
+		bbox := models.BoundingBoxInput{North: 0, South: -30, East: -40, West: -50}
+		clusters, err := actions.MediaGeoClusters(db, user, bbox, 0, nil)
+		if assert.NoError(t, err) && assert.Len(t, clusters, 1) {
+			assert.Equal(t, "rio", clusters[0].SampleMedia.Title)
+		}
+	})
+
+	t.Run("Media in bounding box", func(t *testing.T) {
+		bbox := models.BoundingBoxInput{North: 61, South: 60, East: 25, West: 24}
+		result, err := actions.MediaInBoundingBox(db, user, bbox, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"espoo", "helsinki2", "helsinki1"}, titlesOf(result))
+
+		limit := 1
+		offset := 1
+		result, err = actions.MediaInBoundingBox(db, user, bbox, nil, &models.Pagination{Limit: &limit, Offset: &offset})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"helsinki2"}, titlesOf(result))
+	})
+
+	t.Run("Bounding box across the antimeridian", func(t *testing.T) {
+		bbox := models.BoundingBoxInput{North: 0, South: -30, East: -170, West: 170}
+		result, err := actions.MediaInBoundingBox(db, user, bbox, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"fiji"}, titlesOf(result))
+	})
+
+	t.Run("Invalid bounding box", func(t *testing.T) {
+		_, err := actions.MediaInBoundingBox(db, user, models.BoundingBoxInput{North: -10, South: 10, East: 0, West: 0}, nil, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Filter by album and date", func(t *testing.T) {
+		result, err := actions.MediaInBoundingBox(db, user, world, &models.MediaGeoFilter{AlbumID: &rootAlbum.ID}, nil)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"helsinki1", "helsinki2", "espoo"}, titlesOf(result))
+
+		fromDate := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		result, err = actions.MediaInBoundingBox(db, user, world, &models.MediaGeoFilter{FromDate: &fromDate}, nil)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"helsinki2", "espoo", "fiji"}, titlesOf(result))
+
+		clusters, err := actions.MediaGeoClusters(db, user, world, 2, &models.MediaGeoFilter{AlbumID: &subAlbum.ID, ToDate: &fromDate})
+		assert.NoError(t, err)
+		assert.Empty(t, clusters)
+	})
+
+	t.Run("Other users", func(t *testing.T) {
+		clusters, err := actions.MediaGeoClusters(db, otherUser, world, 2, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, clusters)
+
+		_, err = actions.MediaInBoundingBox(db, otherUser, world, &models.MediaGeoFilter{AlbumID: &rootAlbum.ID}, nil)
+		assert.Error(t, err)
+	})
+}
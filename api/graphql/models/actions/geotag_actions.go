@@ -0,0 +1,172 @@
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/gps_tracks"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Maximum time between a media and the track points it is located by, if not specified
+const defaultGeotagMaxGap = 5 * time.Minute
+
+// ReadUserTrackFile parses a GPX or KML file on the server, which must be inside one of the albums of the user
+func ReadUserTrackFile(db *gorm.DB, user *models.User, trackPath string) (*gps_tracks.Track, error) {
+	trackPath, err := filepath.Abs(trackPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve track path")
+	}
+
+	album, err := userAlbumContainingPath(db, user, trackPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if album == nil {
+		return nil, errors.New("track file must be inside one of your albums")
+	}
+
+	// Symlinks inside the album could point at any file on the server
+	realTrackPath, err := filepath.EvalSymlinks(trackPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve track path")
+	}
+
+	realAlbumPath, err := filepath.EvalSymlinks(album.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve album path")
+	}
+
+	if !pathInside(realAlbumPath, realTrackPath) {
+		return nil, errors.New("track file must be inside one of your albums")
+	}
+
+	file, err := os.Open(realTrackPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "open track file")
+	}
+	defer file.Close()
+
+	track, err := gps_tracks.ParseTrack(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "track file (%s)", filepath.Base(trackPath))
+	}
+
+	return track, nil
+}
+
+// GeotagMediaFromTrack locates media of the user that was shot while the track was recorded.
+// Unless it is a dry run, the coordinates are saved like any other metadata edit.
+func GeotagMediaFromTrack(db *gorm.DB, user *models.User, track *gps_tracks.Track, options models.GeotagOptions) ([]*models.GeotagMatch, error) {
+	timeOffset := time.Duration(0)
+	if options.TimeOffset != nil {
+		timeOffset = time.Duration(*options.TimeOffset) * time.Second
+	}
+
+	maxGap := defaultGeotagMaxGap
+	if options.MaxGap != nil {
+		if *options.MaxGap < 0 {
+			return nil, errors.New("max gap cannot be negative")
+		}
+		maxGap = time.Duration(*options.MaxGap) * time.Second
+	}
+
+	if len(track.Points) == 0 {
+		return nil, errors.New("track contains no points")
+	}
+
+	// The time of the track is the date the media was shot plus the offset
+	userAlbums, err := userAlbumsScope(db, user, "media.album_id", models.AlbumRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Preload("Exif").
+		Scopes(userAlbums).
+		Where("media.motion_photo_id IS NULL").
+		Where("media.date_shot BETWEEN ? AND ?", track.Start().Add(-timeOffset-maxGap), track.End().Add(-timeOffset+maxGap)).
+		Order("media.date_shot, media.id")
+
+	if options.AlbumID != nil {
+		albumIDs, err := viewableSubAlbumIDs(db, user, *options.AlbumID)
+		if err != nil {
+			return nil, err
+		}
+
+		query = query.Where("media.album_id IN (?)", albumIDs)
+	}
+
+	if options.Overwrite == nil || !*options.Overwrite {
+		query = query.Where("media.exif_id IS NULL OR media.exif_id IN (?)",
+			db.Model(&models.MediaEXIF{}).Select("id").Where("gps_latitude IS NULL OR gps_longitude IS NULL"))
+	}
+
+	var media []*models.Media
+	if err := query.Find(&media).Error; err != nil {
+		return nil, errors.Wrap(err, "get media to geotag")
+	}
+
+	matches := make([]*models.GeotagMatch, 0)
+	for _, m := range media {
+		location, found := track.Locate(m.DateShot.Add(timeOffset), maxGap)
+		if !found {
+			continue
+		}
+
+		matches = append(matches, &models.GeotagMatch{
+			Media:          m,
+			Latitude:       location.Latitude,
+			Longitude:      location.Longitude,
+			Interpolated:   location.Interpolated,
+			TimeDifference: int(location.TimeDifference.Round(time.Second).Seconds()),
+		})
+	}
+
+	if options.DryRun != nil && *options.DryRun {
+		return matches, nil
+	}
+
+	inputs := make(map[int]models.MediaMetadataInput, len(matches))
+	for _, match := range matches {
+		inputs[match.Media.ID] = models.MediaMetadataInput{
+			Coordinates: &models.CoordinatesInput{
+				Latitude:  match.Latitude,
+				Longitude: match.Longitude,
+			},
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, match := range matches {
+			if err := updateMediaMetadata(tx, user, match.Media, inputs[match.Media.ID]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.WriteToFile != nil && *options.WriteToFile {
+		matchedMedia := make([]*models.Media, len(matches))
+		for i, match := range matches {
+			matchedMedia[i] = match.Media
+		}
+
+		err := writeMetadataToFiles(matchedMedia, func(m *models.Media) map[string]interface{} {
+			return mediaMetadataTags(m, inputs[m.ID])
+		})
+
+		if err != nil {
+			return nil, errors.Wrap(err, "media was geotagged")
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,138 @@
+package actions_test
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/scanner/gps_tracks"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeotagMediaFromTrack(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	albumPath := t.TempDir()
+	album := models.Album{Title: "album", Path: albumPath}
+	assert.NoError(t, db.Save(&album).Error)
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&album))
+
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	latitude := 10.0
+	longitude := 20.0
+
+	media := []models.Media{
+		{Title: "at_start", Path: "/photos/at_start", AlbumID: album.ID, DateShot: start},
+		{Title: "between", Path: "/photos/between", AlbumID: album.ID, DateShot: start.Add(time.Minute)},
+		{Title: "tagged", Path: "/photos/tagged", AlbumID: album.ID, DateShot: start.Add(time.Minute),
+			Exif: &models.MediaEXIF{GPSLatitude: &latitude, GPSLongitude: &longitude}},
+		{Title: "later", Path: "/photos/later", AlbumID: album.ID, DateShot: start.Add(time.Hour)},
+	}
+	assert.NoError(t, db.Save(&media).Error)
+
+	track := gps_tracks.NewTrack([]gps_tracks.TrackPoint{
+		{Time: start, Latitude: 60.0, Longitude: 24.0},
+		{Time: start.Add(2 * time.Minute), Latitude: 60.2, Longitude: 25.0},
+	})
+
+	titlesOf := func(matches []*models.GeotagMatch) []string {
+		titles := make([]string, 0)
+		for _, match := range matches {
+			titles = append(titles, match.Media.Title)
+		}
+		return titles
+	}
+
+	t.Run("Dry run", func(t *testing.T) {
+		dryRun := true
+		matches, err := actions.GeotagMediaFromTrack(db, user, track, models.GeotagOptions{DryRun: &dryRun})
+		if !assert.NoError(t, err) || !assert.Equal(t, []string{"at_start", "between"}, titlesOf(matches)) {
+			return
+		}
+
+		assert.False(t, matches[0].Interpolated)
+		assert.True(t, matches[1].Interpolated)
+		assert.InDelta(t, 60.1, matches[1].Latitude, 0.0001)
+		assert.Equal(t, 60, matches[1].TimeDifference)
+
+		var between models.Media
+		assert.NoError(t, db.First(&between, media[1].ID).Error)
+		assert.Nil(t, between.ExifID)
+	})
+
+	t.Run("Time offset and overwrite", func(t *testing.T) {
+		dryRun := true
+		overwrite := true
+		timeOffset := -59 * 60
+		matches, err := actions.GeotagMediaFromTrack(db, user, track, models.GeotagOptions{
+			DryRun:     &dryRun,
+			Overwrite:  &overwrite,
+			TimeOffset: &timeOffset,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"later"}, titlesOf(matches))
+
+		matches, err = actions.GeotagMediaFromTrack(db, user, track, models.GeotagOptions{DryRun: &dryRun, Overwrite: &overwrite})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"at_start", "between", "tagged"}, titlesOf(matches))
+	})
+
+	t.Run("Save coordinates", func(t *testing.T) {
+		matches, err := actions.GeotagMediaFromTrack(db, user, track, models.GeotagOptions{})
+		if !assert.NoError(t, err) || !assert.Len(t, matches, 2) {
+			return
+		}
+
+		var between models.Media
+		assert.NoError(t, db.Preload("Exif").First(&between, media[1].ID).Error)
+		if assert.NotNil(t, between.Exif) && assert.NotNil(t, between.Exif.GPSLatitude) {
+			assert.InDelta(t, 60.1, *between.Exif.GPSLatitude, 0.0001)
+			assert.InDelta(t, 24.5, *between.Exif.GPSLongitude, 0.0001)
+		}
+
+		edits, err := actions.MediaMetadataEdits(db, media[1].ID)
+		assert.NoError(t, err)
+		if assert.Len(t, edits, 1) {
+			assert.Equal(t, "coordinates", edits[0].Field)
+		}
+
+		var tagged models.Media
+		assert.NoError(t, db.Preload("Exif").First(&tagged, media[2].ID).Error)
+		assert.Equal(t, latitude, *tagged.Exif.GPSLatitude)
+
+		// Media that was geotagged is not matched again
+		matches, err = actions.GeotagMediaFromTrack(db, user, track, models.GeotagOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("Track file paths", func(t *testing.T) {
+		trackPath := path.Join(albumPath, "walk.gpx")
+		gpx := `<gpx><trk><trkseg><trkpt lat="60" lon="24"><time>2021-06-01T10:00:00Z</time></trkpt></trkseg></trk></gpx>`
+		assert.NoError(t, os.WriteFile(trackPath, []byte(gpx), 0644))
+
+		track, err := actions.ReadUserTrackFile(db, user, trackPath)
+		if assert.NoError(t, err) {
+			assert.Len(t, track.Points, 1)
+		}
+
+		_, err = actions.ReadUserTrackFile(db, user, path.Join(albumPath, "..", "outside.gpx"))
+		assert.EqualError(t, err, "track file must be inside one of your albums")
+
+		outsidePath := path.Join(t.TempDir(), "outside.gpx")
+		assert.NoError(t, os.WriteFile(outsidePath, []byte(gpx), 0644))
+		linkPath := path.Join(albumPath, "link.gpx")
+		assert.NoError(t, os.Symlink(outsidePath, linkPath))
+
+		_, err = actions.ReadUserTrackFile(db, user, linkPath)
+		assert.EqualError(t, err, "track file must be inside one of your albums", "symlinks out of the album are not followed")
+	})
+}
@@ -0,0 +1,199 @@
+package actions
+
+import (
+	"log"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// loginLimiterKeys returns the key of the account or share along with the key of the ip address of the client, if known
+func loginLimiterKeys(db *gorm.DB, key string) []string {
+	keys := []string{key}
+	if ipAddress := models.SessionClientFromContext(db.Statement.Context).IPAddress; ipAddress != "" {
+		keys = append(keys, auth.LoginKeyIP(ipAddress))
+	}
+	return keys
+}
+
+// recordFailedLogin saves the failed attempt for admins to see, errors are only logged
+// so they do not hide the reason the login failed
+func recordFailedLogin(db *gorm.DB, attempt models.FailedLoginAttempt) {
+	if attempt.Username != nil && attempt.UserID == nil {
+		var userIDs []int
+		if err := db.Model(&models.User{}).Where("username = ?", *attempt.Username).Limit(1).Pluck("id", &userIDs).Error; err == nil && len(userIDs) > 0 {
+			attempt.UserID = &userIDs[0]
+		}
+	}
+
+	if err := models.RecordFailedLoginAttempt(db, attempt); err != nil {
+		log.Printf("WARN: %s\n", err)
+	}
+}
+
+// AuthorizeUser checks the username and password,
+// throttling failed attempts for the account and for the ip address of the client
+func AuthorizeUser(db *gorm.DB, username string, password string) (*models.User, error) {
+	limiter := auth.GetLoginLimiter()
+	userKey := auth.LoginKeyUser(username)
+	keys := loginLimiterKeys(db, userKey)
+
+	attempt, err := limiter.Attempt(keys...)
+	if err != nil {
+		recordFailedLogin(db, models.FailedLoginAttempt{
+			Kind:     models.LoginAttemptKindPassword,
+			Username: &username,
+			Blocked:  true,
+		})
+		return nil, err
+	}
+	defer attempt.Done()
+
+	user, err := models.AuthorizeUser(db, username, password)
+	if err != nil {
+		if errors.Is(err, models.ErrorInvalidUserCredentials) || errors.Is(err, models.ErrorUserWithoutPassword) {
+			if err := attempt.Failure(); err != nil {
+				return nil, err
+			}
+
+			recordFailedLogin(db, models.FailedLoginAttempt{
+				Kind:     models.LoginAttemptKindPassword,
+				Username: &username,
+			})
+		}
+		return nil, err
+	}
+
+	if err := limiter.Reset(userKey); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// VerifyShareTokenPassword checks the password a client has entered for a share token,
+// throttling failed attempts for the share and for the ip address of the client
+func VerifyShareTokenPassword(db *gorm.DB, token *models.ShareToken, password *string) (bool, error) {
+	if token.Password == nil {
+		return true, nil
+	}
+
+	if password == nil {
+		return false, nil
+	}
+
+	limiter := auth.GetLoginLimiter()
+	shareKey := auth.LoginKeyShareToken(token.ID)
+	keys := loginLimiterKeys(db, shareKey)
+
+	attempt, err := limiter.Attempt(keys...)
+	if err != nil {
+		recordFailedLogin(db, models.FailedLoginAttempt{
+			Kind:         models.LoginAttemptKindShareToken,
+			ShareTokenID: &token.ID,
+			Blocked:      true,
+		})
+		return false, err
+	}
+	defer attempt.Done()
+
+	valid, err := token.CheckPassword(password)
+	if err != nil {
+		return false, err
+	}
+
+	if !valid {
+		if err := attempt.Failure(); err != nil {
+			return false, err
+		}
+
+		recordFailedLogin(db, models.FailedLoginAttempt{
+			Kind:         models.LoginAttemptKindShareToken,
+			ShareTokenID: &token.ID,
+		})
+		return false, nil
+	}
+
+	if err := limiter.Reset(shareKey); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// FailedLoginAttempts returns the failed login attempts that match the filter, most recent first
+func FailedLoginAttempts(db *gorm.DB, filter *models.FailedLoginAttemptFilter, paginate *models.Pagination) ([]*models.FailedLoginAttempt, error) {
+	query := db.Model(&models.FailedLoginAttempt{})
+
+	if filter != nil {
+		if filter.Kind != nil {
+			query = query.Where("kind = ?", *filter.Kind)
+		}
+
+		if filter.Username != nil {
+			query = query.Where("username = ?", *filter.Username)
+		}
+
+		if filter.ShareTokenID != nil {
+			query = query.Where("share_token_id = ?", *filter.ShareTokenID)
+		}
+
+		if filter.IPAddress != nil {
+			query = query.Where("ip_address = ?", *filter.IPAddress)
+		}
+
+		if filter.From != nil {
+			query = query.Where("created_at >= ?", *filter.From)
+		}
+
+		if filter.To != nil {
+			query = query.Where("created_at < ?", *filter.To)
+		}
+	}
+
+	query = query.Order("created_at DESC").Order("id DESC")
+	query = models.FormatSQL(query, nil, paginate)
+
+	var attempts []*models.FailedLoginAttempt
+	if err := query.Find(&attempts).Error; err != nil {
+		return nil, errors.Wrap(err, "get failed login attempts from database")
+	}
+
+	return attempts, nil
+}
+
+// UnlockLogin forgets the failed attempts of a user, such that a locked out user can log in again right away
+func UnlockLogin(username string) error {
+	return auth.GetLoginLimiter().Reset(auth.LoginKeyUser(username))
+}
+
+// SetPasswordPolicy sets the requirements for new passwords of users and share tokens
+func SetPasswordPolicy(db *gorm.DB, policy models.PasswordPolicy) (*models.PasswordPolicy, error) {
+	if policy.MinLength < 1 || policy.MinLength > 128 {
+		return nil, errors.New("min length must be between 1 and 128")
+	}
+
+	// make sure the site info exists, so the policy is not lost
+	if _, err := models.GetSiteInfo(db); err != nil {
+		return nil, err
+	}
+
+	err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.SiteInfo{}).Updates(map[string]interface{}{
+		"password_min_length":         policy.MinLength,
+		"password_require_mixed_case": policy.RequireMixedCase,
+		"password_require_digit":      policy.RequireDigit,
+		"password_require_symbol":     policy.RequireSymbol,
+	}).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "update password policy")
+	}
+
+	siteInfo, err := models.GetSiteInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &siteInfo.PasswordPolicy, nil
+}
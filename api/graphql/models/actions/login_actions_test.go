@@ -0,0 +1,126 @@
+package actions_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLoginThrottling(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "correct-password"
+	user, err := models.RegisterUser(db, "throttled", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx := models.ContextWithSessionClient(context.Background(), models.SessionClient{
+		UserAgent: "Mozilla/5.0",
+		IPAddress: "192.168.1.20",
+	})
+	clientDB := db.WithContext(ctx)
+
+	policy := auth.DefaultLoginLimitPolicy()
+
+	t.Run("Password", func(t *testing.T) {
+		defer auth.GetLoginLimiter().Reset(auth.LoginKeyUser(user.Username), auth.LoginKeyIP("192.168.1.20"))
+
+		for i := 0; i < policy.FreeAttempts; i++ {
+			_, err := actions.AuthorizeUser(clientDB, "throttled", "wrong-password")
+			assert.ErrorIs(t, err, models.ErrorInvalidUserCredentials)
+		}
+
+		_, err := actions.AuthorizeUser(clientDB, "Throttled", "wrong-password")
+		assert.ErrorIs(t, err, models.ErrorInvalidUserCredentials)
+
+		_, err = actions.AuthorizeUser(clientDB, "throttled", password)
+		var blocked *auth.LoginBlockedError
+		assert.ErrorAs(t, err, &blocked, "blocked even with the correct password")
+
+		var attempts []*models.FailedLoginAttempt
+		if !assert.NoError(t, db.Order("id").Find(&attempts).Error) {
+			return
+		}
+
+		if assert.Len(t, attempts, policy.FreeAttempts+2) {
+			assert.Equal(t, models.LoginAttemptKindPassword, attempts[0].Kind)
+			assert.Equal(t, user.ID, *attempts[0].UserID)
+			assert.Equal(t, "192.168.1.20", attempts[0].IPAddress)
+			assert.False(t, attempts[0].Blocked)
+			assert.True(t, attempts[len(attempts)-1].Blocked)
+		}
+
+		filtered, err := actions.FailedLoginAttempts(db, &models.FailedLoginAttemptFilter{Username: &user.Username}, nil)
+		assert.NoError(t, err)
+		assert.Len(t, filtered, policy.FreeAttempts+1, "username is recorded as it was typed")
+
+		assert.NoError(t, actions.UnlockLogin("throttled"))
+		_, err = actions.AuthorizeUser(db, "throttled", password)
+		assert.NoError(t, err, "unlocked by admin")
+	})
+
+	t.Run("Share token password", func(t *testing.T) {
+		sharePassword := "share-password"
+		share, err := actions.ProtectShareToken(db, user.ID, createShareToken(t, db, user).Value, &sharePassword)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer auth.GetLoginLimiter().Reset(auth.LoginKeyShareToken(share.ID), auth.LoginKeyIP("192.168.1.20"))
+
+		valid, err := actions.VerifyShareTokenPassword(clientDB, share, nil)
+		assert.NoError(t, err)
+		assert.False(t, valid)
+
+		wrong := "wrong-password"
+		for i := 0; i <= policy.FreeAttempts; i++ {
+			valid, err := actions.VerifyShareTokenPassword(clientDB, share, &wrong)
+			assert.NoError(t, err)
+			assert.False(t, valid)
+		}
+
+		_, err = actions.VerifyShareTokenPassword(clientDB, share, &sharePassword)
+		var blocked *auth.LoginBlockedError
+		assert.ErrorAs(t, err, &blocked)
+
+		var count int64
+		assert.NoError(t, db.Model(&models.FailedLoginAttempt{}).Where("share_token_id = ?", share.ID).Count(&count).Error)
+		assert.EqualValues(t, policy.FreeAttempts+2, count)
+	})
+
+	t.Run("Password policy", func(t *testing.T) {
+		_, err := actions.SetPasswordPolicy(db, models.PasswordPolicy{MinLength: 0})
+		assert.Error(t, err)
+
+		policy, err := actions.SetPasswordPolicy(db, models.PasswordPolicy{MinLength: 12, RequireDigit: true})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 12, policy.MinLength)
+		assert.True(t, policy.RequireDigit)
+
+		assert.Error(t, models.ValidatePassword(db, "no-digits-in-here"))
+		assert.NoError(t, models.ValidatePassword(db, "has-1-digit-in-here"))
+
+		short := "short1"
+		_, err = actions.ProtectShareToken(db, user.ID, createShareToken(t, db, user).Value, &short)
+		assert.EqualError(t, err, "password must be at least 12 characters long")
+	})
+}
+
+// createShareToken creates an unprotected share token owned by the user
+func createShareToken(t *testing.T, db *gorm.DB, user *models.User) *models.ShareToken {
+	token := models.ShareToken{
+		Value:   utils.GenerateToken(),
+		OwnerID: user.ID,
+	}
+	assert.NoError(t, db.Create(&token).Error)
+	return &token
+}
@@ -2,15 +2,18 @@ package actions
 
 import (
 	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
 func MyMedia(db *gorm.DB, user *models.User, order *models.Ordering, paginate *models.Pagination) ([]*models.Media, error) {
-	if err := user.FillAlbums(db); err != nil {
+	userAlbums, err := userAlbumsScope(db, user, "media.album_id", models.AlbumRoleViewer)
+	if err != nil {
 		return nil, err
 	}
 
-	query := db.Where("media.album_id IN (SELECT user_albums.album_id FROM user_albums WHERE user_albums.user_id = ?)", user.ID)
+	query := db.Scopes(userAlbums).
+		Where("media.motion_photo_id IS NULL")
 	query = models.FormatSQL(query, order, paginate)
 
 	var media []*models.Media
@@ -20,3 +23,35 @@ func MyMedia(db *gorm.DB, user *models.User, order *models.Ordering, paginate *m
 
 	return media, nil
 }
+
+// userMedia returns the media with the given ids, if the user owns or is an editor of all of them
+func userMedia(db *gorm.DB, user *models.User, mediaIDs []int) ([]*models.Media, error) {
+	if len(mediaIDs) == 0 {
+		return nil, errors.New("no media given")
+	}
+
+	userAlbums, err := userAlbumsScope(db, user, "media.album_id", models.AlbumRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	var media []*models.Media
+	err = db.Preload("Exif").
+		Where("media.id IN (?)", mediaIDs).
+		Scopes(userAlbums).
+		Find(&media).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get media from database")
+	}
+
+	uniqueIDs := make(map[int]bool, len(mediaIDs))
+	for _, id := range mediaIDs {
+		uniqueIDs[id] = true
+	}
+
+	if len(media) != len(uniqueIDs) {
+		return nil, errors.New("media not found")
+	}
+
+	return media, nil
+}
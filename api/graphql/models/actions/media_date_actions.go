@@ -0,0 +1,152 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/exif"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ShiftMediaDates moves the date shot of the media by offset, eg. to correct the clock of a camera.
+// If byCamera is set, all media in the same albums shot by the same cameras are shifted as well.
+// Every shift is recorded as a MediaMetadataEdit of the date shot.
+// If writeToFile is set, the corrected dates are also written back to the media files.
+func ShiftMediaDates(db *gorm.DB, user *models.User, mediaIDs []int, offset time.Duration, byCamera bool, writeToFile bool) ([]*models.Media, error) {
+	media, err := userMedia(db, user, mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if byCamera {
+		media, err = sameCameraMedia(db, media)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		edits := make([]models.MediaMetadataEdit, 0, len(media))
+
+		for _, m := range media {
+			oldDateShot := m.DateShot
+			m.DateShot = m.DateShot.Add(offset)
+			if err := tx.Model(&models.Media{}).Where("id = ?", m.ID).Update("date_shot", m.DateShot).Error; err != nil {
+				return errors.Wrap(err, "update date of media")
+			}
+
+			if m.Exif != nil && m.Exif.DateShot != nil {
+				exifDate := m.Exif.DateShot.Add(offset)
+				m.Exif.DateShot = &exifDate
+				if err := tx.Model(&models.MediaEXIF{}).Where("id = ?", m.Exif.ID).Update("date_shot", exifDate).Error; err != nil {
+					return errors.Wrap(err, "update date of media exif")
+				}
+			}
+
+			edits = append(edits, models.MediaMetadataEdit{
+				MediaID:  m.ID,
+				UserID:   &user.ID,
+				Field:    "dateShot",
+				OldValue: formatEditDate(&oldDateShot),
+				NewValue: formatEditDate(&m.DateShot),
+			})
+		}
+
+		if len(edits) == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&edits).Error; err != nil {
+			return errors.Wrap(err, "save media metadata edit history")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if writeToFile {
+		if err := writeMetadataToFiles(media, mediaDateTags); err != nil {
+			return nil, errors.Wrap(err, "dates were updated")
+		}
+	}
+
+	return media, nil
+}
+
+// cameraKey identifies the camera body that shot the media, or returns false if it is unknown
+func cameraKey(media *models.Media) (string, bool) {
+	if media.Exif == nil || media.Exif.Camera == nil {
+		return "", false
+	}
+
+	key := *media.Exif.Camera
+	if media.Exif.CameraSerialNumber != nil {
+		key += "\x00" + *media.Exif.CameraSerialNumber
+	}
+
+	return key, true
+}
+
+// sameCameraMedia returns the given media together with all media in the same albums, shot by the same cameras
+func sameCameraMedia(db *gorm.DB, media []*models.Media) ([]*models.Media, error) {
+	cameras := make(map[string]bool)
+	albumIDs := make([]int, 0)
+	selected := make(map[int]bool)
+
+	for _, m := range media {
+		selected[m.ID] = true
+		albumIDs = append(albumIDs, m.AlbumID)
+		if key, ok := cameraKey(m); ok {
+			cameras[key] = true
+		}
+	}
+
+	var albumMedia []*models.Media
+	if err := db.Preload("Exif").Where("album_id IN (?)", albumIDs).Find(&albumMedia).Error; err != nil {
+		return nil, errors.Wrap(err, "get media of albums")
+	}
+
+	result := media
+	for _, m := range albumMedia {
+		if selected[m.ID] {
+			continue
+		}
+
+		if key, ok := cameraKey(m); ok && cameras[key] {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
+
+// mediaDateTags returns the exiftool tags holding the date the media was shot
+func mediaDateTags(media *models.Media) map[string]interface{} {
+	const layout = "2006:01:02 15:04:05"
+
+	// Dates of videos are stored in UTC
+	if media.Type == models.MediaTypeVideo {
+		date := media.DateShot.UTC().Format(layout)
+		return map[string]interface{}{
+			"CreateDate":      date,
+			"MediaCreateDate": date,
+			"TrackCreateDate": date,
+		}
+	}
+
+	localDate := exif.LocalDateShot(media.DateShot, media.Exif)
+	tags := map[string]interface{}{
+		"DateTimeOriginal": localDate.Format(layout),
+		"CreateDate":       localDate.Format(layout),
+	}
+
+	if media.Exif != nil && media.Exif.TimezoneOffset != nil {
+		tags["OffsetTimeOriginal"] = *media.Exif.TimezoneOffset
+	}
+
+	return tags
+}
@@ -0,0 +1,103 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShiftMediaDates(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	otherUser, err := models.RegisterUser(db, "other", &password, false)
+	assert.NoError(t, err)
+
+	album := models.Album{
+		Title: "wedding",
+		Path:  "/photos/wedding",
+	}
+
+	assert.NoError(t, db.Save(&album).Error)
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&album))
+
+	camera := func(name string) *string { return &name }
+	date := time.Date(2021, 9, 27, 14, 0, 0, 0, time.UTC)
+
+	media := []models.Media{
+		{Title: "a1", Exif: &models.MediaEXIF{Camera: camera("Canon EOS R5"), CameraSerialNumber: camera("111"), DateShot: &date}},
+		{Title: "a2", Exif: &models.MediaEXIF{Camera: camera("Canon EOS R5"), CameraSerialNumber: camera("111"), DateShot: &date}},
+		{Title: "b1", Exif: &models.MediaEXIF{Camera: camera("Canon EOS R5"), CameraSerialNumber: camera("222"), DateShot: &date}},
+		{Title: "no_exif"},
+	}
+
+	for i := range media {
+		media[i].Path = "/photos/wedding/" + media[i].Title
+		media[i].AlbumID = album.ID
+		media[i].DateShot = date
+		assert.NoError(t, db.Create(&media[i]).Error)
+	}
+
+	mediaDates := func() map[string]time.Time {
+		var result []*models.Media
+		assert.NoError(t, db.Preload("Exif").Find(&result).Error)
+
+		dates := make(map[string]time.Time)
+		for _, m := range result {
+			dates[m.Title] = m.DateShot.UTC()
+			if m.Exif != nil {
+				assert.True(t, m.Exif.DateShot.Equal(m.DateShot), "exif date should follow media date")
+			}
+		}
+		return dates
+	}
+
+	t.Run("Not owned media", func(t *testing.T) {
+		_, err := actions.ShiftMediaDates(db, otherUser, []int{media[0].ID}, time.Hour, false, false)
+		assert.Error(t, err)
+	})
+
+	t.Run("Shift selected media", func(t *testing.T) {
+		shifted, err := actions.ShiftMediaDates(db, user, []int{media[0].ID, media[3].ID}, -90*time.Second, false, false)
+		assert.NoError(t, err)
+		assert.Len(t, shifted, 2)
+
+		dates := mediaDates()
+		assert.Equal(t, date.Add(-90*time.Second), dates["a1"])
+		assert.Equal(t, date, dates["a2"])
+		assert.Equal(t, date.Add(-90*time.Second), dates["no_exif"])
+	})
+
+	t.Run("Shift by camera", func(t *testing.T) {
+		shifted, err := actions.ShiftMediaDates(db, user, []int{media[1].ID}, time.Hour, true, false)
+		assert.NoError(t, err)
+		assert.Len(t, shifted, 2)
+
+		dates := mediaDates()
+		assert.Equal(t, date.Add(time.Hour-90*time.Second), dates["a1"])
+		assert.Equal(t, date.Add(time.Hour), dates["a2"])
+		assert.Equal(t, date, dates["b1"], "media from another camera body should not be shifted")
+	})
+
+	t.Run("Shifts are recorded in the edit history", func(t *testing.T) {
+		edits, err := actions.MediaMetadataEdits(db, media[0].ID)
+		assert.NoError(t, err)
+		if assert.Len(t, edits, 2) {
+			assert.Equal(t, "dateShot", edits[0].Field)
+			assert.Equal(t, user.ID, *edits[0].UserID)
+			assert.Equal(t, date.Add(-90*time.Second).Format(time.RFC3339), *edits[0].OldValue)
+			assert.Equal(t, date.Add(time.Hour-90*time.Second).Format(time.RFC3339), *edits[0].NewValue)
+		}
+
+		edits, err = actions.MediaMetadataEdits(db, media[2].ID)
+		assert.NoError(t, err)
+		assert.Empty(t, edits)
+	})
+}
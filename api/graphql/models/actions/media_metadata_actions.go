@@ -0,0 +1,290 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/exif"
+	"github.com/photoview/photoview/api/scanner/reverse_geocoding"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// UpdateMediaMetadata changes the metadata of the given media, fields of the input that are nil are left unchanged.
+// Every change is recorded as a MediaMetadataEdit.
+// If writeToFile is set, the changes are also written to the media files, or their XMP sidecar for RAW files.
+func UpdateMediaMetadata(db *gorm.DB, user *models.User, mediaIDs []int, input models.MediaMetadataInput, writeToFile bool) ([]*models.Media, error) {
+	if err := validateMediaMetadata(input); err != nil {
+		return nil, err
+	}
+
+	media, err := userMedia(db, user, mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range media {
+			if err := updateMediaMetadata(tx, user, m, input); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if writeToFile {
+		err := writeMetadataToFiles(media, func(m *models.Media) map[string]interface{} {
+			return mediaMetadataTags(m, input)
+		})
+
+		if err != nil {
+			return nil, errors.Wrap(err, "metadata was updated")
+		}
+	}
+
+	return media, nil
+}
+
+func validateMediaMetadata(input models.MediaMetadataInput) error {
+	if input.Title != nil && strings.TrimSpace(*input.Title) == "" {
+		return errors.New("title cannot be empty")
+	}
+
+	if input.Coordinates != nil {
+		if input.Coordinates.Latitude < -90 || input.Coordinates.Latitude > 90 {
+			return errors.New("latitude must be between -90 and 90")
+		}
+
+		if input.Coordinates.Longitude < -180 || input.Coordinates.Longitude > 180 {
+			return errors.New("longitude must be between -180 and 180")
+		}
+	}
+
+	return nil
+}
+
+func updateMediaMetadata(tx *gorm.DB, user *models.User, media *models.Media, input models.MediaMetadataInput) error {
+	if media.Exif == nil {
+		media.Exif = &models.MediaEXIF{}
+	}
+	mediaExif := media.Exif
+
+	edits := make([]models.MediaMetadataEdit, 0)
+	recordEdit := func(field string, oldValue *string, newValue *string) {
+		if oldValue == nil && newValue == nil || oldValue != nil && newValue != nil && *oldValue == *newValue {
+			return
+		}
+
+		edits = append(edits, models.MediaMetadataEdit{
+			MediaID:  media.ID,
+			UserID:   &user.ID,
+			Field:    field,
+			OldValue: oldValue,
+			NewValue: newValue,
+		})
+	}
+
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		recordEdit("title", &media.Title, &title)
+		media.Title = title
+	}
+
+	if input.Description != nil {
+		description := emptyToNil(*input.Description)
+		recordEdit("description", mediaExif.Description, description)
+		mediaExif.Description = description
+	}
+
+	if input.DateShot != nil {
+		dateShot := input.DateShot.UTC()
+		recordEdit("dateShot", formatEditDate(&media.DateShot), formatEditDate(&dateShot))
+		media.DateShot = dateShot
+		mediaExif.DateShot = &dateShot
+	}
+
+	if input.Coordinates != nil {
+		recordEdit("coordinates", formatEditCoordinates(mediaExif.Coordinates()), formatEditCoordinates(&models.Coordinates{
+			Latitude:  input.Coordinates.Latitude,
+			Longitude: input.Coordinates.Longitude,
+		}))
+		mediaExif.GPSLatitude = &input.Coordinates.Latitude
+		mediaExif.GPSLongitude = &input.Coordinates.Longitude
+	}
+
+	if input.Keywords != nil {
+		oldKeywords := mediaExif.Keywords
+		mediaExif.SetKeywordList(input.Keywords)
+		recordEdit("keywords", oldKeywords, mediaExif.Keywords)
+	}
+
+	if input.Copyright != nil {
+		copyright := emptyToNil(*input.Copyright)
+		recordEdit("copyright", mediaExif.Copyright, copyright)
+		mediaExif.Copyright = copyright
+	}
+
+	if len(edits) == 0 {
+		return nil
+	}
+
+	if err := tx.Save(mediaExif).Error; err != nil {
+		return errors.Wrap(err, "save media exif")
+	}
+
+	err := tx.Model(&models.Media{}).Where("id = ?", media.ID).Updates(map[string]interface{}{
+		"title":     media.Title,
+		"date_shot": media.DateShot,
+		"exif_id":   mediaExif.ID,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "save media")
+	}
+	media.ExifID = &mediaExif.ID
+
+	if input.Coordinates != nil {
+		if err := reverse_geocoding.UpdateMediaPlace(tx, media, mediaExif); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Create(&edits).Error; err != nil {
+		return errors.Wrap(err, "save media metadata edit history")
+	}
+
+	return nil
+}
+
+func emptyToNil(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func formatEditDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+
+	formatted := date.UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+func formatEditCoordinates(coordinates *models.Coordinates) *string {
+	if coordinates == nil {
+		return nil
+	}
+
+	formatted := fmt.Sprintf("%f,%f", coordinates.Latitude, coordinates.Longitude)
+	return &formatted
+}
+
+// mediaMetadataTags returns the exiftool tags of the metadata fields that are set in the input
+func mediaMetadataTags(media *models.Media, input models.MediaMetadataInput) map[string]interface{} {
+	tags := make(map[string]interface{})
+
+	stringTag := func(value *string) interface{} {
+		if value == nil {
+			return nil
+		}
+		return *value
+	}
+
+	if input.Title != nil {
+		tags["Title"] = media.Title
+	}
+
+	if input.Description != nil {
+		tags["ImageDescription"] = stringTag(media.Exif.Description)
+		tags["Description"] = stringTag(media.Exif.Description)
+	}
+
+	if input.DateShot != nil {
+		for key, value := range mediaDateTags(media) {
+			tags[key] = value
+		}
+	}
+
+	if input.Coordinates != nil {
+		latitudeRef, longitudeRef := "N", "E"
+		if input.Coordinates.Latitude < 0 {
+			latitudeRef = "S"
+		}
+		if input.Coordinates.Longitude < 0 {
+			longitudeRef = "W"
+		}
+
+		tags["GPSLatitude"] = abs(input.Coordinates.Latitude)
+		tags["GPSLatitudeRef"] = latitudeRef
+		tags["GPSLongitude"] = abs(input.Coordinates.Longitude)
+		tags["GPSLongitudeRef"] = longitudeRef
+	}
+
+	if input.Keywords != nil {
+		keywords := media.Exif.KeywordList()
+		if len(keywords) == 0 {
+			tags["Keywords"] = nil
+			tags["Subject"] = nil
+		} else {
+			keywordValues := make([]interface{}, len(keywords))
+			for i, keyword := range keywords {
+				keywordValues[i] = keyword
+			}
+			tags["Keywords"] = keywordValues
+			tags["Subject"] = keywordValues
+		}
+	}
+
+	if input.Copyright != nil {
+		tags["Copyright"] = stringTag(media.Exif.Copyright)
+		tags["Rights"] = stringTag(media.Exif.Copyright)
+	}
+
+	return tags
+}
+
+func abs(value float64) float64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// writeMetadataToFiles writes the exiftool tags returned by tags to the files of the media
+func writeMetadataToFiles(media []*models.Media, tags func(media *models.Media) map[string]interface{}) error {
+	failed := 0
+	var firstErr error
+
+	for _, m := range media {
+		if err := exif.WriteMetadata(m, tags(m)); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return errors.Wrapf(firstErr, "could not write metadata to %d of %d files", failed, len(media))
+	}
+
+	return nil
+}
+
+// MediaMetadataEdits returns the history of metadata changes of the media, newest first
+func MediaMetadataEdits(db *gorm.DB, mediaID int) ([]*models.MediaMetadataEdit, error) {
+	var edits []*models.MediaMetadataEdit
+	if err := db.Where("media_id = ?", mediaID).Order("created_at DESC, id DESC").Find(&edits).Error; err != nil {
+		return nil, errors.Wrap(err, "get metadata edits of media")
+	}
+
+	return edits, nil
+}
@@ -0,0 +1,138 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/scanner/exif"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateMediaMetadata(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	otherUser, err := models.RegisterUser(db, "other", &password, false)
+	assert.NoError(t, err)
+
+	album := models.Album{
+		Title: "album",
+		Path:  "/photos",
+	}
+
+	assert.NoError(t, db.Save(&album).Error)
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&album))
+
+	description := "old description"
+	media := []models.Media{
+		{Title: "pic1", Path: "/photos/pic1", AlbumID: album.ID, Exif: &models.MediaEXIF{Description: &description}},
+		{Title: "pic2", Path: "/photos/pic2", AlbumID: album.ID},
+	}
+
+	assert.NoError(t, db.Save(&media).Error)
+
+	t.Run("Not owned media", func(t *testing.T) {
+		title := "hacked"
+		_, err := actions.UpdateMediaMetadata(db, otherUser, []int{media[0].ID}, models.MediaMetadataInput{Title: &title}, false)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		title := " "
+		_, err := actions.UpdateMediaMetadata(db, user, []int{media[0].ID}, models.MediaMetadataInput{Title: &title}, false)
+		assert.EqualError(t, err, "title cannot be empty")
+
+		_, err = actions.UpdateMediaMetadata(db, user, []int{media[0].ID}, models.MediaMetadataInput{
+			Coordinates: &models.CoordinatesInput{Latitude: 91, Longitude: 0},
+		}, false)
+		assert.Error(t, err)
+	})
+
+	t.Run("Update single media", func(t *testing.T) {
+		title := "Sunset"
+		newDescription := "A sunset over the sea"
+		dateShot := time.Date(2021, 9, 27, 18, 30, 0, 0, time.UTC)
+
+		updated, err := actions.UpdateMediaMetadata(db, user, []int{media[0].ID}, models.MediaMetadataInput{
+			Title:       &title,
+			Description: &newDescription,
+			DateShot:    &dateShot,
+			Coordinates: &models.CoordinatesInput{Latitude: 65.01, Longitude: 25.47},
+			Keywords:    []string{"sunset", " sea "},
+		}, false)
+		assert.NoError(t, err)
+		assert.Len(t, updated, 1)
+
+		var result models.Media
+		assert.NoError(t, db.Preload("Exif").First(&result, media[0].ID).Error)
+		assert.Equal(t, "Sunset", result.Title)
+		assert.True(t, dateShot.Equal(result.DateShot))
+		assert.Equal(t, newDescription, *result.Exif.Description)
+		assert.InDelta(t, 65.01, *result.Exif.GPSLatitude, 0.0001)
+		assert.Equal(t, []string{"sunset", "sea"}, result.Exif.KeywordList())
+
+		edits, err := actions.MediaMetadataEdits(db, media[0].ID)
+		assert.NoError(t, err)
+		assert.Len(t, edits, 5)
+
+		for _, edit := range edits {
+			assert.Equal(t, user.ID, *edit.UserID)
+			if edit.Field == "description" {
+				assert.Equal(t, "old description", *edit.OldValue)
+				assert.Equal(t, newDescription, *edit.NewValue)
+			}
+		}
+	})
+
+	t.Run("Bulk update", func(t *testing.T) {
+		copyright := "© Jane Doe"
+		_, err := actions.UpdateMediaMetadata(db, user, []int{media[0].ID, media[1].ID}, models.MediaMetadataInput{
+			Copyright: &copyright,
+		}, false)
+		assert.NoError(t, err)
+
+		var result []*models.Media
+		assert.NoError(t, db.Preload("Exif").Find(&result).Error)
+		for _, m := range result {
+			if assert.NotNil(t, m.Exif, "media without exif should get one") {
+				assert.Equal(t, copyright, *m.Exif.Copyright)
+			}
+		}
+
+		// Writing the same value again should not add to the history
+		_, err = actions.UpdateMediaMetadata(db, user, []int{media[1].ID}, models.MediaMetadataInput{
+			Copyright: &copyright,
+		}, false)
+		assert.NoError(t, err)
+
+		edits, err := actions.MediaMetadataEdits(db, media[1].ID)
+		assert.NoError(t, err)
+		assert.Len(t, edits, 1)
+	})
+
+	t.Run("Remove description", func(t *testing.T) {
+		empty := ""
+		_, err := actions.UpdateMediaMetadata(db, user, []int{media[0].ID}, models.MediaMetadataInput{Description: &empty}, false)
+		assert.NoError(t, err)
+
+		var result models.Media
+		assert.NoError(t, db.Preload("Exif").First(&result, media[0].ID).Error)
+		assert.Nil(t, result.Exif.Description)
+	})
+
+	t.Run("Write to file without exiftool", func(t *testing.T) {
+		title := "Written"
+		_, err := actions.UpdateMediaMetadata(db, user, []int{media[1].ID}, models.MediaMetadataInput{Title: &title}, true)
+		assert.ErrorIs(t, err, exif.ErrWriteNotSupported)
+
+		var result models.Media
+		assert.NoError(t, db.First(&result, media[1].ID).Error)
+		assert.Equal(t, "Written", result.Title, "database should be updated even if the file could not be written")
+	})
+}
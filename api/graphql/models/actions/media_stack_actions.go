@@ -0,0 +1,176 @@
+package actions
+
+import (
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func authorizeMediaStack(db *gorm.DB, user *models.User, stack *models.MediaStack) error {
+	var album models.Album
+	if err := db.First(&album, stack.AlbumID).Error; err != nil {
+		return errors.Wrap(err, "get album of media stack")
+	}
+
+	canEdit, err := user.HasAlbumRole(db, &album, models.AlbumRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if !canEdit {
+		return errors.New("forbidden")
+	}
+
+	return nil
+}
+
+// SetMediaStackTop makes the given media the top frame of the stack it belongs to
+func SetMediaStackTop(db *gorm.DB, user *models.User, mediaID int) (*models.MediaStack, error) {
+	var media models.Media
+	if err := db.First(&media, mediaID).Error; err != nil {
+		return nil, errors.Wrap(err, "get media from database")
+	}
+
+	if media.StackID == nil {
+		return nil, errors.New("media is not part of a stack")
+	}
+
+	var stack models.MediaStack
+	if err := db.First(&stack, *media.StackID).Error; err != nil {
+		return nil, errors.Wrap(err, "get media stack from database")
+	}
+
+	if err := authorizeMediaStack(db, user, &stack); err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(&stack).Update("top_media_id", media.ID).Error; err != nil {
+		return nil, errors.Wrap(err, "update top frame of media stack")
+	}
+
+	return &stack, nil
+}
+
+// SplitMediaStack removes the given media from a stack, or dissolves the entire stack if mediaIDs is nil.
+// The media that was removed from the stack is returned.
+func SplitMediaStack(db *gorm.DB, user *models.User, stackID int, mediaIDs []int) ([]*models.Media, error) {
+	var stack models.MediaStack
+	if err := db.First(&stack, stackID).Error; err != nil {
+		return nil, errors.Wrap(err, "get media stack from database")
+	}
+
+	if err := authorizeMediaStack(db, user, &stack); err != nil {
+		return nil, err
+	}
+
+	query := db.Where("stack_id = ?", stack.ID)
+	if mediaIDs != nil {
+		query = query.Where("id IN (?)", mediaIDs)
+	}
+
+	var removedMedia []*models.Media
+	if err := query.Find(&removedMedia).Error; err != nil {
+		return nil, errors.Wrap(err, "get media of stack")
+	}
+
+	if len(removedMedia) == 0 {
+		return removedMedia, nil
+	}
+
+	removedIDs := make([]int, len(removedMedia))
+	for i, media := range removedMedia {
+		removedIDs[i] = media.ID
+		media.StackID = nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Media{}).Where("id IN (?)", removedIDs).Update("stack_id", nil).Error; err != nil {
+			return errors.Wrap(err, "remove media from stack")
+		}
+
+		return models.CleanupMediaStacks(tx, stack.AlbumID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return removedMedia, nil
+}
+
+// MergeMediaStacks combines the given media along with the stacks they belong to into a single stack.
+// All media must belong to the same album.
+func MergeMediaStacks(db *gorm.DB, user *models.User, mediaIDs []int) (*models.MediaStack, error) {
+	if len(mediaIDs) < 2 {
+		return nil, errors.New("at least two media are required to make a stack")
+	}
+
+	var media []*models.Media
+	if err := db.Where("id IN (?)", mediaIDs).Order("date_shot, title").Find(&media).Error; err != nil {
+		return nil, errors.Wrap(err, "get media from database")
+	}
+
+	if len(media) != len(mediaIDs) {
+		return nil, errors.New("media not found")
+	}
+
+	albumID := media[0].AlbumID
+	stackIDs := make([]int, 0)
+	var topMediaID *int
+
+	for _, m := range media {
+		if m.AlbumID != albumID {
+			return nil, errors.New("all media of a stack must belong to the same album")
+		}
+
+		if m.StackID != nil {
+			stackIDs = append(stackIDs, *m.StackID)
+		}
+	}
+
+	stack := models.MediaStack{
+		AlbumID: albumID,
+		Kind:    models.MediaStackKindManual,
+	}
+
+	if err := authorizeMediaStack(db, user, &stack); err != nil {
+		return nil, err
+	}
+
+	// Keep the top frame of the first merged stack, otherwise use the first frame
+	if len(stackIDs) > 0 {
+		var existingStack models.MediaStack
+		if err := db.First(&existingStack, stackIDs[0]).Error; err != nil {
+			return nil, errors.Wrap(err, "get media stack from database")
+		}
+		topMediaID = existingStack.TopMediaID
+	}
+
+	if topMediaID == nil {
+		topMediaID = &media[0].ID
+	}
+	stack.TopMediaID = topMediaID
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&stack).Error; err != nil {
+			return errors.Wrap(err, "create media stack")
+		}
+
+		query := tx.Model(&models.Media{}).Where("id IN (?)", mediaIDs)
+		if len(stackIDs) > 0 {
+			query = query.Or("stack_id IN (?)", stackIDs)
+		}
+
+		if err := query.Updates(map[string]interface{}{"stack_id": stack.ID, "stack_checked": true}).Error; err != nil {
+			return errors.Wrap(err, "add media to stack")
+		}
+
+		return models.CleanupMediaStacks(tx, albumID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &stack, nil
+}
@@ -0,0 +1,91 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaStacks(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	album := models.Album{
+		Title: "album",
+		Path:  "/photos",
+	}
+
+	assert.NoError(t, db.Save(&album).Error)
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&album))
+
+	media := []models.Media{
+		{Title: "pic1", Path: "/photos/pic1", AlbumID: album.ID, DateShot: time.Unix(1632758400, 0)},
+		{Title: "pic2", Path: "/photos/pic2", AlbumID: album.ID, DateShot: time.Unix(1632758401, 0)},
+		{Title: "pic3", Path: "/photos/pic3", AlbumID: album.ID, DateShot: time.Unix(1632758402, 0)},
+		{Title: "pic4", Path: "/photos/pic4", AlbumID: album.ID, DateShot: time.Unix(1628762400, 0)},
+	}
+
+	assert.NoError(t, db.Save(&media).Error)
+
+	anotherUser, err := models.RegisterUser(db, "user2", &password, false)
+	assert.NoError(t, err)
+
+	stack, err := actions.MergeMediaStacks(db, user, []int{media[0].ID, media[1].ID, media[2].ID})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, models.MediaStackKindManual, stack.Kind)
+	assert.Equal(t, media[0].ID, *stack.TopMediaID)
+
+	t.Run("Collapsed timeline only includes top frame", func(t *testing.T) {
+		timelineMedia, err := actions.MyTimeline(db, user, nil, nil, nil, nil)
+		assert.NoError(t, err)
+		assert.Len(t, timelineMedia, 2)
+
+		expand := true
+		timelineMedia, err = actions.MyTimeline(db, user, nil, nil, nil, &expand)
+		assert.NoError(t, err)
+		assert.Len(t, timelineMedia, 4)
+	})
+
+	t.Run("Set top frame", func(t *testing.T) {
+		_, err := actions.SetMediaStackTop(db, anotherUser, media[1].ID)
+		assert.EqualError(t, err, "forbidden")
+
+		updatedStack, err := actions.SetMediaStackTop(db, user, media[1].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, media[1].ID, *updatedStack.TopMediaID)
+
+		timelineMedia, err := actions.MyTimeline(db, user, nil, nil, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "pic2", timelineMedia[0].Title)
+	})
+
+	t.Run("Split media from stack", func(t *testing.T) {
+		removed, err := actions.SplitMediaStack(db, user, stack.ID, []int{media[1].ID})
+		assert.NoError(t, err)
+		assert.Len(t, removed, 1)
+
+		var updatedStack models.MediaStack
+		assert.NoError(t, db.First(&updatedStack, stack.ID).Error)
+		assert.Equal(t, media[0].ID, *updatedStack.TopMediaID, "top frame should be reassigned when removed from stack")
+	})
+
+	t.Run("Dissolve stack", func(t *testing.T) {
+		removed, err := actions.SplitMediaStack(db, user, stack.ID, nil)
+		assert.NoError(t, err)
+		assert.Len(t, removed, 2)
+
+		var stackCount int64
+		assert.NoError(t, db.Model(&models.MediaStack{}).Count(&stackCount).Error)
+		assert.Equal(t, int64(0), stackCount)
+	})
+}
@@ -0,0 +1,224 @@
+package actions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/photoview/photoview/api/database"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// Number of highlight media picked for a year summary
+	yearHighlightCount = 12
+	// Number of albums and face groups in a year summary
+	yearTopCount = 5
+)
+
+// Memories returns the media shot on the same calendar day as the date in earlier years, newest year first.
+// Media from February 29th is included on February 28th of years that are not leap years.
+func Memories(db *gorm.DB, user *models.User, date time.Time) ([]*models.MemoryYear, error) {
+	month := database.DateExtract(db, database.DateCompMonth, "media.date_shot")
+	day := database.DateExtract(db, database.DateCompDay, "media.date_shot")
+	year := database.DateExtract(db, database.DateCompYear, "media.date_shot")
+
+	sameDay := fmt.Sprintf("%s = ? AND %s = ?", month, day)
+	sameDayArgs := []interface{}{int(date.Month()), date.Day()}
+
+	if date.Month() == time.February && date.Day() == 28 && !isLeapYear(date.Year()) {
+		sameDay = fmt.Sprintf("%s = ? AND %s IN (?, ?)", month, day)
+		sameDayArgs = []interface{}{int(date.Month()), 28, 29}
+	}
+
+	timelineMedia, err := userTimelineMedia(db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var media []*models.Media
+	err = timelineMedia.
+		Where(sameDay, sameDayArgs...).
+		Where(fmt.Sprintf("%s < ?", year), date.Year()).
+		Order("media.date_shot DESC").
+		Find(&media).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get memories from database")
+	}
+
+	memories := make([]*models.MemoryYear, 0)
+	for _, m := range media {
+		mediaYear := m.DateShot.Year()
+
+		if len(memories) == 0 || memories[len(memories)-1].Year != mediaYear {
+			memories = append(memories, &models.MemoryYear{
+				Year:     mediaYear,
+				YearsAgo: date.Year() - mediaYear,
+				Media:    make([]*models.Media, 0),
+			})
+		}
+
+		memory := memories[len(memories)-1]
+		memory.Media = append(memory.Media, m)
+	}
+
+	return memories, nil
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+type monthCount struct {
+	Month      int
+	MediaCount int
+}
+
+type idCount struct {
+	ID         int
+	MediaCount int
+}
+
+// YearSummary returns statistics of the media the user shot in the year,
+// along with a selection of highlights where favorites are picked first
+func YearSummary(db *gorm.DB, user *models.User, year int) (*models.YearSummary, error) {
+	timelineMedia, err := userTimelineMedia(db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	yearMedia := func() *gorm.DB {
+		return timelineMedia.Session(&gorm.Session{}).
+			Where("media.date_shot >= ?", time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)).
+			Where("media.date_shot < ?", time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC))
+	}
+
+	summary := models.YearSummary{
+		Year:       year,
+		Months:     make([]*models.YearSummaryMonth, 12),
+		TopAlbums:  make([]*models.YearSummaryAlbum, 0),
+		TopFaces:   make([]*models.YearSummaryFaceGroup, 0),
+		Highlights: make([]*models.Media, 0),
+	}
+
+	for i := range summary.Months {
+		summary.Months[i] = &models.YearSummaryMonth{Month: i + 1}
+	}
+
+	month := database.DateExtract(db, database.DateCompMonth, "media.date_shot")
+
+	var monthCounts []*monthCount
+	err = yearMedia().
+		Select(fmt.Sprintf("%s AS month, COUNT(*) AS media_count", month)).
+		Group(month).
+		Scan(&monthCounts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "count media by month")
+	}
+
+	for _, count := range monthCounts {
+		if count.Month >= 1 && count.Month <= 12 {
+			summary.Months[count.Month-1].MediaCount = count.MediaCount
+			summary.MediaCount += count.MediaCount
+		}
+	}
+
+	var albumCounts []*idCount
+	err = yearMedia().
+		Select("media.album_id AS id, COUNT(*) AS media_count").
+		Group("media.album_id").
+		Order("media_count DESC").
+		Limit(yearTopCount).
+		Scan(&albumCounts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "count media by album")
+	}
+
+	for _, count := range albumCounts {
+		var album models.Album
+		if err := db.First(&album, count.ID).Error; err != nil {
+			return nil, errors.Wrap(err, "get top album")
+		}
+
+		summary.TopAlbums = append(summary.TopAlbums, &models.YearSummaryAlbum{Album: &album, MediaCount: count.MediaCount})
+	}
+
+	var faceCounts []*idCount
+	err = db.Table("image_faces").
+		Select("image_faces.face_group_id AS id, COUNT(DISTINCT image_faces.media_id) AS media_count").
+		Where("image_faces.media_id IN (?)", yearMedia().Select("media.id")).
+		Group("image_faces.face_group_id").
+		Order("media_count DESC").
+		Limit(yearTopCount).
+		Scan(&faceCounts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "count media by face group")
+	}
+
+	for _, count := range faceCounts {
+		var faceGroup models.FaceGroup
+		if err := db.First(&faceGroup, count.ID).Error; err != nil {
+			return nil, errors.Wrap(err, "get top face group")
+		}
+
+		summary.TopFaces = append(summary.TopFaces, &models.YearSummaryFaceGroup{FaceGroup: &faceGroup, MediaCount: count.MediaCount})
+	}
+
+	highlights, err := yearHighlights(db, user, yearMedia)
+	if err != nil {
+		return nil, err
+	}
+	summary.Highlights = highlights
+
+	return &summary, nil
+}
+
+// yearHighlights picks the favorites of the year, and fills up the rest with media spread evenly over the year
+func yearHighlights(db *gorm.DB, user *models.User, yearMedia func() *gorm.DB) ([]*models.Media, error) {
+	favoriteIDs := db.Table("user_media_data").Select("media_id").Where("user_id = ?", user.ID).Where("favorite")
+
+	var highlights []*models.Media
+	err := yearMedia().
+		Where("media.id IN (?)", favoriteIDs).
+		Order("media.date_shot").
+		Limit(yearHighlightCount).
+		Find(&highlights).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get favorite media of year")
+	}
+
+	remaining := yearHighlightCount - len(highlights)
+	if remaining <= 0 {
+		return highlights, nil
+	}
+
+	var otherIDs []int
+	err = yearMedia().
+		Where("media.id NOT IN (?)", favoriteIDs).
+		Order("media.date_shot").
+		Pluck("media.id", &otherIDs).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get media of year")
+	}
+
+	if len(otherIDs) == 0 {
+		return highlights, nil
+	}
+
+	pickedIDs := make([]int, 0, remaining)
+	if len(otherIDs) <= remaining {
+		pickedIDs = otherIDs
+	} else {
+		for i := 0; i < remaining; i++ {
+			pickedIDs = append(pickedIDs, otherIDs[i*len(otherIDs)/remaining])
+		}
+	}
+
+	var picked []*models.Media
+	if err := db.Where("id IN (?)", pickedIDs).Order("date_shot").Find(&picked).Error; err != nil {
+		return nil, errors.Wrap(err, "get highlight media of year")
+	}
+
+	return append(highlights, picked...), nil
+}
@@ -0,0 +1,141 @@
+package actions_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemories(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	album := models.Album{Title: "album", Path: "/photos"}
+	assert.NoError(t, db.Save(&album).Error)
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&album))
+
+	media := []models.Media{
+		{Title: "2019a", Path: "/photos/2019a", AlbumID: album.ID, DateShot: time.Date(2019, 6, 1, 9, 0, 0, 0, time.UTC)},
+		{Title: "2019b", Path: "/photos/2019b", AlbumID: album.ID, DateShot: time.Date(2019, 6, 1, 18, 0, 0, 0, time.UTC)},
+		{Title: "2020", Path: "/photos/2020", AlbumID: album.ID, DateShot: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{Title: "other_day", Path: "/photos/other_day", AlbumID: album.ID, DateShot: time.Date(2020, 6, 2, 12, 0, 0, 0, time.UTC)},
+		{Title: "this_year", Path: "/photos/this_year", AlbumID: album.ID, DateShot: time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{Title: "leap_day", Path: "/photos/leap_day", AlbumID: album.ID, DateShot: time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC)},
+	}
+	assert.NoError(t, db.Save(&media).Error)
+
+	t.Run("Same day in earlier years", func(t *testing.T) {
+		memories, err := actions.Memories(db, user, time.Date(2021, 6, 1, 20, 0, 0, 0, time.UTC))
+		if !assert.NoError(t, err) || !assert.Len(t, memories, 2) {
+			return
+		}
+
+		assert.Equal(t, 2020, memories[0].Year)
+		assert.Equal(t, 1, memories[0].YearsAgo)
+		assert.Len(t, memories[0].Media, 1)
+
+		assert.Equal(t, 2019, memories[1].Year)
+		assert.Equal(t, 2, memories[1].YearsAgo)
+		if assert.Len(t, memories[1].Media, 2) {
+			assert.Equal(t, "2019b", memories[1].Media[0].Title)
+		}
+	})
+
+	t.Run("Leap day", func(t *testing.T) {
+		memories, err := actions.Memories(db, user, time.Date(2021, 2, 28, 12, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		if assert.Len(t, memories, 1) {
+			assert.Equal(t, "leap_day", memories[0].Media[0].Title)
+		}
+
+		memories, err = actions.Memories(db, user, time.Date(2024, 2, 28, 12, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		assert.Empty(t, memories)
+	})
+}
+
+func TestYearSummary(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	album := models.Album{Title: "album", Path: "/photos"}
+	assert.NoError(t, db.Save(&album).Error)
+
+	otherAlbum := models.Album{Title: "other", Path: "/other"}
+	assert.NoError(t, db.Save(&otherAlbum).Error)
+
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&album, &otherAlbum))
+
+	media := make([]models.Media, 0)
+	for i := 0; i < 20; i++ {
+		albumID := album.ID
+		if i%4 == 0 {
+			albumID = otherAlbum.ID
+		}
+
+		media = append(media, models.Media{
+			Title:    fmt.Sprintf("media%d", i),
+			Path:     fmt.Sprintf("/photos/media%d", i),
+			AlbumID:  albumID,
+			DateShot: time.Date(2021, time.Month(i%3+1), i+1, 12, 0, 0, 0, time.UTC),
+		})
+	}
+	media = append(media, models.Media{Title: "other_year", Path: "/photos/other_year", AlbumID: album.ID, DateShot: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)})
+	assert.NoError(t, db.Save(&media).Error)
+
+	_, err = user.FavoriteMedia(db, media[19].ID, true)
+	assert.NoError(t, err)
+
+	faceGroup := models.FaceGroup{}
+	assert.NoError(t, db.Save(&faceGroup).Error)
+	for _, m := range media[:3] {
+		face := models.ImageFace{FaceGroupID: faceGroup.ID, MediaID: m.ID}
+		assert.NoError(t, db.Omit("Media").Create(&face).Error)
+	}
+
+	summary, err := actions.YearSummary(db, user, 2021)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 2021, summary.Year)
+	assert.Equal(t, 20, summary.MediaCount)
+
+	if assert.Len(t, summary.Months, 12) {
+		assert.Equal(t, 7, summary.Months[0].MediaCount)
+		assert.Equal(t, 7, summary.Months[1].MediaCount)
+		assert.Equal(t, 6, summary.Months[2].MediaCount)
+		assert.Equal(t, 0, summary.Months[4].MediaCount)
+	}
+
+	if assert.Len(t, summary.TopAlbums, 2) {
+		assert.Equal(t, album.ID, summary.TopAlbums[0].Album.ID)
+		assert.Equal(t, 15, summary.TopAlbums[0].MediaCount)
+		assert.Equal(t, 5, summary.TopAlbums[1].MediaCount)
+	}
+
+	if assert.Len(t, summary.TopFaces, 1) {
+		assert.Equal(t, faceGroup.ID, summary.TopFaces[0].FaceGroup.ID)
+		assert.Equal(t, 3, summary.TopFaces[0].MediaCount)
+	}
+
+	if assert.Len(t, summary.Highlights, 12) {
+		assert.Equal(t, media[19].ID, summary.Highlights[0].ID)
+	}
+
+	empty, err := actions.YearSummary(db, user, 2000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, empty.MediaCount)
+	assert.Empty(t, empty.Highlights)
+}
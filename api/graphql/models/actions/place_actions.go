@@ -0,0 +1,92 @@
+package actions
+
+import (
+	"sort"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type placeCount struct {
+	CountryCode string
+	Country     string
+	Region      *string
+	City        string
+	MediaCount  int
+}
+
+// MyPlaces returns the countries, regions and cities where the media the user can view was taken,
+// ordered by the number of media taken there
+func MyPlaces(db *gorm.DB, user *models.User) ([]*models.PlaceCountry, error) {
+	userAlbums, err := userAlbumsScope(db, user, "media.album_id", models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []*placeCount
+	err = db.Model(&models.Media{}).
+		Select("place_country_code AS country_code, place_country AS country, place_region AS region, place_city AS city, COUNT(*) AS media_count").
+		Scopes(userAlbums).
+		Where("motion_photo_id IS NULL").
+		Where("place_country_code IS NOT NULL").
+		Group("place_country_code, place_country, place_region, place_city").
+		Order("media_count DESC, place_city").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "count media by place")
+	}
+
+	countries := make([]*models.PlaceCountry, 0)
+	countriesByCode := make(map[string]*models.PlaceCountry)
+	regionsByKey := make(map[string]*models.PlaceRegion)
+
+	for _, count := range counts {
+		country, found := countriesByCode[count.CountryCode]
+		if !found {
+			country = &models.PlaceCountry{
+				CountryCode: count.CountryCode,
+				Name:        count.Country,
+				Regions:     make([]*models.PlaceRegion, 0),
+			}
+			countriesByCode[count.CountryCode] = country
+			countries = append(countries, country)
+		}
+
+		regionKey := count.CountryCode + "\x00"
+		if count.Region != nil {
+			regionKey += *count.Region
+		} else {
+			regionKey += "\x00"
+		}
+
+		region, found := regionsByKey[regionKey]
+		if !found {
+			region = &models.PlaceRegion{
+				Name:   count.Region,
+				Cities: make([]*models.PlaceCity, 0),
+			}
+			regionsByKey[regionKey] = region
+			country.Regions = append(country.Regions, region)
+		}
+
+		region.Cities = append(region.Cities, &models.PlaceCity{
+			Name:       count.City,
+			MediaCount: count.MediaCount,
+		})
+		region.MediaCount += count.MediaCount
+		country.MediaCount += count.MediaCount
+	}
+
+	sort.SliceStable(countries, func(i, j int) bool {
+		return countries[i].MediaCount > countries[j].MediaCount
+	})
+
+	for _, country := range countries {
+		sort.SliceStable(country.Regions, func(i, j int) bool {
+			return country.Regions[i].MediaCount > country.Regions[j].MediaCount
+		})
+	}
+
+	return countries, nil
+}
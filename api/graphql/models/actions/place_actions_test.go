@@ -0,0 +1,104 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMyPlaces(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	otherUser, err := models.RegisterUser(db, "other", &password, false)
+	assert.NoError(t, err)
+
+	album := models.Album{
+		Title: "album",
+		Path:  "/photos",
+	}
+
+	assert.NoError(t, db.Save(&album).Error)
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&album))
+
+	place := func(countryCode string, country string, region *string, city string) models.MediaPlace {
+		return models.MediaPlace{CountryCode: &countryCode, Country: &country, Region: region, City: &city}
+	}
+
+	uusimaa := "Uusimaa"
+	media := []models.Media{
+		{Title: "pic1", Path: "/photos/pic1", AlbumID: album.ID, Place: place("FI", "Finland", &uusimaa, "Helsinki")},
+		{Title: "pic2", Path: "/photos/pic2", AlbumID: album.ID, Place: place("FI", "Finland", &uusimaa, "Helsinki")},
+		{Title: "pic3", Path: "/photos/pic3", AlbumID: album.ID, Place: place("FI", "Finland", &uusimaa, "Espoo")},
+		{Title: "pic4", Path: "/photos/pic4", AlbumID: album.ID, Place: place("NZ", "New Zealand", nil, "Auckland")},
+		{Title: "pic5", Path: "/photos/pic5", AlbumID: album.ID},
+	}
+
+	assert.NoError(t, db.Save(&media).Error)
+
+	t.Run("Place hierarchy", func(t *testing.T) {
+		countries, err := actions.MyPlaces(db, user)
+		if !assert.NoError(t, err) || !assert.Len(t, countries, 2) {
+			return
+		}
+
+		assert.Equal(t, "FI", countries[0].CountryCode)
+		assert.Equal(t, "Finland", countries[0].Name)
+		assert.Equal(t, 3, countries[0].MediaCount)
+
+		if assert.Len(t, countries[0].Regions, 1) {
+			region := countries[0].Regions[0]
+			assert.Equal(t, &uusimaa, region.Name)
+			assert.Equal(t, 3, region.MediaCount)
+
+			if assert.Len(t, region.Cities, 2) {
+				assert.Equal(t, "Helsinki", region.Cities[0].Name)
+				assert.Equal(t, 2, region.Cities[0].MediaCount)
+				assert.Equal(t, "Espoo", region.Cities[1].Name)
+			}
+		}
+
+		assert.Equal(t, "NZ", countries[1].CountryCode)
+		if assert.Len(t, countries[1].Regions, 1) {
+			assert.Nil(t, countries[1].Regions[0].Name)
+			assert.Equal(t, 1, countries[1].Regions[0].MediaCount)
+		}
+	})
+
+	t.Run("Places of other users", func(t *testing.T) {
+		countries, err := actions.MyPlaces(db, otherUser)
+		assert.NoError(t, err)
+		assert.Empty(t, countries)
+	})
+
+	t.Run("Search by place", func(t *testing.T) {
+		tests := map[string][]string{
+			"place:helsinki":      {"pic1", "pic2"},
+			"place:uusimaa":       {"pic1", "pic2", "pic3"},
+			"place:nz":            {"pic4"},
+			`place:"new zealand"`: {"pic4"},
+			"place:finland pic3":  {"pic3"},
+			"place:stockholm":     {},
+		}
+
+		for query, expected := range tests {
+			result, err := actions.Search(db, query, user.ID, nil, nil)
+			if !assert.NoError(t, err, query) {
+				continue
+			}
+
+			titles := make([]string, 0)
+			for _, media := range result.Media {
+				titles = append(titles, media.Title)
+			}
+
+			assert.ElementsMatch(t, expected, titles, query)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package actions
+
+import (
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/storage_stats"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// SetUserQuota sets the limits on the media of a user, a limit that is nil is removed
+func SetUserQuota(db *gorm.DB, userID int, maxMediaCount *int, maxStorageSize *int) (*models.User, error) {
+	if maxMediaCount != nil && *maxMediaCount < 0 {
+		return nil, errors.New("max media count cannot be negative")
+	}
+
+	if maxStorageSize != nil && *maxStorageSize < 0 {
+		return nil, errors.New("max storage size cannot be negative")
+	}
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, errors.Wrap(err, "get user from database")
+	}
+
+	user.MaxMediaCount = maxMediaCount
+	user.MaxStorageSize = nil
+	if maxStorageSize != nil {
+		size := int64(*maxStorageSize)
+		user.MaxStorageSize = &size
+	}
+
+	err := db.Model(&user).Select("MaxMediaCount", "MaxStorageSize").Updates(&user).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "update quota of user")
+	}
+
+	return &user, nil
+}
+
+// UserQuota returns the limits on the media of the user along with the current usage
+func UserQuota(db *gorm.DB, user *models.User) (*models.UserQuota, error) {
+	storage, err := storage_stats.GetUserStorage(db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := models.UserQuota{
+		MaxMediaCount: user.MaxMediaCount,
+		MediaCount:    storage.MediaCount(),
+		StorageSize:   int(storage.OriginalSize),
+	}
+
+	if user.MaxMediaCount != nil && quota.MediaCount >= *user.MaxMediaCount {
+		quota.Exceeded = true
+	}
+
+	if user.MaxStorageSize != nil {
+		maxStorageSize := int(*user.MaxStorageSize)
+		quota.MaxStorageSize = &maxStorageSize
+
+		if storage.OriginalSize >= *user.MaxStorageSize {
+			quota.Exceeded = true
+		}
+	}
+
+	return &quota, nil
+}
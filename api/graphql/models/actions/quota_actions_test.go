@@ -0,0 +1,103 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/scanner/storage_stats"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserQuota(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+	test_utils.FilesystemTest(t)
+	storage_stats.InvalidateAll()
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	album := models.Album{Title: "album", Path: "/photos/album"}
+	if !assert.NoError(t, db.Save(&album).Error) {
+		return
+	}
+
+	if !assert.NoError(t, db.Model(&user).Association("Albums").Append(&album)) {
+		return
+	}
+
+	fileSize := int64(1000)
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		media := models.Media{Title: name, Path: "/photos/album/" + name, AlbumID: album.ID, Type: models.MediaTypePhoto}
+		if !assert.NoError(t, db.Save(&media).Error) {
+			return
+		}
+
+		mediaURL := models.MediaURL{MediaID: media.ID, MediaName: name, Purpose: models.MediaOriginal, FileSize: fileSize}
+		if !assert.NoError(t, db.Save(&mediaURL).Error) {
+			return
+		}
+	}
+
+	t.Run("Unlimited", func(t *testing.T) {
+		quota, err := actions.UserQuota(db, user)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Nil(t, quota.MaxMediaCount)
+		assert.Nil(t, quota.MaxStorageSize)
+		assert.Equal(t, 2, quota.MediaCount)
+		assert.Equal(t, 2000, quota.StorageSize)
+		assert.False(t, quota.Exceeded)
+	})
+
+	t.Run("Set limits", func(t *testing.T) {
+		maxMediaCount := 10
+		maxStorageSize := 2000
+		updatedUser, err := actions.SetUserQuota(db, user.ID, &maxMediaCount, &maxStorageSize)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		quota, err := actions.UserQuota(db, updatedUser)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, &maxMediaCount, quota.MaxMediaCount)
+		assert.Equal(t, &maxStorageSize, quota.MaxStorageSize)
+		assert.True(t, quota.Exceeded)
+	})
+
+	t.Run("Remove limits", func(t *testing.T) {
+		updatedUser, err := actions.SetUserQuota(db, user.ID, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var savedUser models.User
+		if !assert.NoError(t, db.First(&savedUser, user.ID).Error) {
+			return
+		}
+
+		assert.Nil(t, savedUser.MaxMediaCount)
+		assert.Nil(t, savedUser.MaxStorageSize)
+
+		quota, err := actions.UserQuota(db, updatedUser)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.False(t, quota.Exceeded)
+	})
+
+	t.Run("Negative limit", func(t *testing.T) {
+		maxMediaCount := -1
+		_, err := actions.SetUserQuota(db, user.ID, &maxMediaCount, nil)
+		assert.Error(t, err)
+	})
+}
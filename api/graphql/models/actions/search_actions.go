@@ -3,7 +3,6 @@ package actions
 import (
 	"strings"
 
-	"github.com/photoview/photoview/api/database/drivers"
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
@@ -22,19 +21,26 @@ func Search(db *gorm.DB, query string, userID int, _limitMedia *int, _limitAlbum
 		limitAlbums = *_limitAlbums
 	}
 
-	wildQuery := "%" + strings.ToLower(query) + "%"
+	text, terms := parseSearchQuery(query)
+	wildQuery := "%" + strings.ToLower(text) + "%"
 
 	var media []*models.Media
 
-	userSubquery := db.Table("user_albums").Where("user_id = ?", userID)
-	if drivers.POSTGRES.MatchDatabase(db) {
-		userSubquery = userSubquery.Where("album_id = \"Album\".id")
-	} else {
-		userSubquery = userSubquery.Where("album_id = Album.id")
+	user := &models.User{Model: models.Model{ID: userID}}
+
+	userMediaAlbums, err := userAlbumsScope(db, user, "media.album_id", models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaQuery, err := filterMedia(db, db.Joins("Album"), terms)
+	if err != nil {
+		return nil, err
 	}
 
-	err := db.Joins("Album").
-		Where("EXISTS (?)", userSubquery).
+	err = mediaQuery.
+		Scopes(userMediaAlbums).
+		Where("media.motion_photo_id IS NULL").
 		Where("LOWER(media.title) LIKE ? OR LOWER(media.path) LIKE ?", wildQuery, wildQuery).
 		Clauses(clause.OrderBy{
 			Expression: clause.Expr{
@@ -50,8 +56,22 @@ func Search(db *gorm.DB, query string, userID int, _limitMedia *int, _limitAlbum
 
 	var albums []*models.Album
 
+	// Albums have no metadata to filter by, so only the plain text of a filtered search is matched
+	if text == "" && len(terms) > 0 {
+		return &models.SearchResult{
+			Query:  query,
+			Media:  media,
+			Albums: []*models.Album{},
+		}, nil
+	}
+
+	userAlbums, err := userAlbumsScope(db, user, "albums.id", models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
 	err = db.
-		Where("EXISTS (?)", db.Table("user_albums").Where("user_id = ?", userID).Where("album_id = albums.id")).
+		Scopes(userAlbums).
 		Where("albums.title LIKE ? OR albums.path LIKE ?", wildQuery, wildQuery).
 		Clauses(clause.OrderBy{
 			Expression: clause.Expr{
@@ -100,3 +100,79 @@ func TestSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchFilters(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	user, err := models.RegisterUser(db, "user", nil, true)
+	assert.NoError(t, err)
+
+	rootAlbum := models.Album{
+		Title: "root_album",
+		Path:  "/media/",
+	}
+
+	assert.NoError(t, db.Create(&rootAlbum).Error)
+	assert.NoError(t, db.Model(&rootAlbum).Association("Owners").Append(user))
+
+	camera := func(name string) *string { return &name }
+	iso := func(value int64) *int64 { return &value }
+	exposure := func(value float64) *float64 { return &value }
+
+	media := []models.Media{
+		{
+			Title: "helsinki.jpg",
+			Exif:  &models.MediaEXIF{Camera: camera("Canon EOS 600D"), Iso: iso(800), Exposure: exposure(1.0 / 250.0), City: camera("Helsinki"), Country: camera("Finland")},
+		},
+		{
+			Title: "oulu.jpg",
+			Exif:  &models.MediaEXIF{Camera: camera("Canon EOS R5"), Iso: iso(3200), Exposure: exposure(1.0 / 60.0), City: camera("Oulu"), Country: camera("Finland")},
+		},
+		{
+			Title: "new_york.jpg",
+			Exif:  &models.MediaEXIF{Camera: camera("NIKON D750"), Iso: iso(100), Exposure: exposure(1.0 / 250.0), City: camera("New York"), Country: camera("USA")},
+		},
+		{
+			Title: "no_exif.jpg",
+		},
+	}
+
+	for i := range media {
+		media[i].Path = fmt.Sprintf("/media/%s", media[i].Title)
+		media[i].AlbumID = rootAlbum.ID
+		assert.NoError(t, db.Create(&media[i]).Error)
+	}
+
+	searchTests := []struct {
+		query         string
+		expectedMedia []string
+	}{
+		{query: "camera:canon", expectedMedia: []string{"helsinki.jpg", "oulu.jpg"}},
+		{query: "CAMERA:canon iso:>1000", expectedMedia: []string{"oulu.jpg"}},
+		{query: "iso:100..800", expectedMedia: []string{"helsinki.jpg", "new_york.jpg"}},
+		{query: "exposure:1/250", expectedMedia: []string{"helsinki.jpg", "new_york.jpg"}},
+		{query: `city:"new york"`, expectedMedia: []string{"new_york.jpg"}},
+		{query: "country:finland hel", expectedMedia: []string{"helsinki.jpg"}},
+		{query: "unknown:filter", expectedMedia: []string{}},
+	}
+
+	for _, test := range searchTests {
+		t.Run(fmt.Sprintf("Search query: '%s'", test.query), func(t *testing.T) {
+			result, err := actions.Search(db, test.query, user.ID, nil, nil)
+			assert.NoError(t, err)
+
+			titles := make([]string, len(result.Media))
+			for i, media := range result.Media {
+				titles[i] = media.Title
+			}
+
+			assert.ElementsMatch(t, test.expectedMedia, titles)
+			assert.Empty(t, result.Albums)
+		})
+	}
+
+	t.Run("Invalid numeric filter", func(t *testing.T) {
+		_, err := actions.Search(db, "iso:high", user.ID, nil, nil)
+		assert.Error(t, err)
+	})
+}
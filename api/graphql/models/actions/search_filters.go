@@ -0,0 +1,190 @@
+package actions
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// searchFilter filters media by a column of its exif metadata, using a `key:value` term of a search query
+type searchFilter struct {
+	column  string
+	numeric bool
+	// place filters match any part of the place of the media, instead of an exif column
+	place bool
+}
+
+// exifSearchFilters maps the keys of search filters to the media_exif columns they filter
+var exifSearchFilters = map[string]searchFilter{
+	"description":     {column: "description"},
+	"camera":          {column: "camera"},
+	"maker":           {column: "maker"},
+	"make":            {column: "maker"},
+	"lens":            {column: "lens"},
+	"serial":          {column: "camera_serial_number"},
+	"lensserial":      {column: "lens_serial_number"},
+	"artist":          {column: "artist"},
+	"copyright":       {column: "copyright"},
+	"caption":         {column: "caption"},
+	"byline":          {column: "byline"},
+	"city":            {column: "city"},
+	"country":         {column: "country"},
+	"keyword":         {column: "keywords"},
+	"place":           {place: true},
+	"timezone":        {column: "timezone_offset"},
+	"projection":      {column: "projection_type"},
+	"tag":             {column: "raw_tags"},
+	"exposure":        {column: "exposure", numeric: true},
+	"aperture":        {column: "aperture", numeric: true},
+	"iso":             {column: "iso", numeric: true},
+	"focallength":     {column: "focal_length", numeric: true},
+	"flash":           {column: "flash", numeric: true},
+	"orientation":     {column: "orientation", numeric: true},
+	"exposureprogram": {column: "exposure_program", numeric: true},
+	"whitebalance":    {column: "white_balance", numeric: true},
+	"meteringmode":    {column: "metering_mode", numeric: true},
+	"distance":        {column: "subject_distance", numeric: true},
+	"altitude":        {column: "gps_altitude", numeric: true},
+	"direction":       {column: "gps_direction", numeric: true},
+}
+
+// searchTerm is a parsed `key:value` term of a search query
+type searchTerm struct {
+	filter searchFilter
+	value  string
+}
+
+var (
+	searchTokenRegex = regexp.MustCompile(`[^\s"]+(?:"[^"]*"?)?|"[^"]*"?`)
+	searchTermRegex  = regexp.MustCompile(`^([a-zA-Z]+):(.+)$`)
+)
+
+// parseSearchQuery splits a search query into its plain text and its metadata filters.
+// Values containing spaces can be quoted, eg. `city:"new york"`.
+func parseSearchQuery(query string) (string, []searchTerm) {
+	textTokens := make([]string, 0)
+	terms := make([]searchTerm, 0)
+
+	for _, token := range searchTokenRegex.FindAllString(query, -1) {
+		match := searchTermRegex.FindStringSubmatch(token)
+		if match != nil {
+			filter, found := exifSearchFilters[strings.ToLower(match[1])]
+			value := strings.Trim(match[2], `"`)
+			if found && value != "" {
+				terms = append(terms, searchTerm{filter: filter, value: value})
+				continue
+			}
+		}
+
+		textTokens = append(textTokens, strings.Trim(token, `"`))
+	}
+
+	return strings.Join(textTokens, " "), terms
+}
+
+// condition returns the SQL condition on the media_exif table for the term
+func (term searchTerm) condition() (string, []interface{}, error) {
+	if !term.filter.numeric {
+		return fmt.Sprintf("LOWER(%s) LIKE ?", term.filter.column), []interface{}{"%" + strings.ToLower(term.value) + "%"}, nil
+	}
+
+	if bounds := strings.SplitN(term.value, "..", 2); len(bounds) == 2 {
+		min, err := parseSearchNumber(bounds[0])
+		if err != nil {
+			return "", nil, err
+		}
+
+		max, err := parseSearchNumber(bounds[1])
+		if err != nil {
+			return "", nil, err
+		}
+
+		return fmt.Sprintf("%s BETWEEN ? AND ?", term.filter.column), []interface{}{min, max}, nil
+	}
+
+	for _, operator := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(term.value, operator) {
+			value, err := parseSearchNumber(strings.TrimPrefix(term.value, operator))
+			if err != nil {
+				return "", nil, err
+			}
+
+			return fmt.Sprintf("%s %s ?", term.filter.column, operator), []interface{}{value}, nil
+		}
+	}
+
+	value, err := parseSearchNumber(strings.TrimPrefix(term.value, "="))
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Allow a small difference, as values such as apertures are stored as floats
+	tolerance := math.Abs(value) * 0.01
+	return fmt.Sprintf("%s BETWEEN ? AND ?", term.filter.column), []interface{}{value - tolerance, value + tolerance}, nil
+}
+
+// parseSearchNumber parses a number of a search filter, fractions such as 1/250 are also accepted for exposure times
+func parseSearchNumber(value string) (float64, error) {
+	if parts := strings.SplitN(value, "/", 2); len(parts) == 2 {
+		numerator, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return 0, errors.Errorf("invalid number in search filter: %s", value)
+		}
+
+		denominator, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || denominator == 0 {
+			return 0, errors.Errorf("invalid number in search filter: %s", value)
+		}
+
+		return numerator / denominator, nil
+	}
+
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid number in search filter: %s", value)
+	}
+
+	return result, nil
+}
+
+// placeCondition returns the SQL condition on the media table, matching the country, region or city of the media
+func (term searchTerm) placeCondition() (string, []interface{}) {
+	wildValue := "%" + strings.ToLower(term.value) + "%"
+
+	return "LOWER(media.place_city) LIKE ? OR LOWER(media.place_region) LIKE ? OR LOWER(media.place_country) LIKE ? OR LOWER(media.place_country_code) = ?",
+		[]interface{}{wildValue, wildValue, wildValue, strings.ToLower(term.value)}
+}
+
+// filterMedia limits the media query to media whose metadata matches all the terms
+func filterMedia(db *gorm.DB, query *gorm.DB, terms []searchTerm) (*gorm.DB, error) {
+	exifQuery := db.Model(&models.MediaEXIF{}).Select("id")
+	exifTerms := 0
+
+	for _, term := range terms {
+		if term.filter.place {
+			condition, args := term.placeCondition()
+			query = query.Where(condition, args...)
+			continue
+		}
+
+		condition, args, err := term.condition()
+		if err != nil {
+			return nil, err
+		}
+
+		exifQuery = exifQuery.Where(condition, args...)
+		exifTerms++
+	}
+
+	if exifTerms == 0 {
+		return query, nil
+	}
+
+	return query.Where("media.exif_id IN (?)", exifQuery), nil
+}
@@ -0,0 +1,66 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// MySessions returns the access tokens of the user that have not expired, most recently used first
+func MySessions(db *gorm.DB, user *models.User) ([]*models.AccessToken, error) {
+	var sessions []*models.AccessToken
+	err := db.Where("user_id = ?", user.ID).
+		Where("expire > ?", time.Now()).
+		Order("last_used_at DESC").
+		Order("created_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get sessions from database")
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession deletes an access token of the user, such that it can no longer be used
+func RevokeSession(db *gorm.DB, user *models.User, sessionID int) (*models.AccessToken, error) {
+	var session models.AccessToken
+	if err := db.Where("user_id = ?", user.ID).First(&session, sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, errors.Wrap(err, "get session from database")
+	}
+
+	if err := db.Delete(&session).Error; err != nil {
+		return nil, errors.Wrap(err, "delete session")
+	}
+
+	return &session, nil
+}
+
+// RevokeSessionByToken deletes the access token with the given value
+func RevokeSessionByToken(db *gorm.DB, token string) error {
+	if err := db.Where("value = ?", token).Delete(&models.AccessToken{}).Error; err != nil {
+		return errors.Wrap(err, "delete session")
+	}
+
+	return nil
+}
+
+// RevokeAllSessions deletes all access tokens of the user, except the one with the value of keepToken if it is not nil.
+// It returns the number of revoked sessions.
+func RevokeAllSessions(db *gorm.DB, userID int, keepToken *string) (int, error) {
+	query := db.Where("user_id = ?", userID)
+	if keepToken != nil {
+		query = query.Where("value <> ?", *keepToken)
+	}
+
+	result := query.Delete(&models.AccessToken{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "delete sessions of user")
+	}
+
+	return int(result.RowsAffected), nil
+}
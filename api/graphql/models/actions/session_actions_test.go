@@ -0,0 +1,118 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	otherUser, err := models.RegisterUser(db, "other", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	client := models.SessionClient{UserAgent: "Mozilla/5.0", IPAddress: "192.168.1.10"}
+
+	currentToken, err := user.GenerateSessionToken(db, client)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	oldToken, err := user.GenerateSessionToken(db, models.SessionClient{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	lastUsed := time.Now().Add(-time.Hour)
+	if !assert.NoError(t, db.Model(oldToken).Update("last_used_at", lastUsed).Error) {
+		return
+	}
+
+	expiredToken, err := user.GenerateAccessToken(db)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, db.Model(expiredToken).Update("expire", time.Now().Add(-time.Minute)).Error) {
+		return
+	}
+
+	otherToken, err := otherUser.GenerateAccessToken(db)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("List sessions", func(t *testing.T) {
+		sessions, err := actions.MySessions(db, user)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if assert.Len(t, sessions, 2) {
+			assert.Equal(t, currentToken.ID, sessions[0].ID)
+			assert.Equal(t, "Mozilla/5.0", sessions[0].UserAgent)
+			assert.Equal(t, "192.168.1.10", sessions[0].IPAddress)
+			assert.Equal(t, oldToken.ID, sessions[1].ID)
+		}
+	})
+
+	t.Run("Revoke session of other user", func(t *testing.T) {
+		_, err := actions.RevokeSession(db, user, otherToken.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("Delete expired sessions", func(t *testing.T) {
+		deleted, err := models.DeleteExpiredAccessTokens(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.EqualValues(t, 1, deleted)
+	})
+
+	t.Run("Revoke session", func(t *testing.T) {
+		revoked, err := actions.RevokeSession(db, user, oldToken.ID)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, oldToken.ID, revoked.ID)
+
+		sessions, err := actions.MySessions(db, user)
+		if assert.NoError(t, err) {
+			assert.Len(t, sessions, 1)
+		}
+	})
+
+	t.Run("Revoke all sessions", func(t *testing.T) {
+		_, err := user.GenerateAccessToken(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		revoked, err := actions.RevokeAllSessions(db, user.ID, &currentToken.Value)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 1, revoked)
+
+		revoked, err = actions.RevokeAllSessions(db, user.ID, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 1, revoked)
+
+		var otherCount int64
+		assert.NoError(t, db.Model(&models.AccessToken{}).Where("user_id = ?", otherUser.ID).Count(&otherCount).Error)
+		assert.EqualValues(t, 1, otherCount)
+	})
+}
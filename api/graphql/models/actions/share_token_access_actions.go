@@ -0,0 +1,255 @@
+package actions
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrShareNotFound       = errors.New("share not found")
+	ErrShareExpired        = errors.New("share has expired")
+	ErrShareViewLimit      = errors.New("share has reached its view limit")
+	ErrShareDownloadDenied = errors.New("downloads are not allowed for this share")
+)
+
+// Slugs are looked up without throttling, so they need to be long enough not to be guessed by enumeration
+var shareTokenSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{7,63}$`)
+
+// GetShareToken returns the share token with the token value or custom slug
+func GetShareToken(db *gorm.DB, value string) (*models.ShareToken, error) {
+	var token models.ShareToken
+	if err := db.Preload(clause.Associations).Where("value = ? OR slug = ?", value, value).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get share token from database")
+	}
+
+	return &token, nil
+}
+
+// CheckShareTokenAccess returns an error if the share can not be accessed by the client of the request,
+// either because it has expired, or because its view limit was reached before the client opened it
+func CheckShareTokenAccess(db *gorm.DB, token *models.ShareToken) error {
+	if token.Expired() {
+		return ErrShareExpired
+	}
+
+	if token.ViewLimitReached() {
+		opened, err := models.HasShareTokenClient(db, token)
+		if err != nil {
+			return err
+		}
+
+		if !opened {
+			return ErrShareViewLimit
+		}
+	}
+
+	return nil
+}
+
+// OpenShareToken records that the client of the request opened the share.
+// The first time a client opens the share it counts as a view, which fails once the view limit is reached.
+func OpenShareToken(db *gorm.DB, token *models.ShareToken) error {
+	if token.Expired() {
+		return ErrShareExpired
+	}
+
+	counted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		firstOpen, err := models.AddShareTokenClient(tx, token)
+		if err != nil {
+			return err
+		}
+
+		if !firstOpen {
+			return nil
+		}
+
+		query := tx.Model(&models.ShareToken{}).Where("id = ?", token.ID)
+		if token.MaxViews != nil {
+			query = query.Where("views < max_views")
+		}
+
+		result := query.UpdateColumn("views", gorm.Expr("views + 1"))
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "count share token view")
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrShareViewLimit
+		}
+
+		counted = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if counted {
+		token.Views++
+	}
+
+	return models.RecordShareTokenAccess(db, token, models.ShareTokenAccessOpen, nil)
+}
+
+// ShareTokenStats summarizes the access log of the share
+func ShareTokenStats(db *gorm.DB, token *models.ShareToken) (*models.ShareTokenStats, error) {
+	stats := models.ShareTokenStats{
+		Views: token.Views,
+	}
+
+	accesses := func() *gorm.DB {
+		return db.Model(&models.ShareTokenAccess{}).Where("share_token_id = ?", token.ID)
+	}
+
+	var visitors, mediaViews, downloads int64
+	if err := accesses().Distinct("ip_address").Count(&visitors).Error; err != nil {
+		return nil, errors.Wrap(err, "count share token visitors")
+	}
+
+	if err := accesses().Where("kind = ?", models.ShareTokenAccessMedia).Count(&mediaViews).Error; err != nil {
+		return nil, errors.Wrap(err, "count share token media views")
+	}
+
+	if err := accesses().Where("kind = ?", models.ShareTokenAccessDownload).Count(&downloads).Error; err != nil {
+		return nil, errors.Wrap(err, "count share token downloads")
+	}
+
+	stats.Visitors = int(visitors)
+	stats.MediaViews = int(mediaViews)
+	stats.Downloads = int(downloads)
+
+	var lastAccess []*models.ShareTokenAccess
+	if err := accesses().Order("created_at DESC").Limit(1).Find(&lastAccess).Error; err != nil {
+		return nil, errors.Wrap(err, "get last share token access")
+	}
+
+	if len(lastAccess) > 0 {
+		stats.LastAccess = &lastAccess[0].CreatedAt
+	}
+
+	return &stats, nil
+}
+
+// ShareTokenAccessLog returns the accesses of the share, most recent first
+func ShareTokenAccessLog(db *gorm.DB, token *models.ShareToken, paginate *models.Pagination) ([]*models.ShareTokenAccess, error) {
+	query := db.Where("share_token_id = ?", token.ID).Order("created_at DESC").Order("id DESC")
+	query = models.FormatSQL(query, nil, paginate)
+
+	var accesses []*models.ShareTokenAccess
+	if err := query.Find(&accesses).Error; err != nil {
+		return nil, errors.Wrap(err, "get share token access log from database")
+	}
+
+	return accesses, nil
+}
+
+// UpdateShareToken changes the settings of a share token owned by the user, settings left as nil are not changed.
+// Only the changed columns are written, such that views counted while updating are not lost.
+func UpdateShareToken(db *gorm.DB, userID int, tokenValue string, settings models.ShareTokenSettings) (*models.ShareToken, error) {
+	token, err := getUserToken(db, userID, tokenValue)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0)
+
+	if settings.Slug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*settings.Slug))
+		if slug == "" {
+			token.Slug = nil
+		} else {
+			if !shareTokenSlugPattern.MatchString(slug) {
+				return nil, errors.New("slug must be 8 to 64 characters long, and only contain letters, digits and dashes")
+			}
+
+			var count int64
+			err := db.Model(&models.ShareToken{}).Where("(value = ? OR slug = ?) AND id <> ?", slug, slug, token.ID).Count(&count).Error
+			if err != nil {
+				return nil, errors.Wrap(err, "check if share token slug is taken")
+			}
+
+			if count > 0 {
+				return nil, errors.New("slug is already used by another share")
+			}
+
+			token.Slug = &slug
+		}
+		columns = append(columns, "slug")
+	}
+
+	if settings.AllowDownload != nil {
+		token.DisableDownload = !*settings.AllowDownload
+		columns = append(columns, "disable_download")
+	}
+
+	if settings.MaxViews != nil {
+		if *settings.MaxViews < 0 {
+			return nil, errors.New("max views cannot be negative")
+		}
+
+		if *settings.MaxViews == 0 {
+			token.MaxViews = nil
+		} else {
+			token.MaxViews = settings.MaxViews
+		}
+		columns = append(columns, "max_views")
+	}
+
+	if settings.WatermarkText != nil {
+		text := strings.TrimSpace(*settings.WatermarkText)
+		if text == "" {
+			token.WatermarkText = nil
+		} else {
+			if utf8.RuneCountInString(text) > 64 {
+				return nil, errors.New("watermark text cannot be longer than 64 characters")
+			}
+			token.WatermarkText = &text
+		}
+		columns = append(columns, "watermark_text")
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if len(columns) > 0 {
+			if err := tx.Model(token).Select(columns).Updates(token).Error; err != nil {
+				return errors.Wrap(err, "failed to update share token settings")
+			}
+		}
+
+		if settings.ResetViews != nil && *settings.ResetViews {
+			if err := tx.Model(token).UpdateColumn("views", 0).Error; err != nil {
+				return errors.Wrap(err, "failed to reset share token views")
+			}
+
+			// clients that opened the share before count as new views
+			if err := tx.Where("share_token_id = ?", token.ID).Delete(&models.ShareTokenClient{}).Error; err != nil {
+				return errors.Wrap(err, "failed to reset share token clients")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// read back the view count, which may have changed since the token was loaded
+	var views []int
+	if err := db.Model(&models.ShareToken{}).Where("id = ?", token.ID).Pluck("views", &views).Error; err != nil {
+		return nil, errors.Wrap(err, "get share token views")
+	}
+	if len(views) > 0 {
+		token.Views = views[0]
+	}
+
+	return token, nil
+}
@@ -0,0 +1,201 @@
+package actions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestShareTokenAccess(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	user, err := models.RegisterUser(db, "user", nil, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	album := models.Album{Title: "album", Path: "/photos"}
+	if !assert.NoError(t, db.Model(&user).Association("Albums").Append(&album)) {
+		return
+	}
+
+	share, err := actions.AddAlbumShare(db, user, album.ID, nil, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// clientDB returns a database session for requests from the client with the ip address
+	clientDB := func(ipAddress string) *gorm.DB {
+		return db.WithContext(models.ContextWithSessionClient(context.Background(), models.SessionClient{
+			UserAgent: "Mozilla/5.0",
+			IPAddress: ipAddress,
+		}))
+	}
+
+	update := func(settings models.ShareTokenSettings) (*models.ShareToken, error) {
+		return actions.UpdateShareToken(db, user.ID, share.Value, settings)
+	}
+
+	t.Run("Update settings", func(t *testing.T) {
+		allowDownload := false
+		watermark := "  Proofs  "
+		updated, err := update(models.ShareTokenSettings{AllowDownload: &allowDownload, WatermarkText: &watermark})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.False(t, updated.AllowDownload())
+		assert.Equal(t, "Proofs", *updated.WatermarkText)
+
+		allowDownload = true
+		updated, err = update(models.ShareTokenSettings{AllowDownload: &allowDownload})
+		assert.NoError(t, err)
+		assert.False(t, updated.AllowDownload(), "watermarked shares never allow downloads")
+
+		watermark = ""
+		updated, err = update(models.ShareTokenSettings{WatermarkText: &watermark})
+		assert.NoError(t, err)
+		assert.Nil(t, updated.WatermarkText)
+		assert.True(t, updated.AllowDownload())
+
+		maxViews := -1
+		_, err = update(models.ShareTokenSettings{MaxViews: &maxViews})
+		assert.Error(t, err)
+	})
+
+	t.Run("Slug", func(t *testing.T) {
+		for _, invalid := range []string{"ab", "short", "has space", "-dash-first", "ünïcode"} {
+			_, err := update(models.ShareTokenSettings{Slug: &invalid})
+			assert.Error(t, err, invalid)
+		}
+
+		other, err := actions.AddAlbumShare(db, user, album.ID, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		slug := "Smith-Wedding"
+		updated, err := update(models.ShareTokenSettings{Slug: &slug})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "smith-wedding", *updated.Slug)
+
+		_, err = actions.UpdateShareToken(db, user.ID, other.Value, models.ShareTokenSettings{Slug: &slug})
+		assert.EqualError(t, err, "slug is already used by another share")
+
+		found, err := actions.GetShareToken(db, "smith-wedding")
+		if assert.NoError(t, err) {
+			assert.Equal(t, share.ID, found.ID)
+		}
+
+		_, err = actions.GetShareToken(db, "unknown-slug")
+		assert.ErrorIs(t, err, actions.ErrShareNotFound)
+	})
+
+	t.Run("View limit", func(t *testing.T) {
+		maxViews := 2
+		if _, err := update(models.ShareTokenSettings{MaxViews: &maxViews}); !assert.NoError(t, err) {
+			return
+		}
+
+		open := func(ipAddress string) error {
+			token, err := actions.GetShareToken(db, share.Value)
+			if !assert.NoError(t, err) {
+				return err
+			}
+			return actions.OpenShareToken(clientDB(ipAddress), token)
+		}
+
+		assert.NoError(t, open("10.0.0.1"))
+		assert.NoError(t, open("10.0.0.1"), "opening again does not count as a view")
+		assert.NoError(t, open("10.0.0.2"))
+		assert.ErrorIs(t, open("10.0.0.3"), actions.ErrShareViewLimit)
+
+		token, err := actions.GetShareToken(db, share.Value)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 2, token.Views)
+
+		assert.NoError(t, actions.CheckShareTokenAccess(clientDB("10.0.0.2"), token), "opened before the limit was reached")
+		assert.ErrorIs(t, actions.CheckShareTokenAccess(clientDB("10.0.0.3"), token), actions.ErrShareViewLimit)
+
+		resetViews := true
+		if _, err := update(models.ShareTokenSettings{ResetViews: &resetViews}); !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, open("10.0.0.1"))
+
+		token, err = actions.GetShareToken(db, share.Value)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, token.Views, "clients that opened the share before the reset count as new views")
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		expired, err := actions.AddAlbumShare(db, user, album.ID, nil, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		expire := time.Now().Add(-time.Minute)
+		expired.Expire = &expire
+
+		assert.ErrorIs(t, actions.OpenShareToken(db, expired), actions.ErrShareExpired)
+		assert.ErrorIs(t, actions.CheckShareTokenAccess(db, expired), actions.ErrShareExpired)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		media := models.Media{Title: "pic", Path: "/photos/pic", AlbumID: album.ID}
+		if !assert.NoError(t, db.Create(&media).Error) {
+			return
+		}
+
+		assert.NoError(t, models.RecordShareTokenAccess(clientDB("10.0.0.1"), share, models.ShareTokenAccessMedia, &media.ID))
+		assert.NoError(t, models.RecordShareTokenAccess(clientDB("10.0.0.4"), share, models.ShareTokenAccessDownload, nil))
+
+		token, err := actions.GetShareToken(db, share.Value)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		stats, err := actions.ShareTokenStats(db, token)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 1, stats.Views)
+		assert.Equal(t, 3, stats.Visitors)
+		assert.Equal(t, 1, stats.MediaViews)
+		assert.Equal(t, 1, stats.Downloads)
+		assert.NotNil(t, stats.LastAccess)
+
+		accessLog, err := actions.ShareTokenAccessLog(db, token, nil)
+		if assert.NoError(t, err) && assert.Len(t, accessLog, 6) {
+			assert.Equal(t, models.ShareTokenAccessDownload, accessLog[0].Kind)
+			assert.Equal(t, "10.0.0.4", accessLog[0].IPAddress)
+			assert.Equal(t, media.ID, *accessLog[1].MediaID)
+		}
+	})
+
+	t.Run("Opened clients outlive the access log", func(t *testing.T) {
+		token, err := actions.GetShareToken(db, share.Value)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if !assert.NoError(t, actions.OpenShareToken(clientDB("10.0.0.5"), token)) {
+			return
+		}
+		assert.True(t, token.ViewLimitReached())
+
+		_, err = models.DeleteOldShareTokenAccesses(db, -time.Minute)
+		assert.NoError(t, err)
+
+		assert.NoError(t, actions.CheckShareTokenAccess(clientDB("10.0.0.5"), token))
+		assert.ErrorIs(t, actions.CheckShareTokenAccess(clientDB("10.0.0.6"), token), actions.ErrShareViewLimit)
+	})
+}
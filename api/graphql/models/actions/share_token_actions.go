@@ -14,28 +14,24 @@ import (
 
 func AddMediaShare(db *gorm.DB, user *models.User, mediaID int, expire *time.Time, password *string) (*models.ShareToken, error) {
 	var media models.Media
-
-	var query string
-	if drivers.POSTGRES.MatchDatabase(db) {
-		query = "EXISTS (SELECT * FROM user_albums WHERE user_albums.album_id = \"Album\".id AND user_albums.user_id = ?)"
-	} else {
-		query = "EXISTS (SELECT * FROM user_albums WHERE user_albums.album_id = Album.id AND user_albums.user_id = ?)"
-	}
-
-	err := db.Joins("Album").
-		Where(query, user.ID).
-		First(&media, mediaID).
-		Error
-
-	if err != nil {
+	if err := db.Joins("Album").First(&media, mediaID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, auth.ErrUnauthorized
 		} else {
-			return nil, errors.Wrap(err, "failed to validate media owner with database")
+			return nil, errors.Wrap(err, "failed to get media from database")
 		}
 	}
 
-	hashedPassword, err := hashSharePassword(password)
+	isManager, err := user.HasAlbumRole(db, &media.Album, models.AlbumRoleManager)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to validate media owner with database")
+	}
+
+	if !isManager {
+		return nil, auth.ErrUnauthorized
+	}
+
+	hashedPassword, err := hashSharePassword(db, password)
 	if err != nil {
 		return nil, err
 	}
@@ -57,28 +53,26 @@ func AddMediaShare(db *gorm.DB, user *models.User, mediaID int, expire *time.Tim
 }
 
 func AddAlbumShare(db *gorm.DB, user *models.User, albumID int, expire *time.Time, password *string) (*models.ShareToken, error) {
-	var count int64
-	err := db.
-		Model(&models.Album{}).
-		Where("EXISTS (SELECT * FROM user_albums WHERE user_albums.album_id = albums.id AND user_albums.user_id = ?)", user.ID).
-		Count(&count).Error
+	var album models.Album
+	if err := db.First(&album, albumID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, auth.ErrUnauthorized
+		}
+		return nil, errors.Wrap(err, "failed to get album from database")
+	}
 
+	isManager, err := user.HasAlbumRole(db, &album, models.AlbumRoleManager)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to validate album owner with database")
 	}
 
-	if count == 0 {
+	if !isManager {
 		return nil, auth.ErrUnauthorized
 	}
 
-	var hashedPassword *string = nil
-	if password != nil {
-		hashedPassBytes, err := bcrypt.GenerateFromPassword([]byte(*password), 12)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to hash token password")
-		}
-		hashedStr := string(hashedPassBytes)
-		hashedPassword = &hashedStr
+	hashedPassword, err := hashSharePassword(db, password)
+	if err != nil {
+		return nil, err
 	}
 
 	shareToken := models.ShareToken{
@@ -116,7 +110,7 @@ func ProtectShareToken(db *gorm.DB, userID int, tokenValue string, password *str
 		return nil, err
 	}
 
-	hashedPassword, err := hashSharePassword(password)
+	hashedPassword, err := hashSharePassword(db, password)
 	if err != nil {
 		return nil, err
 	}
@@ -130,9 +124,14 @@ func ProtectShareToken(db *gorm.DB, userID int, tokenValue string, password *str
 	return token, nil
 }
 
-func hashSharePassword(password *string) (*string, error) {
+// hashSharePassword checks the password against the password policy and hashes it, a nil password is left as nil
+func hashSharePassword(db *gorm.DB, password *string) (*string, error) {
 	var hashedPassword *string = nil
 	if password != nil {
+		if err := models.ValidatePassword(db, *password); err != nil {
+			return nil, err
+		}
+
 		hashedPassBytes, err := bcrypt.GenerateFromPassword([]byte(*password), 12)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to generate hash for share password")
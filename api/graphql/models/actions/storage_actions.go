@@ -0,0 +1,179 @@
+package actions
+
+import (
+	"sort"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/storage_stats"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Number of albums included in the largest albums of storage statistics
+const largestAlbumsCount = 10
+
+// albumTreeStorage returns the storage of each of the albums including all of their sub albums.
+// The storage of albums that are not in the list is not counted.
+func albumTreeStorage(db *gorm.DB, albums []*models.Album) (map[int]storage_stats.AlbumStorage, error) {
+	ownStorage := make(map[int]storage_stats.AlbumStorage, len(albums))
+	for _, album := range albums {
+		storage, err := storage_stats.GetAlbumStorage(db, album.ID)
+		if err != nil {
+			return nil, err
+		}
+		ownStorage[album.ID] = storage
+	}
+
+	// Add the storage of every album to each of its ancestors
+	albumsByID := make(map[int]*models.Album, len(albums))
+	for _, album := range albums {
+		albumsByID[album.ID] = album
+	}
+
+	treeStorage := make(map[int]storage_stats.AlbumStorage, len(albums))
+	for _, album := range albums {
+		storage := ownStorage[album.ID]
+
+		visited := make(map[int]bool)
+		for current := album; current != nil && !visited[current.ID]; {
+			visited[current.ID] = true
+			treeStorage[current.ID] = treeStorage[current.ID].Add(storage)
+
+			if current.ParentAlbumID == nil {
+				break
+			}
+			current = albumsByID[*current.ParentAlbumID]
+		}
+	}
+
+	return treeStorage, nil
+}
+
+// largestAlbums returns the albums using the most storage, with the storage including their sub albums
+func largestAlbums(albums []*models.Album, treeStorage map[int]storage_stats.AlbumStorage) []*models.AlbumStorage {
+	result := make([]*models.AlbumStorage, 0, len(albums))
+	for _, album := range albums {
+		storage := treeStorage[album.ID]
+		result = append(result, &models.AlbumStorage{
+			Album:        album,
+			MediaCount:   storage.MediaCount(),
+			OriginalSize: int(storage.OriginalSize),
+			CacheSize:    int(storage.CacheSize),
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].OriginalSize+result[i].CacheSize > result[j].OriginalSize+result[j].CacheSize
+	})
+
+	if len(result) > largestAlbumsCount {
+		result = result[:largestAlbumsCount]
+	}
+
+	return result
+}
+
+// AlbumStats returns the number of media and the storage used by the album and its sub albums
+func AlbumStats(db *gorm.DB, user *models.User, albumID int) (*models.AlbumStats, error) {
+	albumIDs, err := viewableSubAlbumIDs(db, user, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	var albums []*models.Album
+	if err := db.Where("id IN (?)", albumIDs).Find(&albums).Error; err != nil {
+		return nil, errors.Wrap(err, "get sub albums from database")
+	}
+
+	treeStorage, err := albumTreeStorage(db, albums)
+	if err != nil {
+		return nil, err
+	}
+
+	var album *models.Album
+	subAlbums := make([]*models.Album, 0, len(albums))
+	for _, a := range albums {
+		if a.ID == albumID {
+			album = a
+		} else {
+			subAlbums = append(subAlbums, a)
+		}
+	}
+
+	if album == nil {
+		return nil, errors.New("album not found")
+	}
+
+	recycleBinSize, err := storage_stats.RecycleBinSize(album.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read recycle bin size of album")
+	}
+
+	storage := treeStorage[albumID]
+	return &models.AlbumStats{
+		PhotoCount:     storage.PhotoCount,
+		VideoCount:     storage.VideoCount,
+		OriginalSize:   int(storage.OriginalSize),
+		CacheSize:      int(storage.CacheSize),
+		RecycleBinSize: int(recycleBinSize),
+		LargestAlbums:  largestAlbums(subAlbums, treeStorage),
+	}, nil
+}
+
+// StorageReport returns the storage used by all media on the server, and by the albums of every user
+func StorageReport(db *gorm.DB) (*models.StorageReport, error) {
+	var albums []*models.Album
+	if err := db.Find(&albums).Error; err != nil {
+		return nil, errors.Wrap(err, "get albums from database")
+	}
+
+	treeStorage, err := albumTreeStorage(db, albums)
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.StorageReport{
+		Users: make([]*models.UserStorage, 0),
+	}
+
+	for _, album := range albums {
+		if album.ParentAlbumID != nil {
+			continue
+		}
+
+		storage := treeStorage[album.ID]
+		report.PhotoCount += storage.PhotoCount
+		report.VideoCount += storage.VideoCount
+		report.OriginalSize += int(storage.OriginalSize)
+		report.CacheSize += int(storage.CacheSize)
+	}
+
+	recycleBinSize, err := storage_stats.RecycleBinSize("")
+	if err != nil {
+		return nil, errors.Wrap(err, "read recycle bin size")
+	}
+	report.RecycleBinSize = int(recycleBinSize)
+
+	report.LargestAlbums = largestAlbums(albums, treeStorage)
+
+	var users []*models.User
+	if err := db.Order("username").Find(&users).Error; err != nil {
+		return nil, errors.Wrap(err, "get users from database")
+	}
+
+	for _, user := range users {
+		userStorage, err := storage_stats.GetUserStorage(db, user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Users = append(report.Users, &models.UserStorage{
+			User:         user,
+			MediaCount:   userStorage.MediaCount(),
+			OriginalSize: int(userStorage.OriginalSize),
+			CacheSize:    int(userStorage.CacheSize),
+		})
+	}
+
+	return &report, nil
+}
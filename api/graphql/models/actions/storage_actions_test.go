@@ -0,0 +1,144 @@
+package actions_test
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/scanner/storage_stats"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFile(t *testing.T, filePath string, size int) {
+	if err := os.MkdirAll(path.Dir(filePath), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filePath, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageStats(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+	test_utils.FilesystemTest(t)
+	storage_stats.InvalidateAll()
+
+	recyclePath := t.TempDir()
+	t.Setenv(string(utils.EnvRecyclePath), recyclePath)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	otherUser, err := models.RegisterUser(db, "other", &password, false)
+	assert.NoError(t, err)
+
+	photosPath := t.TempDir()
+
+	rootAlbum := models.Album{Title: "root", Path: photosPath}
+	assert.NoError(t, db.Save(&rootAlbum).Error)
+
+	subAlbum := models.Album{Title: "sub", Path: path.Join(photosPath, "sub"), ParentAlbumID: &rootAlbum.ID}
+	assert.NoError(t, db.Save(&subAlbum).Error)
+
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&rootAlbum, &subAlbum))
+
+	writeTestFile(t, path.Join(photosPath, "pic1.jpg"), 1000)
+	writeTestFile(t, path.Join(photosPath, "video.mp4"), 5000)
+	writeTestFile(t, path.Join(photosPath, "sub", "pic2.jpg"), 2000)
+
+	media := []models.Media{
+		{Title: "pic1.jpg", Path: path.Join(photosPath, "pic1.jpg"), AlbumID: rootAlbum.ID, Type: models.MediaTypePhoto},
+		{Title: "video.mp4", Path: path.Join(photosPath, "video.mp4"), AlbumID: rootAlbum.ID, Type: models.MediaTypeVideo},
+		{Title: "pic2.jpg", Path: path.Join(photosPath, "sub", "pic2.jpg"), AlbumID: subAlbum.ID, Type: models.MediaTypePhoto},
+	}
+	assert.NoError(t, db.Save(&media).Error)
+
+	// The size of originals with a media url is read from the database instead of the file
+	originalURL := models.MediaURL{MediaID: media[0].ID, MediaName: "pic1.jpg", Purpose: models.MediaOriginal, ContentType: "image/jpeg", FileSize: 1500}
+	assert.NoError(t, db.Save(&originalURL).Error)
+
+	writeTestFile(t, path.Join(utils.MediaCachePath(), strconv.Itoa(rootAlbum.ID), strconv.Itoa(media[1].ID), "thumbnail.jpg"), 300)
+	writeTestFile(t, path.Join(utils.MediaCachePath(), strconv.Itoa(subAlbum.ID), strconv.Itoa(media[2].ID), "thumbnail.jpg"), 200)
+
+	writeTestFile(t, path.Join(recyclePath, photosPath, "sub", "deleted.jpg"), 700)
+
+	t.Run("Album stats", func(t *testing.T) {
+		stats, err := actions.AlbumStats(db, user, rootAlbum.ID)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, 2, stats.PhotoCount)
+		assert.Equal(t, 1, stats.VideoCount)
+		assert.Equal(t, 1500+5000+2000, stats.OriginalSize)
+		assert.Equal(t, 500, stats.CacheSize)
+		assert.Equal(t, 700, stats.RecycleBinSize)
+
+		if assert.Len(t, stats.LargestAlbums, 1) {
+			assert.Equal(t, subAlbum.ID, stats.LargestAlbums[0].Album.ID)
+			assert.Equal(t, 2000, stats.LargestAlbums[0].OriginalSize)
+			assert.Equal(t, 1, stats.LargestAlbums[0].MediaCount)
+		}
+
+		_, err = actions.AlbumStats(db, otherUser, rootAlbum.ID)
+		assert.Error(t, err)
+
+		_, err = actions.GrantAlbumAccess(db, user, rootAlbum.ID, otherUser.Username, models.AlbumRoleViewer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer actions.RevokeAlbumAccess(db, user, rootAlbum.ID, otherUser.ID)
+
+		stats, err = actions.AlbumStats(db, otherUser, rootAlbum.ID)
+		if assert.NoError(t, err, "viewers of the album can see its stats") {
+			assert.Equal(t, 2, stats.PhotoCount)
+		}
+	})
+
+	t.Run("Cache invalidation", func(t *testing.T) {
+		writeTestFile(t, path.Join(utils.MediaCachePath(), strconv.Itoa(subAlbum.ID), strconv.Itoa(media[2].ID), "high-res.jpg"), 800)
+
+		stats, err := actions.AlbumStats(db, user, subAlbum.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, stats.CacheSize)
+
+		storage_stats.InvalidateAlbum(subAlbum.ID)
+
+		stats, err = actions.AlbumStats(db, user, subAlbum.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, stats.CacheSize)
+	})
+
+	t.Run("Storage report", func(t *testing.T) {
+		report, err := actions.StorageReport(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, 2, report.PhotoCount)
+		assert.Equal(t, 1, report.VideoCount)
+		assert.Equal(t, 8500, report.OriginalSize)
+		assert.Equal(t, 1300, report.CacheSize)
+		assert.Equal(t, 700, report.RecycleBinSize)
+
+		if assert.Len(t, report.LargestAlbums, 2) {
+			assert.Equal(t, rootAlbum.ID, report.LargestAlbums[0].Album.ID)
+			assert.Equal(t, 3, report.LargestAlbums[0].MediaCount)
+		}
+
+		if assert.Len(t, report.Users, 2) {
+			assert.Equal(t, "other", report.Users[0].User.Username)
+			assert.Equal(t, 0, report.Users[0].MediaCount)
+			assert.Equal(t, "user", report.Users[1].User.Username)
+			assert.Equal(t, 3, report.Users[1].MediaCount)
+			assert.Equal(t, 8500, report.Users[1].OriginalSize)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package actions
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var ErrStudioForbidden = errors.New("forbidden")
+
+// editableAlbum returns the album if the user is an editor of it
+func editableAlbum(db *gorm.DB, user *models.User, albumID int) (*models.Album, error) {
+	var album models.Album
+	if err := db.First(&album, albumID).Error; err != nil {
+		return nil, errors.Wrap(err, "get album from database")
+	}
+
+	canEdit, err := user.HasAlbumRole(db, &album, models.AlbumRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canEdit {
+		return nil, ErrStudioForbidden
+	}
+
+	return &album, nil
+}
+
+// MarkModify marks the album at the path, and the albums above it, as modified such that they are scanned again.
+// The path must be inside one of the albums of the user, and albums above the album of the user are left untouched.
+func MarkModify(db *gorm.DB, user *models.User, modPath string) error {
+	if !path.IsAbs(modPath) {
+		return errors.New("path must be absolute")
+	}
+	modPath = path.Clean(modPath)
+
+	rootAlbum, err := userAlbumContainingPath(db, user, modPath)
+	if err != nil {
+		return err
+	}
+
+	if rootAlbum == nil {
+		return errors.New("path must be inside one of your albums")
+	}
+
+	modifyTime := time.Now().UTC().Unix()
+	for currentPath := modPath; ; currentPath = path.Dir(currentPath) {
+		err := db.Model(&models.Album{}).
+			Where("path_hash = ?", models.MD5Hash(currentPath)).
+			Update("last_modify_time", modifyTime).Error
+		if err != nil {
+			return errors.Wrapf(err, "mark album as modified (%s)", currentPath)
+		}
+
+		if currentPath == rootAlbum.Path || currentPath == "/" {
+			break
+		}
+	}
+
+	return models.RecordAuditEvent(db, user, models.AuditActionMarkModify, models.AuditTargetPath(modPath), nil)
+}
+
+// MakeFinalDir copies the client album that the album belongs to, that is the album right below the root,
+// to the final directory. Files directly inside the album are copied to an originals directory.
+func MakeFinalDir(db *gorm.DB, user *models.User, albumID int) error {
+	album, err := editableAlbum(db, user, albumID)
+	if err != nil {
+		return err
+	}
+
+	parents, err := album.GetParents(db, nil)
+	if err != nil {
+		return errors.Wrap(err, "get parents of album")
+	}
+
+	if len(parents) >= 2 {
+		album = parents[len(parents)-2]
+
+		canEdit, err := user.HasAlbumRole(db, album, models.AlbumRoleEditor)
+		if err != nil {
+			return err
+		}
+
+		if !canEdit {
+			return ErrStudioForbidden
+		}
+	}
+
+	finalDir := utils.FinalDirPath()
+	if finalDir == "" {
+		return errors.Errorf("final directory is not configured, set the %s environment variable", utils.EnvFinalDir.GetName())
+	}
+
+	newRootPath := path.Join(finalDir, album.Title)
+	newOriginPath := path.Join(newRootPath, "原图")
+
+	dirContent, err := ioutil.ReadDir(album.Path)
+	if err != nil {
+		return errors.Wrapf(err, "read album directory (%s)", album.Path)
+	}
+
+	if err := os.MkdirAll(newOriginPath, os.ModePerm); err != nil {
+		return errors.Wrapf(err, "create final directory (%s)", newOriginPath)
+	}
+
+	copyErrors := 0
+	for _, item := range dirContent {
+		if item.IsDir() {
+			failed, err := copyDir(path.Join(album.Path, item.Name()), path.Join(newRootPath, item.Name()))
+			if err != nil {
+				log.Printf("WARN: could not copy %s to final directory: %s\n", item.Name(), err)
+				copyErrors += failed
+			}
+			continue
+		}
+
+		if err := copyFile(path.Join(album.Path, item.Name()), path.Join(newOriginPath, item.Name())); err != nil {
+			log.Printf("WARN: could not copy %s to final directory: %s\n", item.Name(), err)
+			copyErrors++
+		}
+	}
+
+	return models.RecordAuditEvent(db, user, models.AuditActionMakeFinalDir, models.AuditTargetAlbum(album), map[string]interface{}{
+		"final_dir":    newRootPath,
+		"failed_items": copyErrors,
+	})
+}
+
+// MarkRetouchFile renames the media of the album, such that the favorites of the user are marked for retouching
+// by the symbol prefix of their file name, and the symbol is removed from all other media
+func MarkRetouchFile(db *gorm.DB, user *models.User, albumID int) error {
+	album, err := editableAlbum(db, user, albumID)
+	if err != nil {
+		return err
+	}
+
+	var media []models.Media
+	if err := db.Where("album_id = ?", album.ID).Find(&media).Error; err != nil {
+		return errors.Wrap(err, "get media of album")
+	}
+
+	marked := 0
+	for _, m := range media {
+		var favorite models.UserMediaData
+		err := db.Where("user_id = ? AND media_id = ?", user.ID, m.ID).First(&favorite).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.Wrap(err, "get favorite of media")
+		}
+
+		newPath := utils.RemoveSymbol(m.Path)
+		if favorite.Favorite {
+			newPath = utils.AddSymbol(m.Path)
+			marked++
+		}
+
+		if newPath == m.Path {
+			continue
+		}
+
+		if err := os.Rename(m.Path, newPath); err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("WARN: could not rename media for retouching (%s): %s\n", m.Path, err)
+			}
+			continue
+		}
+
+		err = db.Model(&models.Media{}).Where("id = ?", m.ID).Updates(map[string]interface{}{
+			"path":      newPath,
+			"path_hash": models.MD5Hash(utils.RemoveSymbol(newPath)),
+		}).Error
+		if err != nil {
+			return errors.Wrap(err, "update path of renamed media")
+		}
+	}
+
+	return models.RecordAuditEvent(db, user, models.AuditActionMarkRetouchFile, models.AuditTargetAlbum(album), map[string]interface{}{
+		"marked": marked,
+		"total":  len(media),
+	})
+}
+
+// copyFile copies a single file from src to dst
+func copyFile(src, dst string) error {
+	var err error
+	var srcfd *os.File
+	var dstfd *os.File
+	var srcinfo os.FileInfo
+
+	if srcfd, err = os.Open(src); err != nil {
+		return err
+	}
+	defer srcfd.Close()
+
+	if dstfd, err = os.Create(dst); err != nil {
+		return err
+	}
+	defer dstfd.Close()
+
+	if _, err = io.Copy(dstfd, srcfd); err != nil {
+		return err
+	}
+	if srcinfo, err = os.Stat(src); err != nil {
+		return err
+	}
+	return os.Chmod(dst, srcinfo.Mode())
+}
+
+// copyDir copies a whole directory recursively. Items that can not be copied are logged and skipped,
+// in which case the number of them is returned along with an error.
+func copyDir(src string, dst string) (int, error) {
+	srcinfo, err := os.Stat(src)
+	if err != nil {
+		return 1, err
+	}
+
+	if err := os.MkdirAll(dst, srcinfo.Mode()); err != nil {
+		return 1, err
+	}
+
+	fds, err := ioutil.ReadDir(src)
+	if err != nil {
+		return 1, err
+	}
+
+	failed := 0
+	for _, fd := range fds {
+		srcfp := path.Join(src, fd.Name())
+		dstfp := path.Join(dst, fd.Name())
+
+		if fd.IsDir() {
+			if dirFailed, err := copyDir(srcfp, dstfp); err != nil {
+				log.Printf("WARN: could not copy directory %s: %s\n", srcfp, err)
+				failed += dirFailed
+			}
+		} else {
+			if err := copyFile(srcfp, dstfp); err != nil {
+				log.Printf("WARN: could not copy file %s: %s\n", srcfp, err)
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 {
+		return failed, errors.Errorf("%d items of %s could not be copied", failed, src)
+	}
+
+	return 0, nil
+}
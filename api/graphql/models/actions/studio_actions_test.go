@@ -0,0 +1,193 @@
+package actions_test
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStudioActions(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	otherUser, err := models.RegisterUser(db, "other", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rootPath := t.TempDir()
+	rootAlbum := models.Album{Title: "root", Path: rootPath}
+	if !assert.NoError(t, db.Save(&rootAlbum).Error) {
+		return
+	}
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&rootAlbum))
+
+	clientPath := path.Join(rootPath, "client")
+	assert.NoError(t, os.Mkdir(clientPath, os.ModePerm))
+	clientAlbum := models.Album{Title: "client", Path: clientPath, ParentAlbumID: &rootAlbum.ID}
+	if !assert.NoError(t, db.Save(&clientAlbum).Error) {
+		return
+	}
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&clientAlbum))
+
+	auditEvents := func(action string) []models.AuditEvent {
+		var events []models.AuditEvent
+		assert.NoError(t, db.Where("action = ?", action).Find(&events).Error)
+		return events
+	}
+
+	t.Run("Mark modify outside albums of user", func(t *testing.T) {
+		assert.Error(t, actions.MarkModify(db, user, "/etc"))
+		assert.Error(t, actions.MarkModify(db, user, "client"))
+		assert.Error(t, actions.MarkModify(db, otherUser, clientPath))
+		assert.Empty(t, auditEvents(models.AuditActionMarkModify))
+	})
+
+	t.Run("Mark modify", func(t *testing.T) {
+		if !assert.NoError(t, actions.MarkModify(db, user, path.Join(clientPath, "."))) {
+			return
+		}
+
+		for _, albumID := range []int{rootAlbum.ID, clientAlbum.ID} {
+			var album models.Album
+			assert.NoError(t, db.First(&album, albumID).Error)
+			assert.NotNil(t, album.LastModifyTime)
+		}
+
+		events := auditEvents(models.AuditActionMarkModify)
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, user.ID, *events[0].UserID)
+			assert.Equal(t, clientPath, events[0].Path)
+		}
+	})
+
+	t.Run("Mark retouch file of album not owned", func(t *testing.T) {
+		err := actions.MarkRetouchFile(db, otherUser, clientAlbum.ID)
+		assert.ErrorIs(t, err, actions.ErrStudioForbidden)
+		assert.Empty(t, auditEvents(models.AuditActionMarkRetouchFile))
+	})
+
+	t.Run("Make final dir of album not owned", func(t *testing.T) {
+		err := actions.MakeFinalDir(db, otherUser, clientAlbum.ID)
+		assert.ErrorIs(t, err, actions.ErrStudioForbidden)
+		assert.Empty(t, auditEvents(models.AuditActionMakeFinalDir))
+	})
+
+	t.Run("Make final dir without a final directory configured", func(t *testing.T) {
+		t.Setenv(string(utils.EnvFinalDir), "")
+		assert.Error(t, actions.MakeFinalDir(db, user, clientAlbum.ID))
+		assert.Empty(t, auditEvents(models.AuditActionMakeFinalDir))
+	})
+
+	finalDir := t.TempDir()
+	t.Setenv(string(utils.EnvFinalDir), finalDir)
+
+	writeFile := func(filePath string) {
+		assert.NoError(t, os.MkdirAll(path.Dir(filePath), os.ModePerm))
+		assert.NoError(t, os.WriteFile(filePath, []byte(filePath), 0644))
+	}
+
+	writeFile(path.Join(clientPath, "original.jpg"))
+	writeFile(path.Join(clientPath, "retouched", "edit.jpg"))
+	writeFile(path.Join(clientPath, "retouched", "nested", "edit.jpg"))
+
+	// lastFailedItems returns the number of items that could not be copied by the last final dir operation
+	lastFailedItems := func() int {
+		events := auditEvents(models.AuditActionMakeFinalDir)
+		if !assert.NotEmpty(t, events) {
+			return -1
+		}
+
+		var details struct {
+			FailedItems int `json:"failed_items"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(events[len(events)-1].Details), &details))
+		return details.FailedItems
+	}
+
+	t.Run("Make final dir", func(t *testing.T) {
+		if !assert.NoError(t, actions.MakeFinalDir(db, user, clientAlbum.ID)) {
+			return
+		}
+
+		for _, copied := range []string{
+			path.Join("原图", "original.jpg"),
+			path.Join("retouched", "edit.jpg"),
+			path.Join("retouched", "nested", "edit.jpg"),
+		} {
+			assert.FileExists(t, path.Join(finalDir, "client", copied))
+		}
+
+		assert.Equal(t, 0, lastFailedItems())
+	})
+
+	t.Run("Make final dir with files that can not be copied", func(t *testing.T) {
+		nestedPath := path.Join(clientPath, "retouched", "nested")
+		assert.NoError(t, os.Symlink(path.Join(nestedPath, "missing.jpg"), path.Join(nestedPath, "broken.jpg")))
+		writeFile(path.Join(nestedPath, "after.jpg"))
+
+		if !assert.NoError(t, actions.MakeFinalDir(db, user, clientAlbum.ID)) {
+			return
+		}
+
+		assert.FileExists(t, path.Join(finalDir, "client", "retouched", "nested", "after.jpg"))
+		assert.Equal(t, 1, lastFailedItems())
+	})
+
+	t.Run("Mark retouch file", func(t *testing.T) {
+		addMedia := func(name string) *models.Media {
+			mediaPath := path.Join(clientPath, name)
+			writeFile(mediaPath)
+
+			media := models.Media{Title: name, Path: mediaPath, AlbumID: clientAlbum.ID}
+			assert.NoError(t, db.Save(&media).Error)
+			return &media
+		}
+
+		favorite := addMedia("favorite.jpg")
+		otherFavorite := addMedia("other_favorite.jpg")
+
+		_, err := user.FavoriteMedia(db, favorite.ID, true)
+		assert.NoError(t, err)
+		_, err = otherUser.FavoriteMedia(db, otherFavorite.ID, true)
+		assert.NoError(t, err)
+
+		if !assert.NoError(t, actions.MarkRetouchFile(db, user, clientAlbum.ID)) {
+			return
+		}
+
+		markedPath := utils.AddSymbol(favorite.Path)
+		assert.FileExists(t, markedPath)
+		assert.FileExists(t, otherFavorite.Path, "favorites of other users are not marked")
+
+		var marked models.Media
+		if assert.NoError(t, db.First(&marked, favorite.ID).Error) {
+			assert.Equal(t, markedPath, marked.Path)
+			assert.Equal(t, models.MD5Hash(favorite.Path), marked.PathHash)
+		}
+
+		_, err = user.FavoriteMedia(db, favorite.ID, false)
+		assert.NoError(t, err)
+
+		if !assert.NoError(t, actions.MarkRetouchFile(db, user, clientAlbum.ID)) {
+			return
+		}
+
+		assert.FileExists(t, favorite.Path)
+		if assert.NoError(t, db.First(&marked, favorite.ID).Error) {
+			assert.Equal(t, favorite.Path, marked.Path)
+		}
+	})
+}
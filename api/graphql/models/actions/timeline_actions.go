@@ -1,18 +1,29 @@
 package actions
 
 import (
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/photoview/photoview/api/database/drivers"
 	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
-func MyTimeline(db *gorm.DB, user *models.User, paginate *models.Pagination, onlyFavorites *bool, fromDate *time.Time) ([]*models.Media, error) {
+// Layout of the bucket keys returned by timelineBucketKey
+const timelineBucketLayout = "2006-01-02"
+
+func MyTimeline(db *gorm.DB, user *models.User, paginate *models.Pagination, onlyFavorites *bool, fromDate *time.Time, expandStacks *bool) ([]*models.Media, error) {
+	userAlbums, err := userAlbumsScope(db, user, "albums.id", models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
 
 	query := db.
 		Joins("JOIN albums ON media.album_id = albums.id").
-		Where("albums.id IN (?)", db.Table("user_albums").Select("user_albums.album_id").Where("user_id = ?", user.ID))
+		Scopes(userAlbums).
+		Where("media.motion_photo_id IS NULL")
 
 	switch drivers.GetDatabaseDriverType(db) {
 	case drivers.POSTGRES:
@@ -44,6 +55,10 @@ func MyTimeline(db *gorm.DB, user *models.User, paginate *models.Pagination, onl
 		query = query.Where("media.id IN (?)", db.Table("user_media_data").Select("user_media_data.media_id").Where("user_media_data.user_id = ?", user.ID).Where("user_media_data.favorite"))
 	}
 
+	if expandStacks == nil || !*expandStacks {
+		query = models.CollapseMediaStacks(query)
+	}
+
 	query = models.FormatSQL(query, nil, paginate)
 
 	var media []*models.Media
@@ -53,3 +68,219 @@ func MyTimeline(db *gorm.DB, user *models.User, paginate *models.Pagination, onl
 
 	return media, nil
 }
+
+// userTimelineMedia returns a query of the media the user can view, as it is shown on the timeline
+func userTimelineMedia(db *gorm.DB, user *models.User) (*gorm.DB, error) {
+	userAlbums, err := userAlbumsScope(db, user, "media.album_id", models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Model(&models.Media{}).
+		Scopes(userAlbums).
+		Where("media.motion_photo_id IS NULL")
+
+	return models.CollapseMediaStacks(query), nil
+}
+
+// filterTimelineMedia returns a query of the timeline media of the user,
+// optionally limited to favorites or to an album and its sub albums
+func filterTimelineMedia(db *gorm.DB, user *models.User, onlyFavorites *bool, albumID *int) (*gorm.DB, error) {
+	query, err := userTimelineMedia(db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if onlyFavorites != nil && *onlyFavorites {
+		query = query.Where("media.id IN (?)", db.Table("user_media_data").Select("user_media_data.media_id").Where("user_media_data.user_id = ?", user.ID).Where("user_media_data.favorite"))
+	}
+
+	if albumID != nil {
+		albumIDs, err := viewableSubAlbumIDs(db, user, *albumID)
+		if err != nil {
+			return nil, err
+		}
+
+		query = query.Where("media.album_id IN (?)", albumIDs)
+	}
+
+	return query, nil
+}
+
+// timelineBucketKey returns the SQL expression that truncates the date the media was shot to the start of its bucket,
+// formatted as YYYY-MM-DD
+func timelineBucketKey(db *gorm.DB, granularity models.TimelineGranularity) string {
+	switch drivers.GetDatabaseDriverType(db) {
+	case drivers.POSTGRES:
+		unit := map[models.TimelineGranularity]string{
+			models.TimelineGranularityYear:  "year",
+			models.TimelineGranularityMonth: "month",
+			models.TimelineGranularityDay:   "day",
+		}[granularity]
+
+		return fmt.Sprintf("TO_CHAR(DATE_TRUNC('%s', media.date_shot), 'YYYY-MM-DD')", unit)
+	case drivers.SQLITE:
+		format := map[models.TimelineGranularity]string{
+			models.TimelineGranularityYear:  "%Y-01-01",
+			models.TimelineGranularityMonth: "%Y-%m-01",
+			models.TimelineGranularityDay:   "%Y-%m-%d",
+		}[granularity]
+
+		return fmt.Sprintf("strftime('%s', media.date_shot)", format)
+	case drivers.MYSQL:
+		format := map[models.TimelineGranularity]string{
+			models.TimelineGranularityYear:  "%Y-01-01",
+			models.TimelineGranularityMonth: "%Y-%m-01",
+			models.TimelineGranularityDay:   "%Y-%m-%d",
+		}[granularity]
+
+		return fmt.Sprintf("DATE_FORMAT(media.date_shot, '%s')", format)
+	default:
+		log.Panicf("unsupported database backend: %s", drivers.GetDatabaseDriverType(db))
+		return ""
+	}
+}
+
+// timelineBucketRange returns the start and end of the bucket of the given granularity that contains the date
+func timelineBucketRange(date time.Time, granularity models.TimelineGranularity) (time.Time, time.Time, error) {
+	date = date.UTC()
+
+	switch granularity {
+	case models.TimelineGranularityYear:
+		start := time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0), nil
+	case models.TimelineGranularityMonth:
+		start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0), nil
+	case models.TimelineGranularityDay:
+		start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1), nil
+	default:
+		return time.Time{}, time.Time{}, errors.Errorf("invalid timeline granularity: %s", granularity)
+	}
+}
+
+type timelineBucketCount struct {
+	BucketKey  string
+	MediaCount int
+}
+
+type timelineBucketFirstMedia struct {
+	BucketKey string
+	ID        int
+}
+
+// TimelineBuckets groups the timeline of the user into years, months or days, newest first.
+// Every bucket contains the number of media in it, and the newest media of it.
+func TimelineBuckets(db *gorm.DB, user *models.User, granularity models.TimelineGranularity, onlyFavorites *bool, albumID *int) ([]*models.TimelineBucket, error) {
+	if !granularity.IsValid() {
+		return nil, errors.Errorf("invalid timeline granularity: %s", granularity)
+	}
+
+	query, err := filterTimelineMedia(db, user, onlyFavorites, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketKey := timelineBucketKey(db, granularity)
+
+	var counts []*timelineBucketCount
+	err = query.Session(&gorm.Session{}).
+		Select(fmt.Sprintf("%s AS bucket_key, COUNT(*) AS media_count", bucketKey)).
+		Group(bucketKey).
+		Order("bucket_key DESC").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "count media of timeline buckets")
+	}
+
+	// The first media of every bucket is the one shot last, as the timeline is ordered newest first
+	bucketMedia := query.Session(&gorm.Session{}).
+		Select(fmt.Sprintf("media.id, %s AS bucket_key, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY media.date_shot DESC, media.id) AS bucket_row", bucketKey, bucketKey))
+
+	var firstMediaIDs []*timelineBucketFirstMedia
+	err = db.Table("(?) AS bucket_media", bucketMedia).
+		Select("bucket_media.bucket_key, bucket_media.id").
+		Where("bucket_media.bucket_row = 1").
+		Scan(&firstMediaIDs).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get first media of timeline buckets")
+	}
+
+	mediaIDs := make([]int, 0, len(firstMediaIDs))
+	for _, first := range firstMediaIDs {
+		mediaIDs = append(mediaIDs, first.ID)
+	}
+
+	var firstMedia []*models.Media
+	if len(mediaIDs) > 0 {
+		if err := db.Where("id IN (?)", mediaIDs).Find(&firstMedia).Error; err != nil {
+			return nil, errors.Wrap(err, "get first media of timeline buckets")
+		}
+	}
+
+	mediaByID := make(map[int]*models.Media, len(firstMedia))
+	for _, media := range firstMedia {
+		mediaByID[media.ID] = media
+	}
+
+	firstMediaByBucket := make(map[string]*models.Media, len(firstMediaIDs))
+	for _, first := range firstMediaIDs {
+		firstMediaByBucket[first.BucketKey] = mediaByID[first.ID]
+	}
+
+	buckets := make([]*models.TimelineBucket, 0, len(counts))
+	for _, count := range counts {
+		date, err := time.ParseInLocation(timelineBucketLayout, count.BucketKey, time.UTC)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse timeline bucket (%s)", count.BucketKey)
+		}
+
+		start, _, err := timelineBucketRange(date, granularity)
+		if err != nil {
+			return nil, err
+		}
+
+		bucket := &models.TimelineBucket{
+			Date:        start,
+			Granularity: granularity,
+			MediaCount:  count.MediaCount,
+			FirstMedia:  firstMediaByBucket[count.BucketKey],
+		}
+
+		if bucket.FirstMedia == nil {
+			return nil, errors.Errorf("first media of timeline bucket (%s) not found", count.BucketKey)
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
+
+// TimelineBucketMedia returns the media of a single bucket of the timeline, newest first
+func TimelineBucketMedia(db *gorm.DB, user *models.User, bucket models.TimelineBucketInput, onlyFavorites *bool, albumID *int, paginate *models.Pagination) ([]*models.Media, error) {
+	start, end, err := timelineBucketRange(bucket.Date, bucket.Granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := filterTimelineMedia(db, user, onlyFavorites, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = query.
+		Where("media.date_shot >= ? AND media.date_shot < ?", start, end).
+		Order("media.date_shot DESC").
+		Order("media.id DESC")
+
+	query = models.FormatSQL(query, nil, paginate)
+
+	var media []*models.Media
+	if err := query.Find(&media).Error; err != nil {
+		return nil, errors.Wrap(err, "get media of timeline bucket")
+	}
+
+	return media, nil
+}
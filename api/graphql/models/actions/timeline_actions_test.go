@@ -88,7 +88,7 @@ func TestMyTimeline(t *testing.T) {
 	assert.NoError(t, db.Model(&anotherUser).Association("Albums").Append(&anotherAlbum))
 
 	t.Run("MyTimeline with no filters", func(t *testing.T) {
-		timelineMedia, err := actions.MyTimeline(db, user, nil, nil, nil)
+		timelineMedia, err := actions.MyTimeline(db, user, nil, nil, nil, nil)
 
 		assert.NoError(t, err)
 		assert.Len(t, timelineMedia, 4)
@@ -101,7 +101,7 @@ func TestMyTimeline(t *testing.T) {
 
 	t.Run("MyTimeline with only favorites", func(t *testing.T) {
 		favorites := true
-		timelineMedia, err := actions.MyTimeline(db, user, nil, &favorites, nil)
+		timelineMedia, err := actions.MyTimeline(db, user, nil, &favorites, nil, nil)
 
 		assert.NoError(t, err)
 		assert.Len(t, timelineMedia, 1)
@@ -109,9 +109,139 @@ func TestMyTimeline(t *testing.T) {
 
 	t.Run("MyTimeline before date", func(t *testing.T) {
 		beforeDate := time.Unix(1629792000, 0) // Aug 24 2021 08:00:00
-		timelineMedia, err := actions.MyTimeline(db, user, nil, nil, &beforeDate)
+		timelineMedia, err := actions.MyTimeline(db, user, nil, nil, &beforeDate, nil)
 
 		assert.NoError(t, err)
 		assert.Len(t, timelineMedia, 2)
 	})
 }
+
+func TestTimelineBuckets(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	assert.NoError(t, err)
+
+	rootAlbum := models.Album{
+		Title: "root",
+		Path:  "/photos",
+	}
+
+	assert.NoError(t, db.Save(&rootAlbum).Error)
+
+	childAlbum := models.Album{
+		Title:         "subalbum",
+		Path:          "/photos/subalbum",
+		ParentAlbumID: &rootAlbum.ID,
+	}
+
+	assert.NoError(t, db.Save(&childAlbum).Error)
+
+	assert.NoError(t, db.Model(&user).Association("Albums").Append(&rootAlbum, &childAlbum))
+
+	media := []models.Media{
+		{Title: "pic1", Path: "/photos/pic1", AlbumID: rootAlbum.ID, DateShot: time.Date(2021, 9, 27, 16, 0, 0, 0, time.UTC)},
+		{Title: "pic2", Path: "/photos/pic2", AlbumID: rootAlbum.ID, DateShot: time.Date(2021, 8, 12, 10, 0, 0, 0, time.UTC)},
+		{Title: "pic3", Path: "/photos/subalbum/pic3", AlbumID: childAlbum.ID, DateShot: time.Date(2021, 9, 27, 17, 30, 0, 0, time.UTC)},
+		{Title: "pic4", Path: "/photos/subalbum/pic4", AlbumID: childAlbum.ID, DateShot: time.Date(2021, 8, 12, 13, 45, 0, 0, time.UTC)},
+		{Title: "pic5", Path: "/photos/pic5", AlbumID: rootAlbum.ID, DateShot: time.Date(2019, 12, 31, 23, 0, 0, 0, time.UTC)},
+	}
+
+	assert.NoError(t, db.Save(&media).Error)
+
+	_, err = user.FavoriteMedia(db, media[1].ID, true)
+	assert.NoError(t, err)
+
+	type expectedBucket struct {
+		date       time.Time
+		mediaCount int
+		firstMedia string
+	}
+
+	checkBuckets := func(t *testing.T, expected []expectedBucket, buckets []*models.TimelineBucket) {
+		if !assert.Len(t, buckets, len(expected)) {
+			return
+		}
+
+		for i, bucket := range buckets {
+			assert.True(t, expected[i].date.Equal(bucket.Date), "expected %s, got %s", expected[i].date, bucket.Date)
+			assert.Equal(t, expected[i].mediaCount, bucket.MediaCount)
+			assert.Equal(t, expected[i].firstMedia, bucket.FirstMedia.Title)
+		}
+	}
+
+	t.Run("Years", func(t *testing.T) {
+		buckets, err := actions.TimelineBuckets(db, user, models.TimelineGranularityYear, nil, nil)
+		assert.NoError(t, err)
+		checkBuckets(t, []expectedBucket{
+			{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 4, "pic3"},
+			{time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), 1, "pic5"},
+		}, buckets)
+	})
+
+	t.Run("Months", func(t *testing.T) {
+		buckets, err := actions.TimelineBuckets(db, user, models.TimelineGranularityMonth, nil, nil)
+		assert.NoError(t, err)
+		checkBuckets(t, []expectedBucket{
+			{time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC), 2, "pic3"},
+			{time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC), 2, "pic4"},
+			{time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC), 1, "pic5"},
+		}, buckets)
+	})
+
+	t.Run("Days of album favorites", func(t *testing.T) {
+		onlyFavorites := true
+		buckets, err := actions.TimelineBuckets(db, user, models.TimelineGranularityDay, &onlyFavorites, nil)
+		assert.NoError(t, err)
+		checkBuckets(t, []expectedBucket{
+			{time.Date(2021, 8, 12, 0, 0, 0, 0, time.UTC), 1, "pic2"},
+		}, buckets)
+
+		buckets, err = actions.TimelineBuckets(db, user, models.TimelineGranularityDay, nil, &childAlbum.ID)
+		assert.NoError(t, err)
+		checkBuckets(t, []expectedBucket{
+			{time.Date(2021, 9, 27, 0, 0, 0, 0, time.UTC), 1, "pic3"},
+			{time.Date(2021, 8, 12, 0, 0, 0, 0, time.UTC), 1, "pic4"},
+		}, buckets)
+	})
+
+	t.Run("Media of bucket", func(t *testing.T) {
+		bucket := models.TimelineBucketInput{
+			Date:        time.Date(2021, 9, 15, 0, 0, 0, 0, time.UTC),
+			Granularity: models.TimelineGranularityMonth,
+		}
+
+		result, err := actions.TimelineBucketMedia(db, user, bucket, nil, nil, nil)
+		assert.NoError(t, err)
+		if assert.Len(t, result, 2) {
+			assert.Equal(t, "pic3", result[0].Title)
+			assert.Equal(t, "pic1", result[1].Title)
+		}
+
+		limit := 1
+		bucket.Granularity = models.TimelineGranularityYear
+		result, err = actions.TimelineBucketMedia(db, user, bucket, nil, nil, &models.Pagination{Limit: &limit})
+		assert.NoError(t, err)
+		if assert.Len(t, result, 1) {
+			assert.Equal(t, "pic3", result[0].Title)
+		}
+	})
+
+	t.Run("Invalid granularity", func(t *testing.T) {
+		_, err := actions.TimelineBuckets(db, user, models.TimelineGranularity("WEEK"), nil, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Media shot at the same time", func(t *testing.T) {
+		sameTime := models.Media{Title: "pic6", Path: "/photos/pic6", AlbumID: rootAlbum.ID, DateShot: media[4].DateShot}
+		assert.NoError(t, db.Save(&sameTime).Error)
+
+		buckets, err := actions.TimelineBuckets(db, user, models.TimelineGranularityYear, nil, nil)
+		assert.NoError(t, err)
+		checkBuckets(t, []expectedBucket{
+			{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 4, "pic3"},
+			{time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), 2, "pic5"},
+		}, buckets)
+	})
+}
@@ -0,0 +1,289 @@
+package actions
+
+import (
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/totp"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Issuer shown by authenticator apps next to the code
+const twoFactorIssuer = "Photoview"
+
+var (
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorChallengeExpired is returned when the challenge has expired, been used up or used by another request
+	ErrTwoFactorChallengeExpired = errors.New("the login has expired, please log in again")
+)
+
+// EnrollTwoFactor generates a new two-factor secret for the user,
+// two-factor authentication is enabled once a code of the secret is verified by EnableTwoFactor
+func EnrollTwoFactor(db *gorm.DB, user *models.User) (*models.TwoFactorEnrollment, error) {
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_counter": 0}).Error; err != nil {
+		return nil, errors.Wrap(err, "save two-factor secret")
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.KeyURI(twoFactorIssuer, user.Username, secret),
+	}, nil
+}
+
+// EnableTwoFactor verifies a code of the enrolled secret, enables two-factor authentication for the user
+// and returns the recovery codes of the user
+func EnableTwoFactor(db *gorm.DB, user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	if user.TOTPSecret == nil {
+		return nil, errors.New("two-factor authentication has not been enrolled")
+	}
+
+	if valid, err := verifyTOTPCode(db, user, code); err != nil {
+		return nil, err
+	} else if !valid {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var recoveryCodes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("totp_enabled", true).Error; err != nil {
+			return errors.Wrap(err, "enable two-factor authentication")
+		}
+
+		var err error
+		recoveryCodes, err = user.GenerateRecoveryCodes(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTwoFactor disables two-factor authentication for the user, after verifying a code or recovery code
+func DisableTwoFactor(db *gorm.DB, user *models.User, code string) (*models.User, error) {
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if valid, err := VerifyTwoFactorCode(db, user, code); err != nil {
+		return nil, err
+	} else if !valid {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	if err := removeTwoFactor(db, user, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResetUserTwoFactor disables two-factor authentication for another user, that has lost their authenticator app and recovery codes
+func ResetUserTwoFactor(db *gorm.DB, admin *models.User, userID int) (*models.User, error) {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, errors.Wrap(err, "get user from database")
+	}
+
+	if err := removeTwoFactor(db, admin, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func removeTwoFactor(db *gorm.DB, actor *models.User, user *models.User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":       nil,
+			"totp_enabled":      false,
+			"totp_last_counter": 0,
+		}).Error
+		if err != nil {
+			return errors.Wrap(err, "disable two-factor authentication")
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return errors.Wrap(err, "delete recovery codes")
+		}
+
+		return models.RecordAuditEvent(tx, actor, models.AuditActionDisableTwoFactor, models.AuditTargetUser(user), map[string]interface{}{
+			"username": user.Username,
+		})
+	})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, after verifying a code or recovery code
+func RegenerateRecoveryCodes(db *gorm.DB, user *models.User, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if valid, err := VerifyTwoFactorCode(db, user, code); err != nil {
+		return nil, err
+	} else if !valid {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	return user.GenerateRecoveryCodes(db)
+}
+
+// VerifyTwoFactorCode checks a code from the authenticator app, or uses up a recovery code of the user
+func VerifyTwoFactorCode(db *gorm.DB, user *models.User, code string) (bool, error) {
+	if user.TOTPSecret == nil {
+		return false, nil
+	}
+
+	if valid, err := verifyTOTPCode(db, user, code); err != nil || valid {
+		return valid, err
+	}
+
+	return user.UseRecoveryCode(db, code)
+}
+
+// verifyTOTPCode checks a code from the authenticator app, and records it as used
+func verifyTOTPCode(db *gorm.DB, user *models.User, code string) (bool, error) {
+	counter, valid := totp.Validate(*user.TOTPSecret, code, time.Now(), user.TOTPLastCounter)
+	if !valid {
+		return false, nil
+	}
+
+	// only accept the code if a concurrent request has not used it already
+	result := db.Model(&models.User{}).
+		Where("id = ? AND totp_last_counter < ?", user.ID, counter).
+		Update("totp_last_counter", counter)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "record used two-factor code")
+	}
+
+	user.TOTPLastCounter = counter
+	return result.RowsAffected > 0, nil
+}
+
+// AuthorizeTwoFactor exchanges a challenge issued when the password was accepted, along with a valid code,
+// for an access token of the user
+func AuthorizeTwoFactor(db *gorm.DB, challengeValue string, code string, client models.SessionClient) (*models.AccessToken, error) {
+	var challenge models.TwoFactorChallenge
+	err := db.Preload("User").
+		Where("value = ? AND expire > ?", challengeValue, time.Now()).
+		First(&challenge).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorChallengeExpired
+		}
+		return nil, errors.Wrap(err, "get two-factor challenge from database")
+	}
+
+	limiter := auth.GetLoginLimiter()
+	userKey := auth.LoginKeyUser(challenge.User.Username)
+	keys := loginLimiterKeys(db, userKey)
+
+	attempt, err := limiter.Attempt(keys...)
+	if err != nil {
+		recordFailedLogin(db, models.FailedLoginAttempt{
+			Kind:     models.LoginAttemptKindTwoFactor,
+			Username: &challenge.User.Username,
+			UserID:   &challenge.User.ID,
+			Blocked:  true,
+		})
+		return nil, err
+	}
+	defer attempt.Done()
+
+	valid, err := VerifyTwoFactorCode(db, &challenge.User, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		if err := attempt.Failure(); err != nil {
+			return nil, err
+		}
+
+		recordFailedLogin(db, models.FailedLoginAttempt{
+			Kind:     models.LoginAttemptKindTwoFactor,
+			Username: &challenge.User.Username,
+			UserID:   &challenge.User.ID,
+		})
+
+		challenge.Attempts++
+		if challenge.Attempts >= models.TwoFactorChallengeMaxAttempts {
+			err = db.Delete(&challenge).Error
+		} else {
+			err = db.Model(&challenge).Update("attempts", challenge.Attempts).Error
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "record failed two-factor attempt")
+		}
+
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var token *models.AccessToken
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// only one of concurrent requests with a valid code can use up the challenge
+		result := tx.Where("id = ? AND value = ?", challenge.ID, challenge.Value).Delete(&models.TwoFactorChallenge{})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "delete two-factor challenge")
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorChallengeExpired
+		}
+
+		var err error
+		token, err = challenge.User.GenerateSessionToken(tx, client)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := limiter.Reset(userKey); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// SetRequireAdminTwoFactor sets whether admins must enable two-factor authentication to use admin features.
+// The admin changing it must have enabled it, so they do not lock themselves out.
+func SetRequireAdminTwoFactor(db *gorm.DB, admin *models.User, required bool) (bool, error) {
+	if required && !admin.TOTPEnabled {
+		return false, errors.New("enable two-factor authentication for your own user before requiring it for admins")
+	}
+
+	// make sure the site info exists, so the requirement is not lost
+	if _, err := models.GetSiteInfo(db); err != nil {
+		return false, err
+	}
+
+	err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).
+		Model(&models.SiteInfo{}).
+		Update("require_admin_two_factor", required).Error
+	if err != nil {
+		return false, errors.Wrap(err, "update two-factor requirement")
+	}
+
+	return required, nil
+}
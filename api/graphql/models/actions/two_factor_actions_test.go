@@ -0,0 +1,202 @@
+package actions_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/photoview/photoview/api/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactor(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	password := "1234"
+	user, err := models.RegisterUser(db, "user", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	admin, err := models.RegisterUser(db, "admin", &password, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	enrollment, err := actions.EnrollTwoFactor(db, user)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/Photoview:user?"))
+
+	// code returns the code of the secret, offset periods from now
+	code := func(offset int64) string {
+		value, err := totp.CodeAt(enrollment.Secret, totp.Counter(time.Now())+offset)
+		assert.NoError(t, err)
+		return value
+	}
+
+	client := models.SessionClient{UserAgent: "Mozilla/5.0", IPAddress: "192.168.1.10"}
+
+	var recoveryCodes []string
+
+	t.Run("Enable", func(t *testing.T) {
+		_, err := actions.EnableTwoFactor(db, user, "000000")
+		assert.ErrorIs(t, err, actions.ErrInvalidTwoFactorCode)
+
+		recoveryCodes, err = actions.EnableTwoFactor(db, user, code(-1))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, recoveryCodes, 10)
+
+		assert.NoError(t, db.First(user, user.ID).Error)
+		assert.True(t, user.TOTPEnabled)
+
+		_, err = actions.EnrollTwoFactor(db, user)
+		assert.Error(t, err)
+
+		valid, err := actions.VerifyTwoFactorCode(db, user, code(-1))
+		assert.NoError(t, err)
+		assert.False(t, valid, "code cannot be used twice")
+	})
+
+	t.Run("Authorize with code", func(t *testing.T) {
+		challenge, err := user.CreateTwoFactorChallenge(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = actions.AuthorizeTwoFactor(db, challenge.Value, "000000", client)
+		assert.ErrorIs(t, err, actions.ErrInvalidTwoFactorCode)
+
+		token, err := actions.AuthorizeTwoFactor(db, challenge.Value, code(0), client)
+		if assert.NoError(t, err) {
+			assert.Equal(t, user.ID, token.UserID)
+			assert.Equal(t, "192.168.1.10", token.IPAddress)
+		}
+
+		_, err = actions.AuthorizeTwoFactor(db, challenge.Value, code(1), client)
+		assert.EqualError(t, err, "the login has expired, please log in again")
+	})
+
+	t.Run("Authorize with recovery code", func(t *testing.T) {
+		challenge, err := user.CreateTwoFactorChallenge(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = actions.AuthorizeTwoFactor(db, challenge.Value, " "+strings.ToUpper(recoveryCodes[0]), client)
+		assert.NoError(t, err)
+
+		challenge, err = user.CreateTwoFactorChallenge(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = actions.AuthorizeTwoFactor(db, challenge.Value, recoveryCodes[0], client)
+		assert.ErrorIs(t, err, actions.ErrInvalidTwoFactorCode, "recovery code cannot be used twice")
+	})
+
+	t.Run("Challenge attempts and expiry", func(t *testing.T) {
+		challenge, err := user.CreateTwoFactorChallenge(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		for i := 0; i < models.TwoFactorChallengeMaxAttempts; i++ {
+			// forget the failures, such that the login limiter does not delay the attempts before the challenge is used up
+			assert.NoError(t, auth.GetLoginLimiter().Reset(auth.LoginKeyUser(user.Username)))
+
+			_, err = actions.AuthorizeTwoFactor(db, challenge.Value, "000000", client)
+			assert.ErrorIs(t, err, actions.ErrInvalidTwoFactorCode)
+		}
+
+		_, err = actions.AuthorizeTwoFactor(db, challenge.Value, recoveryCodes[1], client)
+		assert.EqualError(t, err, "the login has expired, please log in again")
+
+		challenge, err = user.CreateTwoFactorChallenge(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, db.Model(challenge).Update("expire", time.Now().Add(-time.Minute)).Error)
+
+		_, err = actions.AuthorizeTwoFactor(db, challenge.Value, recoveryCodes[1], client)
+		assert.EqualError(t, err, "the login has expired, please log in again")
+
+		deleted, err := models.DeleteExpiredTwoFactorChallenges(db)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, deleted)
+	})
+
+	t.Run("Regenerate recovery codes", func(t *testing.T) {
+		newCodes, err := actions.RegenerateRecoveryCodes(db, user, recoveryCodes[1])
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, newCodes, 10)
+
+		valid, err := actions.VerifyTwoFactorCode(db, user, recoveryCodes[2])
+		assert.NoError(t, err)
+		assert.False(t, valid, "old recovery codes are replaced")
+
+		recoveryCodes = newCodes
+	})
+
+	t.Run("Require for admins", func(t *testing.T) {
+		_, err := actions.SetRequireAdminTwoFactor(db, admin, true)
+		assert.Error(t, err)
+
+		admin.TOTPEnabled = true
+		required, err := actions.SetRequireAdminTwoFactor(db, admin, true)
+		assert.NoError(t, err)
+		assert.True(t, required)
+
+		siteInfo, err := models.GetSiteInfo(db)
+		if assert.NoError(t, err) {
+			assert.True(t, siteInfo.RequireAdminTwoFactor)
+		}
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		_, err := actions.DisableTwoFactor(db, user, "000000")
+		assert.ErrorIs(t, err, actions.ErrInvalidTwoFactorCode)
+
+		_, err = actions.DisableTwoFactor(db, user, code(1))
+		assert.NoError(t, err)
+
+		assert.NoError(t, db.First(user, user.ID).Error)
+		assert.False(t, user.TOTPEnabled)
+		assert.Nil(t, user.TOTPSecret)
+
+		var count int64
+		assert.NoError(t, db.Model(&models.TwoFactorRecoveryCode{}).Where("user_id = ?", user.ID).Count(&count).Error)
+		assert.EqualValues(t, 0, count)
+	})
+
+	t.Run("Reset by admin", func(t *testing.T) {
+		enrollment, err = actions.EnrollTwoFactor(db, user)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = actions.EnableTwoFactor(db, user, code(0))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		resetUser, err := actions.ResetUserTwoFactor(db, admin, user.ID)
+		if assert.NoError(t, err) {
+			assert.False(t, resetUser.TOTPEnabled)
+		}
+
+		var event models.AuditEvent
+		if assert.NoError(t, db.Where("action = ?", models.AuditActionDisableTwoFactor).Order("id DESC").First(&event).Error) {
+			assert.Equal(t, admin.ID, *event.UserID)
+			assert.Equal(t, user.ID, *event.TargetID)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// AlbumGrant gives a user a role on an album and all of its sub albums
+type AlbumGrant struct {
+	Model
+	AlbumID     int       `gorm:"not null;uniqueIndex:idx_album_grants_album_user"`
+	Album       Album     `gorm:"constraint:OnDelete:CASCADE;"`
+	UserID      int       `gorm:"not null;uniqueIndex:idx_album_grants_album_user;index"`
+	User        User      `gorm:"constraint:OnDelete:CASCADE;"`
+	Role        AlbumRole `gorm:"not null;size:32"`
+	GrantedByID *int
+	GrantedBy   *User `gorm:"constraint:OnDelete:SET NULL;"`
+}
+
+var albumRoleRanks = map[AlbumRole]int{
+	AlbumRoleViewer:  1,
+	AlbumRoleEditor:  2,
+	AlbumRoleManager: 3,
+}
+
+// Includes returns true if the role has all the permissions of the required role
+func (role AlbumRole) Includes(required AlbumRole) bool {
+	return albumRoleRanks[role] >= albumRoleRanks[required]
+}
+
+// albumRolesIncluding returns all the roles that include the required role
+func albumRolesIncluding(required AlbumRole) []AlbumRole {
+	roles := make([]AlbumRole, 0, len(AllAlbumRole))
+	for _, role := range AllAlbumRole {
+		if role.Includes(required) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// AlbumRole returns the role of the user on the album, owners of an album are managers of it.
+// Roles granted on an album are inherited by its sub albums, and nil is returned if the user has no role on the album.
+func (user *User) AlbumRole(db *gorm.DB, album *Album) (*AlbumRole, error) {
+	ownsAlbum, err := user.OwnsAlbum(db, album)
+	if err != nil {
+		return nil, err
+	}
+
+	if ownsAlbum {
+		role := AlbumRoleManager
+		return &role, nil
+	}
+
+	parents, err := album.GetParents(db, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "get parents of album")
+	}
+
+	parentIDs := make([]int, len(parents))
+	for i, parent := range parents {
+		parentIDs[i] = parent.ID
+	}
+
+	var grants []AlbumGrant
+	if err := db.Where("user_id = ? AND album_id IN (?)", user.ID, parentIDs).Find(&grants).Error; err != nil {
+		return nil, errors.Wrap(err, "get album grants of user")
+	}
+
+	var role *AlbumRole
+	for i := range grants {
+		if role == nil || grants[i].Role.Includes(*role) {
+			role = &grants[i].Role
+		}
+	}
+
+	return role, nil
+}
+
+// HasAlbumRole returns true if the user has the required role, or a role that includes it, on the album
+func (user *User) HasAlbumRole(db *gorm.DB, album *Album, required AlbumRole) (bool, error) {
+	role, err := user.AlbumRole(db, album)
+	if err != nil {
+		return false, err
+	}
+
+	return role != nil && role.Includes(required), nil
+}
+
+// GrantedAlbumIDs returns the ids of the albums, including sub albums, that the user has been granted
+// the required role, or a role that includes it, on. Albums owned by the user are not included.
+func (user *User) GrantedAlbumIDs(db *gorm.DB, required AlbumRole) ([]int, error) {
+	var grantedIDs []int
+	err := db.Model(&AlbumGrant{}).
+		Where("user_id = ? AND role IN (?)", user.ID, albumRolesIncluding(required)).
+		Pluck("album_id", &grantedIDs).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "get album grants of user")
+	}
+
+	if len(grantedIDs) == 0 {
+		return []int{}, nil
+	}
+
+	albums, err := GetChildrenFromAlbums(db, nil, grantedIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "get sub albums of granted albums")
+	}
+
+	albumIDs := make([]int, len(albums))
+	for i, album := range albums {
+		albumIDs[i] = album.ID
+	}
+
+	return albumIDs, nil
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix is the start of every API key, used to tell them apart from access tokens
+const APIKeyPrefix = "pvk_"
+
+// Number of random characters of an API key, after the prefix
+const apiKeyLength = 40
+
+// Number of characters of a key stored in APIKey.Prefix, to identify it
+const apiKeyDisplayLength = 12
+
+// How often the last used time of an API key is saved
+const apiKeyTouchThrottle = 5 * time.Minute
+
+// APIKey is a long-lived key used to authorize automated requests on behalf of a user.
+// Only a hash of the key is stored.
+type APIKey struct {
+	Model
+	UserID  int    `gorm:"not null;index"`
+	User    User   `gorm:"constraint:OnDelete:CASCADE;"`
+	Name    string `gorm:"not null;size:128"`
+	Prefix  string `gorm:"not null;size:16"`
+	KeyHash string `gorm:"not null;size:64;uniqueIndex"`
+	// Scopes is a comma separated list of the scopes granted to the key
+	Scopes     string     `gorm:"not null"`
+	Expire     *time.Time `gorm:"index"`
+	LastUsedAt *time.Time
+}
+
+// IsAPIKey returns true if the token has the format of an API key, rather than an access token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// ScopeList returns the scopes granted to the key
+func (k *APIKey) ScopeList() []APIKeyScope {
+	scopes := make([]APIKeyScope, 0)
+	for _, scope := range strings.Split(k.Scopes, ",") {
+		if scope != "" {
+			scopes = append(scopes, APIKeyScope(scope))
+		}
+	}
+	return scopes
+}
+
+// HasScope returns true if the scope has been granted to the key
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAPIKey creates a new API key for the user with the given scopes.
+// The key itself is returned along with the saved row, as it cannot be recovered from the database.
+func (user *User) GenerateAPIKey(db *gorm.DB, name string, scopes []APIKeyScope, expire *time.Time) (*APIKey, string, error) {
+	const CHARACTERS = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	charLen := big.NewInt(int64(len(CHARACTERS)))
+
+	bytes := make([]byte, apiKeyLength)
+	for i := range bytes {
+		n, err := rand.Int(rand.Reader, charLen)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "generate api key")
+		}
+		bytes[i] = CHARACTERS[n.Int64()]
+	}
+
+	key := APIKeyPrefix + string(bytes)
+
+	scopeNames := make([]string, len(scopes))
+	for i, scope := range scopes {
+		scopeNames[i] = scope.String()
+	}
+
+	apiKey := APIKey{
+		UserID:  user.ID,
+		Name:    name,
+		Prefix:  key[:apiKeyDisplayLength],
+		KeyHash: hashAPIKey(key),
+		Scopes:  strings.Join(scopeNames, ","),
+		Expire:  expire,
+	}
+
+	if err := db.Create(&apiKey).Error; err != nil {
+		return nil, "", errors.Wrap(err, "save api key to database")
+	}
+
+	return &apiKey, key, nil
+}
+
+// VerifyAPIKey returns the API key along with its user, if the key exists and has not expired
+func VerifyAPIKey(db *gorm.DB, key string) (*APIKey, error) {
+	var apiKey APIKey
+	err := db.Preload("User").
+		Where("key_hash = ?", hashAPIKey(key)).
+		Where("expire IS NULL OR expire > ?", time.Now()).
+		First(&apiKey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid api key")
+		}
+		return nil, errors.Wrap(err, "get api key from database")
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchThrottle {
+		if err := db.Model(&apiKey).Update("last_used_at", now).Error; err != nil {
+			return nil, errors.Wrap(err, "update last used time of api key")
+		}
+	}
+
+	return &apiKey, nil
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditActionMarkModify        = "mark_modify"
+	AuditActionMakeFinalDir      = "make_final_dir"
+	AuditActionMarkRetouchFile   = "mark_retouch_file"
+	AuditActionDeleteMedia       = "delete_media"
+	AuditActionDeleteAlbum       = "delete_album"
+	AuditActionCreateShareToken  = "create_share_token"
+	AuditActionDeleteShareToken  = "delete_share_token"
+	AuditActionProtectShareToken = "protect_share_token"
+	AuditActionUpdateShareToken  = "update_share_token"
+	AuditActionGrantAlbumAccess  = "grant_album_access"
+	AuditActionRevokeAlbumAccess = "revoke_album_access"
+	AuditActionCreateUser        = "create_user"
+	AuditActionUpdateUser        = "update_user"
+	AuditActionDeleteUser        = "delete_user"
+	AuditActionSetUserQuota      = "set_user_quota"
+	AuditActionAddRootPath       = "add_root_path"
+	AuditActionRemoveRootAlbum   = "remove_root_album"
+	AuditActionDisableTwoFactor  = "disable_two_factor"
+)
+
+// Types of the targets of audit events
+const (
+	AuditTargetTypeAlbum      = "album"
+	AuditTargetTypeMedia      = "media"
+	AuditTargetTypeUser       = "user"
+	AuditTargetTypeShareToken = "share_token"
+	AuditTargetTypePath       = "path"
+)
+
+// AuditEvent records a destructive or sharing operation, performed by a user or by the server itself
+type AuditEvent struct {
+	Model
+	// UserID is the user that performed the operation, nil if it was performed by the server
+	UserID     *int   `gorm:"index"`
+	User       *User  `gorm:"constraint:OnDelete:SET NULL;"`
+	Action     string `gorm:"not null;size:64;index"`
+	TargetType string `gorm:"size:32;index:idx_audit_events_target"`
+	TargetID   *int   `gorm:"index:idx_audit_events_target"`
+	// Path is the file or directory on disk the operation was performed on, if any
+	Path      string `gorm:"size:1024"`
+	IPAddress string `gorm:"size:64"`
+	// Details is a JSON object with additional information about the operation
+	Details string
+}
+
+// AuditTarget identifies the object an audited operation was performed on
+type AuditTarget struct {
+	Type string
+	ID   *int
+	Path string
+}
+
+func AuditTargetAlbum(album *Album) AuditTarget {
+	return AuditTarget{Type: AuditTargetTypeAlbum, ID: &album.ID, Path: album.Path}
+}
+
+func AuditTargetMedia(media *Media) AuditTarget {
+	return AuditTarget{Type: AuditTargetTypeMedia, ID: &media.ID, Path: media.Path}
+}
+
+func AuditTargetUser(user *User) AuditTarget {
+	return AuditTarget{Type: AuditTargetTypeUser, ID: &user.ID}
+}
+
+func AuditTargetShareToken(token *ShareToken) AuditTarget {
+	return AuditTarget{Type: AuditTargetTypeShareToken, ID: &token.ID}
+}
+
+func AuditTargetPath(path string) AuditTarget {
+	return AuditTarget{Type: AuditTargetTypePath, Path: path}
+}
+
+// RecordAuditEvent saves an entry to the audit log, user is nil for operations not performed by a user.
+// The IP address is taken from the client in the context of db, if any, and details are saved as JSON.
+func RecordAuditEvent(db *gorm.DB, user *User, action string, target AuditTarget, details map[string]interface{}) error {
+	event := AuditEvent{
+		Action:     action,
+		TargetType: target.Type,
+		TargetID:   target.ID,
+		Path:       target.Path,
+		IPAddress:  SessionClientFromContext(db.Statement.Context).IPAddress,
+	}
+
+	if user != nil {
+		event.UserID = &user.ID
+	}
+
+	if len(details) > 0 {
+		detailsJSON, err := json.Marshal(details)
+		if err != nil {
+			return errors.Wrapf(err, "encode details of audit event (%s)", action)
+		}
+		event.Details = string(detailsJSON)
+	}
+
+	if err := db.Create(&event).Error; err != nil {
+		return errors.Wrapf(err, "record audit event (%s)", action)
+	}
+
+	return nil
+}
+
+// DeleteExpiredAuditEvents deletes audit events older than the retention period, and returns how many were deleted
+func DeleteExpiredAuditEvents(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&AuditEvent{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "delete expired audit events")
+	}
+
+	return result.RowsAffected, nil
+}
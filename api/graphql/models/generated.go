@@ -9,12 +9,67 @@ import (
 	"time"
 )
 
+// The number of media and the storage used by an album and its sub albums, sizes are in bytes
+type AlbumStats struct {
+	PhotoCount int `json:"photoCount"`
+	VideoCount int `json:"videoCount"`
+	// Total size of the original media files
+	OriginalSize int `json:"originalSize"`
+	// Total size of the thumbnails and other files generated in the media cache
+	CacheSize int `json:"cacheSize"`
+	// Size of the media deleted from the album into the recycle bin
+	RecycleBinSize int `json:"recycleBinSize"`
+	// The sub albums using the most storage
+	LargestAlbums []*AlbumStorage `json:"largestAlbums"`
+}
+
+// The storage used by an album including its sub albums, sizes are in bytes
+type AlbumStorage struct {
+	Album        *Album `json:"album"`
+	MediaCount   int    `json:"mediaCount"`
+	OriginalSize int    `json:"originalSize"`
+	CacheSize    int    `json:"cacheSize"`
+}
+
+// Filters applied to the audit log, events must match all of the given filters
+type AuditLogFilter struct {
+	// Only events of operations performed by this user
+	UserID     *int    `json:"userId,omitempty"`
+	Action     *string `json:"action,omitempty"`
+	TargetType *string `json:"targetType,omitempty"`
+	TargetID   *int    `json:"targetId,omitempty"`
+	// Only events recorded at or after this time
+	From *time.Time `json:"from,omitempty"`
+	// Only events recorded before this time
+	To *time.Time `json:"to,omitempty"`
+}
+
 type AuthorizeResult struct {
 	Success bool `json:"success"`
 	// A textual status message describing the result, can be used to show an error message when `success` is false
 	Status string `json:"status"`
 	// An access token used to authenticate new API requests as the newly authorized user. Is present when success is true
 	Token *string `json:"token,omitempty"`
+	// Present when the password was accepted, but the user has enabled two-factor authentication.
+	// It must be passed to `authorizeTwoFactor` along with a code within a few minutes, to get the access token
+	TwoFactorChallenge *string `json:"twoFactorChallenge,omitempty"`
+}
+
+// An area of the map, specified by the coordinates of its edges in degrees
+type BoundingBox struct {
+	North float64 `json:"north"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	West  float64 `json:"west"`
+}
+
+// An area of the map, specified by the coordinates of its edges in degrees.
+// If west is greater than east, the area crosses the antimeridian.
+type BoundingBoxInput struct {
+	North float64 `json:"north"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	West  float64 `json:"west"`
 }
 
 type Coordinates struct {
@@ -24,12 +79,109 @@ type Coordinates struct {
 	Longitude float64 `json:"longitude"`
 }
 
+type CoordinatesInput struct {
+	// GPS latitude in degrees
+	Latitude float64 `json:"latitude"`
+	// GPS longitude in degrees
+	Longitude float64 `json:"longitude"`
+}
+
+type CreateAPIKeyResult struct {
+	APIKey *APIKey `json:"apiKey"`
+	// The key itself, it cannot be retrieved again
+	Key string `json:"key"`
+}
+
+// Filters applied to the failed login attempts, attempts must match all of the given filters
+type FailedLoginAttemptFilter struct {
+	Kind         *string `json:"kind,omitempty"`
+	Username     *string `json:"username,omitempty"`
+	ShareTokenID *int    `json:"shareTokenId,omitempty"`
+	IPAddress    *string `json:"ipAddress,omitempty"`
+	// Only attempts made at or after this time
+	From *time.Time `json:"from,omitempty"`
+	// Only attempts made before this time
+	To *time.Time `json:"to,omitempty"`
+}
+
+// A media matched against a GPS track
+type GeotagMatch struct {
+	Media     *Media  `json:"media"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Whether the location lies between two points of the track, rather than at one of them
+	Interpolated bool `json:"interpolated"`
+	// Seconds between the media and the nearest point of the track
+	TimeDifference int `json:"timeDifference"`
+}
+
+// Options of how media is matched against GPS tracks
+type GeotagOptions struct {
+	// Seconds added to the date media was shot to get the UTC time of the track,
+	// eg. to correct the clock of a camera or the timezone of media without one
+	TimeOffset *int `json:"timeOffset,omitempty"`
+	// Maximum number of seconds between a media and the track points it is located by, defaults to 300
+	MaxGap *int `json:"maxGap,omitempty"`
+	// Only geotag media of this album and its sub albums
+	AlbumID *int `json:"albumId,omitempty"`
+	// Also geotag media that already has coordinates
+	Overwrite *bool `json:"overwrite,omitempty"`
+	// Only return the proposed matches, without saving them
+	DryRun *bool `json:"dryRun,omitempty"`
+	// Also write the coordinates to the files using exiftool, RAW files get an XMP sidecar instead
+	WriteToFile *bool `json:"writeToFile,omitempty"`
+}
+
 type MediaDownload struct {
 	// A description of the role of the media file
 	Title    string    `json:"title"`
 	MediaURL *MediaURL `json:"mediaUrl"`
 }
 
+// A group of geotagged media close to each other on the map
+type MediaGeoCluster struct {
+	// The average latitude of the media in the cluster
+	Latitude float64 `json:"latitude"`
+	// The average longitude of the media in the cluster
+	Longitude  float64 `json:"longitude"`
+	MediaCount int     `json:"mediaCount"`
+	// The smallest area containing all media of the cluster
+	Bounds *BoundingBox `json:"bounds"`
+	// A media of the cluster, that can be used as its thumbnail
+	SampleMedia *Media `json:"sampleMedia"`
+}
+
+// Used to limit the media shown on the map
+type MediaGeoFilter struct {
+	// Only include media of this album and its sub albums
+	AlbumID *int `json:"albumId,omitempty"`
+	// Only include media taken on or after this date
+	FromDate *time.Time `json:"fromDate,omitempty"`
+	// Only include media taken before this date
+	ToDate *time.Time `json:"toDate,omitempty"`
+}
+
+// New metadata for media, fields that are not set are left unchanged
+type MediaMetadataInput struct {
+	Title *string `json:"title,omitempty"`
+	// An empty string removes the description
+	Description *string           `json:"description,omitempty"`
+	DateShot    *time.Time        `json:"dateShot,omitempty"`
+	Coordinates *CoordinatesInput `json:"coordinates,omitempty"`
+	// An empty list removes all keywords
+	Keywords []string `json:"keywords,omitempty"`
+	// An empty string removes the copyright notice
+	Copyright *string `json:"copyright,omitempty"`
+}
+
+// Media shot on the same calendar day in an earlier year
+type MemoryYear struct {
+	Year int `json:"year"`
+	// How many years before the requested date the media was shot
+	YearsAgo int      `json:"yearsAgo"`
+	Media    []*Media `json:"media"`
+}
+
 type Notification struct {
 	// A key used to identify the notification, new notification updates with the same key, should replace the old notifications
 	Key  string           `json:"key"`
@@ -63,6 +215,35 @@ type Pagination struct {
 	Offset *int `json:"offset,omitempty"`
 }
 
+type PasswordPolicyInput struct {
+	MinLength        int  `json:"minLength"`
+	RequireMixedCase bool `json:"requireMixedCase"`
+	RequireDigit     bool `json:"requireDigit"`
+	RequireSymbol    bool `json:"requireSymbol"`
+}
+
+// A city where media was taken
+type PlaceCity struct {
+	Name       string `json:"name"`
+	MediaCount int    `json:"mediaCount"`
+}
+
+// A country where media was taken, with the regions within it
+type PlaceCountry struct {
+	CountryCode string         `json:"countryCode"`
+	Name        string         `json:"name"`
+	MediaCount  int            `json:"mediaCount"`
+	Regions     []*PlaceRegion `json:"regions"`
+}
+
+// A region of a country where media was taken, with the cities within it
+type PlaceRegion struct {
+	// The name of the region, null for media where the region is not known
+	Name       *string      `json:"name,omitempty"`
+	MediaCount int          `json:"mediaCount"`
+	Cities     []*PlaceCity `json:"cities"`
+}
+
 type ScannerResult struct {
 	Finished bool     `json:"finished"`
 	Success  bool     `json:"success"`
@@ -81,10 +262,71 @@ type SearchResult struct {
 
 // Credentials used to identify and authenticate a share token
 type ShareTokenCredentials struct {
+	// The token value or the custom slug of the share
 	Token    string  `json:"token"`
 	Password *string `json:"password,omitempty"`
 }
 
+// Settings of a share token
+type ShareTokenSettings struct {
+	// Custom value that can be used in place of the token, an empty string removes it
+	Slug          *string `json:"slug,omitempty"`
+	AllowDownload *bool   `json:"allowDownload,omitempty"`
+	// The number of clients that can open the share, a value of 0 removes the limit
+	MaxViews *int `json:"maxViews,omitempty"`
+	// Set the number of views back to 0
+	ResetViews *bool `json:"resetViews,omitempty"`
+	// Text drawn over the images shown to visitors, an empty string removes the watermark
+	WatermarkText *string `json:"watermarkText,omitempty"`
+}
+
+// Statistics of the access log of a share token, accesses older than the audit log retention are not included
+type ShareTokenStats struct {
+	// The number of clients that have opened the share
+	Views int `json:"views"`
+	// The number of distinct IP addresses that have accessed the share
+	Visitors int `json:"visitors"`
+	// The number of times media of the share has been viewed in full size
+	MediaViews int `json:"mediaViews"`
+	// The number of times albums of the share have been downloaded
+	Downloads int `json:"downloads"`
+	// When the share was last accessed, null if it has never been accessed
+	LastAccess *time.Time `json:"lastAccess,omitempty"`
+}
+
+// The storage used by all media on the server, sizes are in bytes
+type StorageReport struct {
+	PhotoCount int `json:"photoCount"`
+	VideoCount int `json:"videoCount"`
+	// Total size of the original media files
+	OriginalSize int `json:"originalSize"`
+	// Total size of the thumbnails and other files generated in the media cache
+	CacheSize int `json:"cacheSize"`
+	// Size of all media in the recycle bin
+	RecycleBinSize int `json:"recycleBinSize"`
+	// The albums using the most storage, including their sub albums
+	LargestAlbums []*AlbumStorage `json:"largestAlbums"`
+	// The storage used by the albums of every user
+	Users []*UserStorage `json:"users"`
+}
+
+// A year, month or day of the timeline
+type TimelineBucket struct {
+	// The start of the bucket in UTC
+	Date        time.Time           `json:"date"`
+	Granularity TimelineGranularity `json:"granularity"`
+	MediaCount  int                 `json:"mediaCount"`
+	// The newest media of the bucket, which is shown first on the timeline
+	FirstMedia *Media `json:"firstMedia"`
+}
+
+// Identifies a bucket of the timeline
+type TimelineBucketInput struct {
+	// Any date within the bucket
+	Date        time.Time           `json:"date"`
+	Granularity TimelineGranularity `json:"granularity"`
+}
+
 // A group of media from the same album and the same day, that is grouped together in a timeline view
 type TimelineGroup struct {
 	// The full album containing the media in this timeline group
@@ -97,6 +339,174 @@ type TimelineGroup struct {
 	Date time.Time `json:"date"`
 }
 
+// A new two-factor secret, that must be verified by `enableTwoFactor`
+type TwoFactorEnrollment struct {
+	// The base32 encoded secret, for entering it manually in the authenticator app
+	Secret string `json:"secret"`
+	// The otpauth uri of the secret, usually shown as a qr code
+	URI string `json:"uri"`
+}
+
+// The limits on the media of a user, along with how much of them is used
+type UserQuota struct {
+	// Max number of media, `null` if unlimited
+	MaxMediaCount *int `json:"maxMediaCount,omitempty"`
+	// Max total size in bytes of the original media files, `null` if unlimited
+	MaxStorageSize *int `json:"maxStorageSize,omitempty"`
+	MediaCount     int  `json:"mediaCount"`
+	StorageSize    int  `json:"storageSize"`
+	// Whether or not one of the limits has been reached, new media will not be scanned until below the limits again
+	Exceeded bool `json:"exceeded"`
+}
+
+// The storage used by the albums of a user, sizes are in bytes
+type UserStorage struct {
+	User         *User `json:"user"`
+	MediaCount   int   `json:"mediaCount"`
+	OriginalSize int   `json:"originalSize"`
+	CacheSize    int   `json:"cacheSize"`
+}
+
+type VideoTranscodeProfileInput struct {
+	Codec         string  `json:"codec"`
+	Crf           int     `json:"crf"`
+	MaxResolution int     `json:"maxResolution"`
+	AudioBitrate  string  `json:"audioBitrate"`
+	VideoBitrate  *string `json:"videoBitrate,omitempty"`
+	TwoPass       bool    `json:"twoPass"`
+}
+
+// Statistics and highlights of the media shot in a year
+type YearSummary struct {
+	Year       int `json:"year"`
+	MediaCount int `json:"mediaCount"`
+	// The number of media shot in every month of the year, from January to December
+	Months []*YearSummaryMonth `json:"months"`
+	// The albums with the most media shot in the year
+	TopAlbums []*YearSummaryAlbum `json:"topAlbums"`
+	// The face groups present in the most media shot in the year
+	TopFaces []*YearSummaryFaceGroup `json:"topFaces"`
+	// A selection of media of the year, favorites are picked first
+	Highlights []*Media `json:"highlights"`
+}
+
+type YearSummaryAlbum struct {
+	Album      *Album `json:"album"`
+	MediaCount int    `json:"mediaCount"`
+}
+
+type YearSummaryFaceGroup struct {
+	FaceGroup  *FaceGroup `json:"faceGroup"`
+	MediaCount int        `json:"mediaCount"`
+}
+
+type YearSummaryMonth struct {
+	// The month from 1 to 12
+	Month      int `json:"month"`
+	MediaCount int `json:"mediaCount"`
+}
+
+// The role a user has on an album, each role includes the permissions of the roles before it.
+// Owners of an album are managers of it.
+type AlbumRole string
+
+const (
+	// Browse and download media
+	AlbumRoleViewer AlbumRole = "VIEWER"
+	// Favorite, delete and retouch media, and change their metadata
+	AlbumRoleEditor AlbumRole = "EDITOR"
+	// Share the album, with share tokens and by granting roles to other users
+	AlbumRoleManager AlbumRole = "MANAGER"
+)
+
+var AllAlbumRole = []AlbumRole{
+	AlbumRoleViewer,
+	AlbumRoleEditor,
+	AlbumRoleManager,
+}
+
+func (e AlbumRole) IsValid() bool {
+	switch e {
+	case AlbumRoleViewer, AlbumRoleEditor, AlbumRoleManager:
+		return true
+	}
+	return false
+}
+
+func (e AlbumRole) String() string {
+	return string(e)
+}
+
+func (e *AlbumRole) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AlbumRole(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AlbumRole", str)
+	}
+	return nil
+}
+
+func (e AlbumRole) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// The permissions that can be granted to an API key
+type APIKeyScope string
+
+const (
+	// Read albums, media and other content of the user
+	APIKeyScopeRead APIKeyScope = "READ"
+	// Start scans and mark paths as modified
+	APIKeyScopeScan APIKeyScope = "SCAN"
+	// Modify media and their metadata
+	APIKeyScopeUpload APIKeyScope = "UPLOAD"
+	// Create and manage share tokens
+	APIKeyScopeShare APIKeyScope = "SHARE"
+	// Perform admin operations, only for keys of admin users
+	APIKeyScopeAdmin APIKeyScope = "ADMIN"
+)
+
+var AllAPIKeyScope = []APIKeyScope{
+	APIKeyScopeRead,
+	APIKeyScopeScan,
+	APIKeyScopeUpload,
+	APIKeyScopeShare,
+	APIKeyScopeAdmin,
+}
+
+func (e APIKeyScope) IsValid() bool {
+	switch e {
+	case APIKeyScopeRead, APIKeyScopeScan, APIKeyScopeUpload, APIKeyScopeShare, APIKeyScopeAdmin:
+		return true
+	}
+	return false
+}
+
+func (e APIKeyScope) String() string {
+	return string(e)
+}
+
+func (e *APIKeyScope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = APIKeyScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ApiKeyScope", str)
+	}
+	return nil
+}
+
+func (e APIKeyScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 // Supported language translations of the user interface
 type LanguageTranslation string
 
@@ -163,6 +573,103 @@ func (e LanguageTranslation) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
+// The projection of a media, read from its GPano metadata
+type MediaProjection string
+
+const (
+	// A regular image
+	MediaProjectionFlat MediaProjection = "Flat"
+	// A 360° photo sphere, or a part of it
+	MediaProjectionEquirectangular MediaProjection = "Equirectangular"
+	// A panorama that wraps around horizontally
+	MediaProjectionCylindrical MediaProjection = "Cylindrical"
+)
+
+var AllMediaProjection = []MediaProjection{
+	MediaProjectionFlat,
+	MediaProjectionEquirectangular,
+	MediaProjectionCylindrical,
+}
+
+func (e MediaProjection) IsValid() bool {
+	switch e {
+	case MediaProjectionFlat, MediaProjectionEquirectangular, MediaProjectionCylindrical:
+		return true
+	}
+	return false
+}
+
+func (e MediaProjection) String() string {
+	return string(e)
+}
+
+func (e *MediaProjection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = MediaProjection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid MediaProjection", str)
+	}
+	return nil
+}
+
+func (e MediaProjection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// The relation between the media of a stack
+type MediaStackKind string
+
+const (
+	// Frames of a continuous shooting burst
+	MediaStackKindBurst MediaStackKind = "Burst"
+	// Frames of an exposure bracket
+	MediaStackKindBracket MediaStackKind = "Bracket"
+	// Frames with sequentially numbered filenames, shot right after each other
+	MediaStackKindSequence MediaStackKind = "Sequence"
+	// A stack made by a user
+	MediaStackKindManual MediaStackKind = "Manual"
+)
+
+var AllMediaStackKind = []MediaStackKind{
+	MediaStackKindBurst,
+	MediaStackKindBracket,
+	MediaStackKindSequence,
+	MediaStackKindManual,
+}
+
+func (e MediaStackKind) IsValid() bool {
+	switch e {
+	case MediaStackKindBurst, MediaStackKindBracket, MediaStackKindSequence, MediaStackKindManual:
+		return true
+	}
+	return false
+}
+
+func (e MediaStackKind) String() string {
+	return string(e)
+}
+
+func (e *MediaStackKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = MediaStackKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid MediaStackKind", str)
+	}
+	return nil
+}
+
+func (e MediaStackKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 // Specified the type a particular notification is of
 type NotificationType string
 
@@ -303,3 +810,47 @@ func (e *ThumbnailFilter) UnmarshalGQL(v interface{}) error {
 func (e ThumbnailFilter) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
+
+// The length of the buckets the timeline is grouped into
+type TimelineGranularity string
+
+const (
+	TimelineGranularityYear  TimelineGranularity = "YEAR"
+	TimelineGranularityMonth TimelineGranularity = "MONTH"
+	TimelineGranularityDay   TimelineGranularity = "DAY"
+)
+
+var AllTimelineGranularity = []TimelineGranularity{
+	TimelineGranularityYear,
+	TimelineGranularityMonth,
+	TimelineGranularityDay,
+}
+
+func (e TimelineGranularity) IsValid() bool {
+	switch e {
+	case TimelineGranularityYear, TimelineGranularityMonth, TimelineGranularityDay:
+		return true
+	}
+	return false
+}
+
+func (e TimelineGranularity) String() string {
+	return string(e)
+}
+
+func (e *TimelineGranularity) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TimelineGranularity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TimelineGranularity", str)
+	}
+	return nil
+}
+
+func (e TimelineGranularity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
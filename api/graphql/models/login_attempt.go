@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Kinds of failed login attempts
+const (
+	LoginAttemptKindPassword   = "password"
+	LoginAttemptKindTwoFactor  = "two_factor"
+	LoginAttemptKindShareToken = "share_token"
+)
+
+// FailedLoginAttempt records a wrong password or two-factor code, or an attempt rejected because of too many failures,
+// such that admins can see if someone is trying to guess passwords
+type FailedLoginAttempt struct {
+	ID        int       `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	Kind      string    `gorm:"not null;size:32;index"`
+	// Username is the username that was tried, for password and two-factor attempts
+	Username *string `gorm:"size:128"`
+	// UserID is the user with the username, if it exists
+	UserID       *int
+	User         *User `gorm:"constraint:OnDelete:SET NULL;"`
+	ShareTokenID *int
+	ShareToken   *ShareToken `gorm:"constraint:OnDelete:SET NULL;"`
+	IPAddress    string      `gorm:"size:64;index"`
+	UserAgent    string      `gorm:"size:512"`
+	// Blocked is true if the attempt was rejected without checking it, because of too many previous failures
+	Blocked bool `gorm:"not null;default:false"`
+}
+
+// LoginThrottle is the number of recent failed attempts of a client or account,
+// saved to the database when the login limiter is persisted
+type LoginThrottle struct {
+	Key          string    `gorm:"column:limiter_key;primaryKey;size:255"`
+	Failures     int       `gorm:"not null;default:0"`
+	LastFailure  time.Time `gorm:"not null;index"`
+	BlockedUntil *time.Time
+}
+
+// RecordFailedLoginAttempt saves the attempt along with the client of the request
+func RecordFailedLoginAttempt(db *gorm.DB, attempt FailedLoginAttempt) error {
+	client := SessionClientFromContext(db.Statement.Context)
+	attempt.IPAddress = truncateString(client.IPAddress, 64)
+	attempt.UserAgent = truncateString(client.UserAgent, 512)
+
+	if err := db.Create(&attempt).Error; err != nil {
+		return errors.Wrap(err, "save failed login attempt")
+	}
+
+	return nil
+}
+
+// DeleteOldFailedLoginAttempts deletes attempts older than the retention period, and returns how many were deleted
+func DeleteOldFailedLoginAttempts(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&FailedLoginAttempt{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "delete old failed login attempts")
+	}
+
+	return result.RowsAffected, nil
+}
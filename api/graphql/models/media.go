@@ -31,6 +31,24 @@ type Media struct {
 	SideCarHash     *string      `gorm:"unique"`
 	Faces           []*ImageFace `gorm:"constraint:OnDelete:CASCADE;"`
 	Blurhash        *string      `gorm:""`
+	// MotionPhotoID is set on the video part of a live photo, and refers to the still image it belongs to
+	MotionPhotoID *int        `gorm:"index"`
+	StackID       *int        `gorm:"index"`
+	Stack         *MediaStack `gorm:"constraint:OnDelete:SET NULL;"`
+	// StackChecked is set when the scanner has looked for stacks that the media belongs to
+	StackChecked bool `gorm:"not null;default:false"`
+	// Place is found by reverse geocoding the coordinates of the media
+	Place MediaPlace `gorm:"embedded;embeddedPrefix:place_"`
+	// PlaceChecked is set when the coordinates of the media have been reverse geocoded, even if no place was found
+	PlaceChecked bool `gorm:"not null;default:false"`
+}
+
+// MediaPlace is the country, region and city where a media was taken
+type MediaPlace struct {
+	CountryCode *string `gorm:"index"`
+	Country     *string
+	Region      *string
+	City        *string
 }
 
 func (Media) TableName() string {
@@ -102,6 +120,14 @@ const (
 	MediaOriginal  MediaPurpose = "original"
 	VideoWeb       MediaPurpose = "video-web"
 	VideoThumbnail MediaPurpose = "video-thumbnail"
+	// VideoPreview is a short muted clip of a video
+	VideoPreview MediaPurpose = "video-preview"
+	// VideoSprite is an image of frames from a video laid out in a grid, used for scrub previews
+	VideoSprite MediaPurpose = "video-sprite"
+	// VideoSpriteIndex is a WebVTT file mapping time ranges of a video to tiles of the VideoSprite
+	VideoSpriteIndex MediaPurpose = "video-sprite-index"
+	// MotionVideo is a video extracted from a motion photo
+	MotionVideo MediaPurpose = "motion-video"
 )
 
 type MediaURL struct {
@@ -119,7 +145,7 @@ type MediaURL struct {
 func (p *MediaURL) URL() string {
 
 	imageURL := utils.ApiEndpointUrl()
-	if p.Purpose != VideoWeb {
+	if p.Purpose != VideoWeb && p.Purpose != VideoPreview && p.Purpose != MotionVideo {
 		imageURL.Path = path.Join(imageURL.Path, "photo", p.MediaName)
 	} else {
 		imageURL.Path = path.Join(imageURL.Path, "video", p.MediaName)
@@ -135,7 +161,8 @@ func (p *MediaURL) CachedPath() (string, error) {
 		return "", errors.New("mediaURL.Media is nil")
 	}
 
-	if p.Purpose == PhotoThumbnail || p.Purpose == VideoThumbnail || p.Purpose == VideoWeb {
+	if p.Purpose == PhotoThumbnail || p.Purpose == VideoThumbnail || p.Purpose == VideoWeb ||
+		p.Purpose == VideoPreview || p.Purpose == VideoSprite || p.Purpose == VideoSpriteIndex || p.Purpose == MotionVideo {
 		cachedPath = path.Join(utils.MediaCachePath(), strconv.Itoa(int(p.Media.AlbumID)), strconv.Itoa(int(p.MediaID)), p.MediaName)
 	} else if p.Purpose == PhotoHighRes || p.Purpose == MediaOriginal {
 		{
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -20,6 +21,49 @@ type MediaEXIF struct {
 	ExposureProgram *int64
 	GPSLatitude     *float64
 	GPSLongitude    *float64
+	// GPSAltitude is the altitude in meters above sea level
+	GPSAltitude *float64
+	// GPSDirection is the compass direction in degrees the camera was pointing at
+	GPSDirection       *float64
+	WhiteBalance       *int64
+	MeteringMode       *int64
+	SubjectDistance    *float64
+	CameraSerialNumber *string
+	LensSerialNumber   *string
+	Artist             *string
+	Copyright          *string
+	// IPTC fields
+	Caption *string
+	Byline  *string
+	City    *string
+	Country *string
+	// Keywords is a comma separated list of the IPTC keywords or XMP subjects
+	Keywords *string
+	// TimezoneOffset is the offset from UTC of the time the media was shot, eg. +02:00
+	TimezoneOffset *string
+	// DateShotLocal is true if DateShot is still the local time of the camera,
+	// as it was saved before dates were converted to UTC, until it is read again from the media file
+	DateShotLocal bool `gorm:"not null;default:false"`
+	// RawTags is a JSON object of all the metadata tags read from the file
+	RawTags *string `gorm:"type:text"`
+	// BurstID is shared by all frames of a burst
+	BurstID *string
+	// SequenceNumber is the position of the frame in a burst or exposure bracket
+	SequenceNumber *int64
+	// ProjectionType is the GPano projection of a panorama, eg. equirectangular for 360° photos
+	ProjectionType *string
+	// Size of the full panorama, of which the image may only cover a part
+	FullPanoWidth  *int64
+	FullPanoHeight *int64
+	// Size and position of the area of the full panorama that is covered by the image
+	CroppedAreaWidth  *int64
+	CroppedAreaHeight *int64
+	CroppedAreaLeft   *int64
+	CroppedAreaTop    *int64
+	// PoseHeading is the compass heading in degrees of the center of the panorama
+	PoseHeading *float64
+	// HasDepthMap is true if the image has an embedded depth map, eg. from a portrait mode
+	HasDepthMap bool `gorm:"not null;default:false"`
 }
 
 func (MediaEXIF) TableName() string {
@@ -30,6 +74,61 @@ func (exif *MediaEXIF) Media() *Media {
 	panic("not implemented")
 }
 
+// Projection returns how the image should be projected when viewed, based on its GPano metadata
+func (exif *MediaEXIF) Projection() MediaProjection {
+	if exif == nil || exif.ProjectionType == nil {
+		return MediaProjectionFlat
+	}
+
+	switch strings.ToLower(*exif.ProjectionType) {
+	case "equirectangular":
+		return MediaProjectionEquirectangular
+	case "cylindrical":
+		return MediaProjectionCylindrical
+	default:
+		return MediaProjectionFlat
+	}
+}
+
+// KeywordSeparator separates the keywords stored in MediaEXIF.Keywords
+const KeywordSeparator = ", "
+
+// KeywordList returns the keywords of the media as a list
+func (exif *MediaEXIF) KeywordList() []string {
+	keywords := make([]string, 0)
+	if exif == nil || exif.Keywords == nil {
+		return keywords
+	}
+
+	for _, keyword := range strings.Split(*exif.Keywords, ",") {
+		keyword = strings.TrimSpace(keyword)
+		if keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+
+	return keywords
+}
+
+// SetKeywordList stores the list of keywords, an empty list clears the keywords
+func (exif *MediaEXIF) SetKeywordList(keywords []string) {
+	cleaned := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(strings.ReplaceAll(keyword, ",", " "))
+		if keyword != "" {
+			cleaned = append(cleaned, keyword)
+		}
+	}
+
+	if len(cleaned) == 0 {
+		exif.Keywords = nil
+		return
+	}
+
+	joined := strings.Join(cleaned, KeywordSeparator)
+	exif.Keywords = &joined
+}
+
 func (exif *MediaEXIF) Coordinates() *Coordinates {
 	if exif.GPSLatitude == nil || exif.GPSLongitude == nil {
 		return nil
@@ -0,0 +1,17 @@
+package models
+
+// MediaMetadataEdit records a change made by a user to the metadata of a media
+type MediaMetadataEdit struct {
+	Model
+	MediaID  int    `gorm:"not null;index"`
+	Media    Media  `gorm:"constraint:OnDelete:CASCADE;"`
+	UserID   *int   `gorm:"index"`
+	User     *User  `gorm:"constraint:OnDelete:SET NULL;"`
+	Field    string `gorm:"not null"`
+	OldValue *string
+	NewValue *string
+}
+
+func (MediaMetadataEdit) TableName() string {
+	return "media_metadata_edits"
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// MediaStack groups near identical media, such as the frames of a burst or an exposure bracket,
+// such that they can be presented as a single media represented by the top frame
+type MediaStack struct {
+	Model
+	AlbumID    int            `gorm:"not null;index"`
+	Album      Album          `gorm:"constraint:OnDelete:CASCADE;"`
+	TopMediaID *int           `gorm:"index"`
+	Kind       MediaStackKind `gorm:"not null"`
+}
+
+func (MediaStack) TableName() string {
+	return "media_stacks"
+}
+
+// CollapseMediaStacks filters a query on the media table, such that only the top frame of each stack is included
+func CollapseMediaStacks(query *gorm.DB) *gorm.DB {
+	return query.Where("media.stack_id IS NULL OR media.id IN (SELECT media_stacks.top_media_id FROM media_stacks WHERE media_stacks.id = media.stack_id)")
+}
+
+// CleanupMediaStacks removes stacks of the album with less than two media,
+// and assigns a new top frame to stacks where it is no longer a part of the stack
+func CleanupMediaStacks(db *gorm.DB, albumID int) error {
+	var stacks []*MediaStack
+	if err := db.Where("album_id = ?", albumID).Find(&stacks).Error; err != nil {
+		return errors.Wrap(err, "get media stacks of album")
+	}
+
+	for _, stack := range stacks {
+		var mediaIDs []int
+		if err := db.Model(&Media{}).Where("stack_id = ?", stack.ID).Order("date_shot, title").Pluck("id", &mediaIDs).Error; err != nil {
+			return errors.Wrapf(err, "get media of stack (%d)", stack.ID)
+		}
+
+		if len(mediaIDs) < 2 {
+			if err := db.Model(&Media{}).Where("stack_id = ?", stack.ID).Update("stack_id", nil).Error; err != nil {
+				return errors.Wrapf(err, "remove media from stack (%d)", stack.ID)
+			}
+
+			if err := db.Delete(stack).Error; err != nil {
+				return errors.Wrapf(err, "delete media stack (%d)", stack.ID)
+			}
+
+			continue
+		}
+
+		topFound := false
+		for _, id := range mediaIDs {
+			if stack.TopMediaID != nil && *stack.TopMediaID == id {
+				topFound = true
+				break
+			}
+		}
+
+		if !topFound {
+			if err := db.Model(stack).Update("top_media_id", mediaIDs[0]).Error; err != nil {
+				return errors.Wrapf(err, "update top frame of stack (%d)", stack.ID)
+			}
+		}
+	}
+
+	return nil
+}
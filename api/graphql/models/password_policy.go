@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// PasswordPolicy describes the requirements for new passwords of users and share tokens
+type PasswordPolicy struct {
+	MinLength        int  `gorm:"not null;default:8"`
+	RequireMixedCase bool `gorm:"not null;default:false"`
+	RequireDigit     bool `gorm:"not null;default:false"`
+	RequireSymbol    bool `gorm:"not null;default:false"`
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength: 8,
+	}
+}
+
+// Validate returns an error describing the first requirement of the policy that the password does not meet
+func (p PasswordPolicy) Validate(password string) error {
+	if strings.TrimSpace(password) == "" {
+		return errors.New("password cannot be blank")
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		return errors.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case !unicode.IsSpace(char):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireMixedCase && !(hasUpper && hasLower) {
+		return errors.New("password must contain both upper and lower case letters")
+	}
+
+	if p.RequireDigit && !hasDigit {
+		return errors.New("password must contain a digit")
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		return errors.New("password must contain a symbol")
+	}
+
+	return nil
+}
+
+// ValidatePassword checks a new password against the password policy of the site
+func ValidatePassword(db *gorm.DB, password string) error {
+	siteInfo, err := GetSiteInfo(db)
+	if err != nil {
+		return err
+	}
+
+	return siteInfo.PasswordPolicy.Validate(password)
+}
@@ -0,0 +1,44 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	strict := models.PasswordPolicy{
+		MinLength:        10,
+		RequireMixedCase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+	}
+
+	tests := []struct {
+		name     string
+		policy   models.PasswordPolicy
+		password string
+		err      string
+	}{
+		{"Default accepts long password", models.DefaultPasswordPolicy(), "correcthorse", ""},
+		{"Default rejects short password", models.DefaultPasswordPolicy(), "1234", "password must be at least 8 characters long"},
+		{"Blank", models.DefaultPasswordPolicy(), "          ", "password cannot be blank"},
+		{"Length counts characters", models.PasswordPolicy{MinLength: 4}, "æøåü", ""},
+		{"Mixed case", strict, "lowercase-123", "password must contain both upper and lower case letters"},
+		{"Digit", strict, "Mixed-Case-Only", "password must contain a digit"},
+		{"Symbol", strict, "MixedCase123", "password must contain a symbol"},
+		{"Strict accepts", strict, "Mixed-Case-123", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Validate(test.password)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -2,11 +2,18 @@ package models
 
 import (
 	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ShareToken struct {
 	Model
-	Value    string     `gorm:"not null"`
+	Value string `gorm:"not null"`
+	// Slug is an optional custom value that can be used in place of the token value
+	Slug     *string    `gorm:"uniqueIndex;size:64"`
 	OwnerID  int        `gorm:"not null;index"`
 	Owner    User       `gorm:"constraint:OnDelete:CASCADE;"`
 	Expire   *time.Time `gorm:"index"`
@@ -15,8 +22,155 @@ type ShareToken struct {
 	Album    *Album `gorm:"constraint:OnDelete:CASCADE;"`
 	MediaID  *int   `gorm:"index"`
 	Media    *Media `gorm:"constraint:OnDelete:CASCADE;"`
+	// DisableDownload denies visitors downloading original files and albums
+	DisableDownload bool `gorm:"not null;default:false"`
+	// MaxViews is the number of clients that can open the share, nil if there is no limit
+	MaxViews *int
+	// Views is the number of clients that have opened the share
+	Views int `gorm:"not null;default:0"`
+	// WatermarkText is drawn over the images shown to visitors, originals and videos are not shared when it is set
+	WatermarkText *string `gorm:"size:64"`
 }
 
 func (share *ShareToken) Token() string {
 	return share.Value
 }
+
+// Expired returns true if the share has an expire date that has passed
+func (share *ShareToken) Expired() bool {
+	return share.Expire != nil && share.Expire.Before(time.Now())
+}
+
+// ViewLimitReached returns true if no more new clients can open the share
+func (share *ShareToken) ViewLimitReached() bool {
+	return share.MaxViews != nil && share.Views >= *share.MaxViews
+}
+
+// AllowDownload returns whether or not visitors can download original files and albums,
+// which is never the case for watermarked shares
+func (share *ShareToken) AllowDownload() bool {
+	return !share.DisableDownload && share.WatermarkText == nil
+}
+
+// CheckPassword returns true if the share has no password, or if the password matches it
+func (share *ShareToken) CheckPassword(password *string) (bool, error) {
+	if share.Password == nil {
+		return true, nil
+	}
+
+	if password == nil {
+		return false, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(*share.Password), []byte(*password)); err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "could not compare token password hashes")
+	}
+
+	return true, nil
+}
+
+// Kinds of share token accesses
+const (
+	// ShareTokenAccessOpen is recorded when a client opens the share
+	ShareTokenAccessOpen = "open"
+	// ShareTokenAccessMedia is recorded when a client views a media of the share in full size
+	ShareTokenAccessMedia = "media"
+	// ShareTokenAccessDownload is recorded when a client downloads an album of the share
+	ShareTokenAccessDownload = "download"
+)
+
+// ShareTokenAccess is an entry of the access log of a share token
+type ShareTokenAccess struct {
+	ID           int         `gorm:"primarykey"`
+	CreatedAt    time.Time   `gorm:"index"`
+	ShareTokenID int         `gorm:"not null;index"`
+	ShareToken   *ShareToken `gorm:"constraint:OnDelete:CASCADE;"`
+	Kind         string      `gorm:"not null;size:32"`
+	// MediaID is the media that was viewed, nil if the share was opened or an album was downloaded
+	MediaID   *int
+	Media     *Media `gorm:"constraint:OnDelete:SET NULL;"`
+	IPAddress string `gorm:"size:64;index"`
+	UserAgent string `gorm:"size:512"`
+}
+
+// RecordShareTokenAccess saves an access of the share along with the client of the request
+func RecordShareTokenAccess(db *gorm.DB, share *ShareToken, kind string, mediaID *int) error {
+	client := SessionClientFromContext(db.Statement.Context)
+
+	access := ShareTokenAccess{
+		ShareTokenID: share.ID,
+		Kind:         kind,
+		MediaID:      mediaID,
+		IPAddress:    truncateString(client.IPAddress, 64),
+		UserAgent:    truncateString(client.UserAgent, 512),
+	}
+
+	if err := db.Create(&access).Error; err != nil {
+		return errors.Wrap(err, "save share token access")
+	}
+
+	return nil
+}
+
+// ShareTokenClient is a client that has opened a share, such that only the first time it opens the share counts as a view.
+// Unlike the access log it is not deleted after the retention period, but kept for as long as the share exists.
+type ShareTokenClient struct {
+	ID           int `gorm:"primarykey"`
+	CreatedAt    time.Time
+	ShareTokenID int         `gorm:"not null;uniqueIndex:idx_share_token_clients_client"`
+	ShareToken   *ShareToken `gorm:"constraint:OnDelete:CASCADE;"`
+	IPAddress    string      `gorm:"size:64;uniqueIndex:idx_share_token_clients_client"`
+	UserAgent    string      `gorm:"size:512;uniqueIndex:idx_share_token_clients_client"`
+}
+
+// shareTokenClientFromContext returns the client of the request as a client of the share
+func shareTokenClientFromContext(db *gorm.DB, share *ShareToken) ShareTokenClient {
+	client := SessionClientFromContext(db.Statement.Context)
+
+	return ShareTokenClient{
+		ShareTokenID: share.ID,
+		IPAddress:    truncateString(client.IPAddress, 64),
+		UserAgent:    truncateString(client.UserAgent, 512),
+	}
+}
+
+// AddShareTokenClient saves the client of the request as a client that has opened the share,
+// and returns false if it had already opened it
+func AddShareTokenClient(db *gorm.DB, share *ShareToken) (bool, error) {
+	client := shareTokenClientFromContext(db, share)
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&client)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "save share token client")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// HasShareTokenClient returns true if the client of the request has opened the share before
+func HasShareTokenClient(db *gorm.DB, share *ShareToken) (bool, error) {
+	client := shareTokenClientFromContext(db, share)
+
+	var count int64
+	err := db.Model(&ShareTokenClient{}).
+		Where("share_token_id = ? AND ip_address = ? AND user_agent = ?", client.ShareTokenID, client.IPAddress, client.UserAgent).
+		Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, "check if client has opened share token")
+	}
+
+	return count > 0, nil
+}
+
+// DeleteOldShareTokenAccesses deletes accesses older than the retention period, and returns how many were deleted
+func DeleteOldShareTokenAccesses(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&ShareTokenAccess{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "delete old share token accesses")
+	}
+
+	return result.RowsAffected, nil
+}
@@ -1,16 +1,53 @@
 package models
 
 import (
+	"strings"
+
 	db_drivers "github.com/photoview/photoview/api/database/drivers"
+	"github.com/photoview/photoview/api/utils"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
 type SiteInfo struct {
-	InitialSetup         bool `gorm:"not null"`
-	PeriodicScanInterval int  `gorm:"not null"`
-	ConcurrentWorkers    int  `gorm:"not null"`
-	ThumbnailMethod   	 ThumbnailFilter  `gorm:"not null"`
+	InitialSetup          bool                  `gorm:"not null"`
+	PeriodicScanInterval  int                   `gorm:"not null"`
+	ConcurrentWorkers     int                   `gorm:"not null"`
+	ThumbnailMethod       ThumbnailFilter       `gorm:"not null"`
+	VideoTranscodeProfile VideoTranscodeProfile `gorm:"embedded;embeddedPrefix:transcode_"`
+	// RootPathAllowlist is a newline separated list of directories that root paths must be inside of
+	RootPathAllowlist string
+	// AuditLogRetentionDays is how many days audit events are kept, 0 keeps them forever
+	AuditLogRetentionDays int `gorm:"not null;default:0"`
+	// RequireAdminTwoFactor denies admin features to admins that have not enabled two-factor authentication
+	RequireAdminTwoFactor bool           `gorm:"not null;default:false"`
+	PasswordPolicy        PasswordPolicy `gorm:"embedded;embeddedPrefix:password_"`
+}
+
+// VideoTranscodeProfile describes the ffmpeg settings used to encode videos that are not web compatible
+type VideoTranscodeProfile struct {
+	// Codec is the name of the ffmpeg video encoder, eg. libx264, libx265 or h264_nvenc
+	Codec string `gorm:"not null;default:libx264"`
+	// Crf is the constant rate factor, used when encoding in a single pass
+	Crf int `gorm:"not null;default:23"`
+	// MaxResolution is the max width and height of the encoded video
+	MaxResolution int `gorm:"not null;default:1080"`
+	// AudioBitrate is passed to ffmpeg as is, eg. 128k
+	AudioBitrate string `gorm:"not null;default:128k"`
+	// VideoBitrate is the target bitrate used for two-pass encoding, eg. 4M
+	VideoBitrate *string
+	TwoPass      bool `gorm:"not null;default:false"`
+}
+
+func DefaultVideoTranscodeProfile() VideoTranscodeProfile {
+	return VideoTranscodeProfile{
+		Codec:         "libx264",
+		Crf:           23,
+		MaxResolution: 1080,
+		AudioBitrate:  "128k",
+		VideoBitrate:  nil,
+		TwoPass:       false,
+	}
 }
 
 func (SiteInfo) TableName() string {
@@ -24,10 +61,12 @@ func DefaultSiteInfo(db *gorm.DB) SiteInfo {
 	}
 
 	return SiteInfo{
-		InitialSetup:         true,
-		PeriodicScanInterval: 0,
-		ConcurrentWorkers:    defaultConcurrentWorkers,
-		ThumbnailMethod:			ThumbnailFilterNearestNeighbor,
+		InitialSetup:          true,
+		PeriodicScanInterval:  0,
+		ConcurrentWorkers:     defaultConcurrentWorkers,
+		ThumbnailMethod:       ThumbnailFilterNearestNeighbor,
+		VideoTranscodeProfile: DefaultVideoTranscodeProfile(),
+		PasswordPolicy:        DefaultPasswordPolicy(),
 	}
 }
 
@@ -52,3 +91,20 @@ func GetSiteInfo(db *gorm.DB) (*SiteInfo, error) {
 		return siteInfo[0], nil
 	}
 }
+
+// AllowedRootPaths returns the directories that root paths of users must be inside of.
+// If no allowlist has been configured, the one from the environment is used, an empty list allows any directory.
+func (s *SiteInfo) AllowedRootPaths() []string {
+	allowlist := make([]string, 0)
+	for _, dir := range strings.Split(s.RootPathAllowlist, "\n") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			allowlist = append(allowlist, dir)
+		}
+	}
+
+	if len(allowlist) == 0 {
+		return utils.RootPathAllowlist()
+	}
+
+	return allowlist
+}
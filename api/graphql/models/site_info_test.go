@@ -23,6 +23,8 @@ func TestSiteInfo(t *testing.T) {
 	site_info.PeriodicScanInterval = 360
 	site_info.ConcurrentWorkers = 10
 	site_info.ThumbnailMethod = models.ThumbnailFilterLanczos
+	site_info.VideoTranscodeProfile.Codec = "libx265"
+	site_info.VideoTranscodeProfile.Crf = 28
 
 	if !assert.NoError(t, db.Session(&gorm.Session{AllowGlobalUpdate: true}).Save(&site_info).Error) {
 		return
@@ -37,7 +39,14 @@ func TestSiteInfo(t *testing.T) {
 		InitialSetup:         false,
 		PeriodicScanInterval: 360,
 		ConcurrentWorkers:    10,
-		ThumbnailMethod:    	models.ThumbnailFilterLanczos,
+		ThumbnailMethod:      models.ThumbnailFilterLanczos,
+		VideoTranscodeProfile: models.VideoTranscodeProfile{
+			Codec:         "libx265",
+			Crf:           28,
+			MaxResolution: 1080,
+			AudioBitrate:  "128k",
+		},
+		PasswordPolicy: models.DefaultPasswordPolicy(),
 	}, *site_info)
 
 }
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Number of recovery codes generated when enabling two-factor authentication
+const twoFactorRecoveryCodeCount = 10
+
+// How long a user has to enter the two-factor code after the password was accepted
+const TwoFactorChallengeTimeout = 5 * time.Minute
+
+// How many wrong codes can be tried against a challenge, before it is invalidated
+const TwoFactorChallengeMaxAttempts = 5
+
+// TwoFactorRecoveryCode can be used once instead of a code from the authenticator app.
+// Only a hash of the code is stored.
+type TwoFactorRecoveryCode struct {
+	Model
+	UserID   int    `gorm:"not null;index"`
+	User     User   `gorm:"constraint:OnDelete:CASCADE;"`
+	CodeHash string `gorm:"not null;size:64;index"`
+}
+
+// TwoFactorChallenge is issued when the password of a user with two-factor authentication was accepted,
+// and is exchanged for an access token along with a valid code
+type TwoFactorChallenge struct {
+	Model
+	UserID   int       `gorm:"not null;index"`
+	User     User      `gorm:"constraint:OnDelete:CASCADE;"`
+	Value    string    `gorm:"not null;size:24;uniqueIndex"`
+	Expire   time.Time `gorm:"not null;index"`
+	Attempts int       `gorm:"not null;default:0"`
+}
+
+// normalizeRecoveryCode makes recovery codes case insensitive, and ignores spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func hashRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomString(characters string, length int) (string, error) {
+	charLen := big.NewInt(int64(len(characters)))
+
+	bytes := make([]byte, length)
+	for i := range bytes {
+		n, err := rand.Int(rand.Reader, charLen)
+		if err != nil {
+			return "", err
+		}
+		bytes[i] = characters[n.Int64()]
+	}
+
+	return string(bytes), nil
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of the user with new ones.
+// The codes are returned, as they cannot be recovered from the database.
+func (user *User) GenerateRecoveryCodes(db *gorm.DB) ([]string, error) {
+	if err := db.Where("user_id = ?", user.ID).Delete(&TwoFactorRecoveryCode{}).Error; err != nil {
+		return nil, errors.Wrap(err, "delete old recovery codes")
+	}
+
+	codes := make([]string, twoFactorRecoveryCodeCount)
+	rows := make([]TwoFactorRecoveryCode, twoFactorRecoveryCodeCount)
+	for i := range codes {
+		code, err := randomString("abcdefghijkmnpqrstuvwxyz23456789", 10)
+		if err != nil {
+			return nil, errors.Wrap(err, "generate recovery code")
+		}
+
+		codes[i] = code[:5] + "-" + code[5:]
+		rows[i] = TwoFactorRecoveryCode{
+			UserID:   user.ID,
+			CodeHash: hashRecoveryCode(code),
+		}
+	}
+
+	if err := db.Create(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "save recovery codes to database")
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode deletes the recovery code of the user, and returns false if the user has no such code
+func (user *User) UseRecoveryCode(db *gorm.DB, code string) (bool, error) {
+	result := db.Where("user_id = ? AND code_hash = ?", user.ID, hashRecoveryCode(code)).Delete(&TwoFactorRecoveryCode{})
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "use recovery code")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// CreateTwoFactorChallenge creates a challenge that can be exchanged for an access token of the user
+func (user *User) CreateTwoFactorChallenge(db *gorm.DB) (*TwoFactorChallenge, error) {
+	value, err := randomString("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", 24)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate two-factor challenge")
+	}
+
+	challenge := TwoFactorChallenge{
+		UserID: user.ID,
+		Value:  value,
+		Expire: time.Now().Add(TwoFactorChallengeTimeout),
+	}
+
+	if err := db.Create(&challenge).Error; err != nil {
+		return nil, errors.Wrap(err, "save two-factor challenge to database")
+	}
+
+	return &challenge, nil
+}
+
+// DeleteExpiredTwoFactorChallenges deletes all challenges that have expired, and returns how many were deleted
+func DeleteExpiredTwoFactorChallenges(db *gorm.DB) (int64, error) {
+	result := db.Where("expire < ?", time.Now()).Delete(&TwoFactorChallenge{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "delete expired two-factor challenges")
+	}
+
+	return result.RowsAffected, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"time"
@@ -18,6 +19,20 @@ type User struct {
 	// RootPath string  `gorm:"size:512`
 	Albums []Album `gorm:"many2many:user_albums;constraint:OnDelete:CASCADE;"`
 	Admin  bool    `gorm:"default:false"`
+	// Retoucher users can run the studio workflow operations on their albums, which change files on disk
+	Retoucher bool `gorm:"default:false"`
+	// MaxMediaCount is the max number of media in the albums of the user, nil means unlimited
+	MaxMediaCount *int
+	// MaxStorageSize is the max total size in bytes of the original media files of the user, nil means unlimited
+	MaxStorageSize *int64
+	// OIDCSubject is the subject of the account at the OpenID Connect provider that the user is linked to
+	OIDCSubject *string `gorm:"column:oidc_subject;uniqueIndex;size:255"`
+	// TOTPSecret is the base32 encoded secret of two-factor authentication, set when enrolling
+	TOTPSecret *string `gorm:"column:totp_secret;size:64"`
+	// TOTPEnabled is set once the enrollment has been verified with a code
+	TOTPEnabled bool `gorm:"column:totp_enabled;not null;default:false"`
+	// TOTPLastCounter is the counter of the last accepted code, such that a code cannot be used twice
+	TOTPLastCounter int64 `gorm:"column:totp_last_counter;not null;default:0"`
 }
 
 type UserMediaData struct {
@@ -38,6 +53,43 @@ type AccessToken struct {
 	User   User      `gorm:"constraint:OnDelete:CASCADE;"`
 	Value  string    `gorm:"not null;size:24;index"`
 	Expire time.Time `gorm:"not null;index"`
+	// The client that the token was last used from
+	UserAgent  string `gorm:"size:512"`
+	IPAddress  string `gorm:"size:64"`
+	LastUsedAt *time.Time
+}
+
+// SessionClient describes the client that an access token is used from
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+type sessionClientCtxKey struct{}
+
+// ContextWithSessionClient returns a copy of the context that carries the client
+func ContextWithSessionClient(ctx context.Context, client SessionClient) context.Context {
+	return context.WithValue(ctx, sessionClientCtxKey{}, client)
+}
+
+// SessionClientFromContext finds the client stored in the context, an empty client is returned if there is none
+func SessionClientFromContext(ctx context.Context) SessionClient {
+	if ctx == nil {
+		return SessionClient{}
+	}
+
+	client, _ := ctx.Value(sessionClientCtxKey{}).(SessionClient)
+	return client
+}
+
+// DeleteExpiredAccessTokens deletes all access tokens that have expired, and returns how many were deleted
+func DeleteExpiredAccessTokens(db *gorm.DB) (int64, error) {
+	result := db.Where("expire <= ?", time.Now()).Delete(&AccessToken{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, "delete expired access tokens")
+	}
+
+	return result.RowsAffected, nil
 }
 
 type UserPreferences struct {
@@ -72,6 +124,7 @@ func (u *UserPreferences) BeforeSave(tx *gorm.DB) error {
 }
 
 var ErrorInvalidUserCredentials = errors.New("invalid credentials")
+var ErrorUserWithoutPassword = errors.New("user does not have a password")
 
 func AuthorizeUser(db *gorm.DB, username string, password string) (*User, error) {
 	var user User
@@ -85,7 +138,7 @@ func AuthorizeUser(db *gorm.DB, username string, password string) (*User, error)
 	}
 
 	if user.Password == nil {
-		return nil, errors.New("user does not have a password")
+		return nil, ErrorUserWithoutPassword
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)); err != nil {
@@ -124,6 +177,11 @@ func RegisterUser(db *gorm.DB, username string, password *string, admin bool) (*
 }
 
 func (user *User) GenerateAccessToken(db *gorm.DB) (*AccessToken, error) {
+	return user.GenerateSessionToken(db, SessionClient{})
+}
+
+// GenerateSessionToken creates a new access token for the user, recording the client it was created for
+func (user *User) GenerateSessionToken(db *gorm.DB, client SessionClient) (*AccessToken, error) {
 	bytes := make([]byte, 24)
 	if _, err := rand.Read(bytes); err != nil {
 		return nil, errors.New(fmt.Sprintf("Could not generate token: %s\n", err.Error()))
@@ -136,10 +194,14 @@ func (user *User) GenerateAccessToken(db *gorm.DB) (*AccessToken, error) {
 	token_value := string(bytes)
 	expire := time.Now().Add(14 * 24 * time.Hour)
 
+	now := time.Now()
 	token := AccessToken{
-		UserID: user.ID,
-		Value:  token_value,
-		Expire: expire,
+		UserID:     user.ID,
+		Value:      token_value,
+		Expire:     expire,
+		UserAgent:  truncateString(client.UserAgent, 512),
+		IPAddress:  truncateString(client.IPAddress, 64),
+		LastUsedAt: &now,
 	}
 
 	result := db.Create(&token)
@@ -164,6 +226,9 @@ func (user *User) FillAlbums(db *gorm.DB) error {
 	return nil
 }
 
+// OwnsAlbum returns true if the album or one of its parents is an album of the user.
+// Roles granted to the user are not checked here, as AlbumRole builds on the ownership to treat owners as managers,
+// use HasAlbumRole to check if the user is allowed to do something with an album.
 func (user *User) OwnsAlbum(db *gorm.DB, album *Album) (bool, error) {
 
 	if err := user.FillAlbums(db); err != nil {
@@ -206,3 +271,10 @@ func (user *User) FavoriteMedia(db *gorm.DB, mediaID int, favorite bool) (*Media
 
 	return &media, nil
 }
+
+func truncateString(value string, maxLength int) string {
+	if len(value) > maxLength {
+		return value[:maxLength]
+	}
+	return value
+}
@@ -24,7 +24,7 @@ func (r *queryResolver) Album(ctx context.Context, id int, tokenCredentials *mod
 	db := r.DB(ctx)
 	if tokenCredentials != nil {
 
-		shareToken, err := r.ShareToken(ctx, *tokenCredentials)
+		shareToken, err := r.shareTokenFromCredentials(ctx, *tokenCredentials)
 		if err != nil {
 			return nil, err
 		}
@@ -59,11 +59,12 @@ func (r *Resolver) Album() api.AlbumResolver {
 
 type albumResolver struct{ *Resolver }
 
-func (r *albumResolver) Media(ctx context.Context, album *models.Album, order *models.Ordering, paginate *models.Pagination, onlyFavorites *bool) ([]*models.Media, error) {
+func (r *albumResolver) Media(ctx context.Context, album *models.Album, order *models.Ordering, paginate *models.Pagination, onlyFavorites *bool, expandStacks *bool) ([]*models.Media, error) {
 	db := r.DB(ctx)
 
 	query := db.
 		Where("media.album_id = ?", album.ID).
+		Where("media.motion_photo_id IS NULL").
 		Where("media.id IN (?)", db.Model(&models.MediaURL{}).Select("media_urls.media_id").Where("media_urls.media_id = media.id"))
 
 	if onlyFavorites != nil && *onlyFavorites == true {
@@ -79,6 +80,10 @@ func (r *albumResolver) Media(ctx context.Context, album *models.Album, order *m
 		query = query.Where("EXISTS (?)", favoriteQuery)
 	}
 
+	if expandStacks == nil || !*expandStacks {
+		query = models.CollapseMediaStacks(query)
+	}
+
 	query = models.FormatSQL(query, order, paginate)
 
 	var media []*models.Media
@@ -120,15 +125,49 @@ func (r *albumResolver) Owner(ctx context.Context, obj *models.Album) (*models.U
 }
 
 func (r *albumResolver) Shares(ctx context.Context, album *models.Album) ([]*models.ShareToken, error) {
+	db := r.DB(ctx)
+
+	shareTokens := make([]*models.ShareToken, 0)
+
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return shareTokens, nil
+	}
+
+	isManager, err := user.HasAlbumRole(db, album, models.AlbumRoleManager)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isManager {
+		return shareTokens, nil
+	}
 
-	var shareTokens []*models.ShareToken
-	if err := r.DB(ctx).Where("album_id = ?", album.ID).Find(&shareTokens).Error; err != nil {
+	if err := db.Where("album_id = ?", album.ID).Find(&shareTokens).Error; err != nil {
 		return nil, err
 	}
 
 	return shareTokens, nil
 }
 
+func (r *albumResolver) Grants(ctx context.Context, album *models.Album) ([]*models.AlbumGrant, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return make([]*models.AlbumGrant, 0), nil
+	}
+
+	return actions.AlbumGrants(r.DB(ctx), user, album)
+}
+
+func (r *albumResolver) MyRole(ctx context.Context, album *models.Album) (*models.AlbumRole, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, nil
+	}
+
+	return user.AlbumRole(r.DB(ctx), album)
+}
+
 func (r *albumResolver) Path(ctx context.Context, obj *models.Album) ([]*models.Album, error) {
 
 	user := auth.UserFromContext(ctx)
@@ -0,0 +1,81 @@
+package resolvers
+
+import (
+	"context"
+
+	api "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type albumGrantResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) AlbumGrant() api.AlbumGrantResolver {
+	return &albumGrantResolver{r}
+}
+
+func (r *albumGrantResolver) Album(ctx context.Context, obj *models.AlbumGrant) (*models.Album, error) {
+	var album models.Album
+	if err := r.DB(ctx).First(&album, obj.AlbumID).Error; err != nil {
+		return nil, errors.Wrap(err, "get album of album grant")
+	}
+
+	return &album, nil
+}
+
+func (r *albumGrantResolver) User(ctx context.Context, obj *models.AlbumGrant) (*models.User, error) {
+	var user models.User
+	if err := r.DB(ctx).First(&user, obj.UserID).Error; err != nil {
+		return nil, errors.Wrap(err, "get user of album grant")
+	}
+
+	return &user, nil
+}
+
+func (r *albumGrantResolver) GrantedBy(ctx context.Context, obj *models.AlbumGrant) (*models.User, error) {
+	if obj.GrantedByID == nil {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := r.DB(ctx).First(&user, *obj.GrantedByID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get granting user of album grant")
+	}
+
+	return &user, nil
+}
+
+func (r *queryResolver) SharedAlbums(ctx context.Context) ([]*models.Album, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.SharedAlbums(r.DB(ctx), user)
+}
+
+func (r *mutationResolver) GrantAlbumAccess(ctx context.Context, albumID int, username string, role models.AlbumRole) (*models.AlbumGrant, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.GrantAlbumAccess(r.DB(ctx), user, albumID, username, role)
+}
+
+func (r *mutationResolver) RevokeAlbumAccess(ctx context.Context, albumID int, userID int) (*models.AlbumGrant, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.RevokeAlbumAccess(r.DB(ctx), user, albumID, userID)
+}
@@ -0,0 +1,50 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	api "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+)
+
+type apiKeyResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) ApiKey() api.ApiKeyResolver {
+	return &apiKeyResolver{r}
+}
+
+func (r *apiKeyResolver) Scopes(ctx context.Context, obj *models.APIKey) ([]models.APIKeyScope, error) {
+	return obj.ScopeList(), nil
+}
+
+func (r *queryResolver) MyAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.MyAPIKeys(r.DB(ctx), user)
+}
+
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, name string, scopes []models.APIKeyScope, expire *time.Time) (*models.CreateAPIKeyResult, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.CreateAPIKey(r.DB(ctx), user, name, scopes, expire)
+}
+
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id int) (*models.APIKey, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.RevokeAPIKey(r.DB(ctx), user, id)
+}
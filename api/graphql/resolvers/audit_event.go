@@ -0,0 +1,43 @@
+package resolvers
+
+import (
+	"context"
+
+	api "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type auditEventResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) AuditEvent() api.AuditEventResolver {
+	return &auditEventResolver{r}
+}
+
+func (r *auditEventResolver) User(ctx context.Context, obj *models.AuditEvent) (*models.User, error) {
+	if obj.UserID == nil {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := r.DB(ctx).First(&user, *obj.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get user of audit event")
+	}
+
+	return &user, nil
+}
+
+func (r *queryResolver) AuditLog(ctx context.Context, filter *models.AuditLogFilter, paginate *models.Pagination) ([]*models.AuditEvent, error) {
+	return actions.AuditLog(r.DB(ctx), filter, paginate)
+}
+
+func (r *mutationResolver) SetAuditLogRetention(ctx context.Context, days int) (int, error) {
+	return actions.SetAuditLogRetention(r.DB(ctx), days)
+}
@@ -0,0 +1,72 @@
+package resolvers
+
+import (
+	"context"
+
+	api "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type failedLoginAttemptResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) FailedLoginAttempt() api.FailedLoginAttemptResolver {
+	return &failedLoginAttemptResolver{r}
+}
+
+func (r *failedLoginAttemptResolver) User(ctx context.Context, obj *models.FailedLoginAttempt) (*models.User, error) {
+	if obj.UserID == nil {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := r.DB(ctx).First(&user, *obj.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get user of failed login attempt")
+	}
+
+	return &user, nil
+}
+
+func (r *failedLoginAttemptResolver) ShareToken(ctx context.Context, obj *models.FailedLoginAttempt) (*models.ShareToken, error) {
+	if obj.ShareTokenID == nil {
+		return nil, nil
+	}
+
+	var token models.ShareToken
+	if err := r.DB(ctx).Preload("Owner").First(&token, *obj.ShareTokenID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get share token of failed login attempt")
+	}
+
+	return &token, nil
+}
+
+func (r *queryResolver) FailedLoginAttempts(ctx context.Context, filter *models.FailedLoginAttemptFilter, paginate *models.Pagination) ([]*models.FailedLoginAttempt, error) {
+	return actions.FailedLoginAttempts(r.DB(ctx), filter, paginate)
+}
+
+func (r *mutationResolver) UnlockLogin(ctx context.Context, username string) (bool, error) {
+	if err := actions.UnlockLogin(username); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) SetPasswordPolicy(ctx context.Context, policy models.PasswordPolicyInput) (*models.PasswordPolicy, error) {
+	return actions.SetPasswordPolicy(r.DB(ctx), models.PasswordPolicy{
+		MinLength:        policy.MinLength,
+		RequireMixedCase: policy.RequireMixedCase,
+		RequireDigit:     policy.RequireDigit,
+		RequireSymbol:    policy.RequireSymbol,
+	})
+}
@@ -0,0 +1,48 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/scanner/gps_tracks"
+	"github.com/pkg/errors"
+)
+
+func (r *mutationResolver) GeotagMediaFromTracks(ctx context.Context, tracks []*graphql.Upload, trackPaths []string, options *models.GeotagOptions) ([]*models.GeotagMatch, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	db := r.DB(ctx)
+
+	parsedTracks := make([]*gps_tracks.Track, 0, len(tracks)+len(trackPaths))
+	for _, upload := range tracks {
+		track, err := gps_tracks.ParseTrack(upload.File)
+		if err != nil {
+			return nil, errors.Wrapf(err, "track file (%s)", upload.Filename)
+		}
+		parsedTracks = append(parsedTracks, track)
+	}
+
+	for _, trackPath := range trackPaths {
+		track, err := actions.ReadUserTrackFile(db, user, trackPath)
+		if err != nil {
+			return nil, err
+		}
+		parsedTracks = append(parsedTracks, track)
+	}
+
+	if len(parsedTracks) == 0 {
+		return nil, errors.New("no tracks given")
+	}
+
+	if options == nil {
+		options = &models.GeotagOptions{}
+	}
+
+	return actions.GeotagMediaFromTrack(db, user, gps_tracks.MergeTracks(parsedTracks...), *options)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/graphql/models/actions"
 	"github.com/photoview/photoview/api/scanner/face_detection"
+	"github.com/photoview/photoview/api/scanner/storage_stats"
 	"github.com/pkg/errors"
 )
 
@@ -30,12 +31,12 @@ func (r *queryResolver) Media(ctx context.Context, id int, tokenCredentials *mod
 	db := r.DB(ctx)
 	if tokenCredentials != nil {
 
-		shareToken, err := r.ShareToken(ctx, *tokenCredentials)
+		shareToken, err := r.shareTokenFromCredentials(ctx, *tokenCredentials)
 		if err != nil {
 			return nil, err
 		}
 
-		if *shareToken.MediaID == id {
+		if shareToken.MediaID != nil && *shareToken.MediaID == id {
 			return shareToken.Media, nil
 		}
 	}
@@ -45,12 +46,17 @@ func (r *queryResolver) Media(ctx context.Context, id int, tokenCredentials *mod
 		return nil, auth.ErrUnauthorized
 	}
 
+	grantedAlbumIDs, err := user.GrantedAlbumIDs(db, models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
 	var media models.Media
 
-	err := db.
+	err = db.
 		Joins("Album").
 		Where("media.id = ?", id).
-		Where("EXISTS (SELECT * FROM user_albums WHERE user_albums.album_id = media.album_id AND user_albums.user_id = ?)", user.ID).
+		Where("(EXISTS (SELECT * FROM user_albums WHERE user_albums.album_id = media.album_id AND user_albums.user_id = ?) OR media.album_id IN (?))", user.ID, grantedAlbumIDs).
 		Where("media.id IN (?)", db.Model(&models.MediaURL{}).Select("media_id").Where("media_urls.media_id = media.id")).
 		First(&media).Error
 
@@ -72,11 +78,15 @@ func (r *queryResolver) MediaList(ctx context.Context, ids []int) ([]*models.Med
 		return nil, errors.New("no ids provided")
 	}
 
+	grantedAlbumIDs, err := user.GrantedAlbumIDs(db, models.AlbumRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
 	var media []*models.Media
-	err := db.Model(&media).
-		Joins("LEFT JOIN user_albums ON user_albums.album_id = media.album_id").
+	err = db.Model(&media).
 		Where("media.id IN ?", ids).
-		Where("user_albums.user_id = ?", user.ID).
+		Where("(media.album_id IN (SELECT user_albums.album_id FROM user_albums WHERE user_albums.user_id = ?) OR media.album_id IN (?))", user.ID, grantedAlbumIDs).
 		Find(&media).Error
 
 	if err != nil {
@@ -140,6 +150,14 @@ func (r *mediaResolver) Downloads(ctx context.Context, media *models.Media) ([]*
 			title = "Video thumbnail"
 		case url.Purpose == models.VideoWeb:
 			title = "Web optimized video"
+		case url.Purpose == models.VideoPreview:
+			title = "Video preview"
+		case url.Purpose == models.VideoSprite:
+			title = "Video scrub sprite"
+		case url.Purpose == models.VideoSpriteIndex:
+			title = "Video scrub index"
+		case url.Purpose == models.MotionVideo:
+			title = "Motion video"
 		}
 
 		downloads = append(downloads, &models.MediaDownload{
@@ -179,6 +197,59 @@ func (r *mediaResolver) VideoWeb(ctx context.Context, media *models.Media) (*mod
 	return dataloader.For(ctx).MediaVideoWeb.Load(media.ID)
 }
 
+func (r *mediaResolver) VideoPreview(ctx context.Context, media *models.Media) (*models.MediaURL, error) {
+	if media.Type != models.MediaTypeVideo {
+		return nil, nil
+	}
+
+	return dataloader.For(ctx).MediaVideoPreview.Load(media.ID)
+}
+
+func (r *mediaResolver) VideoSprite(ctx context.Context, media *models.Media) (*models.MediaURL, error) {
+	if media.Type != models.MediaTypeVideo {
+		return nil, nil
+	}
+
+	return dataloader.For(ctx).MediaVideoSprite.Load(media.ID)
+}
+
+func (r *mediaResolver) VideoSpriteIndex(ctx context.Context, media *models.Media) (*models.MediaURL, error) {
+	if media.Type != models.MediaTypeVideo {
+		return nil, nil
+	}
+
+	return dataloader.For(ctx).MediaVideoSpriteIndex.Load(media.ID)
+}
+
+func (r *mediaResolver) MotionVideo(ctx context.Context, media *models.Media) (*models.MediaURL, error) {
+	if media.Type != models.MediaTypePhoto {
+		return nil, nil
+	}
+
+	embeddedVideo, err := dataloader.For(ctx).MediaMotionVideo.Load(media.ID)
+	if err != nil || embeddedVideo != nil {
+		return embeddedVideo, err
+	}
+
+	var livePhotoVideos []*models.Media
+	if err := r.DB(ctx).Where("motion_photo_id = ?", media.ID).Limit(1).Find(&livePhotoVideos).Error; err != nil {
+		return nil, errors.Wrapf(err, "get live photo video of media (%s)", media.Path)
+	}
+
+	if len(livePhotoVideos) == 0 {
+		return nil, nil
+	}
+
+	videoID := livePhotoVideos[0].ID
+	webVideo, err := dataloader.For(ctx).MediaVideoWeb.Load(videoID)
+	if err != nil || webVideo != nil {
+		return webVideo, err
+	}
+
+	// videos that are already web compatible are not transcoded, so the original is played instead
+	return dataloader.For(ctx).MediaOriginal.Load(videoID)
+}
+
 func (r *mediaResolver) Exif(ctx context.Context, media *models.Media) (*models.MediaEXIF, error) {
 	if media.Exif != nil {
 		return media.Exif, nil
@@ -192,6 +263,32 @@ func (r *mediaResolver) Exif(ctx context.Context, media *models.Media) (*models.
 	return &exif, nil
 }
 
+func (r *mediaResolver) Projection(ctx context.Context, media *models.Media) (models.MediaProjection, error) {
+	if media.ExifID == nil {
+		return models.MediaProjectionFlat, nil
+	}
+
+	exif, err := r.Exif(ctx, media)
+	if err != nil {
+		return models.MediaProjectionFlat, err
+	}
+
+	return exif.Projection(), nil
+}
+
+func (r *mediaResolver) HasDepthMap(ctx context.Context, media *models.Media) (bool, error) {
+	if media.ExifID == nil {
+		return false, nil
+	}
+
+	exif, err := r.Exif(ctx, media)
+	if err != nil {
+		return false, err
+	}
+
+	return exif.HasDepthMap, nil
+}
+
 func (r *mediaResolver) Favorite(ctx context.Context, media *models.Media) (bool, error) {
 	user := auth.UserFromContext(ctx)
 	if user == nil {
@@ -211,10 +308,30 @@ func (r *mutationResolver) FavoriteMedia(ctx context.Context, mediaID int, favor
 		return nil, auth.ErrUnauthorized
 	}
 
-	return user.FavoriteMedia(r.DB(ctx), mediaID, favorite)
+	db := r.DB(ctx)
+
+	var media models.Media
+	if err := db.Joins("Album").First(&media, mediaID).Error; err != nil {
+		return nil, errors.Wrap(err, "get media from database")
+	}
+
+	canEdit, err := user.HasAlbumRole(db, &media.Album, models.AlbumRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canEdit {
+		return nil, errors.New("forbidden")
+	}
+
+	return user.FavoriteMedia(db, mediaID, favorite)
 }
 
 func (r *mutationResolver) DeleteMedia(ctx context.Context, mediaID int) (*models.Album, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
 
 	var media models.Media
 	var album models.Album
@@ -222,6 +339,19 @@ func (r *mutationResolver) DeleteMedia(ctx context.Context, mediaID int) (*model
 		return nil, errors.Wrap(err, "get media from database")
 	}
 
+	if err := r.DB(ctx).First(&album, media.AlbumID).Error; err != nil {
+		return nil, errors.Wrap(err, "get album of media")
+	}
+
+	canEdit, err := user.HasAlbumRole(r.DB(ctx), &album, models.AlbumRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canEdit {
+		return nil, errors.New("forbidden")
+	}
+
 	if err := r.DB(ctx).Delete(media).Error; err != nil {
 		return nil, errors.Wrap(err, "delete media from database")
 	}
@@ -232,8 +362,14 @@ func (r *mutationResolver) DeleteMedia(ctx context.Context, mediaID int) (*model
 
 	cachePath := path.Join(utils.MediaCachePath(), strconv.Itoa(int(media.AlbumID)), strconv.Itoa(int(media.ID)))
 	os.RemoveAll(cachePath)
+	storage_stats.InvalidateAlbum(media.AlbumID)
 
-	r.DB(ctx).First(&album, media.AlbumID)
+	if err := models.RecordAuditEvent(r.DB(ctx), user, models.AuditActionDeleteMedia, models.AuditTargetMedia(&media), map[string]interface{}{
+		"album_id": album.ID,
+		"moved_to": reyclePath,
+	}); err != nil {
+		return nil, err
+	}
 
 	return &album, nil
 }
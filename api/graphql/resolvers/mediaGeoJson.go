@@ -6,6 +6,8 @@ import (
 	"path"
 
 	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
 	"github.com/photoview/photoview/api/utils"
 )
 
@@ -69,26 +71,26 @@ func makeGeoJSONFeatureGeometryPoint(lat float64, long float64) geoJSONFeatureGe
 	}
 }
 
-func (r *queryResolver) MyMediaGeoJSON(ctx context.Context) (interface{}, error) {
+func (r *queryResolver) MyMediaGeoJSON(ctx context.Context, filter *models.MediaGeoFilter) (interface{}, error) {
 	user := auth.UserFromContext(ctx)
 	if user == nil {
 		return nil, auth.ErrUnauthorized
 	}
 
+	query, err := actions.FilterGeoMedia(r.DB(ctx), user, filter)
+	if err != nil {
+		return nil, err
+	}
+
 	var media []*geoMedia
 
-	err := r.DB(ctx).Table("media").
+	err = query.
 		Select(
-			"media.id AS media_id, media.title AS media_title, "+
-				"media_urls.media_name AS thumbnail_name, media_urls.width AS thumbnail_width, media_urls.height AS thumbnail_height, "+
+			"media.id AS media_id, media.title AS media_title, " +
+				"media_urls.media_name AS thumbnail_name, media_urls.width AS thumbnail_width, media_urls.height AS thumbnail_height, " +
 				"media_exif.gps_latitude AS latitude, media_exif.gps_longitude AS longitude").
-		Joins("INNER JOIN media_exif ON media.exif_id = media_exif.id").
 		Joins("INNER JOIN media_urls ON media.id = media_urls.media_id").
-		Joins("INNER JOIN user_albums ON media.album_id = user_albums.album_id").
-		Where("media_exif.gps_latitude IS NOT NULL").
-		Where("media_exif.gps_longitude IS NOT NULL").
 		Where("media_urls.purpose = 'thumbnail'").
-		Where("user_albums.user_id = ?", user.ID).
 		Scan(&media).Error
 
 	if err != nil {
@@ -124,6 +126,24 @@ func (r *queryResolver) MyMediaGeoJSON(ctx context.Context) (interface{}, error)
 	return featureCollection, nil
 }
 
+func (r *queryResolver) MediaGeoClusters(ctx context.Context, bbox models.BoundingBoxInput, zoom int, filter *models.MediaGeoFilter) ([]*models.MediaGeoCluster, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.MediaGeoClusters(r.DB(ctx), user, bbox, zoom, filter)
+}
+
+func (r *queryResolver) MediaInBoundingBox(ctx context.Context, bbox models.BoundingBoxInput, filter *models.MediaGeoFilter, paginate *models.Pagination) ([]*models.Media, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.MediaInBoundingBox(r.DB(ctx), user, bbox, filter, paginate)
+}
+
 func (r *queryResolver) MapboxToken(ctx context.Context) (*string, error) {
 	mapboxTokenEnv := os.Getenv("MAPBOX_TOKEN")
 	if mapboxTokenEnv == "" {
@@ -0,0 +1,76 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	api "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func (r *mutationResolver) ShiftMediaDates(ctx context.Context, mediaIDs []int, offset int, byCamera *bool, writeToFile *bool) ([]*models.Media, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.ShiftMediaDates(r.DB(ctx), user, mediaIDs, time.Duration(offset)*time.Second,
+		byCamera != nil && *byCamera, writeToFile != nil && *writeToFile)
+}
+
+func (r *mutationResolver) UpdateMediaMetadata(ctx context.Context, mediaIDs []int, metadata models.MediaMetadataInput, writeToFile *bool) ([]*models.Media, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.UpdateMediaMetadata(r.DB(ctx), user, mediaIDs, metadata, writeToFile != nil && *writeToFile)
+}
+
+func (r *mediaResolver) MetadataEdits(ctx context.Context, media *models.Media) ([]*models.MediaMetadataEdit, error) {
+	return actions.MediaMetadataEdits(r.DB(ctx), media.ID)
+}
+
+type mediaEXIFResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) MediaEXIF() api.MediaEXIFResolver {
+	return &mediaEXIFResolver{r}
+}
+
+func (r *mediaEXIFResolver) Keywords(ctx context.Context, exif *models.MediaEXIF) ([]string, error) {
+	return exif.KeywordList(), nil
+}
+
+type mediaMetadataEditResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) MediaMetadataEdit() api.MediaMetadataEditResolver {
+	return &mediaMetadataEditResolver{r}
+}
+
+func (r *mediaMetadataEditResolver) User(ctx context.Context, edit *models.MediaMetadataEdit) (*models.User, error) {
+	if edit.UserID == nil {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := r.DB(ctx).First(&user, *edit.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "get user of metadata edit")
+	}
+
+	return &user, nil
+}
+
+func (r *mediaMetadataEditResolver) Date(ctx context.Context, edit *models.MediaMetadataEdit) (*time.Time, error) {
+	return &edit.CreatedAt, nil
+}
@@ -0,0 +1,85 @@
+package resolvers
+
+import (
+	"context"
+
+	api "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/pkg/errors"
+)
+
+type mediaStackResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) MediaStack() api.MediaStackResolver {
+	return &mediaStackResolver{r}
+}
+
+func (r *mediaResolver) Stack(ctx context.Context, media *models.Media) (*models.MediaStack, error) {
+	if media.StackID == nil {
+		return nil, nil
+	}
+
+	if media.Stack != nil {
+		return media.Stack, nil
+	}
+
+	var stack models.MediaStack
+	if err := r.DB(ctx).First(&stack, *media.StackID).Error; err != nil {
+		return nil, errors.Wrapf(err, "get stack of media (%s)", media.Path)
+	}
+
+	return &stack, nil
+}
+
+func (r *mediaStackResolver) Top(ctx context.Context, stack *models.MediaStack) (*models.Media, error) {
+	if stack.TopMediaID == nil {
+		return nil, nil
+	}
+
+	var media models.Media
+	if err := r.DB(ctx).First(&media, *stack.TopMediaID).Error; err != nil {
+		return nil, errors.Wrap(err, "get top frame of media stack")
+	}
+
+	return &media, nil
+}
+
+func (r *mediaStackResolver) Media(ctx context.Context, stack *models.MediaStack) ([]*models.Media, error) {
+	var media []*models.Media
+	if err := r.DB(ctx).Where("stack_id = ?", stack.ID).Order("date_shot, title").Find(&media).Error; err != nil {
+		return nil, errors.Wrap(err, "get media of stack")
+	}
+
+	return media, nil
+}
+
+func (r *mutationResolver) SetMediaStackTop(ctx context.Context, mediaID int) (*models.MediaStack, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.SetMediaStackTop(r.DB(ctx), user, mediaID)
+}
+
+func (r *mutationResolver) SplitMediaStack(ctx context.Context, stackID int, mediaIDs []int) ([]*models.Media, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.SplitMediaStack(r.DB(ctx), user, stackID, mediaIDs)
+}
+
+func (r *mutationResolver) MergeMediaStacks(ctx context.Context, mediaIDs []int) (*models.MediaStack, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.MergeMediaStacks(r.DB(ctx), user, mediaIDs)
+}
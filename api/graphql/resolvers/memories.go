@@ -0,0 +1,33 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+)
+
+func (r *queryResolver) Memories(ctx context.Context, date *time.Time) ([]*models.MemoryYear, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	if date == nil {
+		now := time.Now()
+		date = &now
+	}
+
+	return actions.Memories(r.DB(ctx), user, *date)
+}
+
+func (r *queryResolver) YearSummary(ctx context.Context, year int) (*models.YearSummary, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.YearSummary(r.DB(ctx), user, year)
+}
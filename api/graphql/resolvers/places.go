@@ -0,0 +1,26 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+)
+
+func (r *queryResolver) MyPlaces(ctx context.Context) ([]*models.PlaceCountry, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.MyPlaces(r.DB(ctx), user)
+}
+
+func (r *mediaResolver) Place(ctx context.Context, media *models.Media) (*models.MediaPlace, error) {
+	if media.Place.CountryCode == nil {
+		return nil, nil
+	}
+
+	return &media.Place, nil
+}
@@ -2,16 +2,12 @@ package resolvers
 
 import (
 	"context"
-	"fmt"
-	"github.com/photoview/photoview/api/utils"
-	"io"
-	"io/ioutil"
-	"os"
-	"path"
 	"time"
 
 	"github.com/photoview/photoview/api/database/drivers"
+	"github.com/photoview/photoview/api/graphql/auth"
 	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
 	"github.com/photoview/photoview/api/scanner/periodic_scanner"
 	"github.com/photoview/photoview/api/scanner/scanner_queue"
 	"github.com/pkg/errors"
@@ -94,129 +90,40 @@ func (r *mutationResolver) SetScannerConcurrentWorkers(ctx context.Context, work
 }
 
 func (r *mutationResolver) MarkModify(ctx context.Context, modPath string) (int, error) {
-	db := r.DB(ctx)
-
-	for modPath != "/" {
-		var album models.Album
-		err := db.Where("path_hash = ?", models.MD5Hash(modPath)).First(&album).Error
-		if err == nil {
-			db.Model(&album).Update("last_modify_time", time.Now().UTC().Unix())
-		}
-		modPath = path.Dir(modPath)
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return 0, auth.ErrUnauthorized
 	}
 
-	return 0, nil
-}
-
-const finalDir = "/data/文档同步/双向同步/客户照片修图"
-
-func (r *mutationResolver) MakeFinalDir(ctx context.Context, albumID int) (int, error) {
-	album := r.findSonOfRoot(ctx, albumID)
-	newRootPath := path.Join(finalDir, album.Title)
-	if utils.EnvFinalDir.GetValue() != "" {
-		newRootPath = path.Join(utils.EnvFinalDir.GetValue(), album.Title)
-	}
-	dirContent, _ := ioutil.ReadDir(album.Path)
-	newOriginPath := path.Join(newRootPath, "原图")
-	_ = os.MkdirAll(newOriginPath, os.ModePerm)
-	for _, item := range dirContent {
-		if item.IsDir() {
-			_ = CopyDir(path.Join(album.Path, item.Name()), path.Join(newRootPath, item.Name()))
-		} else {
-			_ = CopyFile(path.Join(album.Path, item.Name()), path.Join(newOriginPath, item.Name()))
-		}
+	if err := actions.MarkModify(r.DB(ctx), user, modPath); err != nil {
+		return 0, err
 	}
 
 	return 0, nil
 }
 
-func (r *mutationResolver) findSonOfRoot(ctx context.Context, albumID int) *models.Album {
-	album := &models.Album{}
-	db := r.DB(ctx)
-	db.First(album, albumID)
-	parents, _ := album.GetParents(db, nil)
-	if len(parents) < 2 {
-		return album
+func (r *mutationResolver) MakeFinalDir(ctx context.Context, albumID int) (int, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return 0, auth.ErrUnauthorized
 	}
-	return parents[len(parents)-2]
-}
 
-func (r *mutationResolver) MarkRetouchFile(ctx context.Context, albumID int) (int, error) {
-	album := &models.Album{}
-	db := r.DB(ctx)
-	db.First(album, albumID)
-
-	var medias []models.Media
-	db.Where("album_id = ?", albumID).Find(&medias)
-	for _, media := range medias {
-		var favorite models.UserMediaData
-		db.Where("media_id = ?", media.ID).First(&favorite)
-		if favorite.Favorite {
-			os.Rename(media.Path, utils.AddSymbol(media.Path))
-		} else {
-			os.Rename(media.Path, utils.RemoveSymbol(media.Path))
-		}
+	if err := actions.MakeFinalDir(r.DB(ctx), user, albumID); err != nil {
+		return 0, err
 	}
 
 	return 0, nil
 }
 
-// File copies a single file from src to dst
-func CopyFile(src, dst string) error {
-	var err error
-	var srcfd *os.File
-	var dstfd *os.File
-	var srcinfo os.FileInfo
-
-	if srcfd, err = os.Open(src); err != nil {
-		return err
-	}
-	defer srcfd.Close()
-
-	if dstfd, err = os.Create(dst); err != nil {
-		return err
-	}
-	defer dstfd.Close()
-
-	if _, err = io.Copy(dstfd, srcfd); err != nil {
-		return err
-	}
-	if srcinfo, err = os.Stat(src); err != nil {
-		return err
-	}
-	return os.Chmod(dst, srcinfo.Mode())
-}
-
-// Dir copies a whole directory recursively
-func CopyDir(src string, dst string) error {
-	var err error
-	var fds []os.FileInfo
-	var srcinfo os.FileInfo
-
-	if srcinfo, err = os.Stat(src); err != nil {
-		return err
+func (r *mutationResolver) MarkRetouchFile(ctx context.Context, albumID int) (int, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return 0, auth.ErrUnauthorized
 	}
 
-	if err = os.MkdirAll(dst, srcinfo.Mode()); err != nil {
-		return err
+	if err := actions.MarkRetouchFile(r.DB(ctx), user, albumID); err != nil {
+		return 0, err
 	}
 
-	if fds, err = ioutil.ReadDir(src); err != nil {
-		return err
-	}
-	for _, fd := range fds {
-		srcfp := path.Join(src, fd.Name())
-		dstfp := path.Join(dst, fd.Name())
-
-		if fd.IsDir() {
-			if err = CopyDir(srcfp, dstfp); err != nil {
-				fmt.Println(err)
-			}
-		} else {
-			if err = CopyFile(srcfp, dstfp); err != nil {
-				fmt.Println(err)
-			}
-		}
-	}
-	return nil
+	return 0, nil
 }
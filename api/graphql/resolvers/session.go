@@ -0,0 +1,68 @@
+package resolvers
+
+import (
+	"context"
+
+	api "github.com/photoview/photoview/api/graphql"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+)
+
+type sessionResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) Session() api.SessionResolver {
+	return &sessionResolver{r}
+}
+
+func (r *sessionResolver) Current(ctx context.Context, obj *models.AccessToken) (bool, error) {
+	token := auth.TokenFromContext(ctx)
+	return token != nil && *token == obj.Value, nil
+}
+
+func (r *queryResolver) MySessions(ctx context.Context) ([]*models.AccessToken, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.MySessions(r.DB(ctx), user)
+}
+
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	token := auth.TokenFromContext(ctx)
+	if token == nil {
+		return false, auth.ErrUnauthorized
+	}
+
+	if err := actions.RevokeSessionByToken(r.DB(ctx), *token); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) RevokeSession(ctx context.Context, id int) (*models.AccessToken, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.RevokeSession(r.DB(ctx), user, id)
+}
+
+func (r *mutationResolver) RevokeAllSessions(ctx context.Context, includeCurrent *bool) (int, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return 0, auth.ErrUnauthorized
+	}
+
+	keepToken := auth.TokenFromContext(ctx)
+	if includeCurrent != nil && *includeCurrent {
+		keepToken = nil
+	}
+
+	return actions.RevokeAllSessions(r.DB(ctx), user.ID, keepToken)
+}
@@ -6,13 +6,11 @@ import (
 
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 
 	api "github.com/photoview/photoview/api/graphql"
 	"github.com/photoview/photoview/api/graphql/auth"
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/graphql/models/actions"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type shareTokenResolver struct {
@@ -40,57 +38,111 @@ func (r *shareTokenResolver) HasPassword(ctx context.Context, obj *models.ShareT
 	return hasPassword, nil
 }
 
-func (r *queryResolver) ShareToken(ctx context.Context, credentials models.ShareTokenCredentials) (*models.ShareToken, error) {
+func (r *shareTokenResolver) Stats(ctx context.Context, obj *models.ShareToken) (*models.ShareTokenStats, error) {
+	if !canManageShareToken(ctx, obj) {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.ShareTokenStats(r.DB(ctx), obj)
+}
+
+func (r *shareTokenResolver) AccessLog(ctx context.Context, obj *models.ShareToken, paginate *models.Pagination) ([]*models.ShareTokenAccess, error) {
+	if !canManageShareToken(ctx, obj) {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.ShareTokenAccessLog(r.DB(ctx), obj, paginate)
+}
+
+// canManageShareToken returns true if the logged in user is the owner of the token or an admin
+func canManageShareToken(ctx context.Context, token *models.ShareToken) bool {
+	user := auth.UserFromContext(ctx)
+	return user != nil && (user.ID == token.OwnerID || user.Admin)
+}
+
+type shareTokenAccessResolver struct {
+	*Resolver
+}
+
+func (r *Resolver) ShareTokenAccess() api.ShareTokenAccessResolver {
+	return &shareTokenAccessResolver{r}
+}
+
+func (r *shareTokenAccessResolver) Media(ctx context.Context, obj *models.ShareTokenAccess) (*models.Media, error) {
+	if obj.MediaID == nil {
+		return nil, nil
+	}
 
-	var token models.ShareToken
-	if err := r.DB(ctx).Preload(clause.Associations).Where("value = ?", credentials.Token).First(&token).Error; err != nil {
+	var media models.Media
+	if err := r.DB(ctx).First(&media, *obj.MediaID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("share not found")
-		} else {
-			return nil, errors.Wrap(err, "failed to get share token from database")
+			return nil, nil
 		}
+		return nil, errors.Wrap(err, "get media of share token access")
 	}
 
-	if token.Password != nil {
-		if err := bcrypt.CompareHashAndPassword([]byte(*token.Password), []byte(*credentials.Password)); err != nil {
-			if err == bcrypt.ErrMismatchedHashAndPassword {
-				return nil, errors.New("unauthorized")
-			} else {
-				return nil, errors.Wrap(err, "failed to compare token password hashes")
-			}
-		}
+	return &media, nil
+}
+
+// ShareToken is where clients enter the password of a share, so failed attempts are throttled
+func (r *queryResolver) ShareToken(ctx context.Context, credentials models.ShareTokenCredentials) (*models.ShareToken, error) {
+	db := r.DB(ctx)
+
+	token, err := actions.GetShareToken(db, credentials.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := actions.VerifyShareTokenPassword(db, token, credentials.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, errors.New("unauthorized")
+	}
+
+	if err := actions.OpenShareToken(db, token); err != nil {
+		return nil, err
 	}
 
-	return &token, nil
+	return token, nil
 }
 
-func (r *queryResolver) ShareTokenValidatePassword(ctx context.Context, credentials models.ShareTokenCredentials) (bool, error) {
-	var token models.ShareToken
-	if err := r.DB(ctx).Where("value = ?", credentials.Token).First(&token).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, errors.New("share not found")
-		} else {
-			return false, errors.Wrap(err, "failed to get share token from database")
-		}
+// shareTokenFromCredentials returns the share token if the credentials are valid and the client can access the share,
+// without counting it as the client opening the share. The password has already been entered through the ShareToken query,
+// so a wrong one is not counted as a failed attempt.
+func (r *Resolver) shareTokenFromCredentials(ctx context.Context, credentials models.ShareTokenCredentials) (*models.ShareToken, error) {
+	db := r.DB(ctx)
+
+	token, err := actions.GetShareToken(db, credentials.Token)
+	if err != nil {
+		return nil, err
 	}
 
-	if token.Password == nil {
-		return true, nil
+	valid, err := token.CheckPassword(credentials.Password)
+	if err != nil {
+		return nil, err
 	}
 
-	if credentials.Password == nil {
-		return false, nil
+	if !valid {
+		return nil, errors.New("unauthorized")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(*token.Password), []byte(*credentials.Password)); err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			return false, nil
-		} else {
-			return false, errors.Wrap(err, "could not compare token password hashes")
-		}
+	if err := actions.CheckShareTokenAccess(db, token); err != nil {
+		return nil, err
 	}
 
-	return true, nil
+	return token, nil
+}
+
+func (r *queryResolver) ShareTokenValidatePassword(ctx context.Context, credentials models.ShareTokenCredentials) (bool, error) {
+	token, err := actions.GetShareToken(r.DB(ctx), credentials.Token)
+	if err != nil {
+		return false, err
+	}
+
+	return actions.VerifyShareTokenPassword(r.DB(ctx), token, credentials.Password)
 }
 
 func (r *mutationResolver) ShareAlbum(ctx context.Context, albumID int, expire *time.Time, password *string) (*models.ShareToken, error) {
@@ -99,7 +151,12 @@ func (r *mutationResolver) ShareAlbum(ctx context.Context, albumID int, expire *
 		return nil, auth.ErrUnauthorized
 	}
 
-	return actions.AddAlbumShare(r.DB(ctx), user, albumID, expire, password)
+	token, err := actions.AddAlbumShare(r.DB(ctx), user, albumID, expire, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, recordShareTokenEvent(r.DB(ctx), user, models.AuditActionCreateShareToken, token)
 }
 
 func (r *mutationResolver) ShareMedia(ctx context.Context, mediaID int, expire *time.Time, password *string) (*models.ShareToken, error) {
@@ -108,7 +165,12 @@ func (r *mutationResolver) ShareMedia(ctx context.Context, mediaID int, expire *
 		return nil, auth.ErrUnauthorized
 	}
 
-	return actions.AddMediaShare(r.DB(ctx), user, mediaID, expire, password)
+	token, err := actions.AddMediaShare(r.DB(ctx), user, mediaID, expire, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, recordShareTokenEvent(r.DB(ctx), user, models.AuditActionCreateShareToken, token)
 }
 
 func (r *mutationResolver) DeleteShareToken(ctx context.Context, tokenValue string) (*models.ShareToken, error) {
@@ -117,7 +179,12 @@ func (r *mutationResolver) DeleteShareToken(ctx context.Context, tokenValue stri
 		return nil, auth.ErrUnauthorized
 	}
 
-	return actions.DeleteShareToken(r.DB(ctx), user.ID, tokenValue)
+	token, err := actions.DeleteShareToken(r.DB(ctx), user.ID, tokenValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, recordShareTokenEvent(r.DB(ctx), user, models.AuditActionDeleteShareToken, token)
 }
 
 func (r *mutationResolver) ProtectShareToken(ctx context.Context, tokenValue string, password *string) (*models.ShareToken, error) {
@@ -126,5 +193,39 @@ func (r *mutationResolver) ProtectShareToken(ctx context.Context, tokenValue str
 		return nil, auth.ErrUnauthorized
 	}
 
-	return actions.ProtectShareToken(r.DB(ctx), user.ID, tokenValue, password)
+	token, err := actions.ProtectShareToken(r.DB(ctx), user.ID, tokenValue, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, recordShareTokenEvent(r.DB(ctx), user, models.AuditActionProtectShareToken, token)
+}
+
+func (r *mutationResolver) UpdateShareToken(ctx context.Context, tokenValue string, settings models.ShareTokenSettings) (*models.ShareToken, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	token, err := actions.UpdateShareToken(r.DB(ctx), user.ID, tokenValue, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, recordShareTokenEvent(r.DB(ctx), user, models.AuditActionUpdateShareToken, token)
+}
+
+// recordShareTokenEvent writes an audit event for an operation on the share token, the token value itself is not recorded
+func recordShareTokenEvent(db *gorm.DB, user *models.User, action string, token *models.ShareToken) error {
+	return models.RecordAuditEvent(db, user, action, models.AuditTargetShareToken(token), map[string]interface{}{
+		"owner_id":           token.OwnerID,
+		"album_id":           token.AlbumID,
+		"media_id":           token.MediaID,
+		"expire":             token.Expire,
+		"password_protected": token.Password != nil,
+		"slug":               token.Slug,
+		"allow_download":     token.AllowDownload(),
+		"max_views":          token.MaxViews,
+		"watermarked":        token.WatermarkText != nil,
+	})
 }
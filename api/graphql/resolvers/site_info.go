@@ -2,10 +2,15 @@ package resolvers
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
 
 	api "github.com/photoview/photoview/api/graphql"
 	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/oidc"
 	"github.com/photoview/photoview/api/scanner/face_detection"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 func (r *queryResolver) SiteInfo(ctx context.Context) (*models.SiteInfo, error) {
@@ -23,3 +28,37 @@ func (r *Resolver) SiteInfo() api.SiteInfoResolver {
 func (SiteInfoResolver) FaceDetectionEnabled(ctx context.Context, obj *models.SiteInfo) (bool, error) {
 	return face_detection.GlobalFaceDetector != nil, nil
 }
+
+func (SiteInfoResolver) OidcEnabled(ctx context.Context, obj *models.SiteInfo) (bool, error) {
+	config, err := oidc.ConfigFromEnv()
+	return err == nil && config != nil, nil
+}
+
+func (SiteInfoResolver) RootPathAllowlist(ctx context.Context, obj *models.SiteInfo) ([]string, error) {
+	return obj.AllowedRootPaths(), nil
+}
+
+func (r *mutationResolver) SetRootPathAllowlist(ctx context.Context, paths []string) ([]string, error) {
+	db := r.DB(ctx)
+
+	allowlist := make([]string, 0, len(paths))
+	for _, dir := range paths {
+		dir = strings.TrimSpace(dir)
+		if !filepath.IsAbs(dir) {
+			return nil, errors.Errorf("allowed directory must be an absolute path: %s", dir)
+		}
+
+		allowlist = append(allowlist, filepath.Clean(dir))
+	}
+
+	if err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.SiteInfo{}).Update("root_path_allowlist", strings.Join(allowlist, "\n")).Error; err != nil {
+		return nil, errors.Wrap(err, "update root path allowlist")
+	}
+
+	siteInfo, err := models.GetSiteInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return siteInfo.AllowedRootPaths(), nil
+}
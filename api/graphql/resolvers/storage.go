@@ -0,0 +1,22 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+)
+
+func (r *queryResolver) AlbumStats(ctx context.Context, id int) (*models.AlbumStats, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.AlbumStats(r.DB(ctx), user, id)
+}
+
+func (r *queryResolver) StorageReport(ctx context.Context) (*models.StorageReport, error) {
+	return actions.StorageReport(r.DB(ctx))
+}
@@ -9,11 +9,29 @@ import (
 	"github.com/photoview/photoview/api/graphql/models/actions"
 )
 
-func (r *queryResolver) MyTimeline(ctx context.Context, paginate *models.Pagination, onlyFavorites *bool, fromDate *time.Time) ([]*models.Media, error) {
+func (r *queryResolver) MyTimeline(ctx context.Context, paginate *models.Pagination, onlyFavorites *bool, fromDate *time.Time, expandStacks *bool) ([]*models.Media, error) {
 	user := auth.UserFromContext(ctx)
 	if user == nil {
 		return nil, auth.ErrUnauthorized
 	}
 
-	return actions.MyTimeline(r.DB(ctx), user, paginate, onlyFavorites, fromDate)
+	return actions.MyTimeline(r.DB(ctx), user, paginate, onlyFavorites, fromDate, expandStacks)
+}
+
+func (r *queryResolver) TimelineBuckets(ctx context.Context, granularity models.TimelineGranularity, onlyFavorites *bool, albumID *int) ([]*models.TimelineBucket, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.TimelineBuckets(r.DB(ctx), user, granularity, onlyFavorites, albumID)
+}
+
+func (r *queryResolver) Timeline(ctx context.Context, bucket models.TimelineBucketInput, onlyFavorites *bool, albumID *int, paginate *models.Pagination) ([]*models.Media, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.TimelineBucketMedia(r.DB(ctx), user, bucket, onlyFavorites, albumID, paginate)
 }
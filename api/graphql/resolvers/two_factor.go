@@ -0,0 +1,74 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+)
+
+func (r *mutationResolver) AuthorizeTwoFactor(ctx context.Context, challenge string, code string) (*models.AuthorizeResult, error) {
+	token, err := actions.AuthorizeTwoFactor(r.DB(ctx), challenge, code, auth.ClientFromContext(ctx))
+	if err != nil {
+		return &models.AuthorizeResult{
+			Success: false,
+			Status:  err.Error(),
+		}, nil
+	}
+
+	return &models.AuthorizeResult{
+		Success: true,
+		Status:  "ok",
+		Token:   &token.Value,
+	}, nil
+}
+
+func (r *mutationResolver) EnrollTwoFactor(ctx context.Context) (*models.TwoFactorEnrollment, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.EnrollTwoFactor(r.DB(ctx), user)
+}
+
+func (r *mutationResolver) EnableTwoFactor(ctx context.Context, code string) ([]string, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.EnableTwoFactor(r.DB(ctx), user, code)
+}
+
+func (r *mutationResolver) DisableTwoFactor(ctx context.Context, code string) (*models.User, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.DisableTwoFactor(r.DB(ctx), user, code)
+}
+
+func (r *mutationResolver) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.RegenerateRecoveryCodes(r.DB(ctx), user, code)
+}
+
+func (r *mutationResolver) ResetUserTwoFactor(ctx context.Context, userID int) (*models.User, error) {
+	return actions.ResetUserTwoFactor(r.DB(ctx), auth.UserFromContext(ctx), userID)
+}
+
+func (r *mutationResolver) SetRequireAdminTwoFactor(ctx context.Context, required bool) (bool, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return false, auth.ErrUnauthorized
+	}
+
+	return actions.SetRequireAdminTwoFactor(r.DB(ctx), user, required)
+}
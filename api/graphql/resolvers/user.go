@@ -26,6 +26,15 @@ func (r *Resolver) User() api.UserResolver {
 	return &userResolver{r}
 }
 
+func (r *userResolver) Quota(ctx context.Context, user *models.User) (*models.UserQuota, error) {
+	currentUser := auth.UserFromContext(ctx)
+	if currentUser == nil || (!currentUser.Admin && currentUser.ID != user.ID) {
+		return nil, auth.ErrUnauthorized
+	}
+
+	return actions.UserQuota(r.DB(ctx), user)
+}
+
 func (r *queryResolver) User(ctx context.Context, order *models.Ordering, paginate *models.Pagination) ([]*models.User, error) {
 
 	var users []*models.User
@@ -74,7 +83,7 @@ func (r *queryResolver) MyUser(ctx context.Context) (*models.User, error) {
 
 func (r *mutationResolver) AuthorizeUser(ctx context.Context, username string, password string) (*models.AuthorizeResult, error) {
 	db := r.DB(ctx)
-	user, err := models.AuthorizeUser(db, username, password)
+	user, err := actions.AuthorizeUser(db, username, password)
 	if err != nil {
 		return &models.AuthorizeResult{
 			Success: false,
@@ -82,10 +91,23 @@ func (r *mutationResolver) AuthorizeUser(ctx context.Context, username string, p
 		}, nil
 	}
 
+	if user.TOTPEnabled {
+		challenge, err := user.CreateTwoFactorChallenge(db)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.AuthorizeResult{
+			Success:            false,
+			Status:             "two-factor code required",
+			TwoFactorChallenge: &challenge.Value,
+		}, nil
+	}
+
 	var token *models.AccessToken
 
 	transactionError := db.Transaction(func(tx *gorm.DB) error {
-		token, err = user.GenerateAccessToken(tx)
+		token, err = user.GenerateSessionToken(tx, auth.ClientFromContext(ctx))
 		if err != nil {
 			return err
 		}
@@ -115,6 +137,10 @@ func (r *mutationResolver) InitialSetupWizard(ctx context.Context, username stri
 		return nil, errors.New("not initial setup")
 	}
 
+	if err := models.ValidatePassword(db, password); err != nil {
+		return nil, err
+	}
+
 	rootPath = path.Clean(rootPath)
 
 	var token *models.AccessToken
@@ -134,7 +160,7 @@ func (r *mutationResolver) InitialSetupWizard(ctx context.Context, username stri
 			return err
 		}
 
-		token, err = user.GenerateAccessToken(tx)
+		token, err = user.GenerateSessionToken(tx, auth.ClientFromContext(ctx))
 		if err != nil {
 			return err
 		}
@@ -201,10 +227,10 @@ func (r *mutationResolver) ChangeUserPreferences(ctx context.Context, language *
 }
 
 // Admin queries
-func (r *mutationResolver) UpdateUser(ctx context.Context, id int, username *string, password *string, admin *bool) (*models.User, error) {
+func (r *mutationResolver) UpdateUser(ctx context.Context, id int, username *string, password *string, admin *bool, retoucher *bool) (*models.User, error) {
 	db := r.DB(ctx)
 
-	if username == nil && password == nil && admin == nil {
+	if username == nil && password == nil && admin == nil && retoucher == nil {
 		return nil, errors.New("no updates requested")
 	}
 
@@ -218,6 +244,10 @@ func (r *mutationResolver) UpdateUser(ctx context.Context, id int, username *str
 	}
 
 	if password != nil {
+		if err := models.ValidatePassword(db, *password); err != nil {
+			return nil, err
+		}
+
 		hashedPassBytes, err := bcrypt.GenerateFromPassword([]byte(*password), 12)
 		if err != nil {
 			return nil, err
@@ -231,8 +261,31 @@ func (r *mutationResolver) UpdateUser(ctx context.Context, id int, username *str
 		user.Admin = *admin
 	}
 
-	if err := db.Save(&user).Error; err != nil {
-		return nil, errors.Wrap(err, "failed to update user")
+	if retoucher != nil {
+		user.Retoucher = *retoucher
+	}
+
+	transactionError := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return errors.Wrap(err, "failed to update user")
+		}
+
+		if password != nil {
+			if _, err := actions.RevokeAllSessions(tx, user.ID, nil); err != nil {
+				return err
+			}
+		}
+
+		return models.RecordAuditEvent(tx, auth.UserFromContext(ctx), models.AuditActionUpdateUser, models.AuditTargetUser(&user), map[string]interface{}{
+			"username":         username,
+			"password_changed": password != nil,
+			"admin":            admin,
+			"retoucher":        retoucher,
+		})
+	})
+
+	if transactionError != nil {
+		return nil, transactionError
 	}
 
 	return &user, nil
@@ -240,6 +293,12 @@ func (r *mutationResolver) UpdateUser(ctx context.Context, id int, username *str
 
 func (r *mutationResolver) CreateUser(ctx context.Context, username string, password *string, admin bool) (*models.User, error) {
 
+	if password != nil {
+		if err := models.ValidatePassword(r.DB(ctx), *password); err != nil {
+			return nil, err
+		}
+	}
+
 	var user *models.User
 
 	transactionError := r.DB(ctx).Transaction(func(tx *gorm.DB) error {
@@ -249,7 +308,10 @@ func (r *mutationResolver) CreateUser(ctx context.Context, username string, pass
 			return err
 		}
 
-		return nil
+		return models.RecordAuditEvent(tx, auth.UserFromContext(ctx), models.AuditActionCreateUser, models.AuditTargetUser(user), map[string]interface{}{
+			"username": username,
+			"admin":    admin,
+		})
 	})
 
 	if transactionError != nil {
@@ -260,7 +322,27 @@ func (r *mutationResolver) CreateUser(ctx context.Context, username string, pass
 }
 
 func (r *mutationResolver) DeleteUser(ctx context.Context, id int) (*models.User, error) {
-	return actions.DeleteUser(r.DB(ctx), id)
+	db := r.DB(ctx)
+
+	user, err := actions.DeleteUser(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// An admin deleting their own user can no longer be referenced as the actor
+	actor := auth.UserFromContext(ctx)
+	if actor != nil && actor.ID == user.ID {
+		actor = nil
+	}
+
+	err = models.RecordAuditEvent(db, actor, models.AuditActionDeleteUser, models.AuditTargetUser(user), map[string]interface{}{
+		"username": user.Username,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 func (r *mutationResolver) UserAddRootPath(ctx context.Context, id int, rootPath string) (*models.Album, error) {
@@ -278,9 +360,35 @@ func (r *mutationResolver) UserAddRootPath(ctx context.Context, id int, rootPath
 		return nil, err
 	}
 
+	err = models.RecordAuditEvent(db, auth.UserFromContext(ctx), models.AuditActionAddRootPath, models.AuditTargetAlbum(newAlbum), map[string]interface{}{
+		"user_id": user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return newAlbum, nil
 }
 
+func (r *mutationResolver) SetUserQuota(ctx context.Context, userID int, maxMediaCount *int, maxStorageSize *int) (*models.User, error) {
+	db := r.DB(ctx)
+
+	user, err := actions.SetUserQuota(db, userID, maxMediaCount, maxStorageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	err = models.RecordAuditEvent(db, auth.UserFromContext(ctx), models.AuditActionSetUserQuota, models.AuditTargetUser(user), map[string]interface{}{
+		"max_media_count":  maxMediaCount,
+		"max_storage_size": maxStorageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func (r *mutationResolver) UserRemoveRootAlbum(ctx context.Context, userID int, albumID int) (*models.Album, error) {
 	db := r.DB(ctx)
 
@@ -332,7 +440,10 @@ func (r *mutationResolver) UserRemoveRootAlbum(ctx context.Context, userID int,
 			}
 		}
 
-		return nil
+		return models.RecordAuditEvent(tx, auth.UserFromContext(ctx), models.AuditActionRemoveRootAlbum, models.AuditTargetAlbum(&album), map[string]interface{}{
+			"user_id":        userID,
+			"deleted_albums": len(deletedAlbumIDs),
+		})
 	})
 
 	if transactionError != nil {
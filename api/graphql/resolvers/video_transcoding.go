@@ -0,0 +1,153 @@
+package resolvers
+
+import (
+	"context"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/media_encoding/executable_worker"
+	"github.com/photoview/photoview/api/scanner/scanner_queue"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var bitrateRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKmM]?$`)
+
+func (SiteInfoResolver) AvailableVideoEncoders(ctx context.Context, obj *models.SiteInfo) ([]string, error) {
+	if !executable_worker.FfmpegCli.IsInstalled() {
+		return []string{}, nil
+	}
+
+	return executable_worker.FfmpegCli.VideoEncoders()
+}
+
+func (r *mutationResolver) SetVideoTranscodeProfile(ctx context.Context, input models.VideoTranscodeProfileInput, reencodeExisting *bool) (*models.VideoTranscodeProfile, error) {
+	db := r.DB(ctx)
+
+	profile := models.VideoTranscodeProfile{
+		Codec:         input.Codec,
+		Crf:           input.Crf,
+		MaxResolution: input.MaxResolution,
+		AudioBitrate:  input.AudioBitrate,
+		VideoBitrate:  input.VideoBitrate,
+		TwoPass:       input.TwoPass,
+	}
+
+	if err := validateVideoTranscodeProfile(profile); err != nil {
+		return nil, err
+	}
+
+	if err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.SiteInfo{}).Updates(map[string]interface{}{
+		"transcode_codec":          profile.Codec,
+		"transcode_crf":            profile.Crf,
+		"transcode_max_resolution": profile.MaxResolution,
+		"transcode_audio_bitrate":  profile.AudioBitrate,
+		"transcode_video_bitrate":  profile.VideoBitrate,
+		"transcode_two_pass":       profile.TwoPass,
+	}).Error; err != nil {
+		return nil, errors.Wrap(err, "update video transcode profile")
+	}
+
+	var siteInfo models.SiteInfo
+	if err := db.First(&siteInfo).Error; err != nil {
+		return nil, err
+	}
+
+	if reencodeExisting != nil && *reencodeExisting {
+		if err := requeueWebVideos(db); err != nil {
+			return nil, errors.Wrap(err, "re-queue web videos for encoding")
+		}
+	}
+
+	return &siteInfo.VideoTranscodeProfile, nil
+}
+
+func validateVideoTranscodeProfile(profile models.VideoTranscodeProfile) error {
+	if profile.Crf < 0 || profile.Crf > 63 {
+		return errors.New("crf must be between 0 and 63")
+	}
+
+	if profile.MaxResolution < 16 {
+		return errors.New("max resolution must be at least 16")
+	}
+
+	if !bitrateRegex.MatchString(profile.AudioBitrate) {
+		return errors.Errorf("invalid audio bitrate: %s", profile.AudioBitrate)
+	}
+
+	if profile.VideoBitrate != nil && !bitrateRegex.MatchString(*profile.VideoBitrate) {
+		return errors.Errorf("invalid video bitrate: %s", *profile.VideoBitrate)
+	}
+
+	if profile.TwoPass && profile.VideoBitrate == nil {
+		return errors.New("two-pass encoding requires a video bitrate")
+	}
+
+	if !executable_worker.FfmpegCli.IsInstalled() {
+		return errors.New("video encoding is not available, ffmpeg is not installed or disabled")
+	}
+
+	encoders, err := executable_worker.FfmpegCli.VideoEncoders()
+	if err != nil {
+		return err
+	}
+
+	for _, encoder := range encoders {
+		if encoder == profile.Codec {
+			return nil
+		}
+	}
+
+	return errors.Errorf("video encoder not supported by ffmpeg: %s", profile.Codec)
+}
+
+// requeueWebVideos deletes all encoded web videos and adds their albums to the scanner queue,
+// such that they will be encoded again using the current transcode profile
+func requeueWebVideos(db *gorm.DB) error {
+	var webVideoURLs []*models.MediaURL
+	if err := db.Where("purpose = ?", models.VideoWeb).Preload("Media").Find(&webVideoURLs).Error; err != nil {
+		return errors.Wrap(err, "get web videos from database")
+	}
+
+	albumIDs := make([]int, 0)
+	albumSeen := make(map[int]bool)
+
+	for _, mediaURL := range webVideoURLs {
+		cachedPath, err := mediaURL.CachedPath()
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(cachedPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("WARN: could not remove web video from cache (%s): %s\n", cachedPath, err)
+		}
+
+		if !albumSeen[mediaURL.Media.AlbumID] {
+			albumSeen[mediaURL.Media.AlbumID] = true
+			albumIDs = append(albumIDs, mediaURL.Media.AlbumID)
+		}
+	}
+
+	if err := db.Where("purpose = ?", models.VideoWeb).Delete(&models.MediaURL{}).Error; err != nil {
+		return errors.Wrap(err, "delete web videos from database")
+	}
+
+	if len(albumIDs) == 0 {
+		return nil
+	}
+
+	var albums []*models.Album
+	if err := db.Where("id IN (?)", albumIDs).Find(&albums).Error; err != nil {
+		return errors.Wrap(err, "get albums of web videos")
+	}
+
+	for _, album := range albums {
+		if err := scanner_queue.AddAlbumToQueue(album); err != nil {
+			return errors.Wrapf(err, "add album to scanner queue (%d)", album.ID)
+		}
+	}
+
+	return nil
+}
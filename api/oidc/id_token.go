@@ -0,0 +1,261 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Allowed difference between the clocks of the server and the provider, when validating tokens
+const clockSkew = time.Minute
+
+// Claims are the claims of a verified id token
+type Claims map[string]interface{}
+
+// String returns the claim if it is a string, otherwise an empty string
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Strings returns the claim as a list, if it is a string or a list of strings
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+
+	return []string{}
+}
+
+// Matches returns true if the claim is, or contains, the value.
+// If value is empty the claim must be the boolean true.
+func (c Claims) Matches(name string, value string) bool {
+	if value == "" {
+		matches, _ := c[name].(bool)
+		return matches
+	}
+
+	for _, item := range c.Strings(name) {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
+
+// publicKey converts the key to an *rsa.PublicKey or *ecdsa.PublicKey, nil is returned for unsupported keys
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch {
+	case k.Kty == "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+
+	return nil, nil
+}
+
+// signingKey returns the key with the id, the keys of the provider are fetched again if the id is unknown
+func (p *Provider) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if key, found := p.keys[kid]; found {
+		return key, nil
+	}
+
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, metadata.JWKSURI, &keySet); err != nil {
+		return nil, errors.Wrap(err, "fetch signing keys")
+	}
+
+	p.keys = make(map[string]interface{}, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode signing key (%s)", jwk.Kid)
+		}
+
+		if key != nil {
+			p.keys[jwk.Kid] = key
+		}
+	}
+
+	key, found := p.keys[kid]
+	if !found {
+		return nil, errors.Errorf("unknown signing key: %s", kid)
+	}
+
+	return key, nil
+}
+
+// verifySignature checks the signature of the token with the key, for the RS256 and ES256 algorithms
+func verifySignature(alg string, key interface{}, signed []byte, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("signing key is not an RSA key")
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature)
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return errors.New("signing key is not a P-256 key")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	return errors.Errorf("unsupported signing algorithm: %s", alg)
+}
+
+// VerifyIDToken checks the signature, issuer, audience, authorized party, expiry and nonce of the id token and returns its claims
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken string, nonce string) (Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "decode id token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "decode id token signature")
+	}
+
+	key, err := p.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, errors.Wrap(err, "verify id token signature")
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "decode id token claims")
+	}
+
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.String("iss") != metadata.Issuer {
+		return nil, errors.New("id token has the wrong issuer")
+	}
+
+	if !claims.Matches("aud", p.Config.ClientID) {
+		return nil, errors.New("id token has the wrong audience")
+	}
+
+	// a token for several audiences must name the party it was issued to
+	if len(claims.Strings("aud")) > 1 || claims.String("azp") != "" {
+		if claims.String("azp") != p.Config.ClientID {
+			return nil, errors.New("id token was issued to another party")
+		}
+	}
+
+	expire, ok := claims["exp"].(float64)
+	if !ok || time.Unix(int64(expire), 0).Add(clockSkew).Before(time.Now()) {
+		return nil, errors.New("id token has expired")
+	}
+
+	if claims.String("nonce") != nonce {
+		return nil, errors.New("id token has the wrong nonce")
+	}
+
+	if claims.String("sub") == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(segment string, target interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, target)
+}
@@ -0,0 +1,234 @@
+// Package oidc implements logging in with an OpenID Connect provider,
+// using the authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// Config describes the provider and how the claims of its users are mapped to Photoview users
+type Config struct {
+	DiscoveryURL string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// UsernameClaim is the claim used as the username of new users
+	UsernameClaim string
+	// Users are admins if the AdminClaim is, or contains, the AdminValue. Admin is not changed if AdminClaim is empty
+	AdminClaim string
+	AdminValue string
+	// RootPathsClaim is a claim with a path, or list of paths, that are added as root paths of new users
+	RootPathsClaim      string
+	DisableProvisioning bool
+}
+
+// ConfigFromEnv reads the configuration from the environment variables, nil is returned if OIDC is not configured
+func ConfigFromEnv() (*Config, error) {
+	discoveryURL := utils.EnvOIDCDiscoveryURL.GetValue()
+	clientID := utils.EnvOIDCClientID.GetValue()
+	if discoveryURL == "" || clientID == "" {
+		return nil, nil
+	}
+
+	redirectURL := utils.EnvOIDCRedirectURL.GetValue()
+	if redirectURL == "" {
+		apiEndpoint := *utils.ApiEndpointUrl()
+		apiEndpoint.Path = path.Join(apiEndpoint.Path, "oidc/callback")
+		redirectURL = apiEndpoint.String()
+	}
+
+	if parsedURL, err := url.Parse(redirectURL); err != nil || !parsedURL.IsAbs() {
+		return nil, errors.Errorf("redirect url must be an absolute url, set %s", utils.EnvOIDCRedirectURL.GetName())
+	}
+
+	scopes := strings.Fields(utils.EnvOIDCScopes.GetValue())
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	usernameClaim := utils.EnvOIDCUsernameClaim.GetValue()
+	if usernameClaim == "" {
+		usernameClaim = "preferred_username"
+	}
+
+	return &Config{
+		DiscoveryURL:        discoveryURL,
+		ClientID:            clientID,
+		ClientSecret:        utils.EnvOIDCClientSecret.GetValue(),
+		RedirectURL:         redirectURL,
+		Scopes:              scopes,
+		UsernameClaim:       usernameClaim,
+		AdminClaim:          utils.EnvOIDCAdminClaim.GetValue(),
+		AdminValue:          utils.EnvOIDCAdminValue.GetValue(),
+		RootPathsClaim:      utils.EnvOIDCRootPathsClaim.GetValue(),
+		DisableProvisioning: utils.EnvOIDCDisableProvisioning.GetBool(),
+	}, nil
+}
+
+// providerMetadata is the part of the discovery document used by Photoview
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse is the response of the token endpoint of the provider
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// Provider is an OpenID Connect provider, its discovery document and keys are fetched when first needed
+type Provider struct {
+	Config Config
+	client *http.Client
+
+	lock     sync.Mutex
+	metadata *providerMetadata
+	keys     map[string]interface{}
+}
+
+func NewProvider(config Config) *Provider {
+	return &Provider{
+		Config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// getJSON fetches the url and decodes the JSON response into target
+func (p *Provider) getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status: %s", res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(target)
+}
+
+// discover returns the discovery document of the provider, it is only fetched once it has been fetched successfully
+func (p *Provider) discover(ctx context.Context) (*providerMetadata, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	discoveryURL := p.Config.DiscoveryURL
+	if !strings.HasSuffix(discoveryURL, discoveryPath) {
+		discoveryURL = strings.TrimSuffix(discoveryURL, "/") + discoveryPath
+	}
+
+	var metadata providerMetadata
+	if err := p.getJSON(ctx, discoveryURL, &metadata); err != nil {
+		return nil, errors.Wrap(err, "fetch openid configuration")
+	}
+
+	if metadata.Issuer == "" || metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("openid configuration is missing required endpoints")
+	}
+
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// AuthCodeURL returns the url of the provider that the user should be redirected to, to log in
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "parse authorization endpoint")
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.Config.ClientID)
+	query.Set("redirect_uri", p.Config.RedirectURL)
+	query.Set("scope", strings.Join(p.Config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange trades the authorization code returned by the provider for tokens
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string) (*TokenResponse, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.Config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.Config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.Config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "request token")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, errors.Errorf("token endpoint responded with %s: %s", res.Status, body)
+	}
+
+	var token TokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, errors.Wrap(err, "decode token response")
+	}
+
+	if token.IDToken == "" {
+		return nil, errors.New("token response does not contain an id token")
+	}
+
+	return &token, nil
+}
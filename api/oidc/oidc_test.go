@@ -0,0 +1,159 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/oidc"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(test_utils.UnitTestRun(m))
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Example from RFC 7636 appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", oidc.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	first, err := oidc.RandomString()
+	assert.NoError(t, err)
+	second, err := oidc.RandomString()
+	assert.NoError(t, err)
+	assert.Len(t, first, 43)
+	assert.NotEqual(t, first, second)
+}
+
+func TestClaims(t *testing.T) {
+	claims := oidc.Claims{
+		"groups":   []interface{}{"users", "photoview-admins"},
+		"role":     "admin",
+		"verified": true,
+	}
+
+	assert.Equal(t, "admin", claims.String("role"))
+	assert.Equal(t, "", claims.String("groups"))
+	assert.Equal(t, []string{"users", "photoview-admins"}, claims.Strings("groups"))
+	assert.Equal(t, []string{"admin"}, claims.Strings("role"))
+	assert.Empty(t, claims.Strings("missing"))
+
+	assert.True(t, claims.Matches("groups", "photoview-admins"))
+	assert.True(t, claims.Matches("role", "admin"))
+	assert.False(t, claims.Matches("groups", "admin"))
+	assert.True(t, claims.Matches("verified", ""))
+	assert.False(t, claims.Matches("role", ""))
+}
+
+func TestProvider(t *testing.T) {
+	mockProvider := test_utils.NewMockOIDCProvider(t, "photoview")
+	mockProvider.Claims["sub"] = "user-1"
+
+	provider := oidc.NewProvider(oidc.Config{
+		DiscoveryURL: mockProvider.Issuer(),
+		ClientID:     "photoview",
+		RedirectURL:  "http://photoview.test/oidc/callback",
+		Scopes:       []string{"openid"},
+	})
+
+	ctx := context.Background()
+
+	// login returns the code the mock provider redirects back with
+	login := func(codeChallenge string) string {
+		authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", codeChallenge)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+
+		client := http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err := client.Get(authURL)
+		if !assert.NoError(t, err) || !assert.Equal(t, http.StatusFound, res.StatusCode) {
+			return ""
+		}
+		res.Body.Close()
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, "state", location.Query().Get("state"))
+		return location.Query().Get("code")
+	}
+
+	t.Run("Authorization code flow", func(t *testing.T) {
+		verifier, err := oidc.RandomString()
+		assert.NoError(t, err)
+
+		token, err := provider.Exchange(ctx, login(oidc.CodeChallenge(verifier)), verifier)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		claims, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "user-1", claims.String("sub"))
+		}
+	})
+
+	t.Run("Wrong code verifier", func(t *testing.T) {
+		_, err := provider.Exchange(ctx, login(oidc.CodeChallenge("expected")), "other")
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid id tokens", func(t *testing.T) {
+		validClaims := func() map[string]interface{} {
+			return map[string]interface{}{
+				"iss":   mockProvider.Issuer(),
+				"aud":   []string{"photoview", "other"},
+				"azp":   "photoview",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"sub":   "user-1",
+				"nonce": "nonce",
+			}
+		}
+
+		_, err := provider.VerifyIDToken(ctx, mockProvider.SignIDToken(validClaims()), "nonce")
+		assert.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, mockProvider.SignIDToken(validClaims()), "other nonce")
+		assert.EqualError(t, err, "id token has the wrong nonce")
+
+		claims := validClaims()
+		claims["iss"] = "https://attacker.test"
+		_, err = provider.VerifyIDToken(ctx, mockProvider.SignIDToken(claims), "nonce")
+		assert.EqualError(t, err, "id token has the wrong issuer")
+
+		claims = validClaims()
+		claims["aud"] = "other"
+		_, err = provider.VerifyIDToken(ctx, mockProvider.SignIDToken(claims), "nonce")
+		assert.EqualError(t, err, "id token has the wrong audience")
+
+		claims = validClaims()
+		delete(claims, "azp")
+		_, err = provider.VerifyIDToken(ctx, mockProvider.SignIDToken(claims), "nonce")
+		assert.EqualError(t, err, "id token was issued to another party")
+
+		claims = validClaims()
+		claims["azp"] = "other"
+		_, err = provider.VerifyIDToken(ctx, mockProvider.SignIDToken(claims), "nonce")
+		assert.EqualError(t, err, "id token was issued to another party")
+
+		claims = validClaims()
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		_, err = provider.VerifyIDToken(ctx, mockProvider.SignIDToken(claims), "nonce")
+		assert.EqualError(t, err, "id token has expired")
+
+		tampered := mockProvider.SignIDToken(validClaims())
+		otherToken := mockProvider.SignIDToken(claims)
+		tampered = tampered[:len(tampered)-10] + otherToken[len(otherToken)-10:]
+		_, err = provider.VerifyIDToken(ctx, tampered, "nonce")
+		assert.Error(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, "not a token", "nonce")
+		assert.Error(t, err)
+	})
+}
@@ -1,52 +1,200 @@
 package routes
 
 import (
-	"github.com/pkg/errors"
+	"fmt"
+	"log"
 	"net/http"
 
+	"github.com/pkg/errors"
+
 	"github.com/photoview/photoview/api/graphql/auth"
 	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/scanner/media_type"
 	"gorm.io/gorm"
 )
 
-func authenticateMedia(media *models.Media, db *gorm.DB, r *http.Request) (success bool, responseMessage string, responseStatus int, errorMessage error) {
+// authenticateMedia checks that the logged in user can view the media, or that the request has a share token for it.
+// The share token is returned if the request was authenticated by it.
+func authenticateMedia(media *models.Media, db *gorm.DB, r *http.Request) (shareToken *models.ShareToken, success bool, responseMessage string, responseStatus int, errorMessage error) {
 	if media == nil {
-		return false, "internal server error", http.StatusInternalServerError, errors.New("media not found")
+		return nil, false, "internal server error", http.StatusInternalServerError, errors.New("media not found")
 	}
 	user := auth.UserFromContext(r.Context())
 
+	if !auth.HasScope(r.Context(), models.APIKeyScopeRead) {
+		return nil, false, "api key does not have the READ scope", http.StatusForbidden, nil
+	}
+
 	if user != nil {
 		var album models.Album
 		if err := db.First(&album, media.AlbumID).Error; err != nil {
-			return false, "internal server error", http.StatusInternalServerError, err
+			return nil, false, "internal server error", http.StatusInternalServerError, err
 		}
 
-		ownsAlbum, err := user.OwnsAlbum(db, &album)
+		canView, err := user.HasAlbumRole(db, &album, models.AlbumRoleViewer)
 		if err != nil {
-			return false, "internal server error", http.StatusInternalServerError, err
+			return nil, false, "internal server error", http.StatusInternalServerError, err
 		}
 
-		if !ownsAlbum {
-			return false, "invalid credentials", http.StatusForbidden, nil
+		if !canView {
+			return nil, false, "invalid credentials", http.StatusForbidden, nil
 		}
+
+		return nil, true, "success", http.StatusAccepted, nil
 	}
 
-	return true, "success", http.StatusAccepted, nil
+	return shareTokenFromRequest(db, r, &media.ID, &media.AlbumID)
 }
 
-func authenticateAlbum(album *models.Album, db *gorm.DB, r *http.Request) (success bool, responseMessage string, responseStatus int, errorMessage error) {
+// authenticateAlbum checks that the logged in user can view the album, or that the request has a share token for it.
+// The share token is returned if the request was authenticated by it.
+func authenticateAlbum(album *models.Album, db *gorm.DB, r *http.Request) (shareToken *models.ShareToken, success bool, responseMessage string, responseStatus int, errorMessage error) {
 	user := auth.UserFromContext(r.Context())
 
+	if !auth.HasScope(r.Context(), models.APIKeyScopeRead) {
+		return nil, false, "api key does not have the READ scope", http.StatusForbidden, nil
+	}
+
 	if user != nil {
-		ownsAlbum, err := user.OwnsAlbum(db, album)
+		canView, err := user.HasAlbumRole(db, album, models.AlbumRoleViewer)
 		if err != nil {
-			return false, "internal server error", http.StatusInternalServerError, err
+			return nil, false, "internal server error", http.StatusInternalServerError, err
 		}
 
-		if !ownsAlbum {
-			return false, "invalid credentials", http.StatusForbidden, nil
+		if !canView {
+			return nil, false, "invalid credentials", http.StatusForbidden, nil
 		}
+
+		return nil, true, "success", http.StatusAccepted, nil
+	}
+
+	return shareTokenFromRequest(db, r, nil, &album.ID)
+}
+
+// shareTokenFromRequest returns the share token given by the token query parameter,
+// if its password cookie is correct and it shares the media or album
+func shareTokenFromRequest(db *gorm.DB, r *http.Request, mediaID *int, albumID *int) (*models.ShareToken, bool, string, int, error) {
+	tokenValue := r.URL.Query().Get("token")
+	if tokenValue == "" {
+		return nil, false, "unauthorized", http.StatusForbidden, errors.New("share token not provided")
 	}
 
-	return true, "success", http.StatusAccepted, nil
+	db = db.WithContext(r.Context())
+
+	shareToken, err := actions.GetShareToken(db, tokenValue)
+	if err != nil {
+		if errors.Is(err, actions.ErrShareNotFound) {
+			return nil, false, "unauthorized", http.StatusForbidden, err
+		}
+		return nil, false, "internal server error", http.StatusInternalServerError, err
+	}
+
+	// The cookie is named after the value used by the client, which may be the custom slug of the share
+	var password *string
+	if passwordCookie, err := r.Cookie(fmt.Sprintf("share-token-pw-%s", tokenValue)); err == nil {
+		password = &passwordCookie.Value
+	}
+
+	// the password is entered through the graphql api, where failed attempts are throttled,
+	// a cookie that does not match is not counted as a failed attempt
+	valid, err := shareToken.CheckPassword(password)
+	if err != nil {
+		return nil, false, "internal server error", http.StatusInternalServerError, err
+	}
+
+	if !valid {
+		return nil, false, "unauthorized", http.StatusForbidden, errors.New("incorrect password for share token")
+	}
+
+	if err := actions.CheckShareTokenAccess(db, shareToken); err != nil {
+		if errors.Is(err, actions.ErrShareExpired) || errors.Is(err, actions.ErrShareViewLimit) {
+			return nil, false, "unauthorized", http.StatusForbidden, err
+		}
+		return nil, false, "internal server error", http.StatusInternalServerError, err
+	}
+
+	if shareToken.MediaID != nil {
+		if mediaID == nil || *mediaID != *shareToken.MediaID {
+			return nil, false, "unauthorized", http.StatusForbidden, errors.New("media share token does not match the requested media")
+		}
+
+		return shareToken, true, "success", http.StatusAccepted, nil
+	}
+
+	if shareToken.AlbumID == nil || albumID == nil {
+		return nil, false, "unauthorized", http.StatusForbidden, errors.New("album share token does not match the requested album")
+	}
+
+	if *albumID != *shareToken.AlbumID {
+		subAlbums, err := shareToken.Album.GetChildren(db, func(query *gorm.DB) *gorm.DB { return query.Where("sub_albums.id = ?", *albumID) })
+		if err != nil {
+			return nil, false, "internal server error", http.StatusInternalServerError, errors.Wrap(err, "find sub album of share token")
+		}
+
+		if len(subAlbums) == 0 {
+			return nil, false, "unauthorized", http.StatusForbidden, errors.New("album is not shared by the share token")
+		}
+	}
+
+	return shareToken, true, "success", http.StatusAccepted, nil
+}
+
+// shareTokenAllowsPurpose returns an error if the media url can not be served to visitors of the share.
+// Originals are only served if downloads are allowed, and only images that can be watermarked are served by watermarked shares.
+func shareTokenAllowsPurpose(shareToken *models.ShareToken, purpose models.MediaPurpose) error {
+	if purpose == models.MediaOriginal && !shareToken.AllowDownload() {
+		return actions.ErrShareDownloadDenied
+	}
+
+	if shareToken.WatermarkText != nil && !isWatermarkable(purpose) {
+		return errors.Errorf("%s files are not shared by watermarked shares", purpose)
+	}
+
+	return nil
+}
+
+// sharedMediaPurpose returns the purpose the media url is served as to visitors of a share.
+// Web compatible photos have no high-res version, instead their original is shown in full size,
+// so such an original is served as the high-res, like the media url loader of the high-res does.
+func sharedMediaPurpose(db *gorm.DB, mediaURL *models.MediaURL) (models.MediaPurpose, error) {
+	if mediaURL.Purpose != models.MediaOriginal || !isWebImage(mediaURL.ContentType) {
+		return mediaURL.Purpose, nil
+	}
+
+	var highResCount int64
+	err := db.Model(&models.MediaURL{}).
+		Where("media_id = ? AND purpose = ?", mediaURL.MediaID, models.PhotoHighRes).
+		Count(&highResCount).Error
+	if err != nil {
+		return "", errors.Wrap(err, "check if media has a high-res version")
+	}
+
+	if highResCount > 0 {
+		return models.MediaOriginal, nil
+	}
+
+	return models.PhotoHighRes, nil
+}
+
+// isWebImage returns true if the content type is an image that browsers can show
+func isWebImage(contentType string) bool {
+	for _, webType := range media_type.WebMimetypes {
+		if string(webType) == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// isWatermarkable returns true for the media urls that are images a watermark can be drawn on
+func isWatermarkable(purpose models.MediaPurpose) bool {
+	return purpose == models.PhotoThumbnail || purpose == models.PhotoHighRes || purpose == models.VideoThumbnail
+}
+
+// recordShareMediaAccess logs that a visitor of the share viewed the media in full size, errors are only logged
+func recordShareMediaAccess(db *gorm.DB, r *http.Request, shareToken *models.ShareToken, mediaID int) {
+	if err := models.RecordShareTokenAccess(db.WithContext(r.Context()), shareToken, models.ShareTokenAccessMedia, &mediaID); err != nil {
+		log.Printf("WARN: %s\n", err)
+	}
 }
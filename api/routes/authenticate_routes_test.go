@@ -48,7 +48,7 @@ func TestAuthenticateRoute(t *testing.T) {
 			ctx := auth.AddUserToContext(req.Context(), user)
 			req = req.WithContext(ctx)
 
-			success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
+			_, success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
 
 			assert.NoError(t, err)
 			assert.True(t, success)
@@ -59,7 +59,7 @@ func TestAuthenticateRoute(t *testing.T) {
 		t.Run("Request without access token", func(t *testing.T) {
 			req := httptest.NewRequest("GET", "/photo/image.jpg", strings.NewReader("IMAGE DATA"))
 
-			success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
+			_, success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
 
 			assert.Error(t, err)
 			assert.False(t, success)
@@ -84,7 +84,7 @@ func TestAuthenticateRoute(t *testing.T) {
 			}
 			req.AddCookie(&cookie)
 
-			success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
+			_, success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
 
 			assert.NoError(t, err)
 			assert.True(t, success)
@@ -99,7 +99,7 @@ func TestAuthenticateRoute(t *testing.T) {
 			ctx := auth.AddUserToContext(req.Context(), user)
 			req = req.WithContext(ctx)
 
-			success, responseMessage, responseStatus, err := authenticateAlbum(&album, db, req)
+			_, success, responseMessage, responseStatus, err := authenticateAlbum(&album, db, req)
 
 			assert.NoError(t, err)
 			assert.True(t, success)
@@ -110,7 +110,7 @@ func TestAuthenticateRoute(t *testing.T) {
 		t.Run("Request without access token", func(t *testing.T) {
 			req := httptest.NewRequest("GET", "/download/album/1", strings.NewReader("ALBUM DATA"))
 
-			success, responseMessage, responseStatus, err := authenticateAlbum(&album, db, req)
+			_, success, responseMessage, responseStatus, err := authenticateAlbum(&album, db, req)
 
 			assert.Error(t, err)
 			assert.False(t, success)
@@ -135,7 +135,7 @@ func TestAuthenticateRoute(t *testing.T) {
 			}
 			req.AddCookie(&cookie)
 
-			success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
+			_, success, responseMessage, responseStatus, err := authenticateMedia(&media, db, req)
 
 			assert.NoError(t, err)
 			assert.True(t, success)
@@ -145,3 +145,113 @@ func TestAuthenticateRoute(t *testing.T) {
 	})
 
 }
+
+func TestShareTokenFromRequest(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	user, err := models.RegisterUser(db, "username", nil, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	album := models.Album{Title: "album", Path: "/photos"}
+	if !assert.NoError(t, db.Model(&user).Association("Albums").Append(&album)) {
+		return
+	}
+
+	subAlbum := models.Album{Title: "sub_album", Path: "/photos/sub_album", ParentAlbumID: &album.ID}
+	otherAlbum := models.Album{Title: "other_album", Path: "/other"}
+	if !assert.NoError(t, db.Model(&user).Association("Albums").Append(&subAlbum, &otherAlbum)) {
+		return
+	}
+
+	tokenPassword := "token-password-123"
+	shareToken, err := actions.AddAlbumShare(db, user, album.ID, nil, &tokenPassword)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	slug := "family-photos"
+	if _, err := actions.UpdateShareToken(db, user.ID, shareToken.Value, models.ShareTokenSettings{Slug: &slug}); !assert.NoError(t, err) {
+		return
+	}
+
+	// request returns a request for the token, with the password cookie set if password is not empty
+	request := func(token string, password string) *http.Request {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/download/album/1?token=%s", token), nil)
+		if password != "" {
+			req.AddCookie(&http.Cookie{Name: fmt.Sprintf("share-token-pw-%s", token), Value: password})
+		}
+		return req
+	}
+
+	t.Run("Sub album", func(t *testing.T) {
+		share, success, _, _, err := shareTokenFromRequest(db, request(shareToken.Value, tokenPassword), nil, &subAlbum.ID)
+		assert.NoError(t, err)
+		assert.True(t, success)
+		assert.Equal(t, shareToken.ID, share.ID)
+	})
+
+	t.Run("Unrelated album", func(t *testing.T) {
+		_, success, _, status, err := shareTokenFromRequest(db, request(shareToken.Value, tokenPassword), nil, &otherAlbum.ID)
+		assert.Error(t, err)
+		assert.False(t, success)
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("Missing password", func(t *testing.T) {
+		_, success, _, status, _ := shareTokenFromRequest(db, request(shareToken.Value, ""), nil, &album.ID)
+		assert.False(t, success)
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("Slug", func(t *testing.T) {
+		share, success, _, _, err := shareTokenFromRequest(db, request(slug, tokenPassword), nil, &album.ID)
+		assert.NoError(t, err)
+		assert.True(t, success)
+		assert.Equal(t, shareToken.ID, share.ID)
+	})
+
+	t.Run("Wrong password cookie is not counted as a failed attempt", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			_, success, _, status, _ := shareTokenFromRequest(db, request(shareToken.Value, "wrong-password"), nil, &album.ID)
+			assert.False(t, success)
+			assert.Equal(t, http.StatusForbidden, status)
+		}
+
+		var attempts int64
+		assert.NoError(t, db.Model(&models.FailedLoginAttempt{}).Count(&attempts).Error)
+		assert.Zero(t, attempts)
+
+		_, success, _, _, err := shareTokenFromRequest(db, request(shareToken.Value, tokenPassword), nil, &album.ID)
+		assert.NoError(t, err)
+		assert.True(t, success)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		assert.NoError(t, db.Model(shareToken).Update("expire", time.Now().Add(-time.Minute)).Error)
+
+		_, success, _, status, err := shareTokenFromRequest(db, request(shareToken.Value, tokenPassword), nil, &album.ID)
+		assert.ErrorIs(t, err, actions.ErrShareExpired)
+		assert.False(t, success)
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+}
+
+func TestShareTokenAllowsPurpose(t *testing.T) {
+	watermark := "Proofs"
+
+	shareToken := models.ShareToken{}
+	assert.NoError(t, shareTokenAllowsPurpose(&shareToken, models.MediaOriginal))
+	assert.NoError(t, shareTokenAllowsPurpose(&shareToken, models.VideoWeb))
+
+	shareToken.DisableDownload = true
+	assert.ErrorIs(t, shareTokenAllowsPurpose(&shareToken, models.MediaOriginal), actions.ErrShareDownloadDenied)
+	assert.NoError(t, shareTokenAllowsPurpose(&shareToken, models.PhotoHighRes))
+
+	shareToken = models.ShareToken{WatermarkText: &watermark}
+	assert.Error(t, shareTokenAllowsPurpose(&shareToken, models.MediaOriginal))
+	assert.Error(t, shareTokenAllowsPurpose(&shareToken, models.VideoWeb))
+	assert.NoError(t, shareTokenAllowsPurpose(&shareToken, models.PhotoThumbnail))
+	assert.NoError(t, shareTokenAllowsPurpose(&shareToken, models.PhotoHighRes))
+}
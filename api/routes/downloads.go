@@ -12,6 +12,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/photoview/photoview/api/database/drivers"
 	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
 	"gorm.io/gorm"
 )
 
@@ -28,7 +29,8 @@ func RegisterDownloadRoutes(db *gorm.DB, router *mux.Router) {
 			return
 		}
 
-		if success, response, status, err := authenticateAlbum(&album, db, r); !success {
+		shareToken, success, response, status, err := authenticateAlbum(&album, db, r)
+		if !success {
 			if err != nil {
 				log.Printf("WARN: error authenticating album for download: %v\n", err)
 			}
@@ -37,6 +39,12 @@ func RegisterDownloadRoutes(db *gorm.DB, router *mux.Router) {
 			return
 		}
 
+		if shareToken != nil && !shareToken.AllowDownload() {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(actions.ErrShareDownloadDenied.Error()))
+			return
+		}
+
 		var mediaWhereQuery string
 		if drivers.POSTGRES.MatchDatabase(db) {
 			mediaWhereQuery = "\"Media\".album_id = ?"
@@ -57,6 +65,12 @@ func RegisterDownloadRoutes(db *gorm.DB, router *mux.Router) {
 			return
 		}
 
+		if shareToken != nil {
+			if err := models.RecordShareTokenAccess(db.WithContext(r.Context()), shareToken, models.ShareTokenAccessDownload, nil); err != nil {
+				log.Printf("WARN: %s\n", err)
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/zip")
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", album.Title))
 
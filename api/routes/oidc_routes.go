@@ -0,0 +1,294 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/oidc"
+	"github.com/photoview/photoview/api/scanner"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Name of the cookie that binds a login at the provider to the browser that started it
+const oidcStateCookie = "oidc-state"
+
+// How long a user has to log in at the provider
+const oidcLoginTimeout = 10 * time.Minute
+
+// Max age of the auth-token cookie, the same as set by the ui when logging in with a password
+const authTokenCookieMaxAge = 14 * 24 * 60 * 60
+
+// oidcLogin is a login that has been started, but the user has not yet returned from the provider
+type oidcLogin struct {
+	nonce        string
+	codeVerifier string
+	// linkUserID is the user that the account at the provider should be linked to, if any
+	linkUserID *int
+	expire     time.Time
+}
+
+type oidcHandler struct {
+	db       *gorm.DB
+	provider *oidc.Provider
+
+	lock   sync.Mutex
+	logins map[string]oidcLogin
+}
+
+// RegisterOIDCRoutes adds the login and callback routes of the authorization code flow with the provider.
+// When requested by a logged in user with the link query parameter set, the account at the provider is linked to the user.
+func RegisterOIDCRoutes(db *gorm.DB, router *mux.Router, provider *oidc.Provider) {
+	handler := &oidcHandler{
+		db:       db,
+		provider: provider,
+		logins:   make(map[string]oidcLogin),
+	}
+
+	router.HandleFunc("/login", handler.login).Methods(http.MethodGet)
+	router.HandleFunc("/callback", handler.callback).Methods(http.MethodGet)
+}
+
+func (h *oidcHandler) login(w http.ResponseWriter, r *http.Request) {
+	login := oidcLogin{
+		expire: time.Now().Add(oidcLoginTimeout),
+	}
+
+	if r.URL.Query().Get("link") != "" {
+		user := auth.UserFromContext(r.Context())
+		if user == nil {
+			http.Error(w, "must be logged in to link an account", http.StatusUnauthorized)
+			return
+		}
+		login.linkUserID = &user.ID
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		log.Printf("ERROR: starting oidc login: %s\n", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if login.nonce, err = oidc.RandomString(); err != nil {
+		log.Printf("ERROR: starting oidc login: %s\n", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if login.codeVerifier, err = oidc.RandomString(); err != nil {
+		log.Printf("ERROR: starting oidc login: %s\n", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := h.provider.AuthCodeURL(r.Context(), state, login.nonce, oidc.CodeChallenge(login.codeVerifier))
+	if err != nil {
+		log.Printf("ERROR: starting oidc login: %s\n", err)
+		http.Error(w, "could not reach the login provider", http.StatusBadGateway)
+		return
+	}
+
+	h.lock.Lock()
+	now := time.Now()
+	for key, pending := range h.logins {
+		if pending.expire.Before(now) {
+			delete(h.logins, key)
+		}
+	}
+	h.logins[state] = login
+	h.lock.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// takeLogin removes the pending login of the state and returns it, if it has not expired
+func (h *oidcHandler) takeLogin(state string) (*oidcLogin, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	login, found := h.logins[state]
+	if !found {
+		return nil, false
+	}
+	delete(h.logins, state)
+
+	if login.expire.Before(time.Now()) {
+		return nil, false
+	}
+
+	return &login, true
+}
+
+func (h *oidcHandler) callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if providerError := query.Get("error"); providerError != "" {
+		http.Error(w, "login was rejected by the provider: "+providerError, http.StatusUnauthorized)
+		return
+	}
+
+	stateCookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || stateCookie.Value == "" || stateCookie.Value != query.Get("state") {
+		http.Error(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+
+	login, found := h.takeLogin(stateCookie.Value)
+	if !found {
+		http.Error(w, "login has expired, please try again", http.StatusBadRequest)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/", MaxAge: -1})
+
+	token, err := h.provider.Exchange(r.Context(), query.Get("code"), login.codeVerifier)
+	if err != nil {
+		log.Printf("ERROR: oidc token exchange: %s\n", err)
+		http.Error(w, "could not complete the login with the provider", http.StatusBadGateway)
+		return
+	}
+
+	claims, err := h.provider.VerifyIDToken(r.Context(), token.IDToken, login.nonce)
+	if err != nil {
+		log.Printf("ERROR: oidc id token: %s\n", err)
+		http.Error(w, "invalid id token", http.StatusUnauthorized)
+		return
+	}
+
+	var accessToken *models.AccessToken
+	transactionError := h.db.Transaction(func(tx *gorm.DB) error {
+		user, err := oidcUser(tx, h.provider.Config, claims, login.linkUserID)
+		if err != nil {
+			return err
+		}
+
+		accessToken, err = user.GenerateSessionToken(tx, auth.ClientFromContext(r.Context()))
+		return err
+	})
+
+	if transactionError != nil {
+		log.Printf("ERROR: oidc login: %s\n", transactionError)
+		http.Error(w, "could not log in with the account of the provider", http.StatusForbidden)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "auth-token",
+		Value:    accessToken.Value,
+		Path:     "/",
+		MaxAge:   authTokenCookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	redirectURL := "/"
+	if uiEndpoint := utils.UiEndpointUrl(); uiEndpoint != nil && uiEndpoint.String() != "" {
+		redirectURL = uiEndpoint.String()
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// oidcUser finds the user linked to the account at the provider, or links the account to the user with linkUserID.
+// If no user is linked and provisioning is enabled, a new user is created from the claims.
+func oidcUser(db *gorm.DB, config oidc.Config, claims oidc.Claims, linkUserID *int) (*models.User, error) {
+	subject := claims.String("sub")
+
+	var users []*models.User
+	if err := db.Where("oidc_subject = ?", subject).Limit(1).Find(&users).Error; err != nil {
+		return nil, errors.Wrap(err, "get linked user from database")
+	}
+
+	var user *models.User
+	switch {
+	case len(users) > 0:
+		user = users[0]
+		if linkUserID != nil && *linkUserID != user.ID {
+			return nil, errors.New("the account is already linked to another user")
+		}
+	case linkUserID != nil:
+		user = &models.User{}
+		if err := db.First(user, *linkUserID).Error; err != nil {
+			return nil, errors.Wrap(err, "get user to link from database")
+		}
+
+		if err := db.Model(user).Update("oidc_subject", subject).Error; err != nil {
+			return nil, errors.Wrap(err, "link user to account")
+		}
+	default:
+		return provisionOIDCUser(db, config, claims)
+	}
+
+	if config.AdminClaim != "" {
+		admin := claims.Matches(config.AdminClaim, config.AdminValue)
+		if admin != user.Admin {
+			if err := db.Model(user).Update("admin", admin).Error; err != nil {
+				return nil, errors.Wrap(err, "update admin flag of user")
+			}
+		}
+	}
+
+	return user, nil
+}
+
+// provisionOIDCUser creates a new user linked to the account at the provider, mapping the claims to the
+// username, admin flag and root paths of the user
+func provisionOIDCUser(db *gorm.DB, config oidc.Config, claims oidc.Claims) (*models.User, error) {
+	if config.DisableProvisioning {
+		return nil, errors.New("the account is not linked to a user")
+	}
+
+	username := claims.String(config.UsernameClaim)
+	if username == "" {
+		return nil, errors.Errorf("id token has no %s claim to use as username", config.UsernameClaim)
+	}
+
+	var existingCount int64
+	if err := db.Model(&models.User{}).Where("username = ?", username).Count(&existingCount).Error; err != nil {
+		return nil, errors.Wrap(err, "check username of new user")
+	}
+
+	if existingCount > 0 {
+		return nil, errors.Errorf("a user named %s already exists, log in as that user to link the account", username)
+	}
+
+	admin := config.AdminClaim != "" && claims.Matches(config.AdminClaim, config.AdminValue)
+
+	user, err := models.RegisterUser(db, username, nil, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := claims.String("sub")
+	if err := db.Model(user).Update("oidc_subject", subject).Error; err != nil {
+		return nil, errors.Wrap(err, "link new user to account")
+	}
+
+	if config.RootPathsClaim != "" {
+		for _, rootPath := range claims.Strings(config.RootPathsClaim) {
+			if _, err := scanner.NewRootAlbum(db, rootPath, user); err != nil {
+				log.Printf("WARN: could not add root path %s of new user %s: %s\n", rootPath, username, err)
+			}
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,213 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/photoview/photoview/api/dataloader"
+	"github.com/photoview/photoview/api/graphql/auth"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/oidc"
+	"github.com/photoview/photoview/api/routes"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCRoutes(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	mockProvider := test_utils.NewMockOIDCProvider(t, "photoview")
+
+	router := mux.NewRouter()
+	router.Use(dataloader.Middleware(db))
+	router.Use(auth.Middleware(db, nil))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	config := oidc.Config{
+		DiscoveryURL:   mockProvider.Issuer(),
+		ClientID:       "photoview",
+		ClientSecret:   "secret",
+		RedirectURL:    server.URL + "/oidc/callback",
+		Scopes:         []string{"openid", "profile"},
+		UsernameClaim:  "preferred_username",
+		AdminClaim:     "groups",
+		AdminValue:     "photoview-admins",
+		RootPathsClaim: "photoview_paths",
+	}
+	provider := oidc.NewProvider(config)
+	routes.RegisterOIDCRoutes(db, router.PathPrefix("/oidc").Subrouter(), provider)
+
+	serverURL, _ := url.Parse(server.URL)
+
+	// login goes through the login flow with the claims, and returns the status of the callback
+	// and the auth token set by it
+	login := func(jar *cookiejar.Jar, query string, claims map[string]interface{}) (int, string) {
+		mockProvider.Claims = claims
+
+		var callbackStatus int
+		client := http.Client{
+			Jar: jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if via[len(via)-1].URL.Path == "/oidc/callback" {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+		}
+
+		res, err := client.Get(server.URL + "/oidc/login" + query)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		res.Body.Close()
+		callbackStatus = res.StatusCode
+
+		for _, cookie := range res.Cookies() {
+			if cookie.Name == "auth-token" {
+				assert.True(t, cookie.HttpOnly, "auth token cookie must not be readable by scripts")
+			}
+		}
+
+		for _, cookie := range jar.Cookies(serverURL) {
+			if cookie.Name == "auth-token" {
+				return callbackStatus, cookie.Value
+			}
+		}
+
+		return callbackStatus, ""
+	}
+
+	tokenUserID := func(token string) int {
+		var accessToken models.AccessToken
+		assert.NoError(t, db.Where("value = ?", token).First(&accessToken).Error)
+		return accessToken.UserID
+	}
+
+	newJar := func() *cookiejar.Jar {
+		jar, _ := cookiejar.New(nil)
+		return jar
+	}
+
+	rootPath := t.TempDir()
+
+	t.Run("Provision new user", func(t *testing.T) {
+		status, token := login(newJar(), "", map[string]interface{}{
+			"sub":                "subject-1",
+			"preferred_username": "alice",
+			"groups":             []string{"users", "photoview-admins"},
+			"photoview_paths":    rootPath,
+		})
+		if !assert.Equal(t, http.StatusFound, status) || !assert.NotEmpty(t, token) {
+			return
+		}
+
+		var user models.User
+		if !assert.NoError(t, db.Preload("Albums").Where("username = ?", "alice").First(&user).Error) {
+			return
+		}
+
+		assert.True(t, user.Admin)
+		assert.Nil(t, user.Password)
+		if assert.NotNil(t, user.OIDCSubject) {
+			assert.Equal(t, "subject-1", *user.OIDCSubject)
+		}
+		if assert.Len(t, user.Albums, 1) {
+			assert.Equal(t, rootPath, user.Albums[0].Path)
+		}
+
+		assert.Equal(t, user.ID, tokenUserID(token))
+	})
+
+	t.Run("Admin flag follows the claim", func(t *testing.T) {
+		status, token := login(newJar(), "", map[string]interface{}{
+			"sub":                "subject-1",
+			"preferred_username": "renamed",
+			"groups":             []string{"users"},
+		})
+		assert.Equal(t, http.StatusFound, status)
+		assert.NotEmpty(t, token)
+
+		var users []models.User
+		assert.NoError(t, db.Where("oidc_subject = ?", "subject-1").Find(&users).Error)
+		if assert.Len(t, users, 1) {
+			assert.Equal(t, "alice", users[0].Username)
+			assert.False(t, users[0].Admin)
+		}
+	})
+
+	password := "1234"
+	bob, err := models.RegisterUser(db, "bob", &password, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	bobClaims := map[string]interface{}{
+		"sub":                "subject-2",
+		"preferred_username": "bob",
+	}
+
+	t.Run("Existing local user is not linked automatically", func(t *testing.T) {
+		status, token := login(newJar(), "", bobClaims)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Empty(t, token)
+
+		assert.NoError(t, db.First(bob, bob.ID).Error)
+		assert.Nil(t, bob.OIDCSubject)
+	})
+
+	t.Run("Link logged in user", func(t *testing.T) {
+		status, _ := login(newJar(), "?link=1", bobClaims)
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		bobToken, err := bob.GenerateAccessToken(db)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		jar := newJar()
+		jar.SetCookies(serverURL, []*http.Cookie{{Name: "auth-token", Value: bobToken.Value}})
+
+		status, token := login(jar, "?link=1", bobClaims)
+		assert.Equal(t, http.StatusFound, status)
+		assert.NotEqual(t, bobToken.Value, token)
+
+		assert.NoError(t, db.First(bob, bob.ID).Error)
+		if assert.NotNil(t, bob.OIDCSubject) {
+			assert.Equal(t, "subject-2", *bob.OIDCSubject)
+		}
+
+		status, token = login(newJar(), "", bobClaims)
+		assert.Equal(t, http.StatusFound, status)
+		assert.Equal(t, bob.ID, tokenUserID(token))
+	})
+
+	t.Run("Callback with wrong state", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/oidc/callback?code=code&state=forged", nil)
+		req.AddCookie(&http.Cookie{Name: "oidc-state", Value: "other"})
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Provisioning disabled", func(t *testing.T) {
+		provider.Config.DisableProvisioning = true
+		defer func() { provider.Config.DisableProvisioning = false }()
+
+		status, token := login(newJar(), "", map[string]interface{}{
+			"sub":                "subject-3",
+			"preferred_username": "carol",
+		})
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Empty(t, token)
+
+		var count int64
+		assert.NoError(t, db.Model(&models.User{}).Where("username = ?", "carol").Count(&count).Error)
+		assert.EqualValues(t, 0, count)
+	})
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/scanner"
+	"github.com/photoview/photoview/api/scanner/media_encoding"
 )
 
 func RegisterPhotoRoutes(db *gorm.DB, router *mux.Router) {
@@ -27,7 +28,8 @@ func RegisterPhotoRoutes(db *gorm.DB, router *mux.Router) {
 
 		media := mediaURL.Media
 
-		if success, response, status, err := authenticateMedia(media, db, r); !success {
+		shareToken, success, response, status, err := authenticateMedia(media, db, r)
+		if !success {
 			if err != nil {
 				log.Printf("WARN: error authenticating photo: %s\n", err)
 			}
@@ -36,6 +38,26 @@ func RegisterPhotoRoutes(db *gorm.DB, router *mux.Router) {
 			return
 		}
 
+		if shareToken != nil {
+			purpose, err := sharedMediaPurpose(db, &mediaURL)
+			if err != nil {
+				log.Printf("ERROR: %s\n", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("internal server error"))
+				return
+			}
+
+			if err := shareTokenAllowsPurpose(shareToken, purpose); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			if purpose == models.PhotoHighRes || purpose == models.MediaOriginal {
+				recordShareMediaAccess(db, r, shareToken, media.ID)
+			}
+		}
+
 		cachedPath, err := mediaURL.CachedPath()
 		if err != nil {
 			log.Printf("ERROR: %s\n", err)
@@ -64,6 +86,21 @@ func RegisterPhotoRoutes(db *gorm.DB, router *mux.Router) {
 		// Allow caching the resource for 1 day
 		w.Header().Set("Cache-Control", "private, max-age=86400, immutable")
 
+		// The content type of WebVTT files can not be detected from the file contents
+		if mediaURL.Purpose == models.VideoSpriteIndex {
+			w.Header().Set("Content-Type", mediaURL.ContentType)
+		}
+
+		if shareToken != nil && shareToken.WatermarkText != nil {
+			w.Header().Set("Content-Type", "image/jpeg")
+			if err := media_encoding.EncodeWatermarkedJPEG(w, cachedPath, *shareToken.WatermarkText); err != nil {
+				log.Printf("ERROR: %s\n", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("internal server error"))
+			}
+			return
+		}
+
 		http.ServeFile(w, r, cachedPath)
 	})
 }
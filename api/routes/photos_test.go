@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhotoRoutesShareToken(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	user, err := models.RegisterUser(db, "username", nil, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	photoDir := t.TempDir()
+	album := models.Album{Title: "album", Path: photoDir}
+	if !assert.NoError(t, db.Model(&user).Association("Albums").Append(&album)) {
+		return
+	}
+
+	// addPhoto adds a 64x64 jpeg photo to the album, with a media url for its original only
+	addPhoto := func(name string, contentType string) *models.Media {
+		photoPath := path.Join(photoDir, name)
+		file, err := os.Create(photoPath)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		defer file.Close()
+
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for i := range img.Pix {
+			img.Pix[i] = 200
+		}
+		if !assert.NoError(t, jpeg.Encode(file, img, nil)) {
+			return nil
+		}
+
+		media := models.Media{Title: name, Path: photoPath, AlbumID: album.ID}
+		if !assert.NoError(t, db.Save(&media).Error) {
+			return nil
+		}
+
+		original := models.MediaURL{
+			MediaID:     media.ID,
+			MediaName:   name,
+			Width:       64,
+			Height:      64,
+			Purpose:     models.MediaOriginal,
+			ContentType: contentType,
+		}
+		if !assert.NoError(t, db.Save(&original).Error) {
+			return nil
+		}
+
+		return &media
+	}
+
+	webPhoto := addPhoto("web_photo.jpg", "image/jpeg")
+	rawPhoto := addPhoto("raw_photo.cr2", "image/x-canon-cr2")
+	if webPhoto == nil || rawPhoto == nil {
+		return
+	}
+
+	shareToken, err := actions.AddAlbumShare(db, user, album.ID, nil, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	router := mux.NewRouter()
+	RegisterPhotoRoutes(db, router)
+
+	request := func(mediaName string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/%s?token=%s", mediaName, shareToken.Value), nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	allowDownload := false
+	watermark := "Proofs"
+	_, err = actions.UpdateShareToken(db, user.ID, shareToken.Value, models.ShareTokenSettings{
+		AllowDownload: &allowDownload,
+		WatermarkText: &watermark,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("Web compatible original without high-res", func(t *testing.T) {
+		rec := request("web_photo.jpg")
+		if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+			return
+		}
+
+		assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+
+		img, err := jpeg.Decode(rec.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// the watermark darkens some of the light pixels
+		watermarked := false
+		bounds := img.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y && !watermarked; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray); gray.Y < 150 {
+					watermarked = true
+					break
+				}
+			}
+		}
+		assert.True(t, watermarked, "expected the photo to be watermarked")
+
+		var accesses int64
+		assert.NoError(t, db.Model(&models.ShareTokenAccess{}).
+			Where("share_token_id = ? AND kind = ? AND media_id = ?", shareToken.ID, models.ShareTokenAccessMedia, webPhoto.ID).
+			Count(&accesses).Error)
+		assert.EqualValues(t, 1, accesses)
+	})
+
+	t.Run("Original that is not web compatible", func(t *testing.T) {
+		rec := request("raw_photo.cr2")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Original of photo with high-res", func(t *testing.T) {
+		highRes := models.MediaURL{
+			MediaID:     webPhoto.ID,
+			MediaName:   "web_photo_highres.jpg",
+			Width:       64,
+			Height:      64,
+			Purpose:     models.PhotoHighRes,
+			ContentType: "image/jpeg",
+		}
+		if !assert.NoError(t, db.Save(&highRes).Error) {
+			return
+		}
+
+		rec := request("web_photo.jpg")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
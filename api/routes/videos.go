@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/photoview/photoview/api/graphql/models"
@@ -29,7 +30,8 @@ func RegisterVideoRoutes(db *gorm.DB, router *mux.Router) {
 
 		var media = mediaURL.Media
 
-		if success, response, status, err := authenticateMedia(media, db, r); !success {
+		shareToken, success, response, status, err := authenticateMedia(media, db, r)
+		if !success {
 			if err != nil {
 				log.Printf("WARN: error authenticating video: %s\n", err)
 			}
@@ -38,9 +40,22 @@ func RegisterVideoRoutes(db *gorm.DB, router *mux.Router) {
 			return
 		}
 
+		if shareToken != nil {
+			if err := shareTokenAllowsPurpose(shareToken, mediaURL.Purpose); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			// browsers request videos in ranges, only the request for the start of the video is logged as a view
+			if mediaURL.Purpose == models.VideoWeb && isInitialRangeRequest(r) {
+				recordShareMediaAccess(db, r, shareToken, media.ID)
+			}
+		}
+
 		var cachedPath string
 
-		if mediaURL.Purpose == models.VideoWeb {
+		if mediaURL.Purpose == models.VideoWeb || mediaURL.Purpose == models.VideoPreview || mediaURL.Purpose == models.MotionVideo {
 			cachedPath = path.Join(utils.MediaCachePath(), strconv.Itoa(int(media.AlbumID)), strconv.Itoa(int(mediaURL.MediaID)), mediaURL.MediaName)
 		} else {
 			log.Printf("ERROR: Can not handle media_purpose for video: %s\n", mediaURL.Purpose)
@@ -70,3 +85,9 @@ func RegisterVideoRoutes(db *gorm.DB, router *mux.Router) {
 		http.ServeFile(w, r, cachedPath)
 	})
 }
+
+// isInitialRangeRequest returns true if the request is not for a range, or for a range from the start of the file
+func isInitialRangeRequest(r *http.Request) bool {
+	rangeHeader := r.Header.Get("Range")
+	return rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-")
+}
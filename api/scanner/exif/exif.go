@@ -71,3 +71,43 @@ func SaveEXIF(tx *gorm.DB, media *models.Media) (*models.MediaEXIF, error) {
 
 	return exif, nil
 }
+
+// RefreshDateShot reads the date shot and timezone of the media again from the media file,
+// to convert a date that was saved as the local time of the camera to UTC
+func RefreshDateShot(tx *gorm.DB, media *models.Media) error {
+	if media.ExifID == nil {
+		return nil
+	}
+
+	if globalExifParser == nil {
+		return errors.New("No exif parser initialized")
+	}
+
+	exif, err := globalExifParser.ParseExif(media.Path)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse exif data")
+	}
+
+	updates := map[string]interface{}{"date_shot_local": false}
+	if exif != nil && exif.DateShot != nil {
+		updates["date_shot"] = *exif.DateShot
+		updates["timezone_offset"] = exif.TimezoneOffset
+	}
+
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.MediaEXIF{}).Where("id = ?", *media.ExifID).Updates(updates).Error; err != nil {
+			return errors.Wrap(err, "update date of media exif")
+		}
+
+		if exif == nil || exif.DateShot == nil || exif.DateShot.Equal(media.DateShot) {
+			return nil
+		}
+
+		media.DateShot = *exif.DateShot
+		if err := tx.Model(&models.Media{}).Where("id = ?", media.ID).Update("date_shot", media.DateShot).Error; err != nil {
+			return errors.Wrap(err, "update media date_shot")
+		}
+
+		return nil
+	})
+}
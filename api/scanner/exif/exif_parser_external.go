@@ -3,6 +3,7 @@ package exif
 import (
 	"log"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/barasher/go-exiftool"
@@ -54,6 +55,18 @@ func sanitizeEXIF(exif *models.MediaEXIF) {
 	if exif.FocalLength != nil && !isFloatReal(*exif.FocalLength) {
 		exif.FocalLength = nil
 	}
+	if exif.GPSAltitude != nil && !isFloatReal(*exif.GPSAltitude) {
+		exif.GPSAltitude = nil
+	}
+	if exif.GPSDirection != nil && !isFloatReal(*exif.GPSDirection) {
+		exif.GPSDirection = nil
+	}
+	if exif.SubjectDistance != nil && !isFloatReal(*exif.SubjectDistance) {
+		exif.SubjectDistance = nil
+	}
+	if exif.PoseHeading != nil && !isFloatReal(*exif.PoseHeading) {
+		exif.PoseHeading = nil
+	}
 	if (exif.GPSLatitude != nil && !isFloatReal(*exif.GPSLatitude)) ||
 		(exif.GPSLongitude != nil && !isFloatReal(*exif.GPSLongitude)) {
 		exif.GPSLatitude = nil
@@ -110,6 +123,7 @@ func (p *externalExifParser) ParseExif(media_path string) (returnExif *models.Me
 	}
 
 	//Get time of photo
+	var dateOffset *string
 	createDateKeys := []string{"DateTimeOriginal", "CreateDate", "TrackCreateDate", "MediaCreateDate", "FileCreateDate", "ModifyDate", "TrackModifyDate", "MediaModifyDate", "FileModifyDate"}
 	for _, createDateKey := range createDateKeys {
 		date, err := fileInfo.GetString(createDateKey)
@@ -120,12 +134,19 @@ func (p *externalExifParser) ParseExif(media_path string) (returnExif *models.Me
 				found_exif = true
 				newExif.DateShot = &dateTime
 			} else {
-				layoutWithOffset := "2006:01:02 15:04:05+02:00"
+				layoutWithOffset := "2006:01:02 15:04:05-07:00"
 				dateTime, err = time.Parse(layoutWithOffset, date)
 				if err == nil {
 					found_exif = true
-					newExif.DateShot = &dateTime
-				}	
+
+					// Keep the local time, the offset is applied together with the other timezone sources
+					_, offset := dateTime.Zone()
+					localDate := time.Date(dateTime.Year(), dateTime.Month(), dateTime.Day(), dateTime.Hour(), dateTime.Minute(), dateTime.Second(), 0, time.UTC)
+					newExif.DateShot = &localDate
+
+					formattedOffset := formatTimezoneOffset(time.Duration(offset) * time.Second)
+					dateOffset = &formattedOffset
+				}
 			}
 			break
 		}
@@ -194,11 +215,185 @@ func (p *externalExifParser) ParseExif(media_path string) (returnExif *models.Me
 		newExif.GPSLatitude = &latitudeRaw
 	}
 
+	// GPS altitude, negative when below sea level
+	altitude, err := fileInfo.GetFloat("GPSAltitude")
+	if err == nil {
+		found_exif = true
+		if altitudeRef, err := fileInfo.GetInt("GPSAltitudeRef"); err == nil && altitudeRef == 1 && altitude > 0 {
+			altitude = -altitude
+		}
+		newExif.GPSAltitude = &altitude
+	}
+
+	// GPS direction the camera was pointing at
+	direction, err := fileInfo.GetFloat("GPSImgDirection")
+	if err == nil {
+		found_exif = true
+		newExif.GPSDirection = &direction
+	}
+
+	// Get white balance
+	whiteBalance, err := fileInfo.GetInt("WhiteBalance")
+	if err == nil {
+		found_exif = true
+		newExif.WhiteBalance = &whiteBalance
+	}
+
+	// Get metering mode
+	meteringMode, err := fileInfo.GetInt("MeteringMode")
+	if err == nil {
+		found_exif = true
+		newExif.MeteringMode = &meteringMode
+	}
+
+	// Get subject distance
+	subjectDistance, err := fileInfo.GetFloat("SubjectDistance")
+	if err == nil {
+		found_exif = true
+		newExif.SubjectDistance = &subjectDistance
+	}
+
+	stringTags := []struct {
+		keys  []string
+		field **string
+	}{
+		{keys: []string{"SerialNumber", "BodySerialNumber", "InternalSerialNumber"}, field: &newExif.CameraSerialNumber},
+		{keys: []string{"LensSerialNumber"}, field: &newExif.LensSerialNumber},
+		{keys: []string{"Artist", "Creator"}, field: &newExif.Artist},
+		{keys: []string{"Copyright", "Rights", "CopyrightNotice"}, field: &newExif.Copyright},
+		{keys: []string{"Caption-Abstract"}, field: &newExif.Caption},
+		{keys: []string{"By-line"}, field: &newExif.Byline},
+		{keys: []string{"City"}, field: &newExif.City},
+		{keys: []string{"Country-PrimaryLocationName", "Country"}, field: &newExif.Country},
+		{keys: []string{"OffsetTimeOriginal", "OffsetTime"}, field: &newExif.TimezoneOffset},
+	}
+
+	for _, tag := range stringTags {
+		for _, key := range tag.keys {
+			value, err := fileInfo.GetString(key)
+			if err == nil && value != "" {
+				found_exif = true
+				*tag.field = &value
+				break
+			}
+		}
+	}
+
+	// Keywords, from IPTC or XMP
+	for _, keywordsKey := range []string{"Keywords", "Subject"} {
+		keywords, err := fileInfo.GetStrings(keywordsKey)
+		if err == nil && len(keywords) > 0 {
+			found_exif = true
+			newExif.SetKeywordList(keywords)
+			break
+		}
+	}
+
+	// Burst id, shared by the frames of a burst
+	for _, burstKey := range []string{"BurstUUID", "BurstID"} {
+		burstID, err := fileInfo.GetString(burstKey)
+		if err == nil && burstID != "" {
+			found_exif = true
+			newExif.BurstID = &burstID
+			break
+		}
+	}
+
+	// Position of the frame in a burst or exposure bracket
+	for _, sequenceKey := range []string{"SequenceNumber", "BracketShotNumber"} {
+		sequenceNumber, err := fileInfo.GetInt(sequenceKey)
+		if err == nil && sequenceNumber > 0 {
+			found_exif = true
+			newExif.SequenceNumber = &sequenceNumber
+			break
+		}
+	}
+
+	// Panorama projection, from the GPano XMP namespace
+	projectionType, err := fileInfo.GetString("ProjectionType")
+	if err == nil && projectionType != "" {
+		found_exif = true
+		newExif.ProjectionType = &projectionType
+
+		panoramaIntTags := map[string]**int64{
+			"FullPanoWidthPixels":          &newExif.FullPanoWidth,
+			"FullPanoHeightPixels":         &newExif.FullPanoHeight,
+			"CroppedAreaImageWidthPixels":  &newExif.CroppedAreaWidth,
+			"CroppedAreaImageHeightPixels": &newExif.CroppedAreaHeight,
+			"CroppedAreaLeftPixels":        &newExif.CroppedAreaLeft,
+			"CroppedAreaTopPixels":         &newExif.CroppedAreaTop,
+		}
+
+		for key, field := range panoramaIntTags {
+			value, err := fileInfo.GetInt(key)
+			if err == nil {
+				*field = &value
+			}
+		}
+
+		poseHeading, err := fileInfo.GetFloat("PoseHeadingDegrees")
+		if err == nil {
+			newExif.PoseHeading = &poseHeading
+		}
+	}
+
+	// Depth map, embedded by portrait modes as GDepth XMP or as an auxiliary HEIF image
+	for _, depthKey := range []string{"DepthImage", "DepthFormat"} {
+		if _, err := fileInfo.GetString(depthKey); err == nil {
+			found_exif = true
+			newExif.HasDepthMap = true
+			break
+		}
+	}
+
+	auxiliaryType, err := fileInfo.GetString("AuxiliaryImageType")
+	if err == nil && (strings.Contains(auxiliaryType, "depth") || strings.Contains(auxiliaryType, "disparity")) {
+		found_exif = true
+		newExif.HasDepthMap = true
+	}
+
+	// Convert the local date to UTC
+	if newExif.TimezoneOffset == nil {
+		newExif.TimezoneOffset = dateOffset
+	}
+	applyTimezone(&newExif, p.readGPSTime(fileInfo))
+
 	if !found_exif {
 		return nil, nil
 	}
 
+	newExif.RawTags = encodeRawTags(fileInfo.Fields)
+
 	returnExif = &newExif
 	sanitizeEXIF(returnExif)
 	return
 }
+
+// readGPSTime returns the time of the GPS fix, which is always in UTC
+func (p *externalExifParser) readGPSTime(fileInfo exiftool.FileMetadata) *time.Time {
+	if gpsDateTime, err := fileInfo.GetString("GPSDateTime"); err == nil {
+		for _, layout := range []string{"2006:01:02 15:04:05Z07:00", "2006:01:02 15:04:05"} {
+			if gpsTime, err := time.Parse(layout, gpsDateTime); err == nil {
+				gpsTime = gpsTime.UTC()
+				return &gpsTime
+			}
+		}
+	}
+
+	gpsDate, err := fileInfo.GetString("GPSDateStamp")
+	if err != nil {
+		return nil
+	}
+
+	gpsTimeStamp, err := fileInfo.GetString("GPSTimeStamp")
+	if err != nil {
+		return nil
+	}
+
+	gpsTime, err := time.Parse("2006:01:02 15:04:05", gpsDate+" "+gpsTimeStamp)
+	if err != nil {
+		return nil
+	}
+
+	return &gpsTime
+}
@@ -1,16 +1,23 @@
 package exif
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/pkg/errors"
 	"github.com/xor-gate/goexif2/exif"
 	"github.com/xor-gate/goexif2/mknote"
+	"github.com/xor-gate/goexif2/tiff"
 )
 
 // internalExifParser is an exif parser that parses the media without the use of external tools
@@ -38,6 +45,12 @@ func (p internalExifParser) ParseExif(media_path string) (returnExif *models.Med
 
 	exifTags, err := exif.Decode(photoFile)
 	if err != nil {
+		// Panoramas can still be described by XMP, even if the EXIF could not be decoded
+		panoramaExif := models.MediaEXIF{}
+		if found, err := readPanoramaXMP(media_path, &panoramaExif); err == nil && found {
+			return &panoramaExif, nil
+		}
+
 		return nil, nil
 		// return nil, errors.Wrap(err, "Could not decode EXIF")
 	}
@@ -69,6 +82,15 @@ func (p internalExifParser) ParseExif(media_path string) (returnExif *models.Med
 		_, tz := date.Zone()
 		date_utc := date.Add(time.Duration(tz) * time.Second).UTC()
 		newExif.DateShot = &date_utc
+
+		// The offset of the date is read from the Exif sub-IFD, Canon cameras also record their time zone in the maker notes
+		if timezoneOffset := p.readOffsetTime(exifTags, photoFile); timezoneOffset != nil {
+			newExif.TimezoneOffset = timezoneOffset
+		} else if location, err := exifTags.TimeZone(); err == nil && location != nil {
+			_, offset := date_utc.In(location).Zone()
+			timezoneOffset := formatTimezoneOffset(time.Duration(offset) * time.Second)
+			newExif.TimezoneOffset = &timezoneOffset
+		}
 	}
 
 	exposureTag, err := exifTags.Get(exif.ExposureTime)
@@ -146,10 +168,260 @@ func (p internalExifParser) ParseExif(media_path string) (returnExif *models.Med
 		newExif.GPSLongitude = &long
 	}
 
+	altitudeRat, err := p.readRationalTag(exifTags, exif.GPSAltitude, media_path)
+	if err == nil {
+		altitude, _ := altitudeRat.Float64()
+		altitudeRef, err := p.readIntegerTag(exifTags, exif.GPSAltitudeRef, media_path)
+		if err == nil && *altitudeRef == 1 {
+			altitude = -altitude
+		}
+		newExif.GPSAltitude = &altitude
+	}
+
+	directionRat, err := p.readRationalTag(exifTags, exif.GPSImgDirection, media_path)
+	if err == nil {
+		direction, _ := directionRat.Float64()
+		newExif.GPSDirection = &direction
+	}
+
+	whiteBalance, err := p.readIntegerTag(exifTags, exif.WhiteBalance, media_path)
+	if err == nil {
+		whiteBalance64 := int64(*whiteBalance)
+		newExif.WhiteBalance = &whiteBalance64
+	}
+
+	meteringMode, err := p.readIntegerTag(exifTags, exif.MeteringMode, media_path)
+	if err == nil {
+		meteringMode64 := int64(*meteringMode)
+		newExif.MeteringMode = &meteringMode64
+	}
+
+	subjectDistanceRat, err := p.readRationalTag(exifTags, exif.SubjectDistance, media_path)
+	if err == nil {
+		subjectDistance, _ := subjectDistanceRat.Float64()
+		newExif.SubjectDistance = &subjectDistance
+	}
+
+	artist, err := p.readStringTag(exifTags, exif.Artist, media_path)
+	if err == nil {
+		newExif.Artist = artist
+	}
+
+	copyright, err := p.readStringTag(exifTags, exif.Copyright, media_path)
+	if err == nil {
+		newExif.Copyright = copyright
+	}
+
+	// The serial number is only available from the maker notes of Canon and Nikon cameras
+	for _, serialField := range []exif.FieldName{mknote.SerialNumber, mknote.Nikon_SerialNO} {
+		serialTag, err := exifTags.Get(serialField)
+		if err != nil || serialTag == nil {
+			continue
+		}
+
+		if serial, err := serialTag.StringVal(); err == nil {
+			serial = strings.TrimPrefix(strings.TrimRight(serial, "\x00 "), "NO=")
+			newExif.CameraSerialNumber = &serial
+			break
+		}
+
+		if serial, err := serialTag.Int64(0); err == nil {
+			serialString := fmt.Sprintf("%d", serial)
+			newExif.CameraSerialNumber = &serialString
+			break
+		}
+	}
+
+	iptc, err := readIPTC(media_path)
+	if err != nil {
+		log.Printf("WARN: Could not read IPTC: %v\n%s\n", media_path, err)
+	} else if iptc != nil {
+		newExif.Caption = iptc.caption
+		newExif.Byline = iptc.byline
+		newExif.City = iptc.city
+		newExif.Country = iptc.country
+		newExif.SetKeywordList(iptc.keywords)
+	}
+
+	newExif.RawTags = p.readRawTags(exifTags)
+
+	// Convert the local date to UTC
+	applyTimezone(&newExif, p.readGPSTime(exifTags))
+
+	if _, err := readPanoramaXMP(media_path, &newExif); err != nil {
+		log.Printf("WARN: Could not read panorama XMP: %v\n%s\n", media_path, err)
+	}
+
 	returnExif = &newExif
 	return
 }
 
+// readRawTags returns a JSON object of all the decoded EXIF tags
+func (p *internalExifParser) readRawTags(tags *exif.Exif) *string {
+	rawTags := make(map[string]interface{})
+
+	tags.Walk(exif.WalkerFunc(func(name exif.FieldName, tag *tiff.Tag) error {
+		if tag == nil || name == exif.MakerNote {
+			return nil
+		}
+
+		switch tag.Format() {
+		case tiff.StringVal:
+			if value, err := tag.StringVal(); err == nil {
+				rawTags[string(name)] = strings.TrimRight(value, "\x00 ")
+			}
+		case tiff.IntVal:
+			if tag.Count == 1 {
+				if value, err := tag.Int64(0); err == nil {
+					rawTags[string(name)] = value
+				}
+			}
+		case tiff.RatVal:
+			if tag.Count == 1 {
+				if value, err := tag.Rat(0); err == nil {
+					rawTags[string(name)], _ = value.Float64()
+				}
+			}
+		}
+
+		return nil
+	}))
+
+	return encodeRawTags(rawTags)
+}
+
+// readGPSTime returns the time of the GPS fix, which is always in UTC
+func (p *internalExifParser) readGPSTime(tags *exif.Exif) *time.Time {
+	dateTag, err := tags.Get(exif.GPSDateStamp)
+	if err != nil || dateTag == nil {
+		return nil
+	}
+
+	date, err := dateTag.StringVal()
+	if err != nil {
+		return nil
+	}
+
+	timeTag, err := tags.Get(exif.GPSTimeStamp)
+	if err != nil || timeTag == nil || timeTag.Count < 3 {
+		return nil
+	}
+
+	var clock [3]float64
+	for i := range clock {
+		value, err := timeTag.Rat(i)
+		if err != nil {
+			return nil
+		}
+		clock[i], _ = value.Float64()
+	}
+
+	gpsDate, err := time.Parse("2006:01:02", strings.TrimRight(date, "\x00 "))
+	if err != nil {
+		return nil
+	}
+
+	gpsTime := gpsDate.Add(time.Duration(clock[0]*float64(time.Hour)) +
+		time.Duration(clock[1]*float64(time.Minute)) +
+		time.Duration(clock[2]*float64(time.Second)))
+
+	return &gpsTime
+}
+
+// Tags of the Exif sub-IFD with the offsets from UTC of the dates, which are not read by goexif
+const (
+	exifOffsetTimeTag         = 0x9010
+	exifOffsetTimeOriginalTag = 0x9011
+)
+
+// readOffsetTime returns the OffsetTimeOriginal, or otherwise the OffsetTime, of the Exif sub-IFD of the file
+func (p *internalExifParser) readOffsetTime(tags *exif.Exif, file *os.File) *string {
+	pointerTag, err := tags.Get(exif.ExifIFDPointer)
+	if err != nil {
+		return nil
+	}
+
+	dirOffset, err := pointerTag.Int64(0)
+	if err != nil {
+		return nil
+	}
+
+	reader, err := exifTiffReader(file)
+	if err != nil {
+		return nil
+	}
+
+	if _, err := reader.Seek(dirOffset, io.SeekStart); err != nil {
+		return nil
+	}
+
+	dir, _, err := tiff.DecodeDir(reader, tags.Tiff.Order)
+	if err != nil {
+		return nil
+	}
+
+	offsets := make(map[uint16]string)
+	for _, tag := range dir.Tags {
+		if tag.Id != exifOffsetTimeOriginalTag && tag.Id != exifOffsetTimeTag {
+			continue
+		}
+
+		if value, err := tag.StringVal(); err == nil {
+			offsets[tag.Id] = strings.TrimRight(value, "\x00 ")
+		}
+	}
+
+	for _, id := range []uint16{exifOffsetTimeOriginalTag, exifOffsetTimeTag} {
+		if offset, found := offsets[id]; found {
+			if _, ok := parseTimezoneOffset(offset); ok {
+				return &offset
+			}
+		}
+	}
+
+	return nil
+}
+
+// exifTiffReader returns a reader of the TIFF structure that holds the EXIF of the file,
+// which is the APP1 segment of jpeg files, and the file itself for TIFF based raw files
+func exifTiffReader(file *os.File) (tiff.ReadAtReaderSeeker, error) {
+	var start [2]byte
+	if _, err := file.ReadAt(start[:], 0); err != nil {
+		return nil, err
+	}
+
+	if start != [2]byte{0xFF, 0xD8} {
+		return io.NewSectionReader(file, 0, math.MaxInt64), nil
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(file, 2, math.MaxInt64-2))
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return nil, err
+		}
+
+		// The image data follows the start of scan segment
+		if header[0] != 0xFF || header[1] == 0xDA {
+			return nil, errors.New("no EXIF segment found")
+		}
+
+		length := int(binary.BigEndian.Uint16(header[2:])) - 2
+		if length < 0 {
+			return nil, errors.New("invalid jpeg segment")
+		}
+
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(reader, segment); err != nil {
+			return nil, err
+		}
+
+		if header[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return bytes.NewReader(segment[6:]), nil
+		}
+	}
+}
+
 func (p *internalExifParser) readStringTag(tags *exif.Exif, name exif.FieldName, media_path string) (*string, error) {
 	tag, err := tags.Get(name)
 	if err != nil {
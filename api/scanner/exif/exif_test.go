@@ -61,6 +61,20 @@ func TestExifParsers(t *testing.T) {
 				assert.EqualValues(t, *exif.Orientation, 1)
 				assert.InDelta(t, *exif.GPSLatitude, 65.01681388888889, 0.0001)
 				assert.InDelta(t, *exif.GPSLongitude, 25.466863888888888, 0.0001)
+				if assert.NotNil(t, exif.RawTags) {
+					assert.Contains(t, *exif.RawTags, "Canon EOS 600D")
+				}
+			},
+		},
+		{
+			path: "./test_data/offset-time.jpg",
+			assert: func(t *testing.T, exif *models.MediaEXIF) {
+				if assert.NotNil(t, exif.TimezoneOffset) {
+					assert.Equal(t, "+02:00", *exif.TimezoneOffset)
+				}
+				if assert.NotNil(t, exif.DateShot) {
+					assert.Equal(t, time.Date(2021, 9, 27, 14, 0, 0, 0, time.UTC), exif.DateShot.UTC())
+				}
 			},
 		},
 		{
@@ -107,3 +121,42 @@ func TestExifParsers(t *testing.T) {
 // 		assert.Equal(t, exif, &bird_exif)
 // 	}
 // }
+
+func TestRefreshDateShot(t *testing.T) {
+	test_utils.FilesystemTest(t)
+	db := test_utils.DatabaseTest(t)
+
+	exif.InitializeEXIFParser()
+
+	album := models.Album{Title: "test_data", Path: "./test_data"}
+	if !assert.NoError(t, db.Create(&album).Error) {
+		return
+	}
+
+	// The date of the photo as it was saved before dates were converted to UTC
+	localDate := time.Date(2012, 5, 6, 15, 39, 44, 0, time.UTC)
+
+	mediaExif := models.MediaEXIF{DateShot: &localDate, DateShotLocal: true}
+	media := models.Media{
+		Title:    "bird.jpg",
+		Path:     "./test_data/bird.jpg",
+		DateShot: localDate,
+		AlbumID:  album.ID,
+		Exif:     &mediaExif,
+	}
+	if !assert.NoError(t, db.Create(&media).Error) {
+		return
+	}
+
+	if !assert.NoError(t, exif.RefreshDateShot(db, &media)) {
+		return
+	}
+
+	assert.NoError(t, db.First(&mediaExif, mediaExif.ID).Error)
+	assert.False(t, mediaExif.DateShotLocal)
+	assert.WithinDuration(t, time.Unix(1336318784, 0).UTC(), *mediaExif.DateShot, time.Minute)
+
+	var updatedMedia models.Media
+	assert.NoError(t, db.First(&updatedMedia, media.ID).Error)
+	assert.True(t, mediaExif.DateShot.Equal(updatedMedia.DateShot))
+}
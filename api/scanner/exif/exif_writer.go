@@ -0,0 +1,78 @@
+package exif
+
+import (
+	"os"
+	"path"
+
+	"github.com/barasher/go-exiftool"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/media_type"
+	"github.com/pkg/errors"
+)
+
+// ErrWriteNotSupported is returned when metadata is written without exiftool being available
+var ErrWriteNotSupported = errors.New("exiftool is required to write metadata to files")
+
+// emptySidecar is the content of a new XMP sidecar, before exiftool has written any metadata to it
+const emptySidecar = `<?xpacket begin='` + "\uFEFF" + `' id='W5M0MpCehiHzreSzNTczkc9d'?>
+<x:xmpmeta xmlns:x='adobe:ns:meta/'>
+</x:xmpmeta>
+<?xpacket end='w'?>
+`
+
+// MetadataWriteTarget returns the path of the file that metadata of the media should be written to.
+// RAW files are never modified, their metadata is written to an XMP sidecar next to them instead.
+func MetadataWriteTarget(media *models.Media) (string, error) {
+	mediaType, err := media_type.GetMediaType(media.Path)
+	if err != nil {
+		return "", errors.Wrap(err, "get media type")
+	}
+
+	if !mediaType.IsRaw() {
+		return media.Path, nil
+	}
+
+	if media.SideCarPath != nil {
+		return *media.SideCarPath, nil
+	}
+
+	return media.Path + ".xmp", nil
+}
+
+// WriteMetadata writes the given exiftool tags to the original file of the media, or to its XMP sidecar.
+// A nil value removes the tag from the file.
+func WriteMetadata(media *models.Media, tags map[string]interface{}) error {
+	parser, ok := globalExifParser.(*externalExifParser)
+	if !ok || parser.et == nil {
+		return ErrWriteNotSupported
+	}
+
+	target, err := MetadataWriteTarget(media)
+	if err != nil {
+		return err
+	}
+
+	if target != media.Path {
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			if err := os.WriteFile(target, []byte(emptySidecar), 0644); err != nil {
+				return errors.Wrapf(err, "create XMP sidecar (%s)", path.Base(target))
+			}
+		}
+	}
+
+	metadata := []exiftool.FileMetadata{
+		{
+			File:   target,
+			Fields: tags,
+		},
+	}
+
+	parser.et.WriteMetadata(metadata)
+	parser.dataLoader.Clear(media.Path)
+
+	if metadata[0].Err != nil {
+		return errors.Wrapf(metadata[0].Err, "write metadata to file (%s)", path.Base(target))
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// iptcData holds the IPTC fields read by the internal exif parser
+type iptcData struct {
+	caption  *string
+	byline   *string
+	city     *string
+	country  *string
+	keywords []string
+}
+
+// IPTC IIM datasets of the application record
+const (
+	iptcApplicationRecord = 2
+	iptcKeywords          = 25
+	iptcByline            = 80
+	iptcCity              = 90
+	iptcCountry           = 101
+	iptcCaption           = 120
+)
+
+// Photoshop image resource holding the IPTC IIM data
+const photoshopIPTCResource = 0x0404
+
+var photoshopHeader = []byte("Photoshop 3.0\x00")
+
+// readIPTC reads the IPTC fields from the Photoshop APP13 segment of a JPEG file.
+// Returns nil if the file has no IPTC data.
+func readIPTC(media_path string) (*iptcData, error) {
+	file, err := os.Open(media_path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open media to read IPTC")
+	}
+	defer file.Close()
+
+	segment, err := findJPEGSegment(bufio.NewReader(file), 0xED, photoshopHeader)
+	if err != nil || segment == nil {
+		return nil, err
+	}
+
+	iim := findPhotoshopResource(segment[len(photoshopHeader):], photoshopIPTCResource)
+	if iim == nil {
+		return nil, nil
+	}
+
+	return parseIPTC(iim), nil
+}
+
+// findJPEGSegment returns the data of the first segment with the given marker, that starts with prefix
+func findJPEGSegment(reader io.Reader, marker byte, prefix []byte) ([]byte, error) {
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(reader, soi); err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		return nil, nil
+	}
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return nil, nil
+		}
+
+		if header[0] != 0xFF {
+			return nil, nil
+		}
+
+		// Start of scan, no more metadata segments follow
+		if header[1] == 0xDA || header[1] == 0xD9 {
+			return nil, nil
+		}
+
+		length := int(binary.BigEndian.Uint16(header[2:4])) - 2
+		if length < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, errors.Wrap(err, "read jpeg segment")
+		}
+
+		if header[1] == marker && bytes.HasPrefix(data, prefix) {
+			return data, nil
+		}
+	}
+}
+
+// findPhotoshopResource returns the data of the 8BIM image resource with the given id
+func findPhotoshopResource(data []byte, id uint16) []byte {
+	for len(data) >= 12 && bytes.Equal(data[0:4], []byte("8BIM")) {
+		resourceID := binary.BigEndian.Uint16(data[4:6])
+
+		// Pascal string name, padded to an even size
+		nameLength := int(data[6]) + 1
+		if nameLength%2 != 0 {
+			nameLength++
+		}
+
+		sizeOffset := 6 + nameLength
+		if len(data) < sizeOffset+4 {
+			return nil
+		}
+
+		size := int(binary.BigEndian.Uint32(data[sizeOffset : sizeOffset+4]))
+		start := sizeOffset + 4
+		if size < 0 || len(data) < start+size {
+			return nil
+		}
+
+		if resourceID == id {
+			return data[start : start+size]
+		}
+
+		if size%2 != 0 {
+			size++
+		}
+
+		if len(data) < start+size {
+			return nil
+		}
+		data = data[start+size:]
+	}
+
+	return nil
+}
+
+// parseIPTC reads the fields of interest from IPTC IIM data
+func parseIPTC(data []byte) *iptcData {
+	result := iptcData{}
+
+	for len(data) >= 5 && data[0] == 0x1C {
+		record := data[1]
+		dataset := data[2]
+		size := int(binary.BigEndian.Uint16(data[3:5]))
+
+		// Extended datasets are not used by any of the fields of interest
+		if size&0x8000 != 0 || len(data) < 5+size {
+			break
+		}
+
+		value := string(bytes.TrimRight(data[5:5+size], "\x00"))
+		data = data[5+size:]
+
+		if record != iptcApplicationRecord || value == "" {
+			continue
+		}
+
+		switch dataset {
+		case iptcCaption:
+			result.caption = &value
+		case iptcByline:
+			result.byline = &value
+		case iptcCity:
+			result.city = &value
+		case iptcCountry:
+			result.country = &value
+		case iptcKeywords:
+			result.keywords = append(result.keywords, value)
+		}
+	}
+
+	return &result
+}
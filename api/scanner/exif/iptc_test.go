@@ -0,0 +1,89 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeIPTCDataset(dataset byte, value string) []byte {
+	result := []byte{0x1C, iptcApplicationRecord, dataset, 0, 0}
+	binary.BigEndian.PutUint16(result[3:5], uint16(len(value)))
+	return append(result, value...)
+}
+
+func makeJPEGSegment(marker byte, data []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:4], uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+func TestReadIPTC(t *testing.T) {
+	iim := bytes.Join([][]byte{
+		{0x1C, 0x01, 0x5A, 0x00, 0x03, 0x1B, 0x25, 0x47},
+		makeIPTCDataset(iptcCaption, "Photo of a Bird"),
+		makeIPTCDataset(iptcByline, "Jane Doe"),
+		makeIPTCDataset(iptcCity, "Oulu"),
+		makeIPTCDataset(iptcCountry, "Finland"),
+		makeIPTCDataset(iptcKeywords, "bird"),
+		makeIPTCDataset(iptcKeywords, "nature"),
+	}, nil)
+
+	resource := []byte("8BIM\x04\x04\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint32(resource[8:12], uint32(len(iim)))
+	resource = append(resource, iim...)
+
+	otherResource := []byte("8BIM\x04\x25\x03abc\x00\x00\x00\x03xyz\x00")
+
+	app13 := append(append(append([]byte{}, photoshopHeader...), otherResource...), resource...)
+
+	content := bytes.Join([][]byte{
+		{0xFF, 0xD8},
+		makeJPEGSegment(0xE0, []byte("JFIF\x00")),
+		makeJPEGSegment(0xED, app13),
+		{0xFF, 0xDA},
+	}, nil)
+
+	filePath := path.Join(t.TempDir(), "iptc.jpg")
+	assert.NoError(t, os.WriteFile(filePath, content, 0644))
+
+	iptc, err := readIPTC(filePath)
+	assert.NoError(t, err)
+	if assert.NotNil(t, iptc) {
+		assert.Equal(t, "Photo of a Bird", *iptc.caption)
+		assert.Equal(t, "Jane Doe", *iptc.byline)
+		assert.Equal(t, "Oulu", *iptc.city)
+		assert.Equal(t, "Finland", *iptc.country)
+		assert.Equal(t, []string{"bird", "nature"}, iptc.keywords)
+	}
+
+	t.Run("without IPTC", func(t *testing.T) {
+		filePath := path.Join(t.TempDir(), "plain.jpg")
+		assert.NoError(t, os.WriteFile(filePath, []byte{0xFF, 0xD8, 0xFF, 0xDA}, 0644))
+
+		iptc, err := readIPTC(filePath)
+		assert.NoError(t, err)
+		assert.Nil(t, iptc)
+	})
+}
+
+func TestEncodeRawTags(t *testing.T) {
+	rawTags := encodeRawTags(map[string]interface{}{
+		"SourceFile":     "/photos/bird.jpg",
+		"FileSize":       1234,
+		"Make":           "Canon",
+		"ISO":            800,
+		"ThumbnailImage": "(Binary data 1234 bytes, use -b option to extract)",
+		"DepthImage":     "(Binary data 5678 bytes, use -b option to extract)",
+	})
+
+	if assert.NotNil(t, rawTags) {
+		assert.JSONEq(t, `{"Make": "Canon", "ISO": 800}`, *rawTags)
+	}
+
+	assert.Nil(t, encodeRawTags(map[string]interface{}{"SourceFile": "/photos/bird.jpg"}))
+}
@@ -0,0 +1,46 @@
+package exif
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Values longer than this are left out of the raw tags, as they are most likely binary data
+const rawTagMaxLength = 1024
+
+// Tags describing the file rather than the media, that are left out of the raw tags
+var ignoredRawTagPrefixes = []string{"SourceFile", "File", "Directory", "ExifToolVersion", "ThumbnailImage", "PreviewImage"}
+
+// encodeRawTags encodes the metadata tags as a JSON object, leaving out binary data and file system information
+func encodeRawTags(tags map[string]interface{}) *string {
+	rawTags := make(map[string]interface{}, len(tags))
+
+tagLoop:
+	for key, value := range tags {
+		for _, prefix := range ignoredRawTagPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				continue tagLoop
+			}
+		}
+
+		if str, ok := value.(string); ok {
+			if len(str) > rawTagMaxLength || strings.HasPrefix(str, "(Binary data") {
+				continue
+			}
+		}
+
+		rawTags[key] = value
+	}
+
+	if len(rawTags) == 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(rawTags)
+	if err != nil {
+		return nil
+	}
+
+	result := string(encoded)
+	return &result
+}
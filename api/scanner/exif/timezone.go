@@ -0,0 +1,100 @@
+package exif
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+)
+
+// Largest offset from UTC of any time zone
+const maxTimezoneOffset = 14 * time.Hour
+
+// Time zones are all a multiple of 15 minutes from UTC
+const timezoneOffsetStep = 15 * time.Minute
+
+var timezoneOffsetRegex = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})$`)
+
+// parseTimezoneOffset parses a timezone offset such as +02:00, -0530 or Z
+func parseTimezoneOffset(offset string) (time.Duration, bool) {
+	if offset == "Z" {
+		return 0, true
+	}
+
+	match := timezoneOffsetRegex.FindStringSubmatch(offset)
+	if match == nil {
+		return 0, false
+	}
+
+	hours, _ := strconv.Atoi(match[2])
+	minutes, _ := strconv.Atoi(match[3])
+
+	duration := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if match[1] == "-" {
+		duration = -duration
+	}
+
+	if duration > maxTimezoneOffset || duration < -maxTimezoneOffset {
+		return 0, false
+	}
+
+	return duration, true
+}
+
+// formatTimezoneOffset formats a timezone offset as +02:00
+func formatTimezoneOffset(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	return fmt.Sprintf("%s%02d:%02d", sign, int(offset.Hours()), int(offset.Minutes())%60)
+}
+
+// applyTimezone converts the DateShot of the exif, which is the local time of the camera, to UTC.
+// The offset is read from the TimezoneOffset, or otherwise derived from the GPS time which is always in UTC.
+// If neither is known, the date is kept as is.
+func applyTimezone(exif *models.MediaEXIF, gpsTime *time.Time) {
+	if exif.DateShot == nil {
+		return
+	}
+
+	if exif.TimezoneOffset != nil {
+		if offset, ok := parseTimezoneOffset(*exif.TimezoneOffset); ok {
+			utcDate := exif.DateShot.Add(-offset)
+			exif.DateShot = &utcDate
+			return
+		}
+	}
+
+	if gpsTime == nil {
+		return
+	}
+
+	// The GPS fix is usually taken a moment before the photo, so round to the nearest time zone
+	offset := exif.DateShot.Sub(*gpsTime).Round(timezoneOffsetStep)
+	if offset > maxTimezoneOffset || offset < -maxTimezoneOffset {
+		return
+	}
+
+	timezoneOffset := formatTimezoneOffset(offset)
+	exif.TimezoneOffset = &timezoneOffset
+
+	utcDate := exif.DateShot.Add(-offset)
+	exif.DateShot = &utcDate
+}
+
+// LocalDateShot returns the date the media was shot in the local time of the camera,
+// using the timezone offset of the exif if known
+func LocalDateShot(dateShot time.Time, exif *models.MediaEXIF) time.Time {
+	if exif != nil && exif.TimezoneOffset != nil {
+		if offset, ok := parseTimezoneOffset(*exif.TimezoneOffset); ok {
+			return dateShot.In(time.FixedZone(*exif.TimezoneOffset, int(offset.Seconds())))
+		}
+	}
+
+	return dateShot.UTC()
+}
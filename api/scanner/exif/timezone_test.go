@@ -0,0 +1,67 @@
+package exif
+
+import (
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimezoneOffset(t *testing.T) {
+	tests := map[string]time.Duration{
+		"+02:00": 2 * time.Hour,
+		"-05:30": -(5*time.Hour + 30*time.Minute),
+		"+0545":  5*time.Hour + 45*time.Minute,
+		"Z":      0,
+	}
+
+	for input, expected := range tests {
+		offset, ok := parseTimezoneOffset(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, offset, input)
+	}
+
+	for _, invalid := range []string{"", "02:00", "+15:00", "UTC"} {
+		_, ok := parseTimezoneOffset(invalid)
+		assert.False(t, ok, invalid)
+	}
+
+	assert.Equal(t, "-05:30", formatTimezoneOffset(-(5*time.Hour + 30*time.Minute)))
+	assert.Equal(t, "+00:00", formatTimezoneOffset(0))
+}
+
+func TestApplyTimezone(t *testing.T) {
+	localDate := time.Date(2021, 9, 27, 16, 0, 0, 0, time.UTC)
+
+	t.Run("from offset", func(t *testing.T) {
+		offset := "+02:00"
+		exif := models.MediaEXIF{DateShot: &localDate, TimezoneOffset: &offset}
+
+		applyTimezone(&exif, nil)
+		assert.Equal(t, time.Date(2021, 9, 27, 14, 0, 0, 0, time.UTC), *exif.DateShot)
+
+		local := LocalDateShot(*exif.DateShot, &exif)
+		_, zoneOffset := local.Zone()
+		assert.Equal(t, 16, local.Hour())
+		assert.Equal(t, 2*60*60, zoneOffset)
+	})
+
+	t.Run("from gps time", func(t *testing.T) {
+		gpsTime := time.Date(2021, 9, 27, 21, 29, 48, 0, time.UTC)
+		exif := models.MediaEXIF{DateShot: &localDate}
+
+		applyTimezone(&exif, &gpsTime)
+		assert.Equal(t, "-05:30", *exif.TimezoneOffset)
+		assert.Equal(t, time.Date(2021, 9, 27, 21, 30, 0, 0, time.UTC), *exif.DateShot)
+	})
+
+	t.Run("gps time too far off", func(t *testing.T) {
+		gpsTime := localDate.Add(-48 * time.Hour)
+		exif := models.MediaEXIF{DateShot: &localDate}
+
+		applyTimezone(&exif, &gpsTime)
+		assert.Nil(t, exif.TimezoneOffset)
+		assert.Equal(t, localDate, *exif.DateShot)
+	})
+}
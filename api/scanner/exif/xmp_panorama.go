@@ -0,0 +1,121 @@
+package exif
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+)
+
+// Number of bytes at the start of a file searched for the XMP packet
+const xmpHeaderSize = 256 * 1024
+
+// depthMapRegex matches the GDepth metadata of Google portrait images,
+// and the depth items of Dynamic Depth containers
+var depthMapRegex = regexp.MustCompile(`GDepth:Data|GDepth:Format|Item:Semantic="Depth"`)
+
+// xmpPropertyRegex matches an XMP property written either as an attribute or as an element,
+// capturing its prefixed name and its value
+var xmpPropertyRegex = regexp.MustCompile(`([A-Za-z][\w.-]*:[A-Za-z][\w.-]*)(?:="([^"]*)"|>([^<]*)<)`)
+
+// xmpProperties returns the values of the XMP properties with the given prefix,
+// keeping the first value of properties that occur more than once
+func xmpProperties(xmp []byte, prefix string) map[string]string {
+	properties := make(map[string]string)
+
+	for _, match := range xmpPropertyRegex.FindAllSubmatch(xmp, -1) {
+		name := string(match[1])
+		if !strings.HasPrefix(name, prefix+":") {
+			continue
+		}
+
+		if _, found := properties[name]; found {
+			continue
+		}
+
+		value := string(match[2])
+		if value == "" {
+			value = string(match[3])
+		}
+
+		properties[name] = value
+	}
+
+	return properties
+}
+
+func xmpIntValue(properties map[string]string, tag string) *int64 {
+	value, found := properties[tag]
+	if !found {
+		return nil
+	}
+
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &result
+}
+
+func xmpFloatValue(properties map[string]string, tag string) *float64 {
+	value, found := properties[tag]
+	if !found {
+		return nil
+	}
+
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil || !isFloatReal(result) {
+		return nil
+	}
+
+	return &result
+}
+
+// readPanoramaXMP reads the GPano projection tags and the presence of a depth map
+// from the XMP packet at the start of the file, and adds them to exif.
+// Returns true if any panorama or depth metadata was found.
+func readPanoramaXMP(media_path string, exif *models.MediaEXIF) (bool, error) {
+	file, err := os.Open(media_path)
+	if err != nil {
+		return false, errors.Wrap(err, "open media to read XMP")
+	}
+	defer file.Close()
+
+	header := make([]byte, xmpHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, errors.Wrap(err, "read XMP from media")
+	}
+
+	return parsePanoramaXMP(header[:n], exif), nil
+}
+
+func parsePanoramaXMP(xmp []byte, exif *models.MediaEXIF) bool {
+	found := false
+
+	gpano := xmpProperties(xmp, "GPano")
+
+	if projection := gpano["GPano:ProjectionType"]; projection != "" {
+		found = true
+		exif.ProjectionType = &projection
+		exif.FullPanoWidth = xmpIntValue(gpano, "GPano:FullPanoWidthPixels")
+		exif.FullPanoHeight = xmpIntValue(gpano, "GPano:FullPanoHeightPixels")
+		exif.CroppedAreaWidth = xmpIntValue(gpano, "GPano:CroppedAreaImageWidthPixels")
+		exif.CroppedAreaHeight = xmpIntValue(gpano, "GPano:CroppedAreaImageHeightPixels")
+		exif.CroppedAreaLeft = xmpIntValue(gpano, "GPano:CroppedAreaLeftPixels")
+		exif.CroppedAreaTop = xmpIntValue(gpano, "GPano:CroppedAreaTopPixels")
+		exif.PoseHeading = xmpFloatValue(gpano, "GPano:PoseHeadingDegrees")
+	}
+
+	if depthMapRegex.Match(xmp) {
+		found = true
+		exif.HasDepthMap = true
+	}
+
+	return found
+}
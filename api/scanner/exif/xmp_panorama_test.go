@@ -0,0 +1,73 @@
+package exif
+
+import (
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePanoramaXMP(t *testing.T) {
+	t.Run("photo sphere attributes", func(t *testing.T) {
+		xmp := []byte(`<x:xmpmeta><rdf:Description
+			GPano:ProjectionType="equirectangular"
+			GPano:UsePanoramaViewer="True"
+			GPano:CroppedAreaImageWidthPixels="8000"
+			GPano:CroppedAreaImageHeightPixels="2000"
+			GPano:FullPanoWidthPixels="8000"
+			GPano:FullPanoHeightPixels="4000"
+			GPano:CroppedAreaLeftPixels="0"
+			GPano:CroppedAreaTopPixels="1000"
+			GPano:PoseHeadingDegrees="271.5"/></x:xmpmeta>`)
+
+		exif := models.MediaEXIF{}
+		assert.True(t, parsePanoramaXMP(xmp, &exif))
+
+		assert.Equal(t, "equirectangular", *exif.ProjectionType)
+		assert.Equal(t, models.MediaProjectionEquirectangular, exif.Projection())
+		assert.EqualValues(t, 8000, *exif.FullPanoWidth)
+		assert.EqualValues(t, 4000, *exif.FullPanoHeight)
+		assert.EqualValues(t, 8000, *exif.CroppedAreaWidth)
+		assert.EqualValues(t, 2000, *exif.CroppedAreaHeight)
+		assert.EqualValues(t, 0, *exif.CroppedAreaLeft)
+		assert.EqualValues(t, 1000, *exif.CroppedAreaTop)
+		assert.InDelta(t, 271.5, *exif.PoseHeading, 0.001)
+		assert.False(t, exif.HasDepthMap)
+	})
+
+	t.Run("panorama elements", func(t *testing.T) {
+		xmp := []byte(`<rdf:Description><GPano:ProjectionType>cylindrical</GPano:ProjectionType>
+			<GPano:FullPanoWidthPixels>12000</GPano:FullPanoWidthPixels></rdf:Description>`)
+
+		exif := models.MediaEXIF{}
+		assert.True(t, parsePanoramaXMP(xmp, &exif))
+		assert.Equal(t, models.MediaProjectionCylindrical, exif.Projection())
+		assert.EqualValues(t, 12000, *exif.FullPanoWidth)
+		assert.Nil(t, exif.FullPanoHeight)
+	})
+
+	t.Run("depth map", func(t *testing.T) {
+		xmp := []byte(`<rdf:Description GDepth:Format="RangeInverse" GDepth:Mime="image/jpeg" GDepth:Data="/9j/4AAQ"/>`)
+
+		exif := models.MediaEXIF{}
+		assert.True(t, parsePanoramaXMP(xmp, &exif))
+		assert.True(t, exif.HasDepthMap)
+		assert.Nil(t, exif.ProjectionType)
+		assert.Equal(t, models.MediaProjectionFlat, exif.Projection())
+	})
+
+	t.Run("flat image", func(t *testing.T) {
+		exif := models.MediaEXIF{}
+		assert.False(t, parsePanoramaXMP([]byte(`<rdf:Description tiff:Make="Canon"/>`), &exif))
+		assert.Equal(t, models.MediaProjectionFlat, exif.Projection())
+	})
+	t.Run("first of repeated properties", func(t *testing.T) {
+		xmp := []byte(`<rdf:Description GPano:ProjectionType="cylindrical" GPano:FullPanoWidthPixels="6000"/>
+			<rdf:Description GPano:ProjectionType="equirectangular" GPano:FullPanoWidthPixels="9000"/>`)
+
+		exif := models.MediaEXIF{}
+		assert.True(t, parsePanoramaXMP(xmp, &exif))
+		assert.Equal(t, models.MediaProjectionCylindrical, exif.Projection())
+		assert.EqualValues(t, 6000, *exif.FullPanoWidth)
+	})
+}
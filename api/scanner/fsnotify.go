@@ -150,6 +150,14 @@ func deleteFile(watcher *inotify.Watcher, db *gorm.DB, user *models.User, filePa
 	cachePath := path.Join(utils.MediaCachePath(), strconv.Itoa(int(media.AlbumID)), strconv.Itoa(int(media.ID)))
 	os.RemoveAll(cachePath)
 	db.Delete(media)
+
+	err := models.RecordAuditEvent(db, nil, models.AuditActionDeleteMedia, models.AuditTargetMedia(&media), map[string]interface{}{
+		"album_id": media.AlbumID,
+		"source":   "filesystem",
+	})
+	if err != nil {
+		log.Println(err)
+	}
 }
 
 func deleteDir(watcher *inotify.Watcher, db *gorm.DB, user *models.User, filePath string) {
@@ -172,7 +180,9 @@ func deleteDir(watcher *inotify.Watcher, db *gorm.DB, user *models.User, filePat
 			return err
 		}
 
-		return nil
+		return models.RecordAuditEvent(tx, nil, models.AuditActionDeleteAlbum, models.AuditTargetAlbum(&album), map[string]interface{}{
+			"source": "filesystem",
+		})
 	})
 }
 
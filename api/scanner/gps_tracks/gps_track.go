@@ -0,0 +1,149 @@
+package gps_tracks
+
+import (
+	"sort"
+	"time"
+)
+
+// TrackPoint is a position recorded by a GPS logger
+type TrackPoint struct {
+	Time      time.Time
+	Latitude  float64
+	Longitude float64
+}
+
+// Track is a list of positions recorded by a GPS logger, ordered by time
+type Track struct {
+	Points []TrackPoint
+}
+
+// TrackLocation is the position on a track at a given time
+type TrackLocation struct {
+	Latitude  float64
+	Longitude float64
+	// Interpolated is set if the location lies between two points of the track, rather than at one of them
+	Interpolated bool
+	// TimeDifference is the time between the given time and the nearest point of the track
+	TimeDifference time.Duration
+}
+
+// NewTrack returns a track of the points, which are sorted by time
+func NewTrack(points []TrackPoint) *Track {
+	sorted := make([]TrackPoint, len(points))
+	copy(sorted, points)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	return &Track{Points: sorted}
+}
+
+// MergeTracks combines the points of multiple tracks into a single track
+func MergeTracks(tracks ...*Track) *Track {
+	points := make([]TrackPoint, 0)
+	for _, track := range tracks {
+		points = append(points, track.Points...)
+	}
+
+	return NewTrack(points)
+}
+
+// Start returns the time of the first point of the track
+func (t *Track) Start() time.Time {
+	if len(t.Points) == 0 {
+		return time.Time{}
+	}
+	return t.Points[0].Time
+}
+
+// End returns the time of the last point of the track
+func (t *Track) End() time.Time {
+	if len(t.Points) == 0 {
+		return time.Time{}
+	}
+	return t.Points[len(t.Points)-1].Time
+}
+
+// Locate returns the position on the track at the given time.
+// Between two points no more than maxGap apart, the position is interpolated linearly.
+// Otherwise the nearest point is used, if it is within maxGap of the time.
+func (t *Track) Locate(date time.Time, maxGap time.Duration) (TrackLocation, bool) {
+	if len(t.Points) == 0 {
+		return TrackLocation{}, false
+	}
+
+	// Index of the first point at or after the date
+	next := sort.Search(len(t.Points), func(i int) bool {
+		return !t.Points[i].Time.Before(date)
+	})
+
+	if next < len(t.Points) && t.Points[next].Time.Equal(date) {
+		point := t.Points[next]
+		return TrackLocation{Latitude: point.Latitude, Longitude: point.Longitude}, true
+	}
+
+	if next > 0 && next < len(t.Points) {
+		before := t.Points[next-1]
+		after := t.Points[next]
+
+		if gap := after.Time.Sub(before.Time); gap <= maxGap {
+			fraction := float64(date.Sub(before.Time)) / float64(gap)
+
+			// Take the shortest way around when the segment crosses the antimeridian
+			longitudeDelta := after.Longitude - before.Longitude
+			if longitudeDelta > 180 {
+				longitudeDelta -= 360
+			} else if longitudeDelta < -180 {
+				longitudeDelta += 360
+			}
+
+			longitude := before.Longitude + longitudeDelta*fraction
+			if longitude > 180 {
+				longitude -= 360
+			} else if longitude < -180 {
+				longitude += 360
+			}
+
+			return TrackLocation{
+				Latitude:       before.Latitude + (after.Latitude-before.Latitude)*fraction,
+				Longitude:      longitude,
+				Interpolated:   true,
+				TimeDifference: minDuration(date.Sub(before.Time), after.Time.Sub(date)),
+			}, true
+		}
+	}
+
+	// Fall back to the nearest point, eg. just before the track starts or after it ends
+	var nearest *TrackPoint
+	var difference time.Duration
+
+	if next > 0 {
+		nearest = &t.Points[next-1]
+		difference = date.Sub(nearest.Time)
+	}
+
+	if next < len(t.Points) {
+		if afterDifference := t.Points[next].Time.Sub(date); nearest == nil || afterDifference < difference {
+			nearest = &t.Points[next]
+			difference = afterDifference
+		}
+	}
+
+	if difference > maxGap {
+		return TrackLocation{}, false
+	}
+
+	return TrackLocation{
+		Latitude:       nearest.Latitude,
+		Longitude:      nearest.Longitude,
+		TimeDifference: difference,
+	}, true
+}
+
+func minDuration(a time.Duration, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,152 @@
+package gps_tracks_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/scanner/gps_tracks"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = flag.Bool("database", false, "run database integration tests")
+var _ = flag.Bool("filesystem", false, "run filesystem integration tests")
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>Walk</name>
+    <trkseg>
+      <trkpt lat="60.1000" lon="24.9000"><ele>10</ele><time>2021-06-01T10:00:00Z</time></trkpt>
+      <trkpt lat="60.2000" lon="25.0000"><ele>12</ele><time>2021-06-01T10:02:00Z</time></trkpt>
+      <trkpt lat="60.3000" lon="25.1000"></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="61.0000" lon="26.0000"><time>2021-06-01T12:00:00+02:00</time></trkpt>
+    </trkseg>
+  </trk>
+  <wpt lat="59.0000" lon="24.0000"><time>2021-06-01T09:00:00Z</time></wpt>
+</gpx>`
+
+const testKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+  <Document>
+    <Folder>
+      <Placemark>
+        <gx:Track>
+          <when>2021-06-01T10:00:00Z</when>
+          <when>2021-06-01T10:02:00Z</when>
+          <gx:coord>24.9 60.1 10</gx:coord>
+          <gx:coord>25.0 60.2 12</gx:coord>
+        </gx:Track>
+      </Placemark>
+      <Placemark>
+        <TimeStamp><when>2021-06-01T09:00:00Z</when></TimeStamp>
+        <Point><coordinates>24.0,59.0,0</coordinates></Point>
+      </Placemark>
+    </Folder>
+  </Document>
+</kml>`
+
+func TestParseTrack(t *testing.T) {
+	t.Run("GPX", func(t *testing.T) {
+		track, err := gps_tracks.ParseTrack(strings.NewReader(testGPX))
+		if !assert.NoError(t, err) || !assert.Len(t, track.Points, 4) {
+			return
+		}
+
+		assert.Equal(t, time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC), track.Start())
+		assert.Equal(t, time.Date(2021, 6, 1, 10, 2, 0, 0, time.UTC), track.End())
+		assert.Equal(t, 61.0, track.Points[2].Latitude)
+		assert.Equal(t, 59.0, track.Points[0].Latitude)
+		assert.Equal(t, 60.1, track.Points[1].Latitude)
+		assert.Equal(t, 24.9, track.Points[1].Longitude)
+	})
+
+	t.Run("KML", func(t *testing.T) {
+		track, err := gps_tracks.ParseTrack(strings.NewReader(testKML))
+		if !assert.NoError(t, err) || !assert.Len(t, track.Points, 3) {
+			return
+		}
+
+		assert.Equal(t, gps_tracks.TrackPoint{Time: time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC), Latitude: 59, Longitude: 24}, track.Points[0])
+		assert.Equal(t, gps_tracks.TrackPoint{Time: time.Date(2021, 6, 1, 10, 2, 0, 0, time.UTC), Latitude: 60.2, Longitude: 25}, track.Points[2])
+	})
+
+	t.Run("Invalid files", func(t *testing.T) {
+		_, err := gps_tracks.ParseTrack(strings.NewReader(`<svg></svg>`))
+		assert.ErrorIs(t, err, gps_tracks.ErrUnknownTrackFormat)
+
+		_, err = gps_tracks.ParseTrack(strings.NewReader(`not xml`))
+		assert.Error(t, err)
+
+		_, err = gps_tracks.ParseTrack(strings.NewReader(`<gpx><trk><trkseg><trkpt lat="1" lon="2"/></trkseg></trk></gpx>`))
+		assert.Error(t, err)
+	})
+}
+
+func TestTrackLocate(t *testing.T) {
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	track := gps_tracks.NewTrack([]gps_tracks.TrackPoint{
+		{Time: start.Add(2 * time.Minute), Latitude: 60.2, Longitude: 25.0},
+		{Time: start, Latitude: 60.0, Longitude: 24.0},
+		{Time: start.Add(time.Hour), Latitude: 61.0, Longitude: 26.0},
+		{Time: start.Add(time.Hour + time.Minute), Latitude: -17.0, Longitude: 179.0},
+		{Time: start.Add(time.Hour + 2*time.Minute), Latitude: -17.0, Longitude: -179.0},
+	})
+
+	maxGap := 5 * time.Minute
+
+	t.Run("At a point", func(t *testing.T) {
+		location, found := track.Locate(start, maxGap)
+		assert.True(t, found)
+		assert.Equal(t, gps_tracks.TrackLocation{Latitude: 60.0, Longitude: 24.0}, location)
+	})
+
+	t.Run("Interpolated", func(t *testing.T) {
+		location, found := track.Locate(start.Add(30*time.Second), maxGap)
+		assert.True(t, found)
+		assert.True(t, location.Interpolated)
+		assert.InDelta(t, 60.05, location.Latitude, 0.0001)
+		assert.InDelta(t, 24.25, location.Longitude, 0.0001)
+		assert.Equal(t, 30*time.Second, location.TimeDifference)
+	})
+
+	t.Run("Interpolated across the antimeridian", func(t *testing.T) {
+		location, found := track.Locate(start.Add(time.Hour+90*time.Second), maxGap)
+		assert.True(t, found)
+		assert.InDelta(t, 180.0, abs(location.Longitude), 0.0001)
+	})
+
+	t.Run("Gap in the track", func(t *testing.T) {
+		location, found := track.Locate(start.Add(5*time.Minute), maxGap)
+		assert.True(t, found)
+		assert.False(t, location.Interpolated)
+		assert.Equal(t, 60.2, location.Latitude)
+		assert.Equal(t, 3*time.Minute, location.TimeDifference)
+
+		_, found = track.Locate(start.Add(30*time.Minute), maxGap)
+		assert.False(t, found)
+	})
+
+	t.Run("Outside the track", func(t *testing.T) {
+		location, found := track.Locate(start.Add(-time.Minute), maxGap)
+		assert.True(t, found)
+		assert.Equal(t, 60.0, location.Latitude)
+
+		_, found = track.Locate(start.Add(-10*time.Minute), maxGap)
+		assert.False(t, found)
+
+		_, found = track.Locate(start.Add(2*time.Hour), maxGap)
+		assert.False(t, found)
+	})
+}
+
+func abs(value float64) float64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
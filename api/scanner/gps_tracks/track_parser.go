@@ -0,0 +1,228 @@
+package gps_tracks
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Largest track file that will be read
+const maxTrackFileSize = 64 * 1024 * 1024
+
+// ErrUnknownTrackFormat is returned when a track file is neither GPX nor KML
+var ErrUnknownTrackFormat = errors.New("unknown track format, expected GPX or KML")
+
+type gpxPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Time      string  `xml:"time"`
+}
+
+type gpxDocument struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+	Waypoints []gpxPoint `xml:"wpt"`
+}
+
+// kmlTrack is a gx:Track, where every <when> is followed by the <gx:coord> at that time
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"coord"`
+}
+
+type kmlPlacemark struct {
+	When        string     `xml:"TimeStamp>when"`
+	Coordinates string     `xml:"Point>coordinates"`
+	Tracks      []kmlTrack `xml:"Track"`
+	MultiTracks []kmlTrack `xml:"MultiTrack>Track"`
+}
+
+// ParseTrack reads a GPX or KML file, the format is detected from its root element.
+// Points without a time can not be matched to media and are skipped.
+func ParseTrack(reader io.Reader) (*Track, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, maxTrackFileSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "read track file")
+	}
+
+	if len(data) > maxTrackFileSize {
+		return nil, errors.New("track file is too large")
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, errors.Wrap(err, "parse track file")
+	}
+
+	var points []TrackPoint
+	switch strings.ToLower(root.XMLName.Local) {
+	case "gpx":
+		points, err = parseGPX(data)
+	case "kml":
+		points, err = parseKML(data)
+	default:
+		return nil, ErrUnknownTrackFormat
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(points) == 0 {
+		return nil, errors.New("track file contains no points with a time")
+	}
+
+	return NewTrack(points), nil
+}
+
+func parseGPX(data []byte) ([]TrackPoint, error) {
+	var document gpxDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return nil, errors.Wrap(err, "parse GPX file")
+	}
+
+	gpxPoints := make([]gpxPoint, 0)
+	for _, track := range document.Tracks {
+		for _, segment := range track.Segments {
+			gpxPoints = append(gpxPoints, segment.Points...)
+		}
+	}
+
+	for _, route := range document.Routes {
+		gpxPoints = append(gpxPoints, route.Points...)
+	}
+
+	gpxPoints = append(gpxPoints, document.Waypoints...)
+
+	points := make([]TrackPoint, 0, len(gpxPoints))
+	for _, point := range gpxPoints {
+		date, ok := parseTrackTime(point.Time)
+		if !ok || !validCoordinates(point.Latitude, point.Longitude) {
+			continue
+		}
+
+		points = append(points, TrackPoint{Time: date, Latitude: point.Latitude, Longitude: point.Longitude})
+	}
+
+	return points, nil
+}
+
+func parseKML(data []byte) ([]TrackPoint, error) {
+	points := make([]TrackPoint, 0)
+
+	addTrack := func(track kmlTrack) {
+		for i := 0; i < len(track.When) && i < len(track.Coord); i++ {
+			date, ok := parseTrackTime(track.When[i])
+			if !ok {
+				continue
+			}
+
+			// gx:coord is longitude, latitude and altitude separated by spaces
+			if latitude, longitude, ok := parseKMLCoordinates(strings.Fields(track.Coord[i])); ok {
+				points = append(points, TrackPoint{Time: date, Latitude: latitude, Longitude: longitude})
+			}
+		}
+	}
+
+	// Placemarks and tracks can be nested in any number of documents and folders
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "parse KML file")
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Placemark":
+			var placemark kmlPlacemark
+			if err := decoder.DecodeElement(&placemark, &start); err != nil {
+				return nil, errors.Wrap(err, "parse KML placemark")
+			}
+
+			for _, track := range append(placemark.Tracks, placemark.MultiTracks...) {
+				addTrack(track)
+			}
+
+			date, ok := parseTrackTime(placemark.When)
+			if !ok {
+				continue
+			}
+
+			// coordinates is longitude, latitude and altitude separated by commas
+			if latitude, longitude, ok := parseKMLCoordinates(strings.Split(strings.TrimSpace(placemark.Coordinates), ",")); ok {
+				points = append(points, TrackPoint{Time: date, Latitude: latitude, Longitude: longitude})
+			}
+		case "Track":
+			var track kmlTrack
+			if err := decoder.DecodeElement(&track, &start); err != nil {
+				return nil, errors.Wrap(err, "parse KML track")
+			}
+
+			addTrack(track)
+		}
+	}
+
+	return points, nil
+}
+
+func parseKMLCoordinates(values []string) (float64, float64, bool) {
+	if len(values) < 2 {
+		return 0, 0, false
+	}
+
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(values[1]), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	if !validCoordinates(latitude, longitude) {
+		return 0, 0, false
+	}
+
+	return latitude, longitude, true
+}
+
+func parseTrackTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date.UTC(), true
+}
+
+func validCoordinates(latitude float64, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
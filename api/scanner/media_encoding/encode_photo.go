@@ -28,7 +28,9 @@ var thumbFilter = map[models.ThumbnailFilter]imaging.ResampleFilter{
 	models.ThumbnailFilterLanczos:           imaging.Lanczos,
 }
 
-func EncodeThumbnail(db *gorm.DB, inputPath string, outputPath string) (*media_utils.PhotoDimensions, error) {
+// EncodeThumbnail encodes a scaled down jpeg of the image at inputPath to outputPath.
+// Images with a panorama projection are cropped around their center if they are very wide or tall.
+func EncodeThumbnail(db *gorm.DB, inputPath string, outputPath string, projection models.MediaProjection) (*media_utils.PhotoDimensions, error) {
 
 	var siteInfo models.SiteInfo
 	if err := db.First(&siteInfo).Error; err != nil {
@@ -41,6 +43,15 @@ func EncodeThumbnail(db *gorm.DB, inputPath string, outputPath string) (*media_u
 	}
 
 	dimensions := media_utils.PhotoDimensionsFromRect(inputImage.Bounds())
+
+	if projection != models.MediaProjectionFlat {
+		cropDimensions := dimensions.ThumbnailCrop()
+		if cropDimensions != dimensions {
+			inputImage = imaging.CropCenter(inputImage, cropDimensions.Width, cropDimensions.Height)
+			dimensions = cropDimensions
+		}
+	}
+
 	dimensions = dimensions.ThumbnailScale()
 
 	thumbImage := imaging.Resize(inputImage, dimensions.Width, dimensions.Height, thumbFilter[siteInfo.ThumbnailMethod])
@@ -0,0 +1,43 @@
+package media_encoding_test
+
+import (
+	"image/color"
+	"path"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/media_encoding"
+	"github.com/photoview/photoview/api/scanner/media_encoding/media_utils"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeThumbnail(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	_, err := models.GetSiteInfo(db)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	wideImagePath := path.Join(t.TempDir(), "wide.png")
+	wideImage := imaging.New(2000, 200, color.NRGBA{R: 40, G: 80, B: 120, A: 255})
+	if !assert.NoError(t, imaging.Save(wideImage, wideImagePath)) {
+		return
+	}
+
+	t.Run("Wide flat image is not cropped", func(t *testing.T) {
+		thumbSize, err := media_encoding.EncodeThumbnail(db, wideImagePath, path.Join(t.TempDir(), "thumbnail.jpg"), models.MediaProjectionFlat)
+		if assert.NoError(t, err) {
+			assert.Equal(t, media_utils.PhotoDimensions{Width: 1024, Height: 102}, *thumbSize)
+		}
+	})
+
+	t.Run("Wide panorama is cropped", func(t *testing.T) {
+		thumbSize, err := media_encoding.EncodeThumbnail(db, wideImagePath, path.Join(t.TempDir(), "thumbnail.jpg"), models.MediaProjectionCylindrical)
+		if assert.NoError(t, err) {
+			assert.Equal(t, media_utils.PhotoDimensions{Width: 600, Height: 200}, *thumbSize)
+		}
+	})
+}
@@ -6,8 +6,11 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path"
+	"strconv"
 	"strings"
 
+	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/utils"
 	"github.com/pkg/errors"
 	"gopkg.in/vansante/go-ffprobe.v2"
@@ -119,23 +122,128 @@ func (worker *DarktableWorker) EncodeJpeg(inputPath string, outputPath string, j
 	return nil
 }
 
-func (worker *FfmpegWorker) EncodeMp4(inputPath string, outputPath string) error {
-	args := []string{
-		"-i",
-		inputPath,
-		"-vcodec", "h264",
+func (worker *FfmpegWorker) EncodeMp4(inputPath string, outputPath string, profile models.VideoTranscodeProfile) error {
+	if !profile.TwoPass {
+		args := transcodeArgs(inputPath, outputPath, profile, 0, "")
+		cmd := exec.Command(worker.path, args...)
+
+		if err := cmd.Run(); err != nil {
+			return errors.Wrapf(err, "encoding video using: %s %v", worker.path, args)
+		}
+
+		return nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "photoview-ffmpeg")
+	if err != nil {
+		return errors.Wrap(err, "create temporary directory for two-pass log")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	passLogFile := path.Join(tmpDir, "ffmpeg2pass")
+
+	for _, pass := range []int{1, 2} {
+		args := transcodeArgs(inputPath, outputPath, profile, pass, passLogFile)
+		cmd := exec.Command(worker.path, args...)
+
+		if err := cmd.Run(); err != nil {
+			return errors.Wrapf(err, "encoding video (pass %d) using: %s %v", pass, worker.path, args)
+		}
+	}
+
+	return nil
+}
+
+// The device used by the VAAPI encoders, which encode frames uploaded to the GPU
+const vaapiDevice = "/dev/dri/renderD128"
+
+// transcodeArgs builds the ffmpeg arguments for the given profile.
+// A pass of 0 means single pass encoding, otherwise the pass number of a two-pass encoding.
+func transcodeArgs(inputPath string, outputPath string, profile models.VideoTranscodeProfile, pass int, passLogFile string) []string {
+	vaapi := strings.HasSuffix(profile.Codec, "_vaapi")
+
+	args := []string{"-y"}
+	if vaapi {
+		args = append(args, "-vaapi_device", vaapiDevice)
+	}
+
+	args = append(args,
+		"-i", inputPath,
+		"-vcodec", profile.Codec,
+	)
+
+	if pass == 0 || profile.VideoBitrate == nil {
+		args = append(args, qualityArgs(profile.Codec, profile.Crf)...)
+	} else {
+		args = append(args, "-b:v", *profile.VideoBitrate, "-pass", strconv.Itoa(pass), "-passlogfile", passLogFile)
+	}
+
+	filters := fmt.Sprintf("scale='min(%d,iw)':'min(%d,ih)':force_original_aspect_ratio=decrease", profile.MaxResolution, profile.MaxResolution)
+	if vaapi {
+		filters += ",format=nv12,hwupload"
+	}
+
+	args = append(args, "-vf", filters)
+
+	// The first pass only collects statistics, so audio and output are discarded
+	if pass == 1 {
+		return append(args, "-an", "-f", "mp4", os.DevNull)
+	}
+
+	return append(args,
 		"-acodec", "aac",
-		"-vf", "scale='min(1080,iw)':'min(1080,ih)':force_original_aspect_ratio=decrease",
+		"-b:a", profile.AudioBitrate,
 		outputPath,
+	)
+}
+
+// qualityArgs returns the arguments for constant quality encoding,
+// as hardware encoders does not support the -crf option of the software encoders
+func qualityArgs(codec string, crf int) []string {
+	quality := strconv.Itoa(crf)
+
+	switch {
+	case strings.HasSuffix(codec, "_nvenc"):
+		return []string{"-rc", "vbr", "-cq", quality}
+	case strings.HasSuffix(codec, "_qsv"):
+		return []string{"-global_quality", quality}
+	case strings.HasSuffix(codec, "_vaapi"), strings.HasSuffix(codec, "_amf"):
+		return []string{"-qp", quality}
+	default:
+		return []string{"-crf", quality}
 	}
+}
 
-	cmd := exec.Command(worker.path, args...)
+// VideoEncoders returns the names of the video encoders supported by the installed ffmpeg
+func (worker *FfmpegWorker) VideoEncoders() ([]string, error) {
+	output, err := exec.Command(worker.path, "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing encoders using: %s", worker.path)
+	}
 
-	if err := cmd.Run(); err != nil {
-		return errors.Wrapf(err, "encoding video using: %s", worker.path)
+	return parseVideoEncoders(string(output)), nil
+}
+
+// parseVideoEncoders parses the output of `ffmpeg -encoders`, where each encoder is listed as
+// a line of capability flags followed by the name, eg. ` V....D libx264    libx264 H.264 ...`
+func parseVideoEncoders(output string) []string {
+	encoders := make([]string, 0)
+
+	listStarted := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+
+		if !listStarted {
+			listStarted = len(fields) > 0 && strings.HasPrefix(fields[0], "------")
+			continue
+		}
+
+		if len(fields) >= 2 && strings.HasPrefix(fields[0], "V") {
+			encoders = append(encoders, fields[1])
+		}
 	}
 
-	return nil
+	return encoders
 }
 
 func (worker *FfmpegWorker) EncodeVideoThumbnail(inputPath string, outputPath string, probeData *ffprobe.ProbeData) error {
@@ -160,3 +268,55 @@ func (worker *FfmpegWorker) EncodeVideoThumbnail(inputPath string, outputPath st
 
 	return nil
 }
+
+// EncodeVideoPreview encodes a short muted clip of the video, used as an animated preview when browsing
+func (worker *FfmpegWorker) EncodeVideoPreview(inputPath string, outputPath string, probeData *ffprobe.ProbeData, clipSeconds float64) error {
+
+	previewOffset := 0.0
+	if probeData.Format.DurationSeconds > clipSeconds*2 {
+		previewOffset = probeData.Format.DurationSeconds * 0.25
+	}
+
+	args := []string{
+		"-y",
+		"-ss", fmt.Sprintf("%.2f", previewOffset), // seek before input for fast seeking
+		"-i", inputPath,
+		"-t", fmt.Sprintf("%.2f", clipSeconds),
+		"-an", // disable audio
+		"-vcodec", "h264",
+		"-pix_fmt", "yuv420p",
+		"-vf", "scale='min(480,iw)':'min(480,ih)':force_original_aspect_ratio=decrease,scale=trunc(iw/2)*2:trunc(ih/2)*2",
+		"-movflags", "+faststart",
+		outputPath,
+	}
+
+	cmd := exec.Command(worker.path, args...)
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "encoding video preview using: %s %v", worker.path, args)
+	}
+
+	return nil
+}
+
+// EncodeVideoSprite encodes a single image of frames grabbed from the video at the given interval,
+// laid out in a grid with the given number of columns and rows
+func (worker *FfmpegWorker) EncodeVideoSprite(inputPath string, outputPath string, intervalSeconds float64, tileWidth int, columns int, rows int) error {
+	args := []string{
+		"-y",
+		"-i", inputPath,
+		"-an", // disable audio
+		"-vf", fmt.Sprintf("fps=1/%.3f,scale=%d:-2,tile=%dx%d", intervalSeconds, tileWidth, columns, rows),
+		"-frames:v", "1",
+		"-q:v", "5",
+		outputPath,
+	}
+
+	cmd := exec.Command(worker.path, args...)
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "encoding video sprite using: %s %v", worker.path, args)
+	}
+
+	return nil
+}
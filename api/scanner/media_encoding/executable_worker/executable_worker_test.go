@@ -0,0 +1,81 @@
+package executable_worker
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = flag.Bool("database", false, "run database integration tests")
+var _ = flag.Bool("filesystem", false, "run filesystem integration tests")
+
+func TestParseVideoEncoders(t *testing.T) {
+	output := `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D h264_nvenc           NVIDIA NVENC H.264 encoder (codec h264)
+ A....D aac                  AAC (Advanced Audio Coding)
+ S..... srt                  SubRip subtitle
+ V....D libx265              libx265 H.265 / HEVC (codec hevc)
+`
+
+	assert.Equal(t, []string{"libx264", "h264_nvenc", "libx265"}, parseVideoEncoders(output))
+}
+
+func TestTranscodeArgs(t *testing.T) {
+	profile := models.DefaultVideoTranscodeProfile()
+
+	t.Run("single pass", func(t *testing.T) {
+		args := transcodeArgs("in.mov", "out.mp4", profile, 0, "")
+
+		assert.Equal(t, []string{
+			"-y",
+			"-i", "in.mov",
+			"-vcodec", "libx264",
+			"-crf", "23",
+			"-vf", "scale='min(1080,iw)':'min(1080,ih)':force_original_aspect_ratio=decrease",
+			"-acodec", "aac",
+			"-b:a", "128k",
+			"out.mp4",
+		}, args)
+	})
+
+	t.Run("hardware encoder quality", func(t *testing.T) {
+		nvencProfile := profile
+		nvencProfile.Codec = "h264_nvenc"
+
+		args := transcodeArgs("in.mov", "out.mp4", nvencProfile, 0, "")
+		assert.Contains(t, args, "-cq")
+		assert.NotContains(t, args, "-crf")
+	})
+
+	t.Run("vaapi encoder uploads frames to the device", func(t *testing.T) {
+		vaapiProfile := profile
+		vaapiProfile.Codec = "h264_vaapi"
+
+		args := transcodeArgs("in.mov", "out.mp4", vaapiProfile, 0, "")
+		assert.Equal(t, []string{"-y", "-vaapi_device", "/dev/dri/renderD128", "-i", "in.mov"}, args[:5])
+		assert.Contains(t, args, "scale='min(1080,iw)':'min(1080,ih)':force_original_aspect_ratio=decrease,format=nv12,hwupload")
+	})
+
+	t.Run("two pass", func(t *testing.T) {
+		bitrate := "4M"
+		twoPassProfile := profile
+		twoPassProfile.TwoPass = true
+		twoPassProfile.VideoBitrate = &bitrate
+
+		firstPass := transcodeArgs("in.mov", "out.mp4", twoPassProfile, 1, "/tmp/log")
+		assert.Subset(t, firstPass, []string{"-b:v", "4M", "-pass", "1", "-passlogfile", "/tmp/log", "-an"})
+		assert.NotContains(t, firstPass, "out.mp4")
+
+		secondPass := transcodeArgs("in.mov", "out.mp4", twoPassProfile, 2, "/tmp/log")
+		assert.Subset(t, secondPass, []string{"-b:v", "4M", "-pass", "2", "out.mp4"})
+		assert.NotContains(t, secondPass, "-crf")
+	})
+}
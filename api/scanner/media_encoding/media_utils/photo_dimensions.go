@@ -35,6 +35,35 @@ func PhotoDimensionsFromRect(rect image.Rectangle) PhotoDimensions {
 	}
 }
 
+// Max aspect ratio of a thumbnail, wider or taller images such as panoramas are cropped around the center
+const ThumbnailMaxAspectRatio = 3.0
+
+// ThumbnailCrop returns the size of the area in the center of the image that should be used for the thumbnail,
+// so that very wide panoramas are cropped instead of being squashed into a thin strip
+func (dimensions *PhotoDimensions) ThumbnailCrop() PhotoDimensions {
+	if dimensions.Width <= 0 || dimensions.Height <= 0 {
+		return *dimensions
+	}
+
+	aspect := float64(dimensions.Width) / float64(dimensions.Height)
+
+	if aspect > ThumbnailMaxAspectRatio {
+		return PhotoDimensions{
+			Width:  int(float64(dimensions.Height) * ThumbnailMaxAspectRatio),
+			Height: dimensions.Height,
+		}
+	}
+
+	if aspect < 1/ThumbnailMaxAspectRatio {
+		return PhotoDimensions{
+			Width:  dimensions.Width,
+			Height: int(float64(dimensions.Width) * ThumbnailMaxAspectRatio),
+		}
+	}
+
+	return *dimensions
+}
+
 func (dimensions *PhotoDimensions) ThumbnailScale() PhotoDimensions {
 	aspect := float64(dimensions.Width) / float64(dimensions.Height)
 
@@ -0,0 +1,34 @@
+package media_utils_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/photoview/photoview/api/scanner/media_encoding/media_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = flag.Bool("database", false, "run database integration tests")
+var _ = flag.Bool("filesystem", false, "run filesystem integration tests")
+
+func TestThumbnailCrop(t *testing.T) {
+	regular := media_utils.PhotoDimensions{Width: 4000, Height: 3000}
+	assert.Equal(t, regular, regular.ThumbnailCrop())
+
+	photoSphere := media_utils.PhotoDimensions{Width: 8000, Height: 4000}
+	assert.Equal(t, photoSphere, photoSphere.ThumbnailCrop())
+
+	panorama := media_utils.PhotoDimensions{Width: 20000, Height: 2000}
+	assert.Equal(t, media_utils.PhotoDimensions{Width: 6000, Height: 2000}, panorama.ThumbnailCrop())
+
+	vertical := media_utils.PhotoDimensions{Width: 1000, Height: 5000}
+	assert.Equal(t, media_utils.PhotoDimensions{Width: 1000, Height: 3000}, vertical.ThumbnailCrop())
+}
+
+func TestThumbnailScale(t *testing.T) {
+	panorama := media_utils.PhotoDimensions{Width: 6000, Height: 2000}
+	assert.Equal(t, media_utils.PhotoDimensions{Width: 1024, Height: 341}, panorama.ThumbnailScale())
+
+	small := media_utils.PhotoDimensions{Width: 800, Height: 600}
+	assert.Equal(t, small, small.ThumbnailScale())
+}
@@ -0,0 +1,93 @@
+package media_encoding
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	"github.com/disintegration/imaging"
+	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	// register the webp decoder, such that web compatible originals in the webp format can be watermarked
+	_ "golang.org/x/image/webp"
+)
+
+// watermarkOpacity is how visible the watermark is on top of the image, from 0 to 1
+const watermarkOpacity = 0.4
+
+// EncodeWatermarkedJPEG writes the image at the path as a jpeg, with the text repeated across it
+func EncodeWatermarkedJPEG(w io.Writer, imagePath string, text string) error {
+	img, err := imaging.Open(imagePath)
+	if err != nil {
+		return errors.Wrapf(err, "open image to watermark: %s", imagePath)
+	}
+
+	watermarked := DrawWatermark(img, text)
+
+	if err := jpeg.Encode(w, watermarked, &jpeg.Options{Quality: 80}); err != nil {
+		return errors.Wrap(err, "encode watermarked image")
+	}
+
+	return nil
+}
+
+// DrawWatermark returns a copy of the image with the text repeated across it,
+// scaled such that each repetition is about a third of the width of the image
+func DrawWatermark(img image.Image, text string) *image.NRGBA {
+	label := watermarkLabel(text)
+
+	bounds := img.Bounds()
+	labelWidth := bounds.Dx() / 3
+	if labelWidth < label.Bounds().Dx() {
+		labelWidth = label.Bounds().Dx()
+	}
+	label = imaging.Resize(label, labelWidth, 0, imaging.Linear)
+
+	labelSize := label.Bounds().Size()
+	stepX := labelSize.X * 3 / 2
+	stepY := labelSize.Y * 4
+
+	result := imaging.Clone(img)
+	for row, y := 0, labelSize.Y; y < bounds.Dy(); row, y = row+1, y+stepY {
+		// shift every other row, such that the labels are staggered
+		x := -(row % 2) * stepX / 2
+		for ; x < bounds.Dx(); x += stepX {
+			result = imaging.Overlay(result, label, image.Pt(x, y), watermarkOpacity)
+		}
+	}
+
+	return result
+}
+
+// watermarkLabel renders the text as white letters with a dark outline on a transparent background
+func watermarkLabel(text string) *image.NRGBA {
+	face := basicfont.Face7x13
+	padding := 2
+
+	width := font.MeasureString(face, text).Ceil() + 2*padding
+	height := face.Metrics().Height.Ceil() + 2*padding
+	label := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	baseline := padding + face.Metrics().Ascent.Ceil()
+	drawText := func(textColor color.Color, dx, dy int) {
+		drawer := font.Drawer{
+			Dst:  label,
+			Src:  image.NewUniform(textColor),
+			Face: face,
+			Dot:  fixed.P(padding+dx, baseline+dy),
+		}
+		drawer.DrawString(text)
+	}
+
+	outline := color.NRGBA{0, 0, 0, 255}
+	for _, offset := range []image.Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		drawText(outline, offset.X, offset.Y)
+	}
+	drawText(color.White, 0, 0)
+
+	return label
+}
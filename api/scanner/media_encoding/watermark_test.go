@@ -0,0 +1,38 @@
+package media_encoding_test
+
+import (
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/photoview/photoview/api/scanner/media_encoding"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(test_utils.UnitTestRun(m))
+}
+
+func TestDrawWatermark(t *testing.T) {
+	img := imaging.New(600, 400, color.NRGBA{R: 40, G: 80, B: 120, A: 255})
+
+	watermarked := media_encoding.DrawWatermark(img, "Proofs")
+	assert.Equal(t, img.Bounds(), watermarked.Bounds())
+
+	changed := 0
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 600; x++ {
+			if watermarked.NRGBAAt(x, y) != img.NRGBAAt(x, y) {
+				changed++
+			}
+		}
+	}
+
+	assert.Greater(t, changed, 0, "watermark is drawn")
+	assert.Less(t, changed, 600*400/2, "most of the image is left as is")
+
+	// the original image is not modified
+	assert.Equal(t, color.NRGBA{R: 40, G: 80, B: 120, A: 255}, img.NRGBAAt(300, 200))
+}
@@ -0,0 +1,248 @@
+package reverse_geocoding
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+)
+
+// Places further away than this from the coordinates are not considered, eg. for photos taken at sea
+const maxPlaceDistanceKm = 50.0
+
+const earthRadiusKm = 6371.0
+
+// Length in kilometers of one degree of latitude
+const kmPerDegree = 2 * math.Pi * earthRadiusKm / 360
+
+// Names of the GeoNames cities files, in the order they are preferred
+var citiesFileNames = []string{"cities500.txt", "cities1000.txt", "cities5000.txt", "cities15000.txt"}
+
+// Place is the result of reverse geocoding a pair of coordinates
+type Place struct {
+	CountryCode string
+	Country     string
+	Region      *string
+	City        string
+}
+
+type city struct {
+	name        string
+	latitude    float64
+	longitude   float64
+	countryCode string
+	admin1Code  string
+}
+
+// gridCell is a square of one degree of latitude and longitude, used to index the cities by location
+type gridCell struct {
+	latitude  int
+	longitude int
+}
+
+// Geocoder finds the nearest city of coordinates, using a GeoNames dataset loaded into memory
+type Geocoder struct {
+	cities    []city
+	grid      map[gridCell][]int
+	countries map[string]string
+	regions   map[string]string
+}
+
+// GlobalGeocoder is nil if no GeoNames dataset was found
+var GlobalGeocoder *Geocoder = nil
+
+// InitializeGeocoder loads the GeoNames dataset, reverse geocoding is disabled if it can not be found
+func InitializeGeocoder() {
+	geocoder, err := LoadGeocoder(utils.GeoNamesPath())
+	if err != nil {
+		log.Printf("Reverse geocoding disabled: %s\n", err)
+		return
+	}
+
+	log.Printf("Reverse geocoding enabled with %d places\n", len(geocoder.cities))
+	GlobalGeocoder = geocoder
+}
+
+// LoadGeocoder loads a GeoNames dataset from dir.
+// The directory must contain a cities file, and optionally admin1CodesASCII.txt and countryInfo.txt for region and country names.
+func LoadGeocoder(dir string) (*Geocoder, error) {
+	geocoder := Geocoder{
+		grid:      make(map[gridCell][]int),
+		countries: make(map[string]string),
+		regions:   make(map[string]string),
+	}
+
+	citiesPath := ""
+	for _, name := range citiesFileNames {
+		if _, err := os.Stat(path.Join(dir, name)); err == nil {
+			citiesPath = path.Join(dir, name)
+			break
+		}
+	}
+
+	if citiesPath == "" {
+		return nil, errors.Errorf("no GeoNames cities file found in %s", dir)
+	}
+
+	err := readTabSeparated(citiesPath, func(fields []string) {
+		if len(fields) < 11 {
+			return
+		}
+
+		latitude, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return
+		}
+
+		longitude, err := strconv.ParseFloat(fields[5], 64)
+		if err != nil {
+			return
+		}
+
+		geocoder.addCity(city{
+			name:        fields[1],
+			latitude:    latitude,
+			longitude:   longitude,
+			countryCode: fields[8],
+			admin1Code:  fields[10],
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "read GeoNames cities")
+	}
+
+	err = readTabSeparated(path.Join(dir, "admin1CodesASCII.txt"), func(fields []string) {
+		if len(fields) >= 2 {
+			geocoder.regions[fields[0]] = fields[1]
+		}
+	})
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, errors.Wrap(err, "read GeoNames regions")
+	}
+
+	err = readTabSeparated(path.Join(dir, "countryInfo.txt"), func(fields []string) {
+		if len(fields) >= 5 {
+			geocoder.countries[fields[0]] = fields[4]
+		}
+	})
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return nil, errors.Wrap(err, "read GeoNames countries")
+	}
+
+	return &geocoder, nil
+}
+
+// readTabSeparated calls handleLine with the fields of every line of a GeoNames file, comments are skipped
+func readTabSeparated(filePath string, handleLine func(fields []string)) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		if line != "" && !strings.HasPrefix(line, "#") {
+			handleLine(strings.Split(line, "\t"))
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func cellOf(latitude float64, longitude float64) gridCell {
+	return gridCell{
+		latitude:  int(math.Floor(latitude)),
+		longitude: int(math.Floor(longitude)),
+	}
+}
+
+func (g *Geocoder) addCity(c city) {
+	cell := cellOf(c.latitude, c.longitude)
+	g.grid[cell] = append(g.grid[cell], len(g.cities))
+	g.cities = append(g.cities, c)
+}
+
+// Lookup returns the place nearest to the coordinates, or nil if there is none within reach
+func (g *Geocoder) Lookup(latitude float64, longitude float64) *Place {
+	center := cellOf(latitude, longitude)
+
+	// Cells get narrower towards the poles, so more of them must be searched horizontally
+	latitudeCells := int(math.Ceil(maxPlaceDistanceKm / kmPerDegree))
+	longitudeCells := 180
+	if cosLatitude := math.Cos(math.Abs(latitude) * math.Pi / 180); cosLatitude > 0 {
+		longitudeCells = int(math.Min(180, math.Ceil(maxPlaceDistanceKm/(kmPerDegree*cosLatitude))))
+	}
+
+	nearest := -1
+	nearestDistance := maxPlaceDistanceKm
+
+	for dLat := -latitudeCells; dLat <= latitudeCells; dLat++ {
+		for dLong := -longitudeCells; dLong <= longitudeCells; dLong++ {
+			cellLongitude := (center.longitude+dLong+180)%360 - 180
+			if cellLongitude < -180 {
+				cellLongitude += 360
+			}
+
+			cell := gridCell{latitude: center.latitude + dLat, longitude: cellLongitude}
+			for _, index := range g.grid[cell] {
+				distance := haversineDistance(latitude, longitude, g.cities[index].latitude, g.cities[index].longitude)
+				if distance <= nearestDistance {
+					nearest = index
+					nearestDistance = distance
+				}
+			}
+		}
+	}
+
+	if nearest < 0 {
+		return nil
+	}
+
+	c := g.cities[nearest]
+
+	country, found := g.countries[c.countryCode]
+	if !found {
+		country = c.countryCode
+	}
+
+	var region *string
+	if name, found := g.regions[c.countryCode+"."+c.admin1Code]; found {
+		region = &name
+	}
+
+	return &Place{
+		CountryCode: c.countryCode,
+		Country:     country,
+		Region:      region,
+		City:        c.name,
+	}
+}
+
+// haversineDistance returns the distance in kilometers between two coordinates
+func haversineDistance(lat1 float64, long1 float64, lat2 float64, long2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLong := toRadians(long2 - long1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLong/2)*math.Sin(dLong/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,100 @@
+package reverse_geocoding_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/photoview/photoview/api/scanner/reverse_geocoding"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(test_utils.IntegrationTestRun(m))
+}
+
+func writeGeoNamesFile(t *testing.T, dir string, name string, lines ...string) {
+	content := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeocoderLookup(t *testing.T) {
+	dir := t.TempDir()
+
+	writeGeoNamesFile(t, dir, "cities15000.txt",
+		"658225\tHelsinki\tHelsinki\t\t60.16952\t24.93545\tP\tPPLC\tFI\t\t01\t\t\t\t558457\t\t\tEurope/Helsinki\t2019-09-05",
+		"660158\tEspoo\tEspoo\t\t60.2052\t24.6522\tP\tPPLA2\tFI\t\t01\t\t\t\t256760\t\t\tEurope/Helsinki\t2019-09-05",
+		"588409\tTallinn\tTallinn\t\t59.43696\t24.75353\tP\tPPLC\tEE\t\t01\t\t\t\t394024\t\t\tEurope/Tallinn\t2019-09-05",
+		"2193733\tAuckland\tAuckland\t\t-36.84853\t174.76349\tP\tPPLA\tNZ\t\tE7\t\t\t\t417910\t\t\tPacific/Auckland\t2019-09-05",
+		"4031637\tSuva-ish\tSuva-ish\t\t-17.0\t179.9\tP\tPPL\tFJ\t\t\t\t\t\t1000\t\t\tPacific/Fiji\t2019-09-05",
+	)
+
+	writeGeoNamesFile(t, dir, "admin1CodesASCII.txt",
+		"FI.01\tUusimaa\tUusimaa\t830709",
+		"EE.01\tHarjumaa\tHarjumaa\t592170",
+	)
+
+	writeGeoNamesFile(t, dir, "countryInfo.txt",
+		"# ISO\tISO3\tISO-Numeric\tfips\tCountry",
+		"FI\tFIN\t246\tFI\tFinland",
+		"EE\tEST\t233\tEN\tEstonia",
+	)
+
+	geocoder, err := reverse_geocoding.LoadGeocoder(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("Nearest city", func(t *testing.T) {
+		place := geocoder.Lookup(60.17, 24.94)
+		if assert.NotNil(t, place) {
+			assert.Equal(t, "FI", place.CountryCode)
+			assert.Equal(t, "Finland", place.Country)
+			assert.Equal(t, "Helsinki", place.City)
+			if assert.NotNil(t, place.Region) {
+				assert.Equal(t, "Uusimaa", *place.Region)
+			}
+		}
+
+		place = geocoder.Lookup(60.21, 24.66)
+		if assert.NotNil(t, place) {
+			assert.Equal(t, "Espoo", place.City)
+		}
+
+		place = geocoder.Lookup(59.44, 24.75)
+		if assert.NotNil(t, place) {
+			assert.Equal(t, "Tallinn", place.City)
+			assert.Equal(t, "Estonia", place.Country)
+		}
+	})
+
+	t.Run("Unknown region and country name", func(t *testing.T) {
+		place := geocoder.Lookup(-36.85, 174.76)
+		if assert.NotNil(t, place) {
+			assert.Equal(t, "Auckland", place.City)
+			assert.Equal(t, "NZ", place.Country)
+			assert.Nil(t, place.Region)
+		}
+	})
+
+	t.Run("Across the antimeridian", func(t *testing.T) {
+		place := geocoder.Lookup(-17.0, -179.9)
+		if assert.NotNil(t, place) {
+			assert.Equal(t, "Suva-ish", place.City)
+		}
+	})
+
+	t.Run("Too far from any city", func(t *testing.T) {
+		assert.Nil(t, geocoder.Lookup(0, 0))
+		assert.Nil(t, geocoder.Lookup(61.5, 24.9))
+	})
+}
+
+func TestLoadGeocoderMissingDataset(t *testing.T) {
+	_, err := reverse_geocoding.LoadGeocoder(t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package reverse_geocoding
+
+import (
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// LookupMediaPlace returns the place of the coordinates of the exif, or an empty place if it is unknown
+func LookupMediaPlace(exif *models.MediaEXIF) models.MediaPlace {
+	if GlobalGeocoder == nil || exif == nil || exif.GPSLatitude == nil || exif.GPSLongitude == nil {
+		return models.MediaPlace{}
+	}
+
+	place := GlobalGeocoder.Lookup(*exif.GPSLatitude, *exif.GPSLongitude)
+	if place == nil {
+		return models.MediaPlace{}
+	}
+
+	return models.MediaPlace{
+		CountryCode: &place.CountryCode,
+		Country:     &place.Country,
+		Region:      place.Region,
+		City:        &place.City,
+	}
+}
+
+// UpdateMediaPlace reverse geocodes the coordinates of the exif and saves the place on the media,
+// and marks the media as checked such that it is not looked up again if no place was found.
+// Nothing is changed if reverse geocoding is disabled.
+func UpdateMediaPlace(db *gorm.DB, media *models.Media, exif *models.MediaEXIF) error {
+	if GlobalGeocoder == nil {
+		return nil
+	}
+
+	place := LookupMediaPlace(exif)
+
+	err := db.Model(&models.Media{}).Where("id = ?", media.ID).Updates(map[string]interface{}{
+		"place_country_code": place.CountryCode,
+		"place_country":      place.Country,
+		"place_region":       place.Region,
+		"place_city":         place.City,
+		"place_checked":      true,
+	}).Error
+	if err != nil {
+		return errors.Wrap(err, "save place of media")
+	}
+
+	media.Place = place
+	media.PlaceChecked = true
+	return nil
+}
@@ -0,0 +1,66 @@
+package reverse_geocoding_test
+
+import (
+	"path"
+	"testing"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/reverse_geocoding"
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateMediaPlace(t *testing.T) {
+	db := test_utils.DatabaseTest(t)
+
+	dir := t.TempDir()
+	writeGeoNamesFile(t, dir, "cities15000.txt",
+		"658225\tHelsinki\tHelsinki\t\t60.16952\t24.93545\tP\tPPLC\tFI\t\t01\t\t\t\t558457\t\t\tEurope/Helsinki\t2019-09-05",
+	)
+
+	geocoder, err := reverse_geocoding.LoadGeocoder(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	reverse_geocoding.GlobalGeocoder = geocoder
+	defer func() { reverse_geocoding.GlobalGeocoder = nil }()
+
+	album := models.Album{Title: "album", Path: "/photos"}
+	if !assert.NoError(t, db.Create(&album).Error) {
+		return
+	}
+
+	// updatePlace reverse geocodes a new media shot at the coordinates, and returns it as saved in the database
+	updatePlace := func(name string, latitude float64, longitude float64) *models.Media {
+		exif := models.MediaEXIF{GPSLatitude: &latitude, GPSLongitude: &longitude}
+		media := models.Media{Title: name, Path: path.Join(album.Path, name), AlbumID: album.ID, Exif: &exif}
+		if !assert.NoError(t, db.Create(&media).Error) {
+			return nil
+		}
+
+		if !assert.NoError(t, reverse_geocoding.UpdateMediaPlace(db, &media, &exif)) {
+			return nil
+		}
+
+		var saved models.Media
+		assert.NoError(t, db.First(&saved, media.ID).Error)
+		return &saved
+	}
+
+	t.Run("Place found", func(t *testing.T) {
+		media := updatePlace("helsinki.jpg", 60.17, 24.94)
+		if assert.NotNil(t, media) && assert.NotNil(t, media.Place.City) {
+			assert.Equal(t, "Helsinki", *media.Place.City)
+			assert.True(t, media.PlaceChecked)
+		}
+	})
+
+	t.Run("No place found", func(t *testing.T) {
+		media := updatePlace("ocean.jpg", 0, 0)
+		if assert.NotNil(t, media) {
+			assert.Nil(t, media.Place.CountryCode)
+			assert.True(t, media.PlaceChecked, "is not looked up again")
+		}
+	})
+}
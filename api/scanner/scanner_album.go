@@ -6,7 +6,9 @@ import (
 	"log"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/scanner/media_encoding"
@@ -33,6 +35,15 @@ func NewRootAlbum(db *gorm.DB, rootPath string, owner *models.User) (*models.Alb
 		rootPath = path.Join(wd, rootPath)
 	}
 
+	siteInfo, err := models.GetSiteInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if !RootPathAllowed(rootPath, siteInfo.AllowedRootPaths()) {
+		return nil, ErrorRootPathNotAllowed
+	}
+
 	owners := []models.User{
 		*owner,
 	}
@@ -75,6 +86,7 @@ func NewRootAlbum(db *gorm.DB, rootPath string, owner *models.User) (*models.Alb
 }
 
 var ErrorInvalidRootPath = errors.New("invalid root path")
+var ErrorRootPathNotAllowed = errors.New("root path is not inside any of the allowed directories")
 
 func ValidRootPath(rootPath string) bool {
 	_, err := os.Stat(rootPath)
@@ -86,8 +98,45 @@ func ValidRootPath(rootPath string) bool {
 	return true
 }
 
+// RootPathAllowed returns true if the root path is inside one of the directories of the allowlist,
+// symbolic links are resolved such that they cannot be used to escape the allowed directories.
+// An empty allowlist allows any directory.
+func RootPathAllowed(rootPath string, allowlist []string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	rootPath = resolvePath(rootPath)
+
+	for _, allowed := range allowlist {
+		relativePath, err := filepath.Rel(resolvePath(allowed), rootPath)
+		if err != nil {
+			continue
+		}
+
+		if relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolvePath(dir string) string {
+	if absPath, err := filepath.Abs(dir); err == nil {
+		dir = absPath
+	}
+
+	if realPath, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = realPath
+	}
+
+	return filepath.Clean(dir)
+}
+
 func ScanAlbum(ctx scanner_task.TaskContext) error {
 	newCtx, err := scanner_tasks.Tasks.BeforeScanAlbum(ctx)
+	defer scanner_tasks.ReleaseAlbumQuota(newCtx)
 	if err != nil {
 		return errors.Wrapf(err, "before scan album (%s)", ctx.GetAlbum().Path)
 	}
@@ -1,12 +1,15 @@
 package scanner_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/scanner"
 	"github.com/photoview/photoview/api/test_utils"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestNewRootPath(t *testing.T) {
@@ -47,6 +50,18 @@ func TestNewRootPath(t *testing.T) {
 		assert.Equal(t, err.Error(), "invalid root path")
 	})
 
+	t.Run("Insert root album outside of allowlist", func(t *testing.T) {
+		allowed := t.TempDir()
+		err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.SiteInfo{}).Update("root_path_allowlist", allowed).Error
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.SiteInfo{}).Update("root_path_allowlist", "")
+
+		_, err = scanner.NewRootAlbum(db, "./test_data", &user)
+		assert.ErrorIs(t, err, scanner.ErrorRootPathNotAllowed)
+	})
+
 	t.Run("Add existing root album to new user", func(t *testing.T) {
 
 		user2 := models.User{
@@ -70,3 +85,30 @@ func TestNewRootPath(t *testing.T) {
 	})
 
 }
+
+func TestRootPathAllowed(t *testing.T) {
+	base := t.TempDir()
+	allowed := filepath.Join(base, "allowed")
+	outside := filepath.Join(base, "outside")
+
+	for _, dir := range []string{filepath.Join(allowed, "photos"), outside} {
+		if !assert.NoError(t, os.MkdirAll(dir, 0755)) {
+			return
+		}
+	}
+
+	escape := filepath.Join(allowed, "escape")
+	if !assert.NoError(t, os.Symlink(outside, escape)) {
+		return
+	}
+
+	allowlist := []string{allowed}
+
+	assert.True(t, scanner.RootPathAllowed(outside, []string{}), "empty allowlist allows any directory")
+	assert.True(t, scanner.RootPathAllowed(allowed, allowlist))
+	assert.True(t, scanner.RootPathAllowed(filepath.Join(allowed, "photos"), allowlist))
+	assert.False(t, scanner.RootPathAllowed(outside, allowlist))
+	assert.False(t, scanner.RootPathAllowed(allowed+"-other", allowlist))
+	assert.False(t, scanner.RootPathAllowed(filepath.Join(allowed, "photos", "..", ".."), allowlist))
+	assert.False(t, scanner.RootPathAllowed(escape, allowlist), "symlinks must not escape the allowlist")
+}
@@ -39,6 +39,15 @@ func (job *ScannerJob) Run(db *gorm.DB) {
 	}
 }
 
+// lastModifyTime returns the modification time of the album of the job, or 0 if it is unknown
+func (job *ScannerJob) lastModifyTime() int {
+	album := job.ctx.GetAlbum()
+	if album == nil || album.LastModifyTime == nil {
+		return 0
+	}
+	return *album.LastModifyTime
+}
+
 type ScannerQueueSettings struct {
 	max_concurrent_tasks int
 }
@@ -247,14 +256,27 @@ func AddUserToQueue(user *models.User, scan_all bool) error {
 	return nil
 }
 
+// AddAlbumToQueue adds a single album to the scanner queue, even if it has not changed since it was last scanned.
+// Function does not block.
+func AddAlbumToQueue(album *models.Album) error {
+	album_cache := scanner_cache.MakeAlbumCache()
+
+	global_scanner_queue.mutex.Lock()
+	defer global_scanner_queue.mutex.Unlock()
+
+	return global_scanner_queue.addJob(&ScannerJob{
+		ctx: scanner_task.NewTaskContext(context.Background(), global_scanner_queue.db, album, album_cache),
+	})
+}
+
 // Queue should be locked prior to calling this function
 func (queue *ScannerQueue) addJob(job *ScannerJob) error {
 	if exists, err := queue.jobOnQueue(job); exists || err != nil {
 		return err
 	}
 	queue.up_next = append(queue.up_next, *job)
-	sort.Slice(queue.up_next, func(i, j int) bool {
-		return *queue.up_next[i].ctx.GetAlbum().LastModifyTime > *queue.up_next[j].ctx.GetAlbum().LastModifyTime
+	sort.SliceStable(queue.up_next, func(i, j int) bool {
+		return queue.up_next[i].lastModifyTime() > queue.up_next[j].lastModifyTime()
 	})
 	queue.notify()
 
@@ -5,7 +5,9 @@ import (
 
 	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/scanner/exif"
+	"github.com/photoview/photoview/api/scanner/reverse_geocoding"
 	"github.com/photoview/photoview/api/scanner/scanner_task"
+	"github.com/pkg/errors"
 )
 
 type ExifTask struct {
@@ -18,9 +20,57 @@ func (t ExifTask) AfterMediaFound(ctx scanner_task.TaskContext, media *models.Me
 		return nil
 	}
 
-	_, err := exif.SaveEXIF(ctx.GetDB(), media)
+	mediaExif, err := exif.SaveEXIF(ctx.GetDB(), media)
 	if err != nil {
 		log.Printf("WARN: SaveEXIF for %s failed: %s\n", media.Title, err)
+		return nil
+	}
+
+	if err := reverse_geocoding.UpdateMediaPlace(ctx.GetDB(), media, mediaExif); err != nil {
+		log.Printf("WARN: reverse geocoding for %s failed: %s\n", media.Title, err)
+	}
+
+	return nil
+}
+
+// AfterScanAlbum converts the dates of media that were saved as the local time of the camera to UTC,
+// and finds the places of media that were scanned before reverse geocoding was enabled
+func (t ExifTask) AfterScanAlbum(ctx scanner_task.TaskContext, changedMedia []*models.Media, albumMedia []*models.Media) error {
+	db := ctx.GetDB()
+
+	var localDateMedia []*models.Media
+	err := db.Where("album_id = ?", ctx.GetAlbum().ID).
+		Where("exif_id IN (SELECT id FROM media_exif WHERE date_shot_local = ?)", true).
+		Find(&localDateMedia).Error
+	if err != nil {
+		return errors.Wrap(err, "get media with local dates")
+	}
+
+	for _, m := range localDateMedia {
+		if err := exif.RefreshDateShot(db, m); err != nil {
+			log.Printf("WARN: RefreshDateShot for %s failed: %s\n", m.Title, err)
+		}
+	}
+
+	if reverse_geocoding.GlobalGeocoder == nil {
+		return nil
+	}
+
+	var media []*models.Media
+	err = db.Preload("Exif").
+		Where("album_id = ?", ctx.GetAlbum().ID).
+		Where("place_country_code IS NULL").
+		Where("place_checked = ?", false).
+		Where("exif_id IN (SELECT id FROM media_exif WHERE gps_latitude IS NOT NULL AND gps_longitude IS NOT NULL)").
+		Find(&media).Error
+	if err != nil {
+		return errors.Wrap(err, "get media without a place")
+	}
+
+	for _, m := range media {
+		if err := reverse_geocoding.UpdateMediaPlace(db, m, m.Exif); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -2,10 +2,13 @@ package processing_tasks
 
 import (
 	"io/fs"
+	"log"
+	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/photoview/photoview/api/graphql/models"
 	"github.com/photoview/photoview/api/scanner/media_encoding"
 	"github.com/photoview/photoview/api/scanner/media_type"
 	"github.com/photoview/photoview/api/scanner/scanner_task"
@@ -47,6 +50,159 @@ func (t CounterpartFilesTask) BeforeProcessMedia(ctx scanner_task.TaskContext, m
 	return ctx, nil
 }
 
+// AfterMediaFound extracts the video embedded in new motion photos into the cache
+func (t CounterpartFilesTask) AfterMediaFound(ctx scanner_task.TaskContext, media *models.Media, newMedia bool) error {
+	if !newMedia || media.Type != models.MediaTypePhoto {
+		return nil
+	}
+
+	mediaType, err := ctx.GetCache().GetMediaType(media.Path)
+	if err != nil {
+		return errors.Wrap(err, "scan for embedded motion video")
+	}
+
+	if *mediaType != media_type.TypeJpeg {
+		return nil
+	}
+
+	if _, err := saveEmbeddedMotionVideo(ctx, media, nil); err != nil {
+		log.Printf("WARN: extracting motion video of %s failed: %s\n", media.Title, err)
+	}
+
+	return nil
+}
+
+// ProcessMedia extracts the motion video again, if it has been removed from the cache
+func (t CounterpartFilesTask) ProcessMedia(ctx scanner_task.TaskContext, mediaData *media_encoding.EncodeMediaData, mediaCachePath string) ([]*models.MediaURL, error) {
+	if mediaData.Media.Type != models.MediaTypePhoto {
+		return []*models.MediaURL{}, nil
+	}
+
+	motionURL, err := makePhotoURLChecker(ctx.GetDB(), mediaData.Media.ID)(models.MotionVideo)
+	if err != nil {
+		return []*models.MediaURL{}, errors.Wrap(err, "error processing motion video")
+	}
+
+	if motionURL == nil {
+		return []*models.MediaURL{}, nil
+	}
+
+	if _, err := os.Stat(path.Join(mediaCachePath, motionURL.MediaName)); err == nil {
+		return []*models.MediaURL{}, nil
+	}
+
+	updatedURL, err := saveEmbeddedMotionVideo(ctx, mediaData.Media, motionURL)
+	if err != nil {
+		return []*models.MediaURL{}, err
+	}
+
+	if updatedURL == nil {
+		return []*models.MediaURL{}, nil
+	}
+
+	return []*models.MediaURL{updatedURL}, nil
+}
+
+// AfterScanAlbum pairs the videos of live photos with their still image,
+// such that the video can be hidden and presented as a part of the photo
+func (t CounterpartFilesTask) AfterScanAlbum(ctx scanner_task.TaskContext, changedMedia []*models.Media, albumMedia []*models.Media) error {
+	stills := make(map[string]*models.Media)
+	for _, media := range albumMedia {
+		if media.Type == models.MediaTypePhoto {
+			stills[strings.TrimSuffix(media.Path, path.Ext(media.Path))] = media
+		}
+	}
+
+	for _, media := range albumMedia {
+		if media.Type != models.MediaTypeVideo {
+			continue
+		}
+
+		var motionPhotoID *int
+		if still, found := stills[strings.TrimSuffix(media.Path, path.Ext(media.Path))]; found {
+			isLivePhoto, err := isLivePhotoDuration(ctx, media)
+			if err != nil {
+				return err
+			}
+
+			if isLivePhoto {
+				motionPhotoID = &still.ID
+			}
+		}
+
+		if equalIntPointers(media.MotionPhotoID, motionPhotoID) {
+			continue
+		}
+
+		if err := ctx.GetDB().Model(media).Update("motion_photo_id", motionPhotoID).Error; err != nil {
+			return errors.Wrapf(err, "update live photo pairing (%s)", media.Path)
+		}
+	}
+
+	return nil
+}
+
+func isLivePhotoDuration(ctx scanner_task.TaskContext, video *models.Media) (bool, error) {
+	if video.VideoMetadataID == nil {
+		return false, nil
+	}
+
+	var durations []float64
+	if err := ctx.GetDB().Model(&models.VideoMetadata{}).Where("id = ?", *video.VideoMetadataID).Pluck("duration", &durations).Error; err != nil {
+		return false, errors.Wrapf(err, "get duration of video (%s)", video.Path)
+	}
+
+	return len(durations) > 0 && durations[0] <= livePhotoMaxDuration, nil
+}
+
+func equalIntPointers(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// saveEmbeddedMotionVideo extracts the video embedded in a motion photo to the cache,
+// and saves it as the given media url, or a new one if nil.
+// Returns nil if no embedded video was found.
+func saveEmbeddedMotionVideo(ctx scanner_task.TaskContext, photo *models.Media, motionURL *models.MediaURL) (*models.MediaURL, error) {
+	embeddedVideo, err := findEmbeddedMotionVideo(photo.Path)
+	if err != nil || embeddedVideo == nil {
+		return nil, err
+	}
+
+	mediaCachePath, err := photo.CachePath()
+	if err != nil {
+		return nil, errors.Wrap(err, "cache directory error")
+	}
+
+	if motionURL == nil {
+		motionURL = &models.MediaURL{
+			MediaID:     photo.ID,
+			MediaName:   generateUniqueMediaNamePrefixed("motion_video", photo.Path, ".mp4"),
+			Purpose:     models.MotionVideo,
+			ContentType: "video/mp4",
+		}
+	}
+
+	motionVideoPath := path.Join(mediaCachePath, motionURL.MediaName)
+	if err := extractEmbeddedMotionVideo(photo.Path, embeddedVideo, motionVideoPath); err != nil {
+		return nil, err
+	}
+
+	motionURL.FileSize = embeddedVideo.length
+	if videoMetadata, err := ReadVideoStreamMetadata(motionVideoPath); err == nil {
+		motionURL.Width = videoMetadata.Width
+		motionURL.Height = videoMetadata.Height
+	}
+
+	if err := ctx.GetDB().Save(motionURL).Error; err != nil {
+		return nil, errors.Wrapf(err, "save motion video to database (%s)", photo.Title)
+	}
+
+	return motionURL, nil
+}
+
 func scanForCompressedCounterpartFile(imagePath string) *string {
 	ext := filepath.Ext(imagePath)
 	fileExtType, found := media_type.GetExtensionMediaType(ext)
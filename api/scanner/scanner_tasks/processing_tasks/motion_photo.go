@@ -0,0 +1,239 @@
+package processing_tasks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Max duration in seconds of a video, for it to be considered the motion part of a live photo
+const livePhotoMaxDuration = 5.0
+
+// Number of bytes at the start of a file searched for motion photo XMP metadata
+const motionPhotoHeaderSize = 256 * 1024
+
+// Number of bytes at the end of a file searched for the Samsung trailer
+const motionPhotoTrailerSize = 64 * 1024
+
+var (
+	microVideoOffsetRegex = regexp.MustCompile(`GCamera:MicroVideoOffset(?:="|>)([0-9]+)`)
+	containerItemRegex    = regexp.MustCompile(`<Container:Item[^>]*Item:Semantic="MotionPhoto"[^>]*>`)
+	itemLengthRegex       = regexp.MustCompile(`Item:Length="([0-9]+)"`)
+	samsungMotionMarker   = []byte("MotionPhoto_Data")
+	samsungTrailerMarker  = []byte("SEFT")
+)
+
+// embeddedMotionVideo describes the location of an MP4 stream embedded in a motion photo
+type embeddedMotionVideo struct {
+	offset int64
+	length int64
+}
+
+// findEmbeddedMotionVideo looks for an MP4 video embedded at the end of a JPEG,
+// as written by Google (MicroVideo and MotionPhoto XMP) and Samsung (MotionPhoto_Data marker) cameras.
+// Returns nil if the file is not a motion photo.
+func findEmbeddedMotionVideo(imagePath string) (*embeddedMotionVideo, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "open motion photo")
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "stat motion photo")
+	}
+	fileSize := stat.Size()
+
+	header := make([]byte, minInt64(motionPhotoHeaderSize, fileSize))
+	if _, err := file.ReadAt(header, 0); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "read motion photo header")
+	}
+
+	candidates := make([]int64, 0)
+
+	if match := microVideoOffsetRegex.FindSubmatch(header); match != nil {
+		if offset, err := strconv.ParseInt(string(match[1]), 10, 64); err == nil {
+			candidates = append(candidates, fileSize-offset)
+		}
+	}
+
+	if item := containerItemRegex.Find(header); item != nil {
+		if match := itemLengthRegex.FindSubmatch(item); match != nil {
+			if length, err := strconv.ParseInt(string(match[1]), 10, 64); err == nil {
+				candidates = append(candidates, fileSize-length)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		trailerStart := maxInt64(0, fileSize-motionPhotoTrailerSize)
+		trailer := make([]byte, fileSize-trailerStart)
+		if _, err := file.ReadAt(trailer, trailerStart); err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, "read motion photo trailer")
+		}
+
+		if bytes.Contains(trailer, samsungTrailerMarker) || bytes.Contains(trailer, samsungMotionMarker) {
+			offset, err := findSamsungMotionVideo(file)
+			if err != nil {
+				return nil, err
+			}
+			if offset >= 0 {
+				candidates = append(candidates, offset)
+			}
+		}
+	}
+
+	for _, offset := range candidates {
+		if offset <= 0 || offset >= fileSize {
+			continue
+		}
+
+		length := mp4Length(file, offset, fileSize)
+		if length > 0 {
+			return &embeddedMotionVideo{
+				offset: offset,
+				length: length,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findSamsungMotionVideo returns the offset of the video following the last MotionPhoto_Data marker, or -1 if not found.
+// The file is read in chunks, such that large motion photos are not loaded into memory at once.
+func findSamsungMotionVideo(file io.ReadSeeker) (int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return -1, errors.Wrap(err, "seek motion photo")
+	}
+
+	// the end of each chunk is kept for the next, in case the marker is split between them
+	overlap := len(samsungMotionMarker) - 1
+	buffer := make([]byte, motionPhotoTrailerSize+overlap)
+
+	found := int64(-1)
+	kept := 0
+	var position int64
+
+	for {
+		n, err := io.ReadFull(file, buffer[kept:])
+		chunk := buffer[:kept+n]
+
+		if index := bytes.LastIndex(chunk, samsungMotionMarker); index >= 0 {
+			found = position + int64(index+len(samsungMotionMarker))
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return -1, errors.Wrap(err, "read motion photo")
+		}
+
+		kept = overlap
+		copy(buffer, chunk[len(chunk)-kept:])
+		position += int64(len(chunk) - kept)
+	}
+
+	return found, nil
+}
+
+// mp4Length walks the top level boxes of an MP4 stream starting at offset,
+// and returns the length of the stream, or 0 if it is not a valid MP4 stream
+func mp4Length(file io.ReaderAt, offset int64, fileSize int64) int64 {
+	position := offset
+	first := true
+
+	for position+8 <= fileSize {
+		header := make([]byte, 16)
+		n, err := file.ReadAt(header, position)
+		if n < 8 || (err != nil && err != io.EOF) {
+			break
+		}
+
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		boxType := header[4:8]
+
+		if first && string(boxType) != "ftyp" {
+			return 0
+		}
+
+		if !isBoxType(boxType) {
+			break
+		}
+
+		switch boxSize {
+		case 0:
+			// box extends to the end of the file
+			boxSize = fileSize - position
+		case 1:
+			if n < 16 {
+				return 0
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+
+		if boxSize < 8 || position+boxSize > fileSize {
+			break
+		}
+
+		position += boxSize
+		first = false
+	}
+
+	if first {
+		return 0
+	}
+
+	return position - offset
+}
+
+func isBoxType(boxType []byte) bool {
+	for _, c := range boxType {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// extractEmbeddedMotionVideo copies the embedded video of a motion photo to outputPath
+func extractEmbeddedMotionVideo(imagePath string, video *embeddedMotionVideo, outputPath string) error {
+	input, err := os.Open(imagePath)
+	if err != nil {
+		return errors.Wrap(err, "open motion photo")
+	}
+	defer input.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return errors.Wrap(err, "create motion video in cache")
+	}
+	defer output.Close()
+
+	if _, err := io.Copy(output, io.NewSectionReader(input, video.offset, video.length)); err != nil {
+		return errors.Wrap(err, "extract motion video")
+	}
+
+	return nil
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,139 @@
+package processing_tasks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeMP4Box(boxType string, payload []byte) []byte {
+	box := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(box[0:4], uint32(8+len(payload)))
+	copy(box[4:8], boxType)
+	return append(box, payload...)
+}
+
+func makeMP4() []byte {
+	mp4 := makeMP4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2"))
+	mp4 = append(mp4, makeMP4Box("moov", bytes.Repeat([]byte{1}, 32))...)
+	mp4 = append(mp4, makeMP4Box("mdat", bytes.Repeat([]byte{2}, 64))...)
+	return mp4
+}
+
+func writeTestFile(t *testing.T, content []byte) string {
+	filePath := path.Join(t.TempDir(), "motion.jpg")
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestFindEmbeddedMotionVideo(t *testing.T) {
+	mp4 := makeMP4()
+	jpegStart := []byte("\xff\xd8\xff\xe1")
+	jpegEnd := []byte("\xff\xd9")
+
+	t.Run("MicroVideo offset", func(t *testing.T) {
+		xmp := fmt.Sprintf(`<x:xmpmeta><rdf:Description GCamera:MicroVideo="1" GCamera:MicroVideoOffset="%d"/></x:xmpmeta>`, len(mp4))
+		content := append(append(append(jpegStart, xmp...), jpegEnd...), mp4...)
+
+		video, err := findEmbeddedMotionVideo(writeTestFile(t, content))
+		assert.NoError(t, err)
+		if assert.NotNil(t, video) {
+			assert.Equal(t, int64(len(content)-len(mp4)), video.offset)
+			assert.Equal(t, int64(len(mp4)), video.length)
+		}
+	})
+
+	t.Run("MotionPhoto container", func(t *testing.T) {
+		xmp := fmt.Sprintf(`<x:xmpmeta><Container:Directory><rdf:Seq>
+			<rdf:li><Container:Item Item:Mime="image/jpeg" Item:Semantic="Primary"/></rdf:li>
+			<rdf:li><Container:Item Item:Mime="video/mp4" Item:Semantic="MotionPhoto" Item:Length="%d"/></rdf:li>
+		</rdf:Seq></Container:Directory></x:xmpmeta>`, len(mp4))
+		content := append(append(append(jpegStart, xmp...), jpegEnd...), mp4...)
+
+		video, err := findEmbeddedMotionVideo(writeTestFile(t, content))
+		assert.NoError(t, err)
+		if assert.NotNil(t, video) {
+			assert.Equal(t, int64(len(mp4)), video.length)
+		}
+	})
+
+	t.Run("Samsung marker with trailer", func(t *testing.T) {
+		content := append(append(jpegStart, jpegEnd...), samsungMotionMarker...)
+		content = append(content, mp4...)
+		content = append(content, []byte("\x00\x00SEFT")...)
+
+		video, err := findEmbeddedMotionVideo(writeTestFile(t, content))
+		assert.NoError(t, err)
+		if assert.NotNil(t, video) {
+			assert.Equal(t, int64(len(jpegStart)+len(jpegEnd)+len(samsungMotionMarker)), video.offset)
+			assert.Equal(t, int64(len(mp4)), video.length)
+		}
+	})
+
+	t.Run("plain jpeg", func(t *testing.T) {
+		content := append(append(jpegStart, []byte("no motion here")...), jpegEnd...)
+
+		video, err := findEmbeddedMotionVideo(writeTestFile(t, content))
+		assert.NoError(t, err)
+		assert.Nil(t, video)
+	})
+
+	t.Run("offset not pointing at mp4", func(t *testing.T) {
+		xmp := `<x:xmpmeta><rdf:Description GCamera:MicroVideoOffset="4"/></x:xmpmeta>`
+		content := append(append(jpegStart, xmp...), jpegEnd...)
+
+		video, err := findEmbeddedMotionVideo(writeTestFile(t, content))
+		assert.NoError(t, err)
+		assert.Nil(t, video)
+	})
+}
+
+func TestFindSamsungMotionVideo(t *testing.T) {
+	t.Run("marker split between chunks", func(t *testing.T) {
+		markerStart := motionPhotoTrailerSize - 5
+		content := append(bytes.Repeat([]byte{0}, markerStart), samsungMotionMarker...)
+		content = append(content, bytes.Repeat([]byte{1}, 2*motionPhotoTrailerSize)...)
+
+		offset, err := findSamsungMotionVideo(bytes.NewReader(content))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(markerStart+len(samsungMotionMarker)), offset)
+	})
+
+	t.Run("last marker", func(t *testing.T) {
+		content := append([]byte{}, samsungMotionMarker...)
+		content = append(content, bytes.Repeat([]byte{0}, 3*motionPhotoTrailerSize)...)
+		content = append(content, samsungMotionMarker...)
+		content = append(content, []byte("video")...)
+
+		offset, err := findSamsungMotionVideo(bytes.NewReader(content))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)-len("video")), offset)
+	})
+
+	t.Run("no marker", func(t *testing.T) {
+		offset, err := findSamsungMotionVideo(bytes.NewReader(bytes.Repeat([]byte{0}, 2*motionPhotoTrailerSize)))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-1), offset)
+	})
+}
+
+func TestExtractEmbeddedMotionVideo(t *testing.T) {
+	mp4 := makeMP4()
+	content := append([]byte("\xff\xd8\xff\xd9"), mp4...)
+	imagePath := writeTestFile(t, content)
+
+	outputPath := path.Join(t.TempDir(), "motion.mp4")
+	err := extractEmbeddedMotionVideo(imagePath, &embeddedMotionVideo{offset: 4, length: int64(len(mp4))}, outputPath)
+	assert.NoError(t, err)
+
+	extracted, err := os.ReadFile(outputPath)
+	assert.NoError(t, err)
+	assert.Equal(t, mp4, extracted)
+}
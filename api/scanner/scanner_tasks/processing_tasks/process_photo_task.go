@@ -123,7 +123,12 @@ func (t ProcessPhotoTask) ProcessMedia(ctx scanner_task.TaskContext, mediaData *
 			updatedURLs = append(updatedURLs, thumbURL)
 			fmt.Printf("Thumbnail photo found in database but not in cache, re-encoding photo to cache: %s\n", thumbURL.MediaName)
 
-			_, err := media_encoding.EncodeThumbnail(ctx.GetDB(), baseImagePath, thumbPath)
+			projection, err := mediaProjection(ctx.GetDB(), photo)
+			if err != nil {
+				return []*models.MediaURL{}, err
+			}
+
+			_, err = media_encoding.EncodeThumbnail(ctx.GetDB(), baseImagePath, thumbPath, projection)
 			if err != nil {
 				return []*models.MediaURL{}, errors.Wrap(err, "could not create thumbnail cached image")
 			}
@@ -0,0 +1,219 @@
+package processing_tasks
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/media_encoding/executable_worker"
+	"github.com/photoview/photoview/api/scanner/media_encoding/media_utils"
+	"github.com/photoview/photoview/api/scanner/scanner_task"
+	"github.com/pkg/errors"
+	"gopkg.in/vansante/go-ffprobe.v2"
+)
+
+const (
+	videoPreviewSeconds   = 3.0
+	videoSpriteMaxTiles   = 100
+	videoSpriteColumns    = 10
+	videoSpriteTileWidth  = 160
+	videoSpriteMinSeconds = 1.0
+)
+
+// videoSpriteLayout describes how frames of a video are laid out in a sprite image
+type videoSpriteLayout struct {
+	interval float64
+	frames   int
+	columns  int
+	rows     int
+}
+
+func makeVideoSpriteLayout(durationSeconds float64) videoSpriteLayout {
+	interval := math.Max(videoSpriteMinSeconds, durationSeconds/videoSpriteMaxTiles)
+	frames := int(math.Ceil(durationSeconds / interval))
+	if frames < 1 {
+		frames = 1
+	}
+
+	columns := videoSpriteColumns
+	if frames < columns {
+		columns = frames
+	}
+
+	return videoSpriteLayout{
+		interval: interval,
+		frames:   frames,
+		columns:  columns,
+		rows:     int(math.Ceil(float64(frames) / float64(columns))),
+	}
+}
+
+// videoSpriteIndex generates a WebVTT file, that maps time ranges of the video to tiles in the sprite image.
+// The sprite is referenced relative to the index, as both are served from the same route.
+func videoSpriteIndex(spriteName string, durationSeconds float64, layout videoSpriteLayout, tileWidth int, tileHeight int) string {
+	var builder strings.Builder
+	builder.WriteString("WEBVTT\n")
+
+	for i := 0; i < layout.frames; i++ {
+		start := float64(i) * layout.interval
+		end := math.Min(start+layout.interval, durationSeconds)
+
+		x := (i % layout.columns) * tileWidth
+		y := (i / layout.columns) * tileHeight
+
+		fmt.Fprintf(&builder, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", vttTimestamp(start), vttTimestamp(end), spriteName, x, y, tileWidth, tileHeight)
+	}
+
+	return builder.String()
+}
+
+func vttTimestamp(seconds float64) string {
+	duration := time.Duration(seconds * float64(time.Second))
+
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	secs := int(duration.Seconds()) % 60
+	millis := int(duration.Milliseconds()) % 1000
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, secs, millis)
+}
+
+// processVideoPreview encodes the preview clip of the video, if it does not exist already
+func processVideoPreview(ctx scanner_task.TaskContext, video *models.Media, probeData *ffprobe.ProbeData, mediaCachePath string) (*models.MediaURL, error) {
+	previewURL, err := makePhotoURLChecker(ctx.GetDB(), video.ID)(models.VideoPreview)
+	if err != nil {
+		return nil, errors.Wrap(err, "error processing video preview")
+	}
+
+	if previewURL != nil {
+		if _, err := os.Stat(path.Join(mediaCachePath, previewURL.MediaName)); err == nil {
+			return nil, nil
+		}
+	} else {
+		previewURL = &models.MediaURL{
+			MediaID:     video.ID,
+			MediaName:   generateUniqueMediaNamePrefixed("video_preview", video.Path, ".mp4"),
+			Purpose:     models.VideoPreview,
+			ContentType: "video/mp4",
+		}
+	}
+
+	previewPath := path.Join(mediaCachePath, previewURL.MediaName)
+
+	if err := executable_worker.FfmpegCli.EncodeVideoPreview(video.Path, previewPath, probeData, videoPreviewSeconds); err != nil {
+		return nil, errors.Wrapf(err, "failed to generate preview for video (%s)", video.Title)
+	}
+
+	previewMetadata, err := ReadVideoStreamMetadata(previewPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read metadata for video preview (%s)", video.Title)
+	}
+
+	fileStats, err := os.Stat(previewPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading file stats of video preview")
+	}
+
+	previewURL.Width = previewMetadata.Width
+	previewURL.Height = previewMetadata.Height
+	previewURL.FileSize = fileStats.Size()
+
+	if err := ctx.GetDB().Save(previewURL).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to save video preview to database (%s)", video.Title)
+	}
+
+	return previewURL, nil
+}
+
+// processVideoSprite encodes the scrub preview sprite of the video along with its WebVTT index,
+// if they do not exist already
+func processVideoSprite(ctx scanner_task.TaskContext, video *models.Media, probeData *ffprobe.ProbeData, mediaCachePath string) ([]*models.MediaURL, error) {
+	mediaURLFromDB := makePhotoURLChecker(ctx.GetDB(), video.ID)
+
+	spriteURL, err := mediaURLFromDB(models.VideoSprite)
+	if err != nil {
+		return nil, errors.Wrap(err, "error processing video sprite")
+	}
+
+	indexURL, err := mediaURLFromDB(models.VideoSpriteIndex)
+	if err != nil {
+		return nil, errors.Wrap(err, "error processing video sprite index")
+	}
+
+	if spriteURL != nil && indexURL != nil {
+		_, spriteErr := os.Stat(path.Join(mediaCachePath, spriteURL.MediaName))
+		_, indexErr := os.Stat(path.Join(mediaCachePath, indexURL.MediaName))
+		if spriteErr == nil && indexErr == nil {
+			return nil, nil
+		}
+	}
+
+	if spriteURL == nil {
+		spriteURL = &models.MediaURL{
+			MediaID:     video.ID,
+			MediaName:   generateUniqueMediaNamePrefixed("video_sprite", video.Path, ".jpg"),
+			Purpose:     models.VideoSprite,
+			ContentType: "image/jpeg",
+		}
+	}
+
+	if indexURL == nil {
+		indexURL = &models.MediaURL{
+			MediaID:     video.ID,
+			MediaName:   generateUniqueMediaNamePrefixed("video_sprite_index", video.Path, ".vtt"),
+			Purpose:     models.VideoSpriteIndex,
+			ContentType: "text/vtt",
+		}
+	}
+
+	duration := probeData.Format.DurationSeconds
+	layout := makeVideoSpriteLayout(duration)
+	spritePath := path.Join(mediaCachePath, spriteURL.MediaName)
+
+	err = executable_worker.FfmpegCli.EncodeVideoSprite(video.Path, spritePath, layout.interval, videoSpriteTileWidth, layout.columns, layout.rows)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate sprite for video (%s)", video.Title)
+	}
+
+	spriteDimensions, err := media_utils.GetPhotoDimensions(spritePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "get dimensions of video sprite image")
+	}
+
+	spriteStats, err := os.Stat(spritePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading file stats of video sprite")
+	}
+
+	tileWidth := spriteDimensions.Width / layout.columns
+	tileHeight := spriteDimensions.Height / layout.rows
+
+	indexPath := path.Join(mediaCachePath, indexURL.MediaName)
+	index := videoSpriteIndex(spriteURL.MediaName, duration, layout, tileWidth, tileHeight)
+
+	if err := os.WriteFile(indexPath, []byte(index), 0644); err != nil {
+		return nil, errors.Wrap(err, "write video sprite index")
+	}
+
+	spriteURL.Width = spriteDimensions.Width
+	spriteURL.Height = spriteDimensions.Height
+	spriteURL.FileSize = spriteStats.Size()
+
+	indexURL.Width = tileWidth
+	indexURL.Height = tileHeight
+	indexURL.FileSize = int64(len(index))
+
+	if err := ctx.GetDB().Save(spriteURL).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to save video sprite to database (%s)", video.Title)
+	}
+
+	if err := ctx.GetDB().Save(indexURL).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to save video sprite index to database (%s)", video.Title)
+	}
+
+	return []*models.MediaURL{spriteURL, indexURL}, nil
+}
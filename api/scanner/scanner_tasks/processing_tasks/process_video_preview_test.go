@@ -0,0 +1,46 @@
+package processing_tasks
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = flag.Bool("database", false, "run database integration tests")
+var _ = flag.Bool("filesystem", false, "run filesystem integration tests")
+
+func TestMakeVideoSpriteLayout(t *testing.T) {
+	short := makeVideoSpriteLayout(4.5)
+	assert.Equal(t, videoSpriteLayout{interval: 1, frames: 5, columns: 5, rows: 1}, short)
+
+	long := makeVideoSpriteLayout(1000)
+	assert.Equal(t, 10.0, long.interval)
+	assert.Equal(t, 100, long.frames)
+	assert.Equal(t, 10, long.columns)
+	assert.Equal(t, 10, long.rows)
+}
+
+func TestVideoSpriteIndex(t *testing.T) {
+	layout := videoSpriteLayout{interval: 2, frames: 3, columns: 2, rows: 2}
+
+	index := videoSpriteIndex("sprite.jpg", 5.5, layout, 160, 90)
+
+	expected := `WEBVTT
+
+00:00:00.000 --> 00:00:02.000
+sprite.jpg#xywh=0,0,160,90
+
+00:00:02.000 --> 00:00:04.000
+sprite.jpg#xywh=160,0,160,90
+
+00:00:04.000 --> 00:00:05.500
+sprite.jpg#xywh=0,90,160,90
+`
+
+	assert.Equal(t, expected, index)
+}
+
+func TestVttTimestamp(t *testing.T) {
+	assert.Equal(t, "01:02:03.250", vttTimestamp(3723.25))
+}
@@ -94,7 +94,12 @@ func (t ProcessVideoTask) ProcessMedia(ctx scanner_task.TaskContext, mediaData *
 
 		webVideoPath := path.Join(mediaCachePath, web_video_name)
 
-		err = executable_worker.FfmpegCli.EncodeMp4(video.Path, webVideoPath)
+		siteInfo, err := models.GetSiteInfo(ctx.GetDB())
+		if err != nil {
+			return []*models.MediaURL{}, errors.Wrap(err, "get video transcode profile")
+		}
+
+		err = executable_worker.FfmpegCli.EncodeMp4(video.Path, webVideoPath, siteInfo.VideoTranscodeProfile)
 		if err != nil {
 			return []*models.MediaURL{}, errors.Wrapf(err, "could not encode mp4 video (%s)", video.Path)
 		}
@@ -202,6 +207,22 @@ func (t ProcessVideoTask) ProcessMedia(ctx scanner_task.TaskContext, mediaData *
 		}
 	}
 
+	if probeData.Format.DurationSeconds > 0 {
+		previewURL, err := processVideoPreview(ctx, video, probeData, mediaCachePath)
+		if err != nil {
+			return []*models.MediaURL{}, err
+		}
+		if previewURL != nil {
+			updatedURLs = append(updatedURLs, previewURL)
+		}
+
+		spriteURLs, err := processVideoSprite(ctx, video, probeData, mediaCachePath)
+		if err != nil {
+			return []*models.MediaURL{}, err
+		}
+		updatedURLs = append(updatedURLs, spriteURLs...)
+	}
+
 	return updatedURLs, nil
 }
 
@@ -32,6 +32,20 @@ func makePhotoURLChecker(tx *gorm.DB, mediaID int) func(purpose models.MediaPurp
 	}
 }
 
+// mediaProjection returns the projection of the media from its GPano metadata, as saved by the exif task
+func mediaProjection(tx *gorm.DB, media *models.Media) (models.MediaProjection, error) {
+	var exif []*models.MediaEXIF
+	if err := tx.Where("id = (SELECT exif_id FROM media WHERE id = ?)", media.ID).Find(&exif).Error; err != nil {
+		return models.MediaProjectionFlat, errors.Wrapf(err, "get exif of media (%d)", media.ID)
+	}
+
+	if len(exif) == 0 {
+		return models.MediaProjectionFlat, nil
+	}
+
+	return exif[0].Projection(), nil
+}
+
 func generateUniqueMediaNamePrefixed(prefix string, mediaPath string, extension string) string {
 	mediaName := fmt.Sprintf("%s_%s_%s", prefix, path.Base(mediaPath), utils.GenerateToken())
 	mediaName = models.SanitizeMediaName(mediaName)
@@ -0,0 +1,251 @@
+package scanner_tasks
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/graphql/notification"
+	"github.com/photoview/photoview/api/scanner/scanner_task"
+	"github.com/photoview/photoview/api/scanner/storage_stats"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+)
+
+// QuotaTask skips new media of albums whose owners have exceeded their storage quota
+type QuotaTask struct {
+	scanner_task.ScannerTaskBase
+}
+
+type quotaTaskKey string
+
+const albumQuotaKey quotaTaskKey = "album_quota_key"
+
+// ownerQuota is what remains of the quota of an owner of the album being scanned.
+// It is shared by all albums of the owner that are scanned at the same time, and guarded by ownerQuotasMutex.
+type ownerQuota struct {
+	userID         int
+	username       string
+	remainingCount *int
+	remainingSize  *int64
+	scans          int
+}
+
+var (
+	ownerQuotasMutex sync.Mutex
+	ownerQuotas      = make(map[int]*ownerQuota)
+)
+
+// acquireOwnerQuota returns the quota of the user shared with the scans of other albums of the user that are in progress,
+// or loads it if the user has no albums being scanned. It must be released with releaseOwnerQuota.
+func acquireOwnerQuota(userID int, load func() (*ownerQuota, error)) (*ownerQuota, error) {
+	ownerQuotasMutex.Lock()
+	defer ownerQuotasMutex.Unlock()
+
+	quota, found := ownerQuotas[userID]
+	if !found {
+		var err error
+		if quota, err = load(); err != nil {
+			return nil, err
+		}
+		quota.userID = userID
+		ownerQuotas[userID] = quota
+	}
+
+	quota.scans++
+	return quota, nil
+}
+
+// releaseOwnerQuota forgets the shared quota once no album of the user is being scanned,
+// so the next scan reads the storage used from the database again
+func releaseOwnerQuota(quota *ownerQuota) {
+	ownerQuotasMutex.Lock()
+	defer ownerQuotasMutex.Unlock()
+
+	quota.scans--
+	if quota.scans <= 0 {
+		delete(ownerQuotas, quota.userID)
+	}
+}
+
+func (q *ownerQuota) allows(size int64) bool {
+	if q.remainingCount != nil && *q.remainingCount < 1 {
+		return false
+	}
+
+	if q.remainingSize != nil && *q.remainingSize < size {
+		return false
+	}
+
+	return true
+}
+
+func (q *ownerQuota) use(size int64) {
+	if q.remainingCount != nil {
+		*q.remainingCount--
+	}
+
+	if q.remainingSize != nil {
+		*q.remainingSize -= size
+	}
+}
+
+type albumQuota struct {
+	owners   []*ownerQuota
+	skipped  int
+	exceeded map[string]bool
+	released bool
+}
+
+// ReleaseAlbumQuota releases the quotas of the owners of the album acquired by QuotaTask.BeforeScanAlbum,
+// it must be called once the scan of the album has ended, whether it succeeded or not
+func ReleaseAlbumQuota(ctx scanner_task.TaskContext) {
+	quota := getAlbumQuota(ctx)
+	if quota == nil || quota.released {
+		return
+	}
+
+	quota.released = true
+	for _, owner := range quota.owners {
+		releaseOwnerQuota(owner)
+	}
+}
+
+// reserve uses the size of a new media from the quotas of all owners,
+// or returns false and records the exceeded owners if any of them does not allow it
+func (q *albumQuota) reserve(size int64) bool {
+	ownerQuotasMutex.Lock()
+	defer ownerQuotasMutex.Unlock()
+
+	allowed := true
+	for _, owner := range q.owners {
+		if !owner.allows(size) {
+			q.exceeded[owner.username] = true
+			allowed = false
+		}
+	}
+
+	if !allowed {
+		return false
+	}
+
+	for _, owner := range q.owners {
+		owner.use(size)
+	}
+
+	return true
+}
+
+func getAlbumQuota(ctx scanner_task.TaskContext) *albumQuota {
+	quota, _ := ctx.Value(albumQuotaKey).(*albumQuota)
+	return quota
+}
+
+func (t QuotaTask) BeforeScanAlbum(ctx scanner_task.TaskContext) (scanner_task.TaskContext, error) {
+	var owners []*models.User
+	err := ctx.GetDB().
+		Where("id IN (?)", ctx.GetDB().Table("user_albums").Select("user_id").Where("album_id = ?", ctx.GetAlbum().ID)).
+		Where("max_media_count IS NOT NULL OR max_storage_size IS NOT NULL").
+		Find(&owners).Error
+	if err != nil {
+		return ctx, errors.Wrap(err, "get album owners with a quota")
+	}
+
+	quota := albumQuota{
+		owners:   make([]*ownerQuota, 0, len(owners)),
+		exceeded: make(map[string]bool),
+	}
+
+	for _, owner := range owners {
+		ownerQuota, err := acquireOwnerQuota(owner.ID, func() (*ownerQuota, error) {
+			return loadOwnerQuota(ctx, owner)
+		})
+		if err != nil {
+			for _, acquired := range quota.owners {
+				releaseOwnerQuota(acquired)
+			}
+			return ctx, err
+		}
+
+		quota.owners = append(quota.owners, ownerQuota)
+	}
+
+	return ctx.WithValue(albumQuotaKey, &quota), nil
+}
+
+// loadOwnerQuota reads the remaining quota of the owner from the storage used by the media of the owner
+func loadOwnerQuota(ctx scanner_task.TaskContext, owner *models.User) (*ownerQuota, error) {
+	storage, err := storage_stats.GetUserStorage(ctx.GetDB(), owner.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get storage of user (%s)", owner.Username)
+	}
+
+	quota := ownerQuota{username: owner.Username}
+
+	if owner.MaxMediaCount != nil {
+		remainingCount := *owner.MaxMediaCount - storage.MediaCount()
+		quota.remainingCount = &remainingCount
+	}
+
+	if owner.MaxStorageSize != nil {
+		remainingSize := *owner.MaxStorageSize - storage.OriginalSize
+		quota.remainingSize = &remainingSize
+	}
+
+	return &quota, nil
+}
+
+func (t QuotaTask) MediaFound(ctx scanner_task.TaskContext, fileInfo fs.FileInfo, mediaPath string) (bool, error) {
+	quota := getAlbumQuota(ctx)
+	if quota == nil || len(quota.owners) == 0 {
+		return false, nil
+	}
+
+	// Media that has already been scanned is part of the storage used, and is never skipped
+	var existingCount int64
+	err := ctx.GetDB().Model(&models.Media{}).
+		Where("path_hash = ?", models.MD5Hash(utils.RemoveSymbol(mediaPath))).
+		Count(&existingCount).Error
+	if err != nil {
+		return false, errors.Wrap(err, "check if media exists")
+	}
+
+	if existingCount > 0 {
+		return false, nil
+	}
+
+	if !quota.reserve(fileInfo.Size()) {
+		log.Printf("Skipping media %s, the storage quota of the album owners is exceeded\n", mediaPath)
+		quota.skipped++
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (t QuotaTask) AfterScanAlbum(ctx scanner_task.TaskContext, changedMedia []*models.Media, albumMedia []*models.Media) error {
+	quota := getAlbumQuota(ctx)
+	if quota == nil || quota.skipped == 0 {
+		return nil
+	}
+
+	usernames := make([]string, 0, len(quota.exceeded))
+	for _, owner := range quota.owners {
+		if quota.exceeded[owner.username] {
+			usernames = append(usernames, owner.username)
+		}
+	}
+
+	notification.BroadcastNotification(&models.Notification{
+		Key:      fmt.Sprintf("quota-exceeded-%d", ctx.GetAlbum().ID),
+		Type:     models.NotificationTypeMessage,
+		Negative: true,
+		Header:   fmt.Sprintf("Storage quota exceeded for album '%s'", ctx.GetAlbum().Title),
+		Content:  fmt.Sprintf("Skipped %d new media, as the quota of %s has been reached", quota.skipped, strings.Join(usernames, ", ")),
+	})
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package scanner_tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnerQuotaSharedByConcurrentScans(t *testing.T) {
+	loads := 0
+	load := func() (*ownerQuota, error) {
+		loads++
+		remainingCount := 1
+		return &ownerQuota{username: "user", remainingCount: &remainingCount}, nil
+	}
+
+	first, err := acquireOwnerQuota(1, load)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	second, err := acquireOwnerQuota(1, load)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, loads)
+
+	firstAlbum := albumQuota{owners: []*ownerQuota{first}, exceeded: make(map[string]bool)}
+	secondAlbum := albumQuota{owners: []*ownerQuota{second}, exceeded: make(map[string]bool)}
+
+	assert.True(t, firstAlbum.reserve(100))
+	assert.False(t, secondAlbum.reserve(100), "the quota is used up by the scan of the other album")
+	assert.True(t, secondAlbum.exceeded["user"])
+
+	releaseOwnerQuota(first)
+	releaseOwnerQuota(second)
+
+	third, err := acquireOwnerQuota(1, load)
+	if assert.NoError(t, err) {
+		assert.NotSame(t, first, third)
+		assert.Equal(t, 2, loads, "the quota is loaded again once no album of the user is being scanned")
+		releaseOwnerQuota(third)
+	}
+}
@@ -14,6 +14,7 @@ var allTasks []scanner_task.ScannerTask = []scanner_task.ScannerTask{
 	NotificationTask{},
 	IgnorefileTask{},
 	processing_tasks.CounterpartFilesTask{},
+	QuotaTask{},
 	processing_tasks.SidecarTask{},
 	processing_tasks.ProcessPhotoTask{},
 	processing_tasks.ProcessVideoTask{},
@@ -21,6 +22,8 @@ var allTasks []scanner_task.ScannerTask = []scanner_task.ScannerTask{
 	ExifTask{},
 	VideoMetadataTask{},
 	cleanup_tasks.MediaCleanupTask{},
+	StackTask{},
+	StorageStatsTask{},
 }
 
 type scannerTasks struct {
@@ -0,0 +1,187 @@
+package scanner_tasks
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/scanner_task"
+	"github.com/pkg/errors"
+)
+
+// StackTask groups the frames of bursts, exposure brackets and filename sequences into media stacks
+type StackTask struct {
+	scanner_task.ScannerTaskBase
+}
+
+// Max number of seconds between two frames of the same stack
+const stackMaxFrameGap = 1
+
+var filenameSequenceRegex = regexp.MustCompile(`^(.*?)([0-9]+)(\.[^.]*)?$`)
+
+// detectedStack is a group of media that should be stacked
+type detectedStack struct {
+	kind  models.MediaStackKind
+	media []*models.Media
+}
+
+func (t StackTask) AfterScanAlbum(ctx scanner_task.TaskContext, changedMedia []*models.Media, albumMedia []*models.Media) error {
+	db := ctx.GetDB()
+	albumID := ctx.GetAlbum().ID
+
+	var candidates []*models.Media
+	err := db.Preload("Exif").
+		Where("album_id = ?", albumID).
+		Where("stack_checked = ?", false).
+		Where("stack_id IS NULL").
+		Where("motion_photo_id IS NULL").
+		Where("type = ?", models.MediaTypePhoto).
+		Find(&candidates).Error
+	if err != nil {
+		return errors.Wrap(err, "get media to check for stacks")
+	}
+
+	// stacks can lose media to deletes and rescans even when there is nothing new to stack
+	if len(candidates) == 0 {
+		return models.CleanupMediaStacks(db, albumID)
+	}
+
+	for _, stack := range detectStacks(candidates) {
+		mediaStack := models.MediaStack{
+			AlbumID:    albumID,
+			TopMediaID: &stack.media[0].ID,
+			Kind:       stack.kind,
+		}
+
+		if err := db.Create(&mediaStack).Error; err != nil {
+			return errors.Wrap(err, "create media stack")
+		}
+
+		mediaIDs := make([]int, len(stack.media))
+		for i, media := range stack.media {
+			mediaIDs[i] = media.ID
+		}
+
+		if err := db.Model(&models.Media{}).Where("id IN (?)", mediaIDs).Update("stack_id", mediaStack.ID).Error; err != nil {
+			return errors.Wrap(err, "add media to stack")
+		}
+	}
+
+	candidateIDs := make([]int, len(candidates))
+	for i, media := range candidates {
+		candidateIDs[i] = media.ID
+	}
+
+	if err := db.Model(&models.Media{}).Where("id IN (?)", candidateIDs).Update("stack_checked", true).Error; err != nil {
+		return errors.Wrap(err, "mark media as checked for stacks")
+	}
+
+	return models.CleanupMediaStacks(db, albumID)
+}
+
+// detectStacks groups consecutive media shot within a second of each other,
+// that share a burst id, follow each other in a bracket sequence or in filename numbering
+func detectStacks(media []*models.Media) []detectedStack {
+	sorted := make([]*models.Media, len(media))
+	copy(sorted, media)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].DateShot.Equal(sorted[j].DateShot) {
+			return sorted[i].DateShot.Before(sorted[j].DateShot)
+		}
+		return sorted[i].Title < sorted[j].Title
+	})
+
+	stacks := make([]detectedStack, 0)
+	var current *detectedStack
+
+	for i := 1; i < len(sorted); i++ {
+		kind := stackLink(sorted[i-1], sorted[i])
+
+		if kind == nil {
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			stacks = append(stacks, detectedStack{
+				kind:  *kind,
+				media: []*models.Media{sorted[i-1]},
+			})
+			current = &stacks[len(stacks)-1]
+		}
+
+		current.media = append(current.media, sorted[i])
+		if stackKindPriority(*kind) > stackKindPriority(current.kind) {
+			current.kind = *kind
+		}
+	}
+
+	return stacks
+}
+
+// stackLink returns the kind of stack the two consecutive media would form, or nil if they are unrelated
+func stackLink(prev *models.Media, next *models.Media) *models.MediaStackKind {
+	if next.DateShot.Unix()-prev.DateShot.Unix() > stackMaxFrameGap {
+		return nil
+	}
+
+	var kind models.MediaStackKind
+
+	switch {
+	case prev.Exif != nil && next.Exif != nil && prev.Exif.BurstID != nil && next.Exif.BurstID != nil:
+		if *prev.Exif.BurstID != *next.Exif.BurstID {
+			return nil
+		}
+		kind = models.MediaStackKindBurst
+	case prev.Exif != nil && next.Exif != nil && prev.Exif.SequenceNumber != nil && next.Exif.SequenceNumber != nil &&
+		*next.Exif.SequenceNumber == *prev.Exif.SequenceNumber+1:
+		kind = models.MediaStackKindBracket
+	case isFilenameSequence(prev.Title, next.Title):
+		kind = models.MediaStackKindSequence
+	default:
+		return nil
+	}
+
+	return &kind
+}
+
+// isFilenameSequence returns true if the two filenames only differ by a number increased by one, eg. IMG_0041.JPG and IMG_0042.JPG
+func isFilenameSequence(prev string, next string) bool {
+	prevMatch := filenameSequenceRegex.FindStringSubmatch(prev)
+	nextMatch := filenameSequenceRegex.FindStringSubmatch(next)
+
+	if prevMatch == nil || nextMatch == nil {
+		return false
+	}
+
+	if prevMatch[1] != nextMatch[1] || prevMatch[3] != nextMatch[3] {
+		return false
+	}
+
+	prevNumber, err := strconv.Atoi(prevMatch[2])
+	if err != nil {
+		return false
+	}
+
+	nextNumber, err := strconv.Atoi(nextMatch[2])
+	if err != nil {
+		return false
+	}
+
+	return nextNumber == prevNumber+1
+}
+
+func stackKindPriority(kind models.MediaStackKind) int {
+	switch kind {
+	case models.MediaStackKindBurst:
+		return 3
+	case models.MediaStackKindBracket:
+		return 2
+	case models.MediaStackKindSequence:
+		return 1
+	default:
+		return 0
+	}
+}
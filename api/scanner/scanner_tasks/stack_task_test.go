@@ -0,0 +1,99 @@
+package scanner_tasks
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = flag.Bool("database", false, "run database integration tests")
+var _ = flag.Bool("filesystem", false, "run filesystem integration tests")
+
+func makeStackMedia(title string, date time.Time, exif *models.MediaEXIF) *models.Media {
+	return &models.Media{
+		Title:    title,
+		DateShot: date,
+		Exif:     exif,
+	}
+}
+
+func stackTitles(stack detectedStack) []string {
+	titles := make([]string, len(stack.media))
+	for i, media := range stack.media {
+		titles[i] = media.Title
+	}
+	return titles
+}
+
+func TestDetectStacks(t *testing.T) {
+	start := time.Date(2021, 9, 27, 16, 0, 0, 0, time.UTC)
+	burstA := "burst-a"
+	burstB := "burst-b"
+	seq := func(n int64) *models.MediaEXIF {
+		return &models.MediaEXIF{SequenceNumber: &n}
+	}
+
+	t.Run("burst id", func(t *testing.T) {
+		stacks := detectStacks([]*models.Media{
+			makeStackMedia("b.jpg", start.Add(500*time.Millisecond), &models.MediaEXIF{BurstID: &burstA}),
+			makeStackMedia("a.jpg", start, &models.MediaEXIF{BurstID: &burstA}),
+			makeStackMedia("c.jpg", start.Add(time.Second), &models.MediaEXIF{BurstID: &burstB}),
+		})
+
+		if assert.Len(t, stacks, 1) {
+			assert.Equal(t, models.MediaStackKindBurst, stacks[0].kind)
+			assert.Equal(t, []string{"a.jpg", "b.jpg"}, stackTitles(stacks[0]))
+		}
+	})
+
+	t.Run("exposure bracket", func(t *testing.T) {
+		stacks := detectStacks([]*models.Media{
+			makeStackMedia("x.jpg", start, seq(1)),
+			makeStackMedia("y.jpg", start, seq(2)),
+			makeStackMedia("z.jpg", start.Add(time.Second), seq(3)),
+		})
+
+		if assert.Len(t, stacks, 1) {
+			assert.Equal(t, models.MediaStackKindBracket, stacks[0].kind)
+			assert.Len(t, stacks[0].media, 3)
+		}
+	})
+
+	t.Run("filename sequence", func(t *testing.T) {
+		stacks := detectStacks([]*models.Media{
+			makeStackMedia("IMG_0041.JPG", start, nil),
+			makeStackMedia("IMG_0042.JPG", start, nil),
+			makeStackMedia("IMG_0043.JPG", start.Add(10*time.Second), nil),
+			makeStackMedia("IMG_0044.JPG", start.Add(11*time.Second), nil),
+			makeStackMedia("IMG_0046.JPG", start.Add(11*time.Second), nil),
+		})
+
+		if assert.Len(t, stacks, 2) {
+			assert.Equal(t, models.MediaStackKindSequence, stacks[0].kind)
+			assert.Equal(t, []string{"IMG_0041.JPG", "IMG_0042.JPG"}, stackTitles(stacks[0]))
+			assert.Equal(t, []string{"IMG_0043.JPG", "IMG_0044.JPG"}, stackTitles(stacks[1]))
+		}
+	})
+
+	t.Run("unrelated media", func(t *testing.T) {
+		stacks := detectStacks([]*models.Media{
+			makeStackMedia("beach.jpg", start, nil),
+			makeStackMedia("sunset.jpg", start, nil),
+			makeStackMedia("IMG_0001.JPG", start.Add(time.Hour), nil),
+			makeStackMedia("IMG_0002.JPG", start.Add(2*time.Hour), nil),
+		})
+
+		assert.Empty(t, stacks)
+	})
+}
+
+func TestIsFilenameSequence(t *testing.T) {
+	assert.True(t, isFilenameSequence("IMG_0099.JPG", "IMG_0100.JPG"))
+	assert.True(t, isFilenameSequence("DSC9", "DSC10"))
+	assert.False(t, isFilenameSequence("IMG_0001.JPG", "IMG_0001.JPG"))
+	assert.False(t, isFilenameSequence("IMG_0001.JPG", "IMG_0002.CR2"))
+	assert.False(t, isFilenameSequence("IMG_0001.JPG", "DSC_0002.JPG"))
+}
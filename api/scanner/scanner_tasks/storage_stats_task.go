@@ -0,0 +1,17 @@
+package scanner_tasks
+
+import (
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/scanner/scanner_task"
+	"github.com/photoview/photoview/api/scanner/storage_stats"
+)
+
+// StorageStatsTask invalidates the cached storage statistics of albums, as their media may have changed during the scan
+type StorageStatsTask struct {
+	scanner_task.ScannerTaskBase
+}
+
+func (t StorageStatsTask) AfterScanAlbum(ctx scanner_task.TaskContext, changedMedia []*models.Media, albumMedia []*models.Media) error {
+	storage_stats.InvalidateAlbum(ctx.GetAlbum().ID)
+	return nil
+}
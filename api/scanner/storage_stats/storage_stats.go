@@ -0,0 +1,193 @@
+package storage_stats
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/photoview/photoview/api/graphql/models"
+	"github.com/photoview/photoview/api/utils"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// AlbumStorage is the storage used by the media directly inside an album, not including its sub albums
+type AlbumStorage struct {
+	PhotoCount int
+	VideoCount int
+	// OriginalSize is the total size in bytes of the original media files
+	OriginalSize int64
+	// CacheSize is the total size in bytes of the files generated for the album in the media cache
+	CacheSize int64
+}
+
+// MediaCount returns the number of photos and videos of the album
+func (s AlbumStorage) MediaCount() int {
+	return s.PhotoCount + s.VideoCount
+}
+
+// Add returns the sum of the storage of two albums
+func (s AlbumStorage) Add(other AlbumStorage) AlbumStorage {
+	return AlbumStorage{
+		PhotoCount:   s.PhotoCount + other.PhotoCount,
+		VideoCount:   s.VideoCount + other.VideoCount,
+		OriginalSize: s.OriginalSize + other.OriginalSize,
+		CacheSize:    s.CacheSize + other.CacheSize,
+	}
+}
+
+// Computing the storage of an album reads the size of all of its files,
+// so it is cached until the scanner or a deletion invalidates it
+var albumStorageCache = struct {
+	sync.Mutex
+	albums map[int]AlbumStorage
+}{
+	albums: make(map[int]AlbumStorage),
+}
+
+// InvalidateAlbum removes the cached storage of the album, it is computed again the next time it is requested
+func InvalidateAlbum(albumID int) {
+	albumStorageCache.Lock()
+	defer albumStorageCache.Unlock()
+
+	delete(albumStorageCache.albums, albumID)
+}
+
+// InvalidateAll removes the cached storage of all albums
+func InvalidateAll() {
+	albumStorageCache.Lock()
+	defer albumStorageCache.Unlock()
+
+	albumStorageCache.albums = make(map[int]AlbumStorage)
+}
+
+// GetAlbumStorage returns the storage used by the media directly inside the album
+func GetAlbumStorage(db *gorm.DB, albumID int) (AlbumStorage, error) {
+	albumStorageCache.Lock()
+	storage, found := albumStorageCache.albums[albumID]
+	albumStorageCache.Unlock()
+
+	if found {
+		return storage, nil
+	}
+
+	storage, err := computeAlbumStorage(db, albumID)
+	if err != nil {
+		return AlbumStorage{}, err
+	}
+
+	albumStorageCache.Lock()
+	albumStorageCache.albums[albumID] = storage
+	albumStorageCache.Unlock()
+
+	return storage, nil
+}
+
+// GetUserStorage returns the storage used by all albums of the user.
+// Every album is counted by itself, as the albums of a user include their sub albums.
+func GetUserStorage(db *gorm.DB, userID int) (AlbumStorage, error) {
+	var albumIDs []int
+	if err := db.Table("user_albums").Where("user_id = ?", userID).Pluck("album_id", &albumIDs).Error; err != nil {
+		return AlbumStorage{}, errors.Wrapf(err, "get albums of user (%d)", userID)
+	}
+
+	userStorage := AlbumStorage{}
+	for _, albumID := range albumIDs {
+		storage, err := GetAlbumStorage(db, albumID)
+		if err != nil {
+			return AlbumStorage{}, err
+		}
+		userStorage = userStorage.Add(storage)
+	}
+
+	return userStorage, nil
+}
+
+type albumMediaFile struct {
+	ID           int
+	Path         string
+	Type         models.MediaType
+	MotionPhoto  bool
+	OriginalSize *int64
+}
+
+func computeAlbumStorage(db *gorm.DB, albumID int) (AlbumStorage, error) {
+	var files []*albumMediaFile
+	err := db.Table("media").
+		Select("media.id, media.path, media.type, media.motion_photo_id IS NOT NULL AS motion_photo, media_urls.file_size AS original_size").
+		Joins("LEFT JOIN media_urls ON media_urls.media_id = media.id AND media_urls.purpose = ?", models.MediaOriginal).
+		Where("media.album_id = ?", albumID).
+		Scan(&files).Error
+	if err != nil {
+		return AlbumStorage{}, errors.Wrapf(err, "get media files of album (%d)", albumID)
+	}
+
+	storage := AlbumStorage{}
+	for _, file := range files {
+		// The video of a motion photo is part of the photo
+		if !file.MotionPhoto {
+			switch file.Type {
+			case models.MediaTypePhoto:
+				storage.PhotoCount++
+			case models.MediaTypeVideo:
+				storage.VideoCount++
+			}
+		}
+
+		if file.OriginalSize != nil && *file.OriginalSize > 0 {
+			storage.OriginalSize += *file.OriginalSize
+			continue
+		}
+
+		// Only web compatible originals have a media url, the size of other files is read from the filesystem
+		if fileInfo, err := os.Stat(file.Path); err == nil {
+			storage.OriginalSize += fileInfo.Size()
+		}
+	}
+
+	cacheSize, err := DirectorySize(path.Join(utils.MediaCachePath(), strconv.Itoa(albumID)))
+	if err != nil {
+		return AlbumStorage{}, errors.Wrapf(err, "read media cache size of album (%d)", albumID)
+	}
+	storage.CacheSize = cacheSize
+
+	return storage, nil
+}
+
+// DirectorySize returns the total size in bytes of the files in the directory and its sub directories.
+// A directory that does not exist has a size of zero.
+func DirectorySize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		size += info.Size()
+		return nil
+	})
+
+	return size, err
+}
+
+// RecycleBinSize returns the size in bytes of the media deleted from the directory at mediaPath,
+// or of the entire recycle bin if mediaPath is empty
+func RecycleBinSize(mediaPath string) (int64, error) {
+	return DirectorySize(path.Join(utils.RecyclePath(), mediaPath))
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"github.com/photoview/photoview/api/scanner"
 	"io"
 	"log"
 	"net/http"
@@ -17,11 +16,15 @@ import (
 	"github.com/photoview/photoview/api/dataloader"
 	"github.com/photoview/photoview/api/graphql/auth"
 	graphql_endpoint "github.com/photoview/photoview/api/graphql/endpoint"
+	"github.com/photoview/photoview/api/graphql/models/actions"
+	"github.com/photoview/photoview/api/oidc"
 	"github.com/photoview/photoview/api/routes"
+	"github.com/photoview/photoview/api/scanner"
 	"github.com/photoview/photoview/api/scanner/exif"
 	"github.com/photoview/photoview/api/scanner/face_detection"
 	"github.com/photoview/photoview/api/scanner/media_encoding/executable_worker"
 	"github.com/photoview/photoview/api/scanner/periodic_scanner"
+	"github.com/photoview/photoview/api/scanner/reverse_geocoding"
 	"github.com/photoview/photoview/api/scanner/scanner_queue"
 	"github.com/photoview/photoview/api/server"
 	"github.com/photoview/photoview/api/utils"
@@ -65,10 +68,20 @@ func main() {
 		log.Panicf("Could not initialize periodic scanner: %s", err)
 	}
 
+	if err := auth.InitializeLoginLimiter(db); err != nil {
+		log.Panicf("Invalid login limiter configuration: %s\n", err)
+	}
+
+	auth.InitializeSessionCleanup(db)
+
+	actions.InitializeAuditLogCleanup(db)
+
 	executable_worker.InitializeExecutableWorkers()
 
 	exif.InitializeEXIFParser()
 
+	reverse_geocoding.InitializeGeocoder()
+
 	if err := face_detection.InitializeFaceDetector(db); err != nil {
 		log.Panicf("Could not initialize face detector: %s\n", err)
 	}
@@ -79,10 +92,15 @@ func main() {
 
 	go scanner_queue.AddAllToQueue(true)
 
+	proxyAuth, err := auth.ProxyAuthFromEnv()
+	if err != nil {
+		log.Panicf("Invalid reverse proxy authentication configuration: %s\n", err)
+	}
+
 	rootRouter := mux.NewRouter()
 
 	rootRouter.Use(dataloader.Middleware(db))
-	rootRouter.Use(auth.Middleware(db))
+	rootRouter.Use(auth.Middleware(db, proxyAuth))
 	rootRouter.Use(server.LoggingMiddleware)
 	rootRouter.Use(server.CORSMiddleware(devMode))
 
@@ -109,6 +127,16 @@ func main() {
 	downloadsRouter := endpointRouter.PathPrefix("/download").Subrouter()
 	routes.RegisterDownloadRoutes(db, downloadsRouter)
 
+	oidcConfig, err := oidc.ConfigFromEnv()
+	if err != nil {
+		log.Panicf("Invalid OpenID Connect configuration: %s\n", err)
+	}
+
+	if oidcConfig != nil {
+		oidcRouter := endpointRouter.PathPrefix("/oidc").Subrouter()
+		routes.RegisterOIDCRoutes(db, oidcRouter, oidc.NewProvider(*oidcConfig))
+	}
+
 	shouldServeUI := utils.ShouldServeUI()
 
 	if shouldServeUI {
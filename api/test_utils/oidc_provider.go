@@ -0,0 +1,167 @@
+package test_utils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockOIDCProvider is a local OpenID Connect provider, that issues RS256 signed id tokens
+// for the claims of the next login
+type MockOIDCProvider struct {
+	Server   *httptest.Server
+	ClientID string
+	Key      *rsa.PrivateKey
+
+	lock sync.Mutex
+	// Claims are added to the id token of the next login, along with the standard claims
+	Claims map[string]interface{}
+	codes  map[string]mockOIDCAuthorization
+}
+
+type mockOIDCAuthorization struct {
+	nonce         string
+	codeChallenge string
+	claims        map[string]interface{}
+}
+
+// NewMockOIDCProvider starts a mock provider that is closed when the test finishes
+func NewMockOIDCProvider(t *testing.T, clientID string) *MockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key of mock oidc provider: %s", err)
+	}
+
+	provider := &MockOIDCProvider{
+		ClientID: clientID,
+		Key:      key,
+		Claims:   make(map[string]interface{}),
+		codes:    make(map[string]mockOIDCAuthorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("/authorize", provider.authorize)
+	mux.HandleFunc("/token", provider.token)
+	mux.HandleFunc("/keys", provider.keys)
+
+	provider.Server = httptest.NewServer(mux)
+	t.Cleanup(provider.Server.Close)
+
+	return provider
+}
+
+// Issuer is the url of the provider, which is also its discovery url
+func (p *MockOIDCProvider) Issuer() string {
+	return p.Server.URL
+}
+
+func (p *MockOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/keys",
+	})
+}
+
+func (p *MockOIDCProvider) keys(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": "mock",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.Key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.Key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize logs in immediately and redirects back with a code
+func (p *MockOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	codeBytes := make([]byte, 16)
+	rand.Read(codeBytes)
+	code := base64.RawURLEncoding.EncodeToString(codeBytes)
+
+	p.lock.Lock()
+	claims := make(map[string]interface{}, len(p.Claims))
+	for name, value := range p.Claims {
+		claims[name] = value
+	}
+	p.codes[code] = mockOIDCAuthorization{
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		claims:        claims,
+	}
+	p.lock.Unlock()
+
+	http.Redirect(w, r, query.Get("redirect_uri")+"?code="+code+"&state="+query.Get("state"), http.StatusFound)
+}
+
+// token exchanges a code for an id token, if the code verifier matches the code challenge
+func (p *MockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	p.lock.Lock()
+	authorization, found := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.lock.Unlock()
+
+	verifierHash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || base64.RawURLEncoding.EncodeToString(verifierHash[:]) != authorization.codeChallenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := map[string]interface{}{
+		"iss":   p.Issuer(),
+		"aud":   p.ClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": authorization.nonce,
+	}
+	for name, value := range authorization.claims {
+		claims[name] = value
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"id_token":     p.SignIDToken(claims),
+	})
+}
+
+// SignIDToken returns an id token with the claims, signed by the key of the provider
+func (p *MockOIDCProvider) SignIDToken(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "mock", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return strings.Join([]string{signed, base64.RawURLEncoding.EncodeToString(signature)}, ".")
+}
@@ -14,7 +14,7 @@ func RunScannerOnUser(t *testing.T, db *gorm.DB, user *models.User) {
 		return
 	}
 
-	if !assert.NoError(t, scanner_queue.AddUserToQueue(user)) {
+	if !assert.NoError(t, scanner_queue.AddUserToQueue(user, false)) {
 		return
 	}
 
@@ -27,7 +27,7 @@ func RunScannerAll(t *testing.T, db *gorm.DB) {
 		return
 	}
 
-	if !assert.NoError(t, scanner_queue.AddAllToQueue()) {
+	if !assert.NoError(t, scanner_queue.AddAllToQueue(false)) {
 		return
 	}
 
@@ -0,0 +1,90 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/photoview/photoview/api/test_utils"
+	"github.com/photoview/photoview/api/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(test_utils.UnitTestRun(m))
+}
+
+// Secret of the SHA1 test vectors in RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeAt(t *testing.T) {
+	// The last six digits of the eight digit codes in RFC 6238 appendix B
+	testVectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unixTime, expected := range testVectors {
+		code, err := totp.CodeAt(rfcSecret, totp.Counter(time.Unix(unixTime, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, unixTime)
+	}
+
+	_, err := totp.CodeAt("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := totp.Counter(now)
+
+	code, err := totp.CodeAt(rfcSecret, counter)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	validCounter, valid := totp.Validate(rfcSecret, code, now, 0)
+	assert.True(t, valid)
+	assert.Equal(t, counter, validCounter)
+
+	_, valid = totp.Validate(rfcSecret, code, now.Add(totp.Period*time.Second), 0)
+	assert.True(t, valid, "code of previous period is accepted")
+
+	_, valid = totp.Validate(rfcSecret, code, now.Add(3*totp.Period*time.Second), 0)
+	assert.False(t, valid, "old code is rejected")
+
+	_, valid = totp.Validate(rfcSecret, code, now, counter)
+	assert.False(t, valid, "code cannot be used twice")
+
+	_, valid = totp.Validate(rfcSecret, "000000", now, 0)
+	assert.False(t, valid)
+
+	_, valid = totp.Validate(rfcSecret, "12345", now, 0)
+	assert.False(t, valid)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, secret, 32)
+
+	_, err = totp.CodeAt(secret, 1)
+	assert.NoError(t, err)
+
+	uri, err := url.Parse(totp.KeyURI("Photoview", "alice", secret))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "otpauth", uri.Scheme)
+		assert.Equal(t, "totp", uri.Host)
+		assert.Equal(t, "/Photoview:alice", uri.Path)
+		assert.Equal(t, secret, uri.Query().Get("secret"))
+		assert.Equal(t, "Photoview", uri.Query().Get("issuer"))
+	}
+}
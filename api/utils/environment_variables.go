@@ -16,6 +16,8 @@ const (
 	EnvMediaCachePath            EnvironmentVariable = "PHOTOVIEW_MEDIA_CACHE"
 	EnvFaceRecognitionModelsPath EnvironmentVariable = "PHOTOVIEW_FACE_RECOGNITION_MODELS_PATH"
 	EnvRecyclePath               EnvironmentVariable = "PHOTOVIEW_RECYCLE_PATH"
+	EnvGeoNamesPath              EnvironmentVariable = "PHOTOVIEW_GEONAMES_PATH"
+	EnvRootPathAllowlist         EnvironmentVariable = "PHOTOVIEW_ROOT_PATH_ALLOWLIST"
 )
 
 // Network related
@@ -41,6 +43,35 @@ const (
 	EnvDisableRawProcessing   EnvironmentVariable = "PHOTOVIEW_DISABLE_RAW_PROCESSING"
 )
 
+// OpenID Connect related
+const (
+	EnvOIDCDiscoveryURL        EnvironmentVariable = "PHOTOVIEW_OIDC_DISCOVERY_URL"
+	EnvOIDCClientID            EnvironmentVariable = "PHOTOVIEW_OIDC_CLIENT_ID"
+	EnvOIDCClientSecret        EnvironmentVariable = "PHOTOVIEW_OIDC_CLIENT_SECRET"
+	EnvOIDCRedirectURL         EnvironmentVariable = "PHOTOVIEW_OIDC_REDIRECT_URL"
+	EnvOIDCScopes              EnvironmentVariable = "PHOTOVIEW_OIDC_SCOPES"
+	EnvOIDCUsernameClaim       EnvironmentVariable = "PHOTOVIEW_OIDC_USERNAME_CLAIM"
+	EnvOIDCAdminClaim          EnvironmentVariable = "PHOTOVIEW_OIDC_ADMIN_CLAIM"
+	EnvOIDCAdminValue          EnvironmentVariable = "PHOTOVIEW_OIDC_ADMIN_VALUE"
+	EnvOIDCRootPathsClaim      EnvironmentVariable = "PHOTOVIEW_OIDC_ROOT_PATHS_CLAIM"
+	EnvOIDCDisableProvisioning EnvironmentVariable = "PHOTOVIEW_OIDC_DISABLE_PROVISIONING"
+)
+
+// Reverse proxy authentication related
+const (
+	EnvProxyAuthHeader         EnvironmentVariable = "PHOTOVIEW_PROXY_AUTH_HEADER"
+	EnvProxyAuthTrustedProxies EnvironmentVariable = "PHOTOVIEW_PROXY_AUTH_TRUSTED_PROXIES"
+	EnvProxyAuthAutoCreate     EnvironmentVariable = "PHOTOVIEW_PROXY_AUTH_AUTO_CREATE"
+)
+
+// Login rate limiting related
+const (
+	EnvLoginMaxAttempts      EnvironmentVariable = "PHOTOVIEW_LOGIN_MAX_ATTEMPTS"
+	EnvLoginMaxAttemptsPerIP EnvironmentVariable = "PHOTOVIEW_LOGIN_MAX_ATTEMPTS_PER_IP"
+	EnvLoginLockoutMinutes   EnvironmentVariable = "PHOTOVIEW_LOGIN_LOCKOUT_MINUTES"
+	EnvLoginPersistLimiter   EnvironmentVariable = "PHOTOVIEW_LOGIN_PERSIST_LIMITER"
+)
+
 // ShootSoftware relates
 const (
 	EnvShootSoftware EnvironmentVariable = "ShootSoftware"
@@ -91,6 +122,12 @@ func UIPath() string {
 	return "./ui"
 }
 
+// FinalDirPath returns the directory that the final albums of the studio are copied to,
+// or an empty string if it has not been configured
+func FinalDirPath() string {
+	return EnvFinalDir.GetValue()
+}
+
 // UIPath returns the value from where the static UI files are located if SERVE_UI=1
 func RecyclePath() string {
 	if path := EnvRecyclePath.GetValue(); path != "" {
@@ -66,6 +66,28 @@ func FaceRecognitionModelsPath() string {
 	return EnvFaceRecognitionModelsPath.GetValue()
 }
 
+// GeoNamesPath returns the directory holding the GeoNames dataset used for reverse geocoding
+func GeoNamesPath() string {
+	if EnvGeoNamesPath.GetValue() == "" {
+		return path.Join("data", "geonames")
+	}
+
+	return EnvGeoNamesPath.GetValue()
+}
+
+// RootPathAllowlist returns the base directories that root paths of users must be inside of,
+// used until an admin configures the allowlist. An empty list allows any directory.
+func RootPathAllowlist() []string {
+	allowlist := make([]string, 0)
+	for _, dir := range filepath.SplitList(EnvRootPathAllowlist.GetValue()) {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			allowlist = append(allowlist, dir)
+		}
+	}
+
+	return allowlist
+}
+
 // IsDirSymlink checks that the given path is a symlink and resolves to a
 // directory.
 func IsDirSymlink(path string) (bool, error) {